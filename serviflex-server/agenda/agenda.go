@@ -0,0 +1,172 @@
+// Package agenda concentra as regras de expediente dos profissionais: quais intervalos
+// de um dia estão abertos para atendimento e quais horários ainda podem ser agendados.
+// As funções são puras e recebem os dados já carregados dos repositórios.
+package agenda
+
+import (
+	"servico-api/models"
+	"sort"
+	"time"
+)
+
+// Intervalo é um período de tempo fechado no início e aberto no fim: [Inicio, Fim)
+type Intervalo struct {
+	Inicio time.Time `json:"inicio"`
+	Fim    time.Time `json:"fim"`
+}
+
+// Contem indica se o período [inicio, inicio+duracao) cabe inteiro no intervalo
+func (i Intervalo) Contem(inicio time.Time, duracao time.Duration) bool {
+	return !inicio.Before(i.Inicio) && !inicio.Add(duracao).After(i.Fim)
+}
+
+// Expediente converte os horários cadastrados para o dia da semana de "dia" em intervalos
+// concretos naquela data, no fuso de "dia", já sem as pausas de cada turno. Horários com
+// hora inválida são ignorados.
+func Expediente(horarios []models.Horario, dia time.Time) []Intervalo {
+	var intervalos []Intervalo
+	for _, hr := range horarios {
+		turno, ok := intervaloNaData(dia, hr.HoraInicio, hr.HoraFim)
+		if !ok {
+			continue
+		}
+		partes := []Intervalo{turno}
+		for _, p := range hr.Pausas {
+			if pausa, ok := intervaloNaData(dia, p.HoraInicio, p.HoraFim); ok {
+				partes = recortar(partes, pausa)
+			}
+		}
+		intervalos = append(intervalos, partes...)
+	}
+	return intervalos
+}
+
+// intervaloNaData monta o intervalo entre duas horas "15:04" na data de "dia"
+func intervaloNaData(dia time.Time, horaInicio, horaFim string) (Intervalo, bool) {
+	inicio, ok := naData(dia, horaInicio)
+	if !ok {
+		return Intervalo{}, false
+	}
+	fim, ok := naData(dia, horaFim)
+	if !ok || !fim.After(inicio) {
+		return Intervalo{}, false
+	}
+	return Intervalo{Inicio: inicio, Fim: fim}, true
+}
+
+// naData combina a data de "dia" com uma hora no formato "15:04"
+func naData(dia time.Time, hora string) (time.Time, bool) {
+	h, err := time.Parse("15:04", hora)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Date(dia.Year(), dia.Month(), dia.Day(), h.Hour(), h.Minute(), 0, 0, dia.Location()), true
+}
+
+// AplicarExcecoes ajusta o expediente de "dia" às exceções que valem naquela data:
+// horários extras são acrescentados (e unidos a intervalos vizinhos) e bloqueios são
+// recortados. Um bloqueio de dia inteiro fecha o dia. Exceções de outras datas são ignoradas.
+func AplicarExcecoes(expediente []Intervalo, excecoes []models.ExcecaoHorario, dia time.Time) []Intervalo {
+	data := dia.Format("2006-01-02")
+	var bloqueios []Intervalo
+	resultado := append([]Intervalo(nil), expediente...)
+	for _, e := range excecoes {
+		if !e.Abrange(data) {
+			continue
+		}
+		if e.Tipo == models.ExcecaoBloqueio && e.DiaInteiro() {
+			return nil
+		}
+		periodo, ok := intervaloNaData(dia, e.HoraInicio, e.HoraFim)
+		if !ok {
+			continue
+		}
+		if e.Tipo == models.ExcecaoExtra {
+			resultado = append(resultado, periodo)
+		} else {
+			bloqueios = append(bloqueios, periodo)
+		}
+	}
+
+	resultado = unir(resultado)
+	for _, b := range bloqueios {
+		resultado = recortar(resultado, b)
+	}
+	return resultado
+}
+
+// unir ordena os intervalos e junta os que se sobrepõem ou se encostam
+func unir(intervalos []Intervalo) []Intervalo {
+	sort.Slice(intervalos, func(a, b int) bool { return intervalos[a].Inicio.Before(intervalos[b].Inicio) })
+	var unidos []Intervalo
+	for _, i := range intervalos {
+		if n := len(unidos); n > 0 && !i.Inicio.After(unidos[n-1].Fim) {
+			if i.Fim.After(unidos[n-1].Fim) {
+				unidos[n-1].Fim = i.Fim
+			}
+			continue
+		}
+		unidos = append(unidos, i)
+	}
+	return unidos
+}
+
+// recortar remove o período bloqueado de cada intervalo, dividindo-o se preciso
+func recortar(intervalos []Intervalo, bloqueio Intervalo) []Intervalo {
+	var restantes []Intervalo
+	for _, i := range intervalos {
+		if !bloqueio.Inicio.Before(i.Fim) || !i.Inicio.Before(bloqueio.Fim) {
+			restantes = append(restantes, i)
+			continue
+		}
+		if i.Inicio.Before(bloqueio.Inicio) {
+			restantes = append(restantes, Intervalo{Inicio: i.Inicio, Fim: bloqueio.Inicio})
+		}
+		if bloqueio.Fim.Before(i.Fim) {
+			restantes = append(restantes, Intervalo{Inicio: bloqueio.Fim, Fim: i.Fim})
+		}
+	}
+	return restantes
+}
+
+// Cabe indica se um atendimento iniciado em "inicio" termina dentro de algum intervalo
+func Cabe(expediente []Intervalo, inicio time.Time, duracao time.Duration) bool {
+	for _, i := range expediente {
+		if i.Contem(inicio, duracao) {
+			return true
+		}
+	}
+	return false
+}
+
+// HorariosLivres devolve os inícios em que um atendimento de "duracao" cabe no expediente
+// sem cruzar nenhum agendamento ocupado. Os candidatos avançam de "passo" em "passo" a
+// partir do início de cada intervalo; inícios anteriores a "aPartirDe" são descartados.
+func HorariosLivres(expediente []Intervalo, ocupados []models.Agendamento, duracao, passo time.Duration, aPartirDe time.Time) []time.Time {
+	livres := []time.Time{}
+	if passo <= 0 || duracao <= 0 {
+		return livres
+	}
+	for _, i := range expediente {
+		for inicio := i.Inicio; i.Contem(inicio, duracao); inicio = inicio.Add(passo) {
+			if inicio.Before(aPartirDe) {
+				continue
+			}
+			candidato := models.Agendamento{DataHora: inicio, DuracaoMin: int(duracao / time.Minute)}
+			if !ocupado(candidato, ocupados) {
+				livres = append(livres, inicio)
+			}
+		}
+	}
+	sort.Slice(livres, func(a, b int) bool { return livres[a].Before(livres[b]) })
+	return livres
+}
+
+func ocupado(candidato models.Agendamento, ocupados []models.Agendamento) bool {
+	for _, ag := range ocupados {
+		if ag.Ocupa() && ag.Sobrepoe(candidato) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,184 @@
+package agenda
+
+import (
+	"servico-api/models"
+	"testing"
+	"time"
+)
+
+func TestExpedienteECabe(t *testing.T) {
+	dia := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+	expediente := Expediente([]models.Horario{
+		{HoraInicio: "08:00", HoraFim: "12:00"},
+		{HoraInicio: "14:00", HoraFim: "18:00"},
+		{HoraInicio: "xx", HoraFim: "10:00"},    // inválido
+		{HoraInicio: "19:00", HoraFim: "18:00"}, // fim antes do início
+	}, dia)
+	if len(expediente) != 2 {
+		t.Fatalf("esperava 2 intervalos válidos, obtive %v", expediente)
+	}
+
+	casos := []struct {
+		hora    string
+		duracao time.Duration
+		cabe    bool
+	}{
+		{"08:00", 30 * time.Minute, true},
+		{"11:30", 30 * time.Minute, true},
+		{"11:45", 30 * time.Minute, false}, // passa do fim do turno
+		{"12:30", 30 * time.Minute, false}, // intervalo de almoço
+		{"07:50", 30 * time.Minute, false},
+		{"17:30", 30 * time.Minute, true},
+	}
+	for _, caso := range casos {
+		h, _ := time.Parse("15:04", caso.hora)
+		inicio := dia.Add(time.Duration(h.Hour())*time.Hour + time.Duration(h.Minute())*time.Minute)
+		if got := Cabe(expediente, inicio, caso.duracao); got != caso.cabe {
+			t.Errorf("%s: Cabe = %v, esperado %v", caso.hora, got, caso.cabe)
+		}
+	}
+}
+
+func TestExpedienteComPausas(t *testing.T) {
+	dia := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+	expediente := Expediente([]models.Horario{
+		{HoraInicio: "08:00", HoraFim: "18:00", Pausas: []models.Pausa{{HoraInicio: "12:00", HoraFim: "13:00"}, {HoraInicio: "15:00", HoraFim: "15:15"}}},
+	}, dia)
+	if len(expediente) != 3 {
+		t.Fatalf("esperava o turno dividido em 3 intervalos, obtive %v", expediente)
+	}
+	as := func(h, m int) time.Time { return dia.Add(time.Duration(h)*time.Hour + time.Duration(m)*time.Minute) }
+	if Cabe(expediente, as(11, 45), 30*time.Minute) {
+		t.Error("atendimento não deveria invadir o almoço")
+	}
+	if !Cabe(expediente, as(13, 0), time.Hour) || Cabe(expediente, as(14, 30), time.Hour) {
+		t.Error("pausa das 15:00 não respeitada")
+	}
+}
+
+func TestHorariosLivres(t *testing.T) {
+	dia := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+	as := func(h, m int) time.Time { return dia.Add(time.Duration(h)*time.Hour + time.Duration(m)*time.Minute) }
+	expediente := Expediente([]models.Horario{{HoraInicio: "09:00", HoraFim: "11:00"}}, dia)
+	ocupados := []models.Agendamento{
+		{DataHora: as(9, 30), DuracaoMin: 45}, // 09:30–10:15
+	}
+
+	livres := HorariosLivres(expediente, ocupados, 30*time.Minute, 15*time.Minute, as(9, 0))
+	esperados := []time.Time{as(9, 0), as(10, 15), as(10, 30)}
+	if len(livres) != len(esperados) {
+		t.Fatalf("livres = %v, esperado %v", livres, esperados)
+	}
+	for i := range esperados {
+		if !livres[i].Equal(esperados[i]) {
+			t.Errorf("livres[%d] = %v, esperado %v", i, livres[i], esperados[i])
+		}
+	}
+
+	// Horários já passados não são oferecidos
+	if livres := HorariosLivres(expediente, nil, 30*time.Minute, 30*time.Minute, as(10, 1)); len(livres) != 1 || !livres[0].Equal(as(10, 30)) {
+		t.Errorf("esperava só 10:30, obtive %v", livres)
+	}
+}
+
+func TestAplicarExcecoes(t *testing.T) {
+	dia := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+	as := func(h, m int) time.Time { return dia.Add(time.Duration(h)*time.Hour + time.Duration(m)*time.Minute) }
+	expediente := Expediente([]models.Horario{{HoraInicio: "08:00", HoraFim: "12:00"}}, dia)
+
+	ajustado := AplicarExcecoes(expediente, []models.ExcecaoHorario{
+		{Tipo: models.ExcecaoBloqueio, DataInicio: "2025-03-10", DataFim: "2025-03-10", HoraInicio: "09:00", HoraFim: "10:00"},
+		{Tipo: models.ExcecaoExtra, DataInicio: "2025-03-08", DataFim: "2025-03-12", HoraInicio: "12:00", HoraFim: "14:00"},
+		{Tipo: models.ExcecaoBloqueio, DataInicio: "2025-03-11", DataFim: "2025-03-11"}, // outro dia
+	}, dia)
+	esperado := []Intervalo{{as(8, 0), as(9, 0)}, {as(10, 0), as(14, 0)}}
+	if len(ajustado) != len(esperado) {
+		t.Fatalf("expediente ajustado = %v, esperado %v", ajustado, esperado)
+	}
+	for i := range esperado {
+		if !ajustado[i].Inicio.Equal(esperado[i].Inicio) || !ajustado[i].Fim.Equal(esperado[i].Fim) {
+			t.Fatalf("expediente ajustado = %v, esperado %v", ajustado, esperado)
+		}
+	}
+	// O horário extra emenda no expediente: um atendimento das 11:30 às 12:30 cabe
+	if !Cabe(ajustado, as(11, 30), time.Hour) {
+		t.Error("atendimento que atravessa o início do horário extra deveria caber")
+	}
+
+	folga := []models.ExcecaoHorario{{Tipo: models.ExcecaoBloqueio, DataInicio: "2025-03-01", DataFim: "2025-03-15"}}
+	if livre := AplicarExcecoes(expediente, folga, dia); len(livre) != 0 {
+		t.Fatalf("férias deveriam fechar o dia, obtive %v", livre)
+	}
+}
+
+func TestExpedienteEmDiasDeHorarioDeVerao(t *testing.T) {
+	casos := []struct {
+		fuso   string
+		dia    time.Time // dia da mudança de horário
+		livres int       // inícios de hora em hora no turno 00:00–04:00
+	}{
+		{"America/New_York", time.Date(2025, 3, 9, 0, 0, 0, 0, time.UTC), 3},   // 02:00 não existe
+		{"America/New_York", time.Date(2025, 11, 2, 0, 0, 0, 0, time.UTC), 5},  // 01:00 acontece duas vezes
+		{"Europe/Lisbon", time.Date(2025, 3, 30, 0, 0, 0, 0, time.UTC), 3},     // 01:00 vira 02:00
+		{"America/Sao_Paulo", time.Date(2025, 11, 2, 0, 0, 0, 0, time.UTC), 4}, // sem horário de verão desde 2019
+	}
+	for _, caso := range casos {
+		loc, err := models.CarregarFuso(caso.fuso)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dia := time.Date(caso.dia.Year(), caso.dia.Month(), caso.dia.Day(), 0, 0, 0, 0, loc)
+		expediente := Expediente([]models.Horario{{HoraInicio: "00:00", HoraFim: "04:00"}}, dia)
+		livres := HorariosLivres(expediente, nil, time.Hour, time.Hour, dia)
+		if len(livres) != caso.livres {
+			t.Errorf("%s em %s: %d horários livres, esperado %d (%v)", caso.fuso, dia.Format("2006-01-02"), len(livres), caso.livres, livres)
+		}
+		for _, h := range livres {
+			if h.Location() != loc {
+				t.Errorf("%s: horário %v fora do fuso do estabelecimento", caso.fuso, h)
+			}
+		}
+	}
+}
+
+func TestMapaOcupacaoELivres(t *testing.T) {
+	dia := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC) // segunda-feira
+	expediente := Expediente([]models.Horario{
+		{HoraInicio: "08:00", HoraFim: "12:00"},
+		{HoraInicio: "14:00", HoraFim: "18:00"},
+	}, dia)
+	em := func(hora, minuto, duracao int, status string) models.Agendamento {
+		return models.Agendamento{DataHora: dia.Add(time.Duration(hora)*time.Hour + time.Duration(minuto)*time.Minute), DuracaoMin: duracao, Status: status}
+	}
+	agendamentos := []models.Agendamento{
+		em(9, 30, 60, models.StatusConcluido),
+		em(9, 45, 30, models.StatusConfirmado), // dentro do anterior, conta uma vez
+		em(11, 45, 30, models.StatusPendente),  // só 15 minutos dentro do expediente
+		em(14, 0, 60, models.StatusCancelado),  // cancelado não ocupa
+		em(19, 0, 60, models.StatusConcluido),  // fora do expediente
+	}
+
+	var m MapaOcupacao
+	m.Somar(expediente, agendamentos)
+	if m.Total != (Ocupacao{CapacidadeMin: 480, OcupadoMin: 75, OciosoMin: 405, Taxa: 15.6}) {
+		t.Errorf("total inesperado: %+v", m.Total)
+	}
+	if m.DiaSemana[0] != m.Total || m.DiaSemana[1].CapacidadeMin != 0 {
+		t.Errorf("dias da semana inesperados: %+v", m.DiaSemana)
+	}
+	if m.DiaEHora[0][9].Taxa != 50 || m.DiaEHora[0][11].OcupadoMin != 15 || m.DiaEHora[0][12].CapacidadeMin != 0 {
+		t.Errorf("mapa de calor inesperado: %+v", m.DiaEHora[0])
+	}
+
+	var semanas MapaOcupacao
+	semanas.Juntar(m)
+	semanas.Juntar(MapaOcupacao{})
+	if semanas.Total != m.Total {
+		t.Errorf("juntar alterou o total: %+v", semanas.Total)
+	}
+
+	livres := Livres(expediente, agendamentos)
+	if len(livres) != 3 || Duracao(livres) != 405*time.Minute || livres[1].Inicio.Hour() != 10 || livres[1].Fim.Minute() != 45 {
+		t.Errorf("trechos livres inesperados: %v", livres)
+	}
+}
@@ -0,0 +1,128 @@
+package agenda
+
+import (
+	"math"
+	"servico-api/models"
+	"time"
+)
+
+// Duracao soma a duração dos intervalos
+func Duracao(intervalos []Intervalo) time.Duration {
+	var total time.Duration
+	for _, i := range intervalos {
+		total += i.Fim.Sub(i.Inicio)
+	}
+	return total
+}
+
+// Ocupados devolve os trechos do expediente cobertos por agendamentos que ocupam a
+// agenda. Atendimentos fora do expediente não contam; sobreposições contam uma vez só.
+func Ocupados(expediente []Intervalo, agendamentos []models.Agendamento) []Intervalo {
+	var ocupados []Intervalo
+	for _, ag := range agendamentos {
+		if !ag.Ocupa() {
+			continue
+		}
+		for _, i := range expediente {
+			inicio, fim := ag.DataHora, ag.Fim()
+			if inicio.Before(i.Inicio) {
+				inicio = i.Inicio
+			}
+			if fim.After(i.Fim) {
+				fim = i.Fim
+			}
+			if inicio.Before(fim) {
+				ocupados = append(ocupados, Intervalo{Inicio: inicio, Fim: fim})
+			}
+		}
+	}
+	return unir(ocupados)
+}
+
+// Livres devolve os trechos do expediente sem nenhum agendamento que ocupe a agenda
+func Livres(expediente []Intervalo, agendamentos []models.Agendamento) []Intervalo {
+	livres := unir(append([]Intervalo(nil), expediente...))
+	for _, o := range Ocupados(expediente, agendamentos) {
+		livres = recortar(livres, o)
+	}
+	return livres
+}
+
+// PorHora distribui a duração dos intervalos pelas horas do relógio (0 a 23) em que caem
+func PorHora(intervalos []Intervalo) [24]time.Duration {
+	var horas [24]time.Duration
+	for _, i := range intervalos {
+		for inicio := i.Inicio; inicio.Before(i.Fim); {
+			fim := time.Date(inicio.Year(), inicio.Month(), inicio.Day(), inicio.Hour()+1, 0, 0, 0, inicio.Location())
+			if fim.After(i.Fim) {
+				fim = i.Fim
+			}
+			horas[inicio.Hour()] += fim.Sub(inicio)
+			inicio = fim
+		}
+	}
+	return horas
+}
+
+// Ocupacao compara o tempo de expediente com o tempo tomado por atendimentos
+type Ocupacao struct {
+	CapacidadeMin int     `json:"capacidade_min"`
+	OcupadoMin    int     `json:"ocupado_min"`
+	OciosoMin     int     `json:"ocioso_min"`
+	Taxa          float64 `json:"taxa"` // percentual da capacidade ocupado, com uma casa decimal
+}
+
+// somar acrescenta capacidade e uso e recalcula os campos derivados
+func (o *Ocupacao) somar(capacidade, ocupado time.Duration) {
+	o.CapacidadeMin += int(capacidade / time.Minute)
+	o.OcupadoMin += int(ocupado / time.Minute)
+	o.OciosoMin = o.CapacidadeMin - o.OcupadoMin
+	o.Taxa = 0
+	if o.CapacidadeMin > 0 {
+		o.Taxa = math.Round(float64(o.OcupadoMin)*1000/float64(o.CapacidadeMin)) / 10
+	}
+}
+
+// MapaOcupacao acumula a ocupação de vários dias de expediente no total, por dia da
+// semana (índice 0 é segunda) e por dia da semana e hora do dia
+type MapaOcupacao struct {
+	Total     Ocupacao
+	DiaSemana [7]Ocupacao
+	DiaEHora  [7][24]Ocupacao
+}
+
+// Somar acrescenta o expediente de um dia e os agendamentos que o ocupam
+func (m *MapaOcupacao) Somar(expediente []Intervalo, agendamentos []models.Agendamento) {
+	if len(expediente) == 0 {
+		return
+	}
+	ocupados := Ocupados(expediente, agendamentos)
+	capacidade, uso := Duracao(expediente), Duracao(ocupados)
+	dia := models.DiaSemanaDe(expediente[0].Inicio.Weekday()) - 1
+
+	m.Total.somar(capacidade, uso)
+	m.DiaSemana[dia].somar(capacidade, uso)
+	capacidadeHora, usoHora := PorHora(expediente), PorHora(ocupados)
+	for h := range capacidadeHora {
+		if capacidadeHora[h] > 0 {
+			m.DiaEHora[dia][h].somar(capacidadeHora[h], usoHora[h])
+		}
+	}
+}
+
+// Juntar acrescenta ao mapa tudo o que foi somado em outro
+func (m *MapaOcupacao) Juntar(outro MapaOcupacao) {
+	minutos := func(n int) time.Duration { return time.Duration(n) * time.Minute }
+	juntar := func(o *Ocupacao, x Ocupacao) {
+		if x.CapacidadeMin > 0 {
+			o.somar(minutos(x.CapacidadeMin), minutos(x.OcupadoMin))
+		}
+	}
+	juntar(&m.Total, outro.Total)
+	for d := range outro.DiaSemana {
+		juntar(&m.DiaSemana[d], outro.DiaSemana[d])
+		for h := range outro.DiaEHora[d] {
+			juntar(&m.DiaEHora[d][h], outro.DiaEHora[d][h])
+		}
+	}
+}
@@ -0,0 +1,194 @@
+// Package autorizacao define as políticas de acesso declaradas nas rotas:
+// quais tipos de usuário podem chamar cada handler e se o chamador é dono do recurso.
+package autorizacao
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Tipos de usuário emitidos no token de acesso
+const (
+	TipoCliente      = "clientes"
+	TipoProfissional = "profissionais"
+	TipoAdmin        = "admin"
+)
+
+// ErrRecursoNaoEncontrado deve ser devolvido por um BuscarDono quando o recurso não existe
+var ErrRecursoNaoEncontrado = errors.New("recurso não encontrado")
+
+// errDadosInvalidos indica que a requisição não traz o identificador exigido pela política
+var errDadosInvalidos = errors.New("dados inválidos")
+
+// Identidade é o chamador autenticado, conforme injetado por utils.AutenticacaoMiddleware
+type Identidade struct {
+	UID  string
+	Tipo string
+}
+
+// Regra decide se a identidade pode acessar o recurso da requisição
+type Regra func(c *gin.Context, quem Identidade) (bool, error)
+
+// Origem extrai da requisição o identificador usado por uma regra
+type Origem func(c *gin.Context) (string, error)
+
+// BuscarDono devolve o UID do dono de um recurso a partir do seu ID
+type BuscarDono func(ctx context.Context, id string) (string, error)
+
+// Exigir cria um middleware que só deixa a requisição seguir se todas as regras permitirem
+func Exigir(regras ...Regra) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		quem := Identidade{UID: c.GetString("uid"), Tipo: c.GetString("tipo")}
+		if quem.UID == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Usuário não autenticado"})
+			return
+		}
+
+		for _, regra := range regras {
+			permitido, err := regra(c, quem)
+			switch {
+			case errors.Is(err, ErrRecursoNaoEncontrado):
+				c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Recurso não encontrado"})
+				return
+			case errors.Is(err, errDadosInvalidos):
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos"})
+				return
+			case err != nil:
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Erro ao verificar permissões"})
+				return
+			case !permitido:
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Acesso negado"})
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// Algum permite o acesso se pelo menos uma das regras permitir
+func Algum(regras ...Regra) Regra {
+	return func(c *gin.Context, quem Identidade) (bool, error) {
+		for _, regra := range regras {
+			permitido, err := regra(c, quem)
+			if err != nil {
+				return false, err
+			}
+			if permitido {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+}
+
+// Todas permite o acesso apenas se todas as regras permitirem
+func Todas(regras ...Regra) Regra {
+	return func(c *gin.Context, quem Identidade) (bool, error) {
+		for _, regra := range regras {
+			permitido, err := regra(c, quem)
+			if err != nil || !permitido {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+}
+
+// Tipo permite o acesso aos usuários de um dos tipos informados
+func Tipo(tipos ...string) Regra {
+	return func(c *gin.Context, quem Identidade) (bool, error) {
+		for _, t := range tipos {
+			if quem.Tipo == t {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+}
+
+// Admin permite o acesso apenas a administradores
+var Admin = Tipo(TipoAdmin)
+
+// Proprio permite o acesso quando o identificador da requisição é o próprio UID do chamador
+func Proprio(origem Origem) Regra {
+	return func(c *gin.Context, quem Identidade) (bool, error) {
+		id, err := origem(c)
+		if err != nil {
+			return false, err
+		}
+		return id == quem.UID, nil
+	}
+}
+
+// Dono permite o acesso quando o chamador é o dono do recurso identificado pela origem
+func Dono(origem Origem, buscar BuscarDono) Regra {
+	return func(c *gin.Context, quem Identidade) (bool, error) {
+		id, err := origem(c)
+		if err != nil {
+			return false, err
+		}
+		dono, err := buscar(c.Request.Context(), id)
+		if err != nil {
+			return false, err
+		}
+		return dono != "" && dono == quem.UID, nil
+	}
+}
+
+// Param lê o identificador de um parâmetro de rota
+func Param(nome string) Origem {
+	return func(c *gin.Context) (string, error) {
+		if v := c.Param(nome); v != "" {
+			return v, nil
+		}
+		return "", errDadosInvalidos
+	}
+}
+
+// Query lê o identificador de um parâmetro de query string
+func Query(nome string) Origem {
+	return func(c *gin.Context) (string, error) {
+		if v := c.Query(nome); v != "" {
+			return v, nil
+		}
+		return "", errDadosInvalidos
+	}
+}
+
+// CampoJSON lê o identificador de um campo do corpo JSON, preservando o corpo para o handler
+func CampoJSON(nome string) Origem {
+	return func(c *gin.Context) (string, error) {
+		corpo, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return "", fmt.Errorf("erro ao ler corpo: %w", err)
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(corpo))
+
+		var campos map[string]interface{}
+		if err := json.Unmarshal(corpo, &campos); err != nil {
+			return "", errDadosInvalidos
+		}
+		if v, ok := campos[nome].(string); ok && v != "" {
+			return v, nil
+		}
+		return "", errDadosInvalidos
+	}
+}
+
+// ConformeParam escolhe a regra de acordo com o valor de um parâmetro de rota
+func ConformeParam(nome string, regras map[string]Regra) Regra {
+	return func(c *gin.Context, quem Identidade) (bool, error) {
+		regra, ok := regras[c.Param(nome)]
+		if !ok {
+			return false, errDadosInvalidos
+		}
+		return regra(c, quem)
+	}
+}
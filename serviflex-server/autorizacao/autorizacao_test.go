@@ -0,0 +1,71 @@
+package autorizacao
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestExigir(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	donos := map[string]string{"est-1": "prof-1"}
+	buscarDono := func(ctx context.Context, id string) (string, error) {
+		dono, ok := donos[id]
+		if !ok {
+			return "", ErrRecursoNaoEncontrado
+		}
+		return dono, nil
+	}
+	donoOuAdmin := Exigir(Algum(Admin, Dono(Param("id"), buscarDono)))
+
+	casos := []struct {
+		nome   string
+		regra  gin.HandlerFunc
+		uid    string
+		tipo   string
+		id     string
+		corpo  string
+		status int
+	}{
+		{"sem identidade", donoOuAdmin, "", "", "est-1", "", http.StatusUnauthorized},
+		{"dono", donoOuAdmin, "prof-1", TipoProfissional, "est-1", "", http.StatusOK},
+		{"admin", donoOuAdmin, "adm-1", TipoAdmin, "est-1", "", http.StatusOK},
+		{"outro profissional", donoOuAdmin, "prof-2", TipoProfissional, "est-1", "", http.StatusForbidden},
+		{"recurso inexistente", donoOuAdmin, "prof-1", TipoProfissional, "est-9", "", http.StatusNotFound},
+		{"tipo permitido", Exigir(Tipo(TipoCliente)), "cli-1", TipoCliente, "x", "", http.StatusOK},
+		{"tipo negado", Exigir(Tipo(TipoCliente)), "prof-1", TipoProfissional, "x", "", http.StatusForbidden},
+		{"próprio pelo corpo", Exigir(Todas(Tipo(TipoProfissional), Proprio(CampoJSON("profissional_id")))), "prof-1", TipoProfissional, "x", `{"profissional_id":"prof-1"}`, http.StatusOK},
+		{"outro pelo corpo", Exigir(Proprio(CampoJSON("profissional_id"))), "prof-1", TipoProfissional, "x", `{"profissional_id":"prof-2"}`, http.StatusForbidden},
+		{"corpo sem o campo", Exigir(Proprio(CampoJSON("profissional_id"))), "prof-1", TipoProfissional, "x", `{}`, http.StatusBadRequest},
+	}
+
+	for _, caso := range casos {
+		t.Run(caso.nome, func(t *testing.T) {
+			r := gin.New()
+			var corpoNoHandler string
+			r.POST("/recurso/:id", func(c *gin.Context) {
+				c.Set("uid", caso.uid)
+				c.Set("tipo", caso.tipo)
+			}, caso.regra, func(c *gin.Context) {
+				corpo, _ := io.ReadAll(c.Request.Body)
+				corpoNoHandler = string(corpo)
+				c.Status(http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest("POST", "/recurso/"+caso.id, strings.NewReader(caso.corpo)))
+			if w.Code != caso.status {
+				t.Fatalf("status %d, esperado %d", w.Code, caso.status)
+			}
+			if w.Code == http.StatusOK && corpoNoHandler != caso.corpo {
+				t.Fatalf("corpo não preservado para o handler: %q", corpoNoHandler)
+			}
+		})
+	}
+}
@@ -1,37 +1,142 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
 	"servico-api/config"
 	"servico-api/routes"
-	"github.com/gin-gonic/gin"
+	"servico-api/storage"
+	"servico-api/storage/firestoredb"
+	"servico-api/storage/memoria"
+	"servico-api/storage/postgres"
+	"syscall"
+	"time"
+
 	"github.com/gin-contrib/cors" // Importando o pacote de CORS
+	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
+// tempoEncerramento limita a espera pelas requisições em andamento ao encerrar
+const tempoEncerramento = 20 * time.Second
+
+// abrirRepositorios conecta ao backend configurado (firestore, postgres ou memoria) e
+// devolve os repositórios junto com a função que libera a conexão
+func abrirRepositorios(ctx context.Context, cfg config.Config) (*storage.Repositorios, func()) {
+	switch cfg.Storage.Backend {
+	case "postgres":
+		pool, err := postgres.Conectar(ctx, cfg.Storage.DatabaseURL)
+		if err != nil {
+			log.Fatalf("Erro ao conectar ao PostgreSQL: %v", err)
+		}
+		aplicadas, err := postgres.Migrar(ctx, pool)
+		if err != nil {
+			log.Fatalf("Erro ao aplicar migrações: %v", err)
+		}
+		for _, m := range aplicadas {
+			log.Printf("Migração %s aplicada", m)
+		}
+		return postgres.NovosRepositorios(pool), pool.Close
+
+	case "memoria":
+		log.Println("Usando armazenamento em memória: os dados serão perdidos ao encerrar")
+		return memoria.NovosRepositorios(), func() {}
+
+	default:
+		if err := config.InitFirebase(ctx, cfg.Firestore); err != nil {
+			log.Fatalf("Erro ao inicializar o Firebase: %v", err)
+		}
+
+		// Um único cliente Firestore compartilhado pelos repositórios
+		client, err := config.App.Firestore(ctx)
+		if err != nil {
+			log.Fatalf("Erro ao conectar ao Firestore: %v", err)
+		}
+		return firestoredb.NovosRepositorios(client), func() { client.Close() }
+	}
+}
+
 func main() {
-	// Configuração do Firebase (não relacionada ao CORS, mas necessária para o seu backend)
-	config.InitFirebase()
+	arquivo := flag.String("config", os.Getenv("SERVIFLEX_CONFIG"), "arquivo de configuração YAML ou TOML (opcional)")
+	flag.Parse()
 
-	r := gin.Default()
+	cfg, err := config.Carregar(*arquivo)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := cfg.Validar(); err != nil {
+		log.Fatal(err)
+	}
+	config.ConfigurarLog(cfg.LogNivel)
+	config.InitJWT(cfg.JWT)
 
-	// Configuração do CORS
-	r.Use(cors.Default()) // Permite todas as origens. Pode ser ajustado para mais restrições
+	repos, fechar := abrirRepositorios(context.Background(), cfg)
+	defer fechar()
 
-	// Caso queira permitir apenas uma origem específica (como o seu frontend):
-	// r.Use(cors.New(cors.Config{
-	// 	AllowOrigins:     []string{"http://localhost:5173"},
-	// 	AllowMethods:     []string{"GET", "POST", "PUT", "DELETE"},
-	// 	AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-	// 	AllowCredentials: true,
-	// }))
+	r := gin.New()
+	r.Use(gin.Recovery())
+	if config.LogRequisicoes(cfg.LogNivel) {
+		r.Use(gin.Logger())
+	}
+
+	// Configuração do CORS
+	// As origens vêm da configuração ("*" libera todas); o header Authorization
+	// carrega o token de acesso
+	corsConfig := cors.DefaultConfig()
+	if cfg.PermiteTodasOrigens() {
+		corsConfig.AllowAllOrigins = true
+	} else {
+		corsConfig.AllowOrigins = cfg.CORSOrigens
+		corsConfig.AllowCredentials = true
+	}
+	corsConfig.AddAllowHeaders("Authorization")
+	r.Use(cors.New(corsConfig))
 
 	// Configuração das rotas
-	routes.SetupRoutes(r)
+	routes.SetupRoutes(r, repos)
 
 	// Configuração do Swagger
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
-	// Inicia o servidor na porta 8080
-	r.Run(":8080")
+	// Inicia o servidor na porta configurada
+	srv := &http.Server{
+		Addr:              cfg.Endereco(),
+		Handler:           r,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	// SIGINT/SIGTERM param de aceitar conexões e aguardam as requisições em andamento;
+	// só depois os repositórios (e o cliente do banco) são fechados pelo defer
+	sinal, parar := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer parar()
+
+	erroServidor := make(chan error, 1)
+	go func() {
+		log.Printf("Servidor ouvindo em %s (storage: %s)", cfg.Endereco(), cfg.Storage.Backend)
+		erroServidor <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-erroServidor:
+		if !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("Erro no servidor HTTP: %v", err)
+		}
+		return
+	case <-sinal.Done():
+	}
+	parar()
+
+	log.Println("Encerrando: aguardando requisições em andamento...")
+	ctx, cancelar := context.WithTimeout(context.Background(), tempoEncerramento)
+	defer cancelar()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("Encerramento forçado após %s: %v", tempoEncerramento, err)
+	}
+	log.Println("Servidor encerrado")
 }
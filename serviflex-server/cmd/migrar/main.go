@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"servico-api/config"
+	"servico-api/migracoes"
+	"strings"
+)
+
+// Executa uma migração de dados pontual, ex.: go run ./cmd/migrar -nome senhas
+func main() {
+	nome := flag.String("nome", "", "migração a executar ("+strings.Join(migracoes.Nomes(), ", ")+")")
+	arquivo := flag.String("config", os.Getenv("SERVIFLEX_CONFIG"), "arquivo de configuração YAML ou TOML (opcional)")
+	flag.Parse()
+
+	migracao, ok := migracoes.Disponiveis[*nome]
+	if !ok {
+		log.Fatalf("Migração desconhecida %q. Disponíveis: %s", *nome, strings.Join(migracoes.Nomes(), ", "))
+	}
+
+	cfg, err := config.Carregar(*arquivo)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := cfg.Firestore.Validar(); err != nil {
+		log.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if err := config.InitFirebase(ctx, cfg.Firestore); err != nil {
+		log.Fatalf("Erro ao inicializar o Firebase: %v", err)
+	}
+	client, err := config.App.Firestore(ctx)
+	if err != nil {
+		log.Fatalf("Erro ao conectar ao Firestore: %v", err)
+	}
+	defer client.Close()
+
+	alterados, err := migracao(ctx, client)
+	if err != nil {
+		log.Fatalf("Migração %s falhou após %d documentos: %v", *nome, alterados, err)
+	}
+	log.Printf("Migração %s concluída: %d documentos alterados", *nome, alterados)
+}
@@ -0,0 +1,211 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Config reúne as configurações do servidor. Os valores vêm, em ordem de prioridade,
+// das variáveis de ambiente, do arquivo de configuração opcional e dos padrões.
+type Config struct {
+	Porta       int             `yaml:"porta" toml:"porta"`
+	CORSOrigens []string        `yaml:"cors_origens" toml:"cors_origens"` // "*" libera todas
+	LogNivel    string          `yaml:"log_nivel" toml:"log_nivel"`       // debug | info | warn | error
+	Storage     StorageConfig   `yaml:"storage" toml:"storage"`
+	Firestore   FirestoreConfig `yaml:"firestore" toml:"firestore"`
+	JWT         JWTConfig       `yaml:"jwt" toml:"jwt"`
+}
+
+// StorageConfig escolhe o backend dos repositórios
+type StorageConfig struct {
+	Backend     string `yaml:"backend" toml:"backend"` // firestore | postgres | memoria
+	DatabaseURL string `yaml:"database_url" toml:"database_url"`
+}
+
+// FirestoreConfig identifica o projeto Firebase e, opcionalmente, o emulador local
+type FirestoreConfig struct {
+	ProjetoID    string `yaml:"projeto_id" toml:"projeto_id"`
+	Credenciais  string `yaml:"credenciais" toml:"credenciais"`     // arquivo JSON da conta de serviço
+	EmuladorHost string `yaml:"emulador_host" toml:"emulador_host"` // ex.: localhost:8081
+}
+
+// TamanhoMinimoSegredo é o menor segredo aceito para assinar os tokens com HS256
+const TamanhoMinimoSegredo = 32
+
+// JWTConfig contém a chave de assinatura dos tokens de acesso
+type JWTConfig struct {
+	Segredo string `yaml:"segredo" toml:"segredo"`
+}
+
+// Padrao devolve a configuração usada quando nada é informado
+func Padrao() Config {
+	return Config{
+		Porta:       8080,
+		CORSOrigens: []string{"*"},
+		LogNivel:    "info",
+		Storage:     StorageConfig{Backend: "firestore"},
+	}
+}
+
+// Carregar monta a configuração a partir do arquivo (YAML ou TOML, opcional quando
+// vazio) e das variáveis de ambiente. Não valida; use Validar em seguida.
+func Carregar(arquivo string) (Config, error) {
+	cfg := Padrao()
+	if arquivo != "" {
+		if err := lerArquivo(arquivo, &cfg); err != nil {
+			return cfg, err
+		}
+	}
+	if err := aplicarAmbiente(&cfg, os.LookupEnv); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+func lerArquivo(arquivo string, cfg *Config) error {
+	conteudo, err := os.ReadFile(arquivo)
+	if err != nil {
+		return fmt.Errorf("erro ao ler o arquivo de configuração: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(arquivo)) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(conteudo))
+		dec.KnownFields(true)
+		err = dec.Decode(cfg)
+	case ".toml":
+		dec := toml.NewDecoder(bytes.NewReader(conteudo))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(cfg)
+	default:
+		return fmt.Errorf("arquivo de configuração %s: formato não suportado (use .yaml, .yml ou .toml)", arquivo)
+	}
+	if err != nil {
+		return fmt.Errorf("arquivo de configuração %s inválido: %w", arquivo, err)
+	}
+	return nil
+}
+
+// aplicarAmbiente sobrescreve a configuração com as variáveis de ambiente definidas
+func aplicarAmbiente(cfg *Config, lookup func(string) (string, bool)) error {
+	texto := func(nome string, destino *string) {
+		if v, ok := lookup(nome); ok && v != "" {
+			*destino = v
+		}
+	}
+
+	if v, ok := lookup("PORT"); ok && v != "" {
+		porta, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("PORT deve ser um número, recebido %q", v)
+		}
+		cfg.Porta = porta
+	}
+	if v, ok := lookup("CORS_ORIGINS"); ok && v != "" {
+		cfg.CORSOrigens = nil
+		for _, origem := range strings.Split(v, ",") {
+			if origem = strings.TrimSpace(origem); origem != "" {
+				cfg.CORSOrigens = append(cfg.CORSOrigens, origem)
+			}
+		}
+	}
+	texto("LOG_LEVEL", &cfg.LogNivel)
+	texto("STORAGE_BACKEND", &cfg.Storage.Backend)
+	texto("DATABASE_URL", &cfg.Storage.DatabaseURL)
+	texto("GOOGLE_CLOUD_PROJECT", &cfg.Firestore.ProjetoID)
+	texto("FIRESTORE_PROJECT_ID", &cfg.Firestore.ProjetoID)
+	texto("GOOGLE_APPLICATION_CREDENTIALS", &cfg.Firestore.Credenciais)
+	texto("FIRESTORE_EMULATOR_HOST", &cfg.Firestore.EmuladorHost)
+	texto("JWT_SECRET", &cfg.JWT.Segredo)
+	return nil
+}
+
+// Validar confere a configuração completa do servidor e lista todos os problemas encontrados
+func (c Config) Validar() error {
+	var problemas []string
+
+	if c.Porta < 1 || c.Porta > 65535 {
+		problemas = append(problemas, fmt.Sprintf("porta %d fora do intervalo 1-65535", c.Porta))
+	}
+	if len(c.CORSOrigens) == 0 {
+		problemas = append(problemas, "informe ao menos uma origem CORS (ou \"*\")")
+	}
+	for _, origem := range c.CORSOrigens {
+		if origem == "*" {
+			continue
+		}
+		u, err := url.Parse(origem)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			problemas = append(problemas, fmt.Sprintf("origem CORS %q inválida (esperado http(s)://host[:porta])", origem))
+		}
+	}
+	if _, ok := niveisLog[strings.ToLower(c.LogNivel)]; !ok {
+		problemas = append(problemas, fmt.Sprintf("log_nivel %q inválido (use debug, info, warn ou error)", c.LogNivel))
+	}
+	switch {
+	case len(c.JWT.Segredo) == 0:
+		problemas = append(problemas, "JWT_SECRET não definida")
+	case len(c.JWT.Segredo) < TamanhoMinimoSegredo:
+		problemas = append(problemas, fmt.Sprintf("JWT_SECRET precisa de ao menos %d bytes", TamanhoMinimoSegredo))
+	}
+
+	switch c.Storage.Backend {
+	case "firestore":
+		if err := c.Firestore.Validar(); err != nil {
+			problemas = append(problemas, err.Error())
+		}
+	case "postgres":
+		if c.Storage.DatabaseURL == "" {
+			problemas = append(problemas, "DATABASE_URL é obrigatória com o backend postgres")
+		}
+	case "memoria":
+	default:
+		problemas = append(problemas, fmt.Sprintf("backend de storage %q inválido (use firestore, postgres ou memoria)", c.Storage.Backend))
+	}
+
+	return juntarProblemas(problemas)
+}
+
+// Validar confere os dados necessários para conectar ao Firestore
+func (f FirestoreConfig) Validar() error {
+	var problemas []string
+	if f.ProjetoID == "" {
+		problemas = append(problemas, "projeto do Firestore não definido (FIRESTORE_PROJECT_ID)")
+	}
+	if f.Credenciais != "" && f.EmuladorHost == "" {
+		if _, err := os.Stat(f.Credenciais); err != nil {
+			problemas = append(problemas, fmt.Sprintf("arquivo de credenciais %q inacessível: %v", f.Credenciais, err))
+		}
+	}
+	return juntarProblemas(problemas)
+}
+
+func juntarProblemas(problemas []string) error {
+	if len(problemas) == 0 {
+		return nil
+	}
+	return fmt.Errorf("configuração inválida:\n  - %s", strings.Join(problemas, "\n  - "))
+}
+
+// PermiteTodasOrigens indica se a lista de origens CORS libera qualquer origem
+func (c Config) PermiteTodasOrigens() bool {
+	for _, origem := range c.CORSOrigens {
+		if origem == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// Endereco devolve o endereço de escuta do servidor HTTP
+func (c Config) Endereco() string {
+	return fmt.Sprintf(":%d", c.Porta)
+}
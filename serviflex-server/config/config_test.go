@@ -0,0 +1,121 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func escreverArquivo(t *testing.T, nome, conteudo string) string {
+	t.Helper()
+	caminho := filepath.Join(t.TempDir(), nome)
+	if err := os.WriteFile(caminho, []byte(conteudo), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return caminho
+}
+
+func TestCarregarArquivoEAmbiente(t *testing.T) {
+	yamlCfg := escreverArquivo(t, "config.yaml", `
+porta: 9090
+cors_origens: ["https://app.serviflex.com.br"]
+log_nivel: warn
+storage:
+  backend: postgres
+  database_url: postgres://arquivo
+jwt:
+  segredo: segredo-do-arquivo-com-mais-de-32-bytes
+`)
+	tomlCfg := escreverArquivo(t, "config.toml", `
+porta = 9090
+cors_origens = ["https://app.serviflex.com.br"]
+log_nivel = "warn"
+
+[storage]
+backend = "postgres"
+database_url = "postgres://arquivo"
+
+[jwt]
+segredo = "segredo-do-arquivo-com-mais-de-32-bytes"
+`)
+
+	for _, arquivo := range []string{yamlCfg, tomlCfg} {
+		t.Run(filepath.Ext(arquivo), func(t *testing.T) {
+			t.Setenv("DATABASE_URL", "postgres://ambiente")
+			cfg, err := Carregar(arquivo)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Porta != 9090 || cfg.LogNivel != "warn" || cfg.JWT.Segredo != "segredo-do-arquivo-com-mais-de-32-bytes" {
+				t.Errorf("valores do arquivo não aplicados: %+v", cfg)
+			}
+			if cfg.Storage.DatabaseURL != "postgres://ambiente" {
+				t.Errorf("a variável de ambiente deveria prevalecer, obtive %q", cfg.Storage.DatabaseURL)
+			}
+			if err := cfg.Validar(); err != nil {
+				t.Errorf("configuração deveria ser válida: %v", err)
+			}
+		})
+	}
+}
+
+func TestCarregarRejeitaCampoDesconhecido(t *testing.T) {
+	arquivo := escreverArquivo(t, "config.yaml", "prota: 9090\n")
+	if _, err := Carregar(arquivo); err == nil {
+		t.Error("campo desconhecido deveria ser rejeitado")
+	}
+	if _, err := Carregar(escreverArquivo(t, "config.json", "{}")); err == nil {
+		t.Error("extensão não suportada deveria ser rejeitada")
+	}
+}
+
+func TestValidarListaTodosOsProblemas(t *testing.T) {
+	cfg := Padrao()
+	cfg.Porta = 0
+	cfg.CORSOrigens = []string{"localhost:5173"}
+	cfg.LogNivel = "verboso"
+	cfg.Storage.Backend = "mongo"
+
+	err := cfg.Validar()
+	if err == nil {
+		t.Fatal("esperava erro de validação")
+	}
+	for _, trecho := range []string{"porta 0", "origem CORS", "log_nivel", "JWT_SECRET", "mongo"} {
+		if !strings.Contains(err.Error(), trecho) {
+			t.Errorf("mensagem deveria citar %q: %v", trecho, err)
+		}
+	}
+}
+
+func TestValidarSegredoCurto(t *testing.T) {
+	cfg := Padrao()
+	cfg.Storage.Backend = "memoria"
+	cfg.JWT.Segredo = strings.Repeat("s", TamanhoMinimoSegredo-1)
+	if err := cfg.Validar(); err == nil || !strings.Contains(err.Error(), "JWT_SECRET precisa de ao menos 32 bytes") {
+		t.Errorf("segredo curto deveria ser recusado: %v", err)
+	}
+	cfg.JWT.Segredo += "s"
+	if err := cfg.Validar(); err != nil {
+		t.Errorf("segredo com o tamanho mínimo deveria ser aceito: %v", err)
+	}
+}
+
+func TestValidarFirestore(t *testing.T) {
+	cfg := Padrao()
+	cfg.JWT.Segredo = strings.Repeat("s", TamanhoMinimoSegredo)
+	if err := cfg.Validar(); err == nil || !strings.Contains(err.Error(), "FIRESTORE_PROJECT_ID") {
+		t.Errorf("projeto ausente deveria ser apontado: %v", err)
+	}
+
+	cfg.Firestore = FirestoreConfig{ProjetoID: "demo", Credenciais: "/nao/existe.json"}
+	if err := cfg.Validar(); err == nil {
+		t.Error("arquivo de credenciais inexistente deveria ser apontado")
+	}
+
+	// Com o emulador as credenciais não são usadas
+	cfg.Firestore.EmuladorHost = "localhost:8081"
+	if err := cfg.Validar(); err != nil {
+		t.Errorf("emulador dispensa credenciais: %v", err)
+	}
+}
@@ -2,8 +2,8 @@ package config
 
 import (
 	"context"
-	"fmt"
 	"log"
+	"os"
 
 	firebase "firebase.google.com/go"
 	"google.golang.org/api/option"
@@ -11,19 +11,29 @@ import (
 
 var App *firebase.App
 
-func InitFirebase() {
-	ctx := context.Background()
-	credsPath := "C:/Users/mathe/Downloads/Arquivo/config/pdsi-23063-firebase-adminsdk-fbsvc-8df5cdf4f5.json"
-	if credsPath == "" {
-		log.Fatal("Arquivo de credenciais do Firebase não encontrado")
+// InitFirebase inicializa o App do Firebase com o projeto e as credenciais configurados.
+// Com o emulador definido, as credenciais são dispensadas e o cliente Firestore se
+// conecta ao endereço em FIRESTORE_EMULATOR_HOST.
+func InitFirebase(ctx context.Context, cfg FirestoreConfig) error {
+	var opts []option.ClientOption
+	switch {
+	case cfg.EmuladorHost != "":
+		// A biblioteca do Firestore lê o emulador desta variável
+		if err := os.Setenv("FIRESTORE_EMULATOR_HOST", cfg.EmuladorHost); err != nil {
+			return err
+		}
+		log.Println("Usando o emulador do Firestore em", cfg.EmuladorHost)
+	case cfg.Credenciais != "":
+		log.Println("Usando credenciais:", cfg.Credenciais)
+		opts = append(opts, option.WithCredentialsFile(cfg.Credenciais))
+	default:
+		log.Println("Usando as credenciais padrão do ambiente (ADC)")
 	}
-	fmt.Println("Usando credenciais:", credsPath)
-	opt := option.WithCredentialsFile(credsPath)
-	app, err := firebase.NewApp(ctx, &firebase.Config{
-		ProjectID: "pdsi-23063",
-	}, opt)
+
+	app, err := firebase.NewApp(ctx, &firebase.Config{ProjectID: cfg.ProjetoID}, opts...)
 	if err != nil {
-		log.Fatalf("Erro ao inicializar o Firebase: %v", err)
+		return err
 	}
 	App = app
+	return nil
 }
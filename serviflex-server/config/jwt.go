@@ -0,0 +1,14 @@
+package config
+
+import "time"
+
+// JWTSecret é a chave usada para assinar e validar os tokens de acesso
+var JWTSecret []byte
+
+// JWTDuracao define por quanto tempo um token de acesso permanece válido
+var JWTDuracao = 24 * time.Hour
+
+// InitJWT define a chave de assinatura dos tokens de acesso
+func InitJWT(cfg JWTConfig) {
+	JWTSecret = []byte(cfg.Segredo)
+}
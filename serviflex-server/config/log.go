@@ -0,0 +1,32 @@
+package config
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+var niveisLog = map[string]slog.Level{
+	"debug": slog.LevelDebug,
+	"info":  slog.LevelInfo,
+	"warn":  slog.LevelWarn,
+	"error": slog.LevelError,
+}
+
+// ConfigurarLog aplica o nível de log ao slog padrão e ao modo do Gin. Em debug o Gin
+// detalha as rotas registradas; em warn e error o log por requisição é desligado.
+func ConfigurarLog(nivel string) {
+	n := niveisLog[strings.ToLower(nivel)]
+	slog.SetLogLoggerLevel(n)
+	if n == slog.LevelDebug {
+		gin.SetMode(gin.DebugMode)
+	} else {
+		gin.SetMode(gin.ReleaseMode)
+	}
+}
+
+// LogRequisicoes indica se o middleware de log por requisição deve ser usado
+func LogRequisicoes(nivel string) bool {
+	return niveisLog[strings.ToLower(nivel)] <= slog.LevelInfo
+}
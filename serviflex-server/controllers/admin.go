@@ -1,91 +1,78 @@
 package controllers
 
 import (
-	"context"
+	"errors"
 	"net/http"
-	"servico-api/config"
 	"servico-api/models"
+	"servico-api/storage"
+	"servico-api/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
 // ListarAdmins retorna todos os admins
-func ListarAdmins(c *gin.Context) {
-	ctx := context.Background()
-	client, err := config.App.Firestore(ctx)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao conectar"})
-		return
-	}
-	defer client.Close()
-
-	docs, err := client.Collection("admin").Documents(ctx).GetAll()
+func (h *Handler) ListarAdmins(c *gin.Context) {
+	ctx := c.Request.Context()
+	admins, err := h.repos.Usuarios.ListarAdmins(ctx)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar admins"})
 		return
 	}
 
-	var admins []models.Admin
-	for _, doc := range docs {
-		var a models.Admin
-		if err := doc.DataTo(&a); err == nil {
-			admins = append(admins, a)
-		}
+	for i := range admins {
+		admins[i].Senha = ""
 	}
 	c.JSON(http.StatusOK, admins)
 }
 
 // BuscarAdminPorID retorna um admin por ID
-func BuscarAdminPorID(c *gin.Context) {
+func (h *Handler) BuscarAdminPorID(c *gin.Context) {
 	id := c.Param("id")
-	ctx := context.Background()
-	client, err := config.App.Firestore(ctx)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao conectar"})
-		return
-	}
-	defer client.Close()
+	ctx := c.Request.Context()
 
-	doc, err := client.Collection("admin").Doc(id).Get(ctx)
-	if err != nil || !doc.Exists() {
+	a, err := h.repos.Usuarios.BuscarAdmin(ctx, id)
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Admin não encontrado"})
 		return
 	}
 
-	var a models.Admin
-	doc.DataTo(&a)
+	a.Senha = ""
 	c.JSON(http.StatusOK, a)
 }
 
 // CriarAdmin cria um novo admin
-func CriarAdmin(c *gin.Context) {
+func (h *Handler) CriarAdmin(c *gin.Context) {
 	var input models.Admin
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos"})
 		return
 	}
-	input.ID = uuid.New().String()
-	input.Tipo = "admin"
-
-	ctx := context.Background()
-	client, err := config.App.Firestore(ctx)
+	if input.Senha == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Senha é obrigatória"})
+		return
+	}
+	hash, err := utils.HashSenha(input.Senha)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao conectar"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao processar senha"})
 		return
 	}
-	defer client.Close()
 
-	_, err = client.Collection("admin").Doc(input.ID).Set(ctx, input)
-	if err != nil {
+	input.ID = uuid.New().String()
+	input.Tipo = "admin"
+	input.Senha = hash
+
+	ctx := c.Request.Context()
+	if err := h.repos.Usuarios.SalvarAdmin(ctx, input); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao criar admin"})
 		return
 	}
+	input.Senha = ""
 	c.JSON(http.StatusCreated, input)
 }
 
 // EditarAdmin atualiza os dados de um admin
-func EditarAdmin(c *gin.Context) {
+func (h *Handler) EditarAdmin(c *gin.Context) {
 	id := c.Param("id")
 	var input models.Admin
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -95,16 +82,22 @@ func EditarAdmin(c *gin.Context) {
 	input.ID = id
 	input.Tipo = "admin"
 
-	ctx := context.Background()
-	client, err := config.App.Firestore(ctx)
+	ctx := c.Request.Context()
+	atual, err := h.repos.Usuarios.BuscarAdmin(ctx, id)
+	if errors.Is(err, storage.ErrNaoEncontrado) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Admin não encontrado"})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao conectar"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar admin"})
+		return
+	}
+	if input.Senha, err = senhaParaSalvar(input.Senha, atual.Senha); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao processar senha"})
 		return
 	}
-	defer client.Close()
 
-	_, err = client.Collection("admin").Doc(id).Set(ctx, input)
-	if err != nil {
+	if err := h.repos.Usuarios.SalvarAdmin(ctx, input); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao atualizar admin"})
 		return
 	}
@@ -112,18 +105,11 @@ func EditarAdmin(c *gin.Context) {
 }
 
 // ExcluirAdmin remove um admin
-func ExcluirAdmin(c *gin.Context) {
+func (h *Handler) ExcluirAdmin(c *gin.Context) {
 	id := c.Param("id")
-	ctx := context.Background()
-	client, err := config.App.Firestore(ctx)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao conectar"})
-		return
-	}
-	defer client.Close()
+	ctx := c.Request.Context()
 
-	_, err = client.Collection("admin").Doc(id).Delete(ctx)
-	if err != nil {
+	if err := h.repos.Usuarios.ExcluirAdmin(ctx, id); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao excluir admin"})
 		return
 	}
@@ -0,0 +1,330 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"servico-api/agenda"
+	"servico-api/models"
+	"servico-api/storage"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errAtendimentoNaoIniciado impede concluir ou registrar falta antes do horário marcado
+var errAtendimentoNaoIniciado = errors.New("o atendimento ainda não começou")
+
+// validarAgendamento aplica as regras de AgendarHorario: o procedimento precisa existir
+// para o profissional e copia nome, preço e duração dele para o agendamento, que então
+// passa por validarHorario. Em caso de erro já escreve a resposta e devolve false.
+func (h *Handler) validarAgendamento(c *gin.Context, agendamento *models.Agendamento) bool {
+	proc, err := h.procedimentoAgendado(c.Request.Context(), *agendamento)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Procedimento inválido"})
+		return false
+	}
+	agendamento.ProcedimentoID = proc.ID
+	agendamento.Procedimento = proc.Nome
+	agendamento.Preco = proc.Preco
+	agendamento.DuracaoMin = proc.DuracaoMin
+	return h.validarHorario(c, agendamento)
+}
+
+// validarHorario confere se o atendimento, com a duração do agendamento, cabe no
+// expediente do profissional, já considerando as exceções de horário da data. O
+// estabelecimento é sempre o do profissional, cuja política de cancelamento vale para o
+// agendamento; um estabelecimento_id diferente é recusado. Passa o horário para o fuso do
+// profissional e, em caso de erro, já escreve a resposta e devolve false.
+func (h *Handler) validarHorario(c *gin.Context, agendamento *models.Agendamento) bool {
+	ctx := c.Request.Context()
+	duracao := time.Duration(agendamento.DuracaoMin) * time.Minute
+
+	estabID, err := h.estabelecimentoDoProfissional(ctx, agendamento.ProfissionalID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar profissional"})
+		return false
+	}
+	if agendamento.EstabelecimentoID != "" && agendamento.EstabelecimentoID != estabID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Profissional não atende nesse estabelecimento"})
+		return false
+	}
+	agendamento.EstabelecimentoID = estabID
+
+	// Dia da semana e expediente valem no fuso do estabelecimento do profissional
+	loc, err := h.fusoDoEstabelecimento(ctx, estabID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar fuso horário do profissional"})
+		return false
+	}
+	localDateTime := agendamento.DataHora.In(loc)
+	diaSemana := models.DiaSemanaDe(localDateTime.Weekday())
+
+	// Buscar horários do profissional
+	horarios, err := h.repos.Horarios.ListarPorDia(ctx, agendamento.ProfissionalID, diaSemana)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar horários"})
+		return false
+	}
+
+	// Folgas, feriados e horários extras daquela data
+	data := localDateTime.Format("2006-01-02")
+	excecoes, err := h.repos.Excecoes.ListarPorProfissional(ctx, agendamento.ProfissionalID, data, data)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar exceções de horário"})
+		return false
+	}
+
+	expediente := agenda.AplicarExcecoes(agenda.Expediente(horarios, localDateTime), excecoes, localDateTime)
+	if len(expediente) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Profissional não trabalha nesse dia"})
+		return false
+	}
+
+	// Validar se o horário está dentro do expediente
+	if !agenda.Cabe(expediente, localDateTime, duracao) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Horário não está dentro do expediente do profissional"})
+		return false
+	}
+
+	agendamento.DataHora = localDateTime
+	return true
+}
+
+// procedimentoAgendado localiza o procedimento pelo procedimento_id do agendamento ou,
+// em pedidos e registros anteriores ao ID, pelo nome. O procedimento precisa ser do
+// profissional do agendamento.
+func (h *Handler) procedimentoAgendado(ctx context.Context, ag models.Agendamento) (*models.Procedimento, error) {
+	if ag.ProcedimentoID == "" {
+		return h.repos.Procedimentos.BuscarPorNome(ctx, ag.ProfissionalID, ag.Procedimento)
+	}
+	proc, err := h.repos.Procedimentos.Buscar(ctx, ag.ProcedimentoID)
+	if err != nil {
+		return nil, err
+	}
+	if proc.ProfissionalID != ag.ProfissionalID {
+		return nil, storage.ErrNaoEncontrado
+	}
+	return proc, nil
+}
+
+// responderConflito escreve o 409 com o intervalo conflitante; devolve false se err não
+// for um conflito de horário
+func responderConflito(c *gin.Context, err error) bool {
+	var conflito *storage.ConflitoAgendamento
+	if !errors.As(err, &conflito) {
+		return false
+	}
+	c.JSON(http.StatusConflict, gin.H{
+		"error":    "Horário indisponível: o profissional já tem um agendamento nesse intervalo",
+		"conflito": gin.H{"inicio": conflito.Inicio, "fim": conflito.Fim},
+	})
+	return true
+}
+
+// transicaoDe registra o usuário autenticado como autor de uma mudança de status
+func transicaoDe(c *gin.Context, motivo string) models.TransicaoStatus {
+	return models.TransicaoStatus{
+		PorUID:  c.GetString("uid"),
+		PorTipo: c.GetString("tipo"),
+		Em:      time.Now(),
+		Motivo:  motivo,
+	}
+}
+
+// BuscarAgendamento retorna um agendamento com seu histórico de status
+// @Summary Buscar agendamento
+// @Tags Agendamentos
+// @Produce json
+// @Param id path string true "ID do agendamento"
+// @Success 200 {object} models.Agendamento
+// @Router /agendamentos/{id} [get]
+func (h *Handler) BuscarAgendamento(c *gin.Context) {
+	ag, err := h.repos.Agendamentos.Buscar(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, storage.ErrNaoEncontrado) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Agendamento não encontrado"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar agendamento"})
+		return
+	}
+	ag.Status = ag.StatusAtual()
+	h.responderAgendamento(c, http.StatusOK, *ag)
+}
+
+// ConfirmarAgendamento marca um agendamento pendente como confirmado pelo profissional
+// @Summary Confirmar agendamento
+// @Tags Agendamentos
+// @Produce json
+// @Param id path string true "ID do agendamento"
+// @Success 200 {object} models.Agendamento
+// @Router /agendamentos/{id}/confirmar [post]
+func (h *Handler) ConfirmarAgendamento(c *gin.Context) {
+	h.mudarStatus(c, models.StatusConfirmado, false)
+}
+
+// ConcluirAgendamento marca um agendamento confirmado como realizado
+// @Summary Concluir agendamento
+// @Tags Agendamentos
+// @Produce json
+// @Param id path string true "ID do agendamento"
+// @Success 200 {object} models.Agendamento
+// @Router /agendamentos/{id}/concluir [post]
+func (h *Handler) ConcluirAgendamento(c *gin.Context) {
+	h.mudarStatus(c, models.StatusConcluido, true)
+}
+
+// CancelarAgendamento cancela um agendamento pendente ou confirmado
+// @Summary Cancelar agendamento
+// @Tags Agendamentos
+// @Accept json
+// @Produce json
+// @Param id path string true "ID do agendamento"
+// @Param dados body models.MotivoInput false "Motivo do cancelamento"
+// @Success 200 {object} models.Agendamento
+// @Router /agendamentos/{id}/cancelar [post]
+func (h *Handler) CancelarAgendamento(c *gin.Context) {
+	h.mudarStatus(c, models.StatusCancelado, false)
+}
+
+// MarcarNaoComparecimento registra que o cliente não compareceu ao atendimento confirmado
+// @Summary Registrar não comparecimento
+// @Tags Agendamentos
+// @Produce json
+// @Param id path string true "ID do agendamento"
+// @Success 200 {object} models.Agendamento
+// @Router /agendamentos/{id}/nao-compareceu [post]
+func (h *Handler) MarcarNaoComparecimento(c *gin.Context) {
+	h.mudarStatus(c, models.StatusNaoCompareceu, true)
+}
+
+// mudarStatus aplica a transição ao agendamento da rota. Com exigirInicio, a transição
+// só é aceita a partir do horário marcado. Cancelamentos e faltas passam pela política
+// de cancelamento e podem gerar uma taxa no agendamento.
+func (h *Handler) mudarStatus(c *gin.Context, para string, exigirInicio bool) {
+	var input models.MotivoInput
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos"})
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	transicao := transicaoDe(c, input.Motivo)
+
+	// A taxa é calculada sobre o agendamento lido dentro da atualização, para que um
+	// reagendamento concorrente não deixe uma taxa com horário ou preço antigos
+	var errPolitica error
+	ag, err := h.repos.Agendamentos.Atualizar(ctx, c.Param("id"), func(ag *models.Agendamento) error {
+		if exigirInicio && transicao.Em.Before(ag.DataHora) {
+			return errAtendimentoNaoIniciado
+		}
+		var politica *models.PoliticaCancelamento
+		var preco float64
+		if cobraTaxa(para, transicao.PorTipo) {
+			if politica, preco, errPolitica = h.politicaCancelamento(ctx, *ag); errPolitica != nil {
+				return errPolitica
+			}
+		}
+		if err := ag.Transicionar(para, transicao); err != nil {
+			return err
+		}
+		if politica != nil {
+			ag.Taxa = politica.Taxa(*ag, preco)
+		}
+		return nil
+	})
+	if errPolitica != nil && errors.Is(err, errPolitica) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao consultar a política de cancelamento"})
+		return
+	}
+	h.responderAtualizacao(c, ag, err)
+}
+
+// ReagendarAgendamento move o agendamento para outro horário, repetindo as validações de
+// expediente de AgendarHorario. Da cópia feita na reserva, só a duração é atualizada, pela
+// do procedimento se ele ainda existir; nome e preço ficam como foram reservados. O
+// agendamento volta a pendente até o profissional confirmar.
+// @Summary Reagendar
+// @Tags Agendamentos
+// @Accept json
+// @Produce json
+// @Param id path string true "ID do agendamento"
+// @Param dados body models.ReagendamentoInput true "Novo horário"
+// @Success 200 {object} models.Agendamento
+// @Failure 409 {object} map[string]interface{} "Conflito com outro agendamento"
+// @Router /agendamentos/{id}/reagendar [post]
+func (h *Handler) ReagendarAgendamento(c *gin.Context) {
+	var input models.ReagendamentoInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos"})
+		return
+	}
+	ctx := c.Request.Context()
+
+	atual, err := h.repos.Agendamentos.Buscar(ctx, c.Param("id"))
+	if err != nil {
+		h.responderAtualizacao(c, nil, err)
+		return
+	}
+
+	// O novo horário vale no estabelecimento atual do profissional, que pode ter mudado desde a reserva
+	candidato := *atual
+	candidato.DataHora = input.DataHora
+	candidato.EstabelecimentoID = ""
+
+	// Só a duração é atualizada pela do procedimento atual; nome e preço continuam os da
+	// reserva. Se o procedimento foi removido, vale a duração copiada na reserva.
+	proc, err := h.procedimentoAgendado(ctx, candidato)
+	switch {
+	case err == nil:
+		candidato.DuracaoMin = proc.DuracaoMin
+	case !errors.Is(err, storage.ErrNaoEncontrado):
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar procedimento"})
+		return
+	}
+	if !h.validarHorario(c, &candidato) {
+		return
+	}
+
+	transicao := transicaoDe(c, input.Motivo)
+	ag, err := h.repos.Agendamentos.Atualizar(ctx, atual.ID, func(ag *models.Agendamento) error {
+		if err := ag.Reagendar(candidato.DataHora, candidato.DuracaoMin, transicao); err != nil {
+			return err
+		}
+		// O agendamento passa ao estabelecimento em que o novo horário foi validado
+		ag.EstabelecimentoID = candidato.EstabelecimentoID
+		return nil
+	})
+	h.responderAtualizacao(c, ag, err)
+}
+
+// responderAgendamento escreve o agendamento com o horário no fuso do profissional
+func (h *Handler) responderAgendamento(c *gin.Context, status int, ag models.Agendamento) {
+	lista := []models.Agendamento{ag}
+	if err := h.localizarAgendamentos(c.Request.Context(), lista); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar fuso horário do profissional"})
+		return
+	}
+	c.JSON(status, lista[0])
+}
+
+// responderAtualizacao traduz o resultado de AgendamentoRepository.Atualizar em resposta HTTP
+func (h *Handler) responderAtualizacao(c *gin.Context, ag *models.Agendamento, err error) {
+	switch {
+	case err == nil:
+		h.responderAgendamento(c, http.StatusOK, *ag)
+	case errors.Is(err, storage.ErrNaoEncontrado):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Agendamento não encontrado"})
+	case errors.Is(err, models.ErrTransicaoInvalida):
+		c.JSON(http.StatusConflict, gin.H{"error": "O status atual do agendamento não permite esta operação"})
+	case errors.Is(err, errAtendimentoNaoIniciado):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "O atendimento ainda não começou"})
+	default:
+		if !responderConflito(c, err) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao atualizar agendamento"})
+		}
+	}
+}
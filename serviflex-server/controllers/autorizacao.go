@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"servico-api/autorizacao"
+	"servico-api/storage"
+)
+
+// erroPolitica traduz a ausência do recurso para o erro esperado pelas políticas
+func erroPolitica(err error) (string, error) {
+	if errors.Is(err, storage.ErrNaoEncontrado) {
+		return "", autorizacao.ErrRecursoNaoEncontrado
+	}
+	return "", err
+}
+
+// ResponsavelEstabelecimento devolve o UID do responsável pelo estabelecimento
+func (h *Handler) ResponsavelEstabelecimento(ctx context.Context, id string) (string, error) {
+	e, err := h.repos.Estabelecimentos.Buscar(ctx, id)
+	if err != nil {
+		return erroPolitica(err)
+	}
+	return e.ResponsavelUID, nil
+}
+
+// ProfissionalDoProcedimento devolve o UID do profissional dono do procedimento
+func (h *Handler) ProfissionalDoProcedimento(ctx context.Context, id string) (string, error) {
+	p, err := h.repos.Procedimentos.Buscar(ctx, id)
+	if err != nil {
+		return erroPolitica(err)
+	}
+	return p.ProfissionalID, nil
+}
+
+// ProfissionalDoHorario devolve o UID do profissional dono do horário
+func (h *Handler) ProfissionalDoHorario(ctx context.Context, id string) (string, error) {
+	hr, err := h.repos.Horarios.Buscar(ctx, id)
+	if err != nil {
+		return erroPolitica(err)
+	}
+	return hr.ProfissionalID, nil
+}
+
+// ProfissionalDaExcecao devolve o UID do profissional dono da exceção de horário
+func (h *Handler) ProfissionalDaExcecao(ctx context.Context, id string) (string, error) {
+	e, err := h.repos.Excecoes.Buscar(ctx, id)
+	if err != nil {
+		return erroPolitica(err)
+	}
+	return e.ProfissionalID, nil
+}
+
+// DestinatarioNotificacao devolve o UID de quem recebeu a notificação
+func (h *Handler) DestinatarioNotificacao(ctx context.Context, id string) (string, error) {
+	n, err := h.repos.Notificacoes.Buscar(ctx, id)
+	if err != nil {
+		return erroPolitica(err)
+	}
+	return n.ParaUID, nil
+}
+
+// ClienteDoAgendamento devolve o UID do cliente que fez o agendamento
+func (h *Handler) ClienteDoAgendamento(ctx context.Context, id string) (string, error) {
+	ag, err := h.repos.Agendamentos.Buscar(ctx, id)
+	if err != nil {
+		return erroPolitica(err)
+	}
+	return ag.ClienteID, nil
+}
+
+// ProfissionalDoAgendamento devolve o UID do profissional que fará o atendimento
+func (h *Handler) ProfissionalDoAgendamento(ctx context.Context, id string) (string, error) {
+	ag, err := h.repos.Agendamentos.Buscar(ctx, id)
+	if err != nil {
+		return erroPolitica(err)
+	}
+	return ag.ProfissionalID, nil
+}
+
+// ClienteDaAvaliacao devolve o UID do cliente que escreveu a avaliação
+func (h *Handler) ClienteDaAvaliacao(ctx context.Context, id string) (string, error) {
+	a, err := h.repos.Avaliacoes.Buscar(ctx, id)
+	if err != nil {
+		return erroPolitica(err)
+	}
+	return a.ClienteID, nil
+}
+
+// ProfissionalDaAvaliacao devolve o UID do profissional avaliado
+func (h *Handler) ProfissionalDaAvaliacao(ctx context.Context, id string) (string, error) {
+	a, err := h.repos.Avaliacoes.Buscar(ctx, id)
+	if err != nil {
+		return erroPolitica(err)
+	}
+	return a.ProfissionalID, nil
+}
+
+// ResponsavelDaAvaliacao devolve o UID do responsável pelo estabelecimento avaliado;
+// avaliações sem estabelecimento não têm responsável
+func (h *Handler) ResponsavelDaAvaliacao(ctx context.Context, id string) (string, error) {
+	a, err := h.repos.Avaliacoes.Buscar(ctx, id)
+	if err != nil {
+		return erroPolitica(err)
+	}
+	if a.EstabelecimentoID == "" {
+		return "", nil
+	}
+	return h.ResponsavelEstabelecimento(ctx, a.EstabelecimentoID)
+}
@@ -0,0 +1,247 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"servico-api/models"
+	"servico-api/storage"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errJanelaEdicaoEncerrada recusa a edição de avaliações enviadas há mais de 7 dias
+var errJanelaEdicaoEncerrada = errors.New("janela de edição encerrada")
+
+// CriarAvaliacao registra a avaliação do cliente sobre um atendimento concluído. Cada
+// agendamento aceita uma única avaliação, enviada em até 30 dias após o atendimento.
+// @Summary Avaliar atendimento
+// @Tags Avaliações
+// @Accept json
+// @Produce json
+// @Param dados body models.AvaliacaoInput true "Agendamento, nota de 1 a 5 e comentário"
+// @Success 201 {object} models.Avaliacao
+// @Failure 409 {object} map[string]interface{} "Atendimento não concluído, fora do prazo ou já avaliado"
+// @Router /avaliacoes [post]
+func (h *Handler) CriarAvaliacao(c *gin.Context) {
+	var input models.AvaliacaoInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos"})
+		return
+	}
+	if err := input.Validar(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx := c.Request.Context()
+
+	ag, err := h.repos.Agendamentos.Buscar(ctx, input.AgendamentoID)
+	if errors.Is(err, storage.ErrNaoEncontrado) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Agendamento não encontrado"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar agendamento"})
+		return
+	}
+	if ag.StatusAtual() != models.StatusConcluido {
+		c.JSON(http.StatusConflict, gin.H{"error": "Só é possível avaliar atendimentos concluídos"})
+		return
+	}
+	agora := time.Now()
+	if agora.After(ag.Fim().Add(models.PrazoAvaliacao)) {
+		c.JSON(http.StatusConflict, gin.H{"error": "O prazo para avaliar este atendimento terminou"})
+		return
+	}
+
+	avaliacao := models.Avaliacao{
+		ID:                ag.ID,
+		AgendamentoID:     ag.ID,
+		ProfissionalID:    ag.ProfissionalID,
+		ClienteID:         ag.ClienteID,
+		EstabelecimentoID: ag.EstabelecimentoID,
+		Nota:              input.Nota,
+		Comentario:        input.Comentario,
+		Data:              agora,
+	}
+	err = h.repos.Avaliacoes.Criar(ctx, avaliacao)
+	if errors.Is(err, storage.ErrJaExiste) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Este atendimento já foi avaliado"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao salvar avaliação"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, avaliacao)
+}
+
+// EditarAvaliacao altera nota e comentário de uma avaliação em até 7 dias após o envio
+// @Summary Editar avaliação
+// @Tags Avaliações
+// @Accept json
+// @Produce json
+// @Param id path string true "ID da avaliação"
+// @Param dados body models.AvaliacaoInput true "Nova nota e comentário"
+// @Success 200 {object} models.Avaliacao
+// @Failure 409 {object} map[string]interface{} "Janela de edição encerrada"
+// @Router /avaliacoes/{id} [put]
+func (h *Handler) EditarAvaliacao(c *gin.Context) {
+	var input models.AvaliacaoInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos"})
+		return
+	}
+	if err := input.Validar(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx := c.Request.Context()
+
+	agora := time.Now()
+	avaliacao, err := h.repos.Avaliacoes.Atualizar(ctx, c.Param("id"), func(a *models.Avaliacao) error {
+		if a.SituacaoAtual() == models.SituacaoExcluida {
+			return storage.ErrNaoEncontrado
+		}
+		if !a.Editavel(agora) {
+			return errJanelaEdicaoEncerrada
+		}
+		a.Nota = input.Nota
+		a.Comentario = input.Comentario
+		a.EditadaEm = &agora
+		return nil
+	})
+	h.responderAvaliacao(c, avaliacao, err, false)
+}
+
+// ResponderAvaliacao publica a resposta do profissional ou do responsável pelo
+// estabelecimento. Cada avaliação aceita uma única resposta.
+// @Summary Responder avaliação
+// @Tags Avaliações
+// @Accept json
+// @Produce json
+// @Param id path string true "ID da avaliação"
+// @Param dados body models.RespostaInput true "Texto da resposta"
+// @Success 200 {object} models.Avaliacao
+// @Failure 409 {object} map[string]interface{} "Avaliação já respondida ou fora do ar"
+// @Router /avaliacoes/{id}/resposta [post]
+func (h *Handler) ResponderAvaliacao(c *gin.Context) {
+	var input models.RespostaInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos"})
+		return
+	}
+	input.Texto = strings.TrimSpace(input.Texto)
+	if input.Texto == "" || len([]rune(input.Texto)) > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A resposta deve ter de 1 a 1000 caracteres"})
+		return
+	}
+
+	resposta := models.RespostaAvaliacao{Texto: input.Texto, PorUID: c.GetString("uid"), Em: time.Now()}
+	avaliacao, err := h.repos.Avaliacoes.Atualizar(c.Request.Context(), c.Param("id"), func(a *models.Avaliacao) error {
+		return a.Responder(resposta)
+	})
+	h.responderAvaliacao(c, avaliacao, err, false)
+}
+
+// DenunciarAvaliacao registra a denúncia de abuso de qualquer usuário e coloca a
+// avaliação na fila de moderação dos admins
+// @Summary Denunciar avaliação
+// @Tags Avaliações
+// @Accept json
+// @Produce json
+// @Param id path string true "ID da avaliação"
+// @Param dados body models.MotivoInput true "Motivo da denúncia"
+// @Success 200 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{} "Avaliação já denunciada pelo usuário"
+// @Router /avaliacoes/{id}/denunciar [post]
+func (h *Handler) DenunciarAvaliacao(c *gin.Context) {
+	motivo, ok := motivoObrigatorio(c)
+	if !ok {
+		return
+	}
+
+	denuncia := models.DenunciaAvaliacao{PorUID: c.GetString("uid"), Motivo: motivo, Em: time.Now()}
+	_, err := h.repos.Avaliacoes.Atualizar(c.Request.Context(), c.Param("id"), func(a *models.Avaliacao) error {
+		return a.Denunciar(denuncia)
+	})
+	if err != nil {
+		h.responderAvaliacao(c, nil, err, false)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"mensagem": "Denúncia registrada, a avaliação será analisada"})
+}
+
+// motivoObrigatorio lê o corpo {"motivo": "..."} e responde 400 se o motivo estiver vazio
+func motivoObrigatorio(c *gin.Context) (string, bool) {
+	var input models.MotivoInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos"})
+		return "", false
+	}
+	motivo := strings.TrimSpace(input.Motivo)
+	if motivo == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Informe o motivo"})
+		return "", false
+	}
+	return motivo, true
+}
+
+// avaliacoesPublicas mantém só as avaliações visíveis, sem os dados de moderação
+func avaliacoesPublicas(avaliacoes []models.Avaliacao) []models.Avaliacao {
+	publicas := []models.Avaliacao{}
+	for _, a := range avaliacoes {
+		if a.Visivel() {
+			publicas = append(publicas, a.Publica())
+		}
+	}
+	return publicas
+}
+
+// resumosPontuados busca os resumos de notas dos ids e calcula a pontuação bayesiana de
+// cada um em relação ao resumo geral do tipo de alvo. Ids sem avaliações recebem o resumo
+// zerado, cuja pontuação é a própria média geral.
+func (h *Handler) resumosPontuados(ctx context.Context, alvo string, ids []string) (map[string]models.ResumoAvaliacoes, error) {
+	geral, err := h.repos.Avaliacoes.BuscarResumo(ctx, alvo, "")
+	if err != nil {
+		return nil, err
+	}
+	resumos, err := h.repos.Avaliacoes.BuscarResumos(ctx, alvo, ids)
+	if err != nil {
+		return nil, err
+	}
+	pontuados := make(map[string]models.ResumoAvaliacoes, len(ids))
+	for _, id := range ids {
+		resumo := resumos[id].ComHistograma()
+		resumo.Pontuacao = resumo.Bayesiana(geral)
+		pontuados[id] = resumo
+	}
+	return pontuados, nil
+}
+
+// responderAvaliacao traduz o resultado de AvaliacaoRepository.Atualizar em resposta HTTP.
+// Só com completa as denúncias e o histórico de moderação vão na resposta.
+func (h *Handler) responderAvaliacao(c *gin.Context, a *models.Avaliacao, err error, completa bool) {
+	switch {
+	case err == nil:
+		if !completa {
+			*a = a.Publica()
+		}
+		c.JSON(http.StatusOK, a)
+	case errors.Is(err, storage.ErrNaoEncontrado):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Avaliação não encontrada"})
+	case errors.Is(err, errJanelaEdicaoEncerrada):
+		c.JSON(http.StatusConflict, gin.H{"error": "O prazo para editar esta avaliação terminou"})
+	case errors.Is(err, models.ErrJaRespondida):
+		c.JSON(http.StatusConflict, gin.H{"error": "Esta avaliação já foi respondida"})
+	case errors.Is(err, models.ErrJaDenunciada):
+		c.JSON(http.StatusConflict, gin.H{"error": "Você já denunciou esta avaliação"})
+	case errors.Is(err, models.ErrModeracaoInvalida):
+		c.JSON(http.StatusConflict, gin.H{"error": "A situação atual da avaliação não permite esta operação"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao atualizar avaliação"})
+	}
+}
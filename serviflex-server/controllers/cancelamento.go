@@ -0,0 +1,56 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"servico-api/autorizacao"
+	"servico-api/models"
+	"servico-api/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// politicaValida rejeita com 400 uma política de cancelamento fora dos limites
+func politicaValida(c *gin.Context, politica *models.PoliticaCancelamento) bool {
+	if politica == nil {
+		return true
+	}
+	if err := politica.Validar(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Política de cancelamento inválida: " + err.Error()})
+		return false
+	}
+	return true
+}
+
+// cobraTaxa indica se a transição pode gerar taxa: faltas sempre, cancelamentos só
+// quando feitos pelo próprio cliente
+func cobraTaxa(para, tipo string) bool {
+	return para == models.StatusNaoCompareceu || (para == models.StatusCancelado && tipo == autorizacao.TipoCliente)
+}
+
+// politicaCancelamento devolve a política que vale para o agendamento e o preço sobre o
+// qual as taxas incidem, que é o preço copiado na reserva. Um procedimento que não existe
+// mais deixa valer a política do estabelecimento; agendamentos antigos sem estabelecimento
+// usam o do profissional.
+func (h *Handler) politicaCancelamento(ctx context.Context, ag models.Agendamento) (*models.PoliticaCancelamento, float64, error) {
+	proc, err := h.procedimentoAgendado(ctx, ag)
+	if err != nil && !errors.Is(err, storage.ErrNaoEncontrado) {
+		return nil, 0, err
+	}
+
+	estabID := ag.EstabelecimentoID
+	if estabID == "" {
+		if estabID, err = h.estabelecimentoDoProfissional(ctx, ag.ProfissionalID); err != nil {
+			return nil, 0, err
+		}
+	}
+	var estab *models.Estabelecimento
+	if estabID != "" {
+		estab, err = h.repos.Estabelecimentos.Buscar(ctx, estabID)
+		if err != nil && !errors.Is(err, storage.ErrNaoEncontrado) {
+			return nil, 0, err
+		}
+	}
+	return models.PoliticaAplicavel(estab, proc), ag.Preco, nil
+}
@@ -2,16 +2,15 @@ package controllers
 
 import (
 	"context"
-	"fmt"
 	"net/http"
-	"servico-api/config"
+	"servico-api/exportacao"
 	"servico-api/models"
-	"time"
+	"servico-api/storage"
+	"servico-api/utils"
 
-	"cloud.google.com/go/firestore"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-) // adicione no topo se ainda não tiver
+)
 
 // AgendarHorario cria um agendamento entre cliente e profissional
 // @Summary Agendar horário
@@ -20,130 +19,75 @@ import (
 // @Produce json
 // @Param agendamento body models.Agendamento true "Dados do agendamento"
 // @Success 201 {object} models.Agendamento
+// @Failure 409 {object} map[string]interface{} "Conflito com outro agendamento"
 // @Router /agendamentos [post]
-func AgendarHorario(c *gin.Context) {
+func (h *Handler) AgendarHorario(c *gin.Context) {
 	var agendamento models.Agendamento
 	if err := c.ShouldBindJSON(&agendamento); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos"})
 		return
 	}
 
-	ctx := context.Background()
-	client, err := config.App.Firestore(ctx)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao conectar com Firestore"})
-		return
-	}
-	defer client.Close()
-
-	// Busca procedimento
-	procDocs, err := client.Collection("procedimentos").
-		Where("profissional_id", "==", agendamento.ProfissionalID).
-		Where("nome", "==", agendamento.Procedimento).
-		Limit(1).Documents(ctx).GetAll()
-
-	if err != nil || len(procDocs) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Procedimento inválido"})
-		return
-	}
-
-	duracaoMin := int(procDocs[0].Data()["duracao_min"].(int64))
-	duracao := time.Duration(duracaoMin) * time.Minute
-
-	// Corrigir o fuso e descobrir dia da semana
-	localDateTime := agendamento.DataHora.In(time.Local)
-	diaSemana := diaDaSemana(localDateTime.Weekday())
-	fmt.Println("Dia da semana calculado:", diaSemana)
-
-	// Buscar horários do profissional no Firestore
-	horariosSnap, err := client.Collection("horarios").
-		Where("profissional_id", "==", agendamento.ProfissionalID).
-		Where("dia_semana", "==", diaSemana).
-		Documents(ctx).GetAll()
-
-	if err != nil {
-		fmt.Println("Erro ao buscar horários:", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar horários"})
+	if !h.validarAgendamento(c, &agendamento) {
 		return
 	}
 
-	if len(horariosSnap) == 0 {
-		fmt.Println("Nenhum horário encontrado para", agendamento.ProfissionalID, "no dia", diaSemana)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Profissional não trabalha nesse dia"})
-		return
-	}
-
-	fmt.Println("Horários encontrados:")
-	for _, h := range horariosSnap {
-		fmt.Println(h.Data())
-	}
-
-	// Validar se o horário está dentro do expediente
-	agHora := agendamento.DataHora.In(time.Local)
-	inicioAg := time.Date(0, 1, 1, agHora.Hour(), agHora.Minute(), 0, 0, time.UTC)
-	fimAg := inicioAg.Add(duracao)
-
-	valid := false
-	for _, h := range horariosSnap {
-		data := h.Data()
-		hIniStr := data["hora_inicio"].(string)
-		hFimStr := data["hora_fim"].(string)
-
-		hIni, _ := time.Parse("15:04", hIniStr)
-		hFim, _ := time.Parse("15:04", hFimStr)
-
-		if (inicioAg.Equal(hIni) || inicioAg.After(hIni)) && (fimAg.Equal(hFim) || fimAg.Before(hFim)) {
-			valid = true
-			break
+	// Reservar o horário: falha se outro agendamento do profissional se sobrepõe
+	agendamento.ID = uuid.New().String()
+	agendamento.Iniciar(transicaoDe(c, ""))
+	if err := h.repos.Agendamentos.Reservar(c.Request.Context(), agendamento); err != nil {
+		if !responderConflito(c, err) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao salvar agendamento"})
 		}
-	}
-
-	if !valid {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Horário não está dentro do expediente do profissional"})
 		return
 	}
-
-	// Criar agendamento
-	agendamento.ID = uuid.New().String()
-	client.Collection("agendamentos").Doc(agendamento.ID).Set(ctx, agendamento)
 	c.JSON(http.StatusCreated, agendamento)
 }
 
-// ListarAgendamentosPorCliente retorna todos os agendamentos do cliente ordenados por data
+// ListarAgendamentosPorCliente retorna todos os agendamentos do cliente ordenados por data.
+// Com o parâmetro formato, exporta a agenda como arquivo, no horário local de cada atendimento.
 // @Summary Listar agendamentos do cliente
 // @Tags Agendamentos
 // @Produce json
 // @Param id path string true "ID do cliente"
+// @Param formato query string false "json (padrão), csv, xlsx ou pdf"
 // @Success 200 {array} models.Agendamento
 // @Router /agendamentos/cliente/{id} [get]
-func ListarAgendamentosPorCliente(c *gin.Context) {
+func (h *Handler) ListarAgendamentosPorCliente(c *gin.Context) {
 	clienteID := c.Param("id")
-	ctx := context.Background()
-	client, err := config.App.Firestore(ctx)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro na conexão com Firestore"})
+	ctx := c.Request.Context()
+
+	formato, ok := formatoExportacao(c)
+	if !ok {
+		return
+	}
+	if formato != exportacao.FormatoJSON {
+		// Um cliente pode ter atendimentos em vários estabelecimentos: o arquivo não leva cabeçalho de estabelecimento
+		loc, err := models.CarregarFuso("")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao carregar fuso horário"})
+			return
+		}
+		doc := documento{titulo: "Agendamentos", arquivo: []string{"agendamentos", clienteID}}
+		if nome, err := h.nomeCliente(ctx, clienteID); err == nil && nome != "" {
+			doc.titulo += " de " + nome
+		}
+		nomes := nomesEmCache(h.nomeProfissional)
+		h.exportarAgendamentos(c, formato, loc, storage.FiltroAgendamento{ClienteID: clienteID}, doc, "Profissional", func(ctx context.Context, ag models.Agendamento) (string, error) {
+			return nomes(ctx, ag.ProfissionalID)
+		})
 		return
 	}
-	defer client.Close()
-
-	docs, err := client.Collection("agendamentos").
-		Where("cliente_id", "==", clienteID).
-		OrderBy("data_hora", firestore.Asc).
-		Documents(ctx).GetAll()
 
+	agendamentos, err := h.repos.Agendamentos.Listar(ctx, storage.FiltroAgendamento{ClienteID: clienteID})
+	if err == nil {
+		err = h.localizarAgendamentos(ctx, agendamentos)
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar agendamentos"})
 		return
 	}
 
-	var agendamentos []models.Agendamento
-	for _, doc := range docs {
-		var ag models.Agendamento
-		if err := doc.DataTo(&ag); err == nil {
-			agendamentos = append(agendamentos, ag)
-		}
-	}
-
 	c.JSON(http.StatusOK, agendamentos)
 }
 
@@ -159,7 +103,7 @@ func ListarAgendamentosPorCliente(c *gin.Context) {
 // @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
 // @Router /usuarios/{id} [put]
-func EditarUsuario(c *gin.Context) {
+func (h *Handler) EditarUsuario(c *gin.Context) {
 	id := c.Param("id")
 	tipo := c.Query("tipo") // Recebe o tipo como query parameter
 
@@ -173,18 +117,11 @@ func EditarUsuario(c *gin.Context) {
 		return
 	}
 
-	ctx := context.Background()
-	client, err := config.App.Firestore(ctx)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao conectar com Firestore"})
-		return
-	}
-	defer client.Close()
+	ctx := c.Request.Context()
 
 	// Verifica se o usuário existe na collection específica
-	docRef := client.Collection(tipo).Doc(id)
-	snap, err := docRef.Get(ctx)
-	if err != nil || !snap.Exists() {
+	atual, err := h.repos.Usuarios.BuscarUsuario(ctx, tipo, id)
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Usuário não encontrado na collection " + tipo})
 		return
 	}
@@ -198,7 +135,11 @@ func EditarUsuario(c *gin.Context) {
 			return
 		}
 		input.ID = id // Mantém o ID original
-		if _, err := docRef.Set(ctx, input); err != nil {
+		if input.Senha, err = senhaParaSalvar(input.Senha, atual.Senha); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao processar senha"})
+			return
+		}
+		if err := h.repos.Usuarios.SalvarCliente(ctx, input); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao atualizar cliente"})
 			return
 		}
@@ -210,7 +151,13 @@ func EditarUsuario(c *gin.Context) {
 			return
 		}
 		input.ID = id // Mantém o ID original
-		if _, err := docRef.Set(ctx, input); err != nil {
+		// O estabelecimento só muda pelo aceite de convite ou pela remoção do vínculo
+		input.EstabelecimentoID = atual.EstabelecimentoID
+		if input.Senha, err = senhaParaSalvar(input.Senha, atual.Senha); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao processar senha"})
+			return
+		}
+		if err := h.repos.Usuarios.SalvarProfissional(ctx, input); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao atualizar profissional"})
 			return
 		}
@@ -222,7 +169,11 @@ func EditarUsuario(c *gin.Context) {
 			return
 		}
 		input.ID = id // Mantém o ID original
-		if _, err := docRef.Set(ctx, input); err != nil {
+		if input.Senha, err = senhaParaSalvar(input.Senha, atual.Senha); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao processar senha"})
+			return
+		}
+		if err := h.repos.Usuarios.SalvarAdmin(ctx, input); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao atualizar admin"})
 			return
 		}
@@ -231,23 +182,10 @@ func EditarUsuario(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Usuário atualizado com sucesso"})
 }
 
-func diaDaSemana(weekday time.Weekday) string {
-	switch weekday {
-	case time.Monday:
-		return "Segunda"
-	case time.Tuesday:
-		return "Terça"
-	case time.Wednesday:
-		return "Quarta"
-	case time.Thursday:
-		return "Quinta"
-	case time.Friday:
-		return "Sexta"
-	case time.Saturday:
-		return "Sábado"
-	case time.Sunday:
-		return "Domingo"
-	default:
-		return ""
+// senhaParaSalvar devolve o hash da nova senha ou, se ela vier vazia, mantém o hash já armazenado
+func senhaParaSalvar(nova, hashAtual string) (string, error) {
+	if nova == "" {
+		return hashAtual, nil
 	}
+	return utils.HashSenha(nova)
 }
@@ -0,0 +1,394 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"servico-api/exportacao"
+	"servico-api/models"
+	"servico-api/relatorios"
+	"servico-api/storage"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ComissoesInput substitui todas as regras de comissão do estabelecimento; lista vazia remove as regras
+type ComissoesInput struct {
+	Regras []models.RegraComissao `json:"regras"`
+}
+
+// ListarComissoes devolve as regras de comissão do estabelecimento
+// @Summary Listar regras de comissão
+// @Tags Comissões
+// @Produce json
+// @Param id path string true "ID do estabelecimento"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]string
+// @Router /estabelecimentos/{id}/comissoes [get]
+func (h *Handler) ListarComissoes(c *gin.Context) {
+	estabID := c.Param("id")
+
+	e, err := h.repos.Estabelecimentos.Buscar(c.Request.Context(), estabID)
+	if errors.Is(err, storage.ErrNaoEncontrado) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Estabelecimento não encontrado"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar estabelecimento"})
+		return
+	}
+
+	regras := e.Comissoes
+	if regras == nil {
+		regras = []models.RegraComissao{}
+	}
+	c.JSON(http.StatusOK, gin.H{"estabelecimento_id": estabID, "regras": regras})
+}
+
+// DefinirComissoes substitui as regras de comissão do estabelecimento. Cada regra pode se
+// restringir a um profissional vinculado e a um procedimento de um deles; a mais
+// específica vale para cada atendimento.
+// @Summary Definir regras de comissão
+// @Tags Comissões
+// @Accept json
+// @Produce json
+// @Param id path string true "ID do estabelecimento"
+// @Param regras body ComissoesInput true "Regras percentual, fixa ou escalonada"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /estabelecimentos/{id}/comissoes [put]
+func (h *Handler) DefinirComissoes(c *gin.Context) {
+	estabID := c.Param("id")
+	ctx := c.Request.Context()
+
+	var input ComissoesInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos"})
+		return
+	}
+	if err := models.ValidarRegrasComissao(input.Regras); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Regras de comissão inválidas: " + err.Error()})
+		return
+	}
+
+	e, err := h.repos.Estabelecimentos.Buscar(ctx, estabID)
+	if errors.Is(err, storage.ErrNaoEncontrado) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Estabelecimento não encontrado"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar estabelecimento"})
+		return
+	}
+
+	// Regras só podem citar profissionais vinculados e procedimentos deles
+	vinculos, err := h.repos.Estabelecimentos.ListarProfissionais(ctx, estabID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar profissionais"})
+		return
+	}
+	vinculados := make(map[string]bool, len(vinculos))
+	for _, v := range vinculos {
+		vinculados[v.UID] = true
+	}
+	for _, r := range input.Regras {
+		if r.ProfissionalID != "" && !vinculados[r.ProfissionalID] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Profissional " + r.ProfissionalID + " não está vinculado ao estabelecimento"})
+			return
+		}
+		if r.ProcedimentoID == "" {
+			continue
+		}
+		proc, err := h.repos.Procedimentos.Buscar(ctx, r.ProcedimentoID)
+		if err != nil && !errors.Is(err, storage.ErrNaoEncontrado) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar procedimento"})
+			return
+		}
+		if proc == nil || !vinculados[proc.ProfissionalID] || (r.ProfissionalID != "" && proc.ProfissionalID != r.ProfissionalID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Procedimento " + r.ProcedimentoID + " não pertence a um profissional do estabelecimento"})
+			return
+		}
+	}
+
+	e.Comissoes = input.Regras
+	if err := h.repos.Estabelecimentos.Salvar(ctx, *e); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao salvar regras de comissão"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"estabelecimento_id": estabID, "regras": input.Regras})
+}
+
+// periodoRepasse interpreta de e ate no fuso do estabelecimento; sem período, o mês
+// atual. Extratos não agrupam nem comparam. Em caso de erro já escreve a resposta.
+func periodoRepasse(c *gin.Context, loc *time.Location) (relatorios.Periodo, bool) {
+	hoje := time.Now().In(loc)
+	padrao := relatorios.UltimosMeses(hoje, 1)
+	padrao.Agrupar = relatorios.SemAgrupamento
+	consulta, err := relatorios.Interpretar(c.Query, padrao, hoje)
+	if err == nil && (consulta.Agrupar != relatorios.SemAgrupamento || consulta.Comparar) {
+		err = errors.New("extratos de repasse não aceitam agrupar nem comparar")
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return relatorios.Periodo{}, false
+	}
+	return consulta.Periodo, true
+}
+
+// repassesDoPeriodo calcula o extrato de cada profissional com atendimentos concluídos
+// no estabelecimento durante o período; com profissionalID, só o dele. Os profissionais
+// vinculados sem atendimentos também aparecem, zerados.
+func (h *Handler) repassesDoPeriodo(c *gin.Context, e *models.Estabelecimento, loc *time.Location, periodo relatorios.Periodo, profissionalID string) ([]models.ExtratoRepasse, error) {
+	ctx := c.Request.Context()
+	filtro := noPeriodo(storage.FiltroAgendamento{EstabelecimentoID: e.ID, ProfissionalID: profissionalID, Status: models.StatusConcluido}, periodo)
+	agendamentos, err := h.repos.Agendamentos.Listar(ctx, filtro)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	porProfissional := make(map[string][]models.Agendamento)
+	nomes := make(map[string]string)
+	adicionar := func(id string) {
+		if _, ok := porProfissional[id]; !ok {
+			ids = append(ids, id)
+			porProfissional[id] = nil
+		}
+	}
+	if profissionalID != "" {
+		adicionar(profissionalID)
+	} else {
+		vinculos, err := h.repos.Estabelecimentos.ListarProfissionais(ctx, e.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range vinculos {
+			adicionar(v.UID)
+			nomes[v.UID] = v.Nome
+		}
+	}
+	for _, ag := range agendamentos {
+		ag.DataHora = ag.DataHora.In(loc)
+		adicionar(ag.ProfissionalID)
+		porProfissional[ag.ProfissionalID] = append(porProfissional[ag.ProfissionalID], ag)
+	}
+
+	extratos := make([]models.ExtratoRepasse, 0, len(ids))
+	for _, id := range ids {
+		extrato := models.CalcularRepasse(e.Comissoes, id, porProfissional[id])
+		extrato.ProfissionalNome = nomes[id]
+		if extrato.ProfissionalNome == "" {
+			if extrato.ProfissionalNome, err = h.nomeProfissional(ctx, id); err != nil && !errors.Is(err, storage.ErrNaoEncontrado) {
+				return nil, err
+			}
+		}
+		extratos = append(extratos, extrato)
+	}
+	sort.SliceStable(extratos, func(i, j int) bool { return extratos[i].Faturado > extratos[j].Faturado })
+	return extratos, nil
+}
+
+// errJaAtendeu interrompe a busca por um atendimento do profissional no estabelecimento
+var errJaAtendeu = errors.New("o profissional já atendeu no estabelecimento")
+
+// atendeNoEstabelecimento indica se o profissional está vinculado ao estabelecimento ou
+// já teve um agendamento nele, o que vale como vínculo anterior: só um vínculo válido
+// grava o estabelecimento no agendamento
+func (h *Handler) atendeNoEstabelecimento(ctx context.Context, estabID, profID string) (bool, error) {
+	vinculado, err := h.vinculadoAoEstabelecimento(ctx, estabID, profID)
+	if err != nil || vinculado {
+		return vinculado, err
+	}
+	filtro := storage.FiltroAgendamento{EstabelecimentoID: estabID, ProfissionalID: profID}
+	err = h.repos.Agendamentos.Percorrer(ctx, filtro, func(models.Agendamento) error {
+		return errJaAtendeu
+	})
+	if errors.Is(err, errJaAtendeu) {
+		return true, nil
+	}
+	return false, err
+}
+
+// estabelecimentoDoRepasse busca o estabelecimento e seu fuso. Em caso de erro já escreve a resposta.
+func (h *Handler) estabelecimentoDoRepasse(c *gin.Context) (*models.Estabelecimento, *time.Location, bool) {
+	e, err := h.repos.Estabelecimentos.Buscar(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, storage.ErrNaoEncontrado) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Estabelecimento não encontrado"})
+		return nil, nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar estabelecimento"})
+		return nil, nil, false
+	}
+	loc, err := e.Fuso()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar fuso horário do estabelecimento"})
+		return nil, nil, false
+	}
+	return e, loc, true
+}
+
+// RelatorioRepassesEstabelecimento resume, por profissional, o faturamento dos
+// atendimentos concluídos no período e a divisão entre comissão e estabelecimento
+// @Summary Repasses do estabelecimento
+// @Tags Comissões
+// @Produce json
+// @Param id path string true "ID do estabelecimento"
+// @Param de query string false "Data inicial (AAAA-MM-DD), padrão o primeiro dia do mês"
+// @Param ate query string false "Data final (AAAA-MM-DD), padrão hoje"
+// @Param formato query string false "json (padrão), csv, xlsx ou pdf"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Router /relatorios/repasses/estabelecimento/{id} [get]
+func (h *Handler) RelatorioRepassesEstabelecimento(c *gin.Context) {
+	formato, ok := formatoExportacao(c)
+	if !ok {
+		return
+	}
+	e, loc, ok := h.estabelecimentoDoRepasse(c)
+	if !ok {
+		return
+	}
+	periodo, ok := periodoRepasse(c, loc)
+	if !ok {
+		return
+	}
+	extratos, err := h.repassesDoPeriodo(c, e, loc, periodo, "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao calcular repasses"})
+		return
+	}
+
+	total := models.ExtratoRepasse{}
+	for i := range extratos {
+		total.Quantidade += extratos[i].Quantidade
+		total.Faturado += extratos[i].Faturado
+		total.Comissao += extratos[i].Comissao
+		total.Estabelecimento += extratos[i].Estabelecimento
+		total.SemRegra += extratos[i].SemRegra
+		extratos[i].Itens = nil // o detalhe fica no extrato de cada profissional
+	}
+
+	if formato != exportacao.FormatoJSON {
+		doc := documento{titulo: "Repasses aos profissionais", arquivo: []string{"repasses", e.ID}, estabelecimentoID: e.ID}
+		colunas := []exportacao.Coluna{
+			{Titulo: "Profissional", Peso: 2}, {Titulo: "Atendimentos"}, {Titulo: "Faturado (R$)"},
+			{Titulo: "Comissão (R$)"}, {Titulo: "Estabelecimento (R$)"}, {Titulo: "Sem regra"},
+		}
+		esc := h.iniciarExportacao(c, formato, doc, detalhesRepasse(periodo, loc), colunas)
+		if esc == nil {
+			return
+		}
+		for _, x := range append(extratos, total) {
+			nome := x.ProfissionalNome
+			if x.ProfissionalID == "" {
+				nome = "Total"
+			}
+			if err = esc.Linha(nome, x.Quantidade, x.Faturado, x.Comissao, x.Estabelecimento, x.SemRegra); err != nil {
+				break
+			}
+		}
+		terminarExportacao(c, esc, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"estabelecimento_id":      e.ID,
+		"periodo":                 periodo,
+		"profissionais":           extratos,
+		"quantidade_atendimentos": total.Quantidade,
+		"total_faturado":          total.Faturado,
+		"total_comissao":          total.Comissao,
+		"total_estabelecimento":   total.Estabelecimento,
+		"atendimentos_sem_regra":  total.SemRegra,
+	})
+}
+
+// RelatorioRepasseProfissional detalha o extrato de um profissional no estabelecimento:
+// cada atendimento concluído no período com a regra aplicada e a comissão. Só há extrato
+// de quem está ou já esteve vinculado ao estabelecimento.
+// @Summary Extrato de repasse do profissional
+// @Tags Comissões
+// @Produce json
+// @Param id path string true "ID do estabelecimento"
+// @Param profId path string true "ID do profissional"
+// @Param de query string false "Data inicial (AAAA-MM-DD), padrão o primeiro dia do mês"
+// @Param ate query string false "Data final (AAAA-MM-DD), padrão hoje"
+// @Param formato query string false "json (padrão), csv, xlsx ou pdf"
+// @Success 200 {object} models.ExtratoRepasse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string "Profissional nunca vinculado ao estabelecimento"
+// @Router /relatorios/repasses/estabelecimento/{id}/profissional/{profId} [get]
+func (h *Handler) RelatorioRepasseProfissional(c *gin.Context) {
+	formato, ok := formatoExportacao(c)
+	if !ok {
+		return
+	}
+	e, loc, ok := h.estabelecimentoDoRepasse(c)
+	if !ok {
+		return
+	}
+	// Sem vínculo, atual ou anterior, não há extrato com os dados do estabelecimento
+	profID := c.Param("profId")
+	atende, err := h.atendeNoEstabelecimento(c.Request.Context(), e.ID, profID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar profissionais"})
+		return
+	}
+	if !atende {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Profissional não atende neste estabelecimento"})
+		return
+	}
+	periodo, ok := periodoRepasse(c, loc)
+	if !ok {
+		return
+	}
+	extratos, err := h.repassesDoPeriodo(c, e, loc, periodo, profID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao calcular repasse"})
+		return
+	}
+	extrato := extratos[0]
+
+	if formato != exportacao.FormatoJSON {
+		doc := documento{titulo: "Extrato de repasse", arquivo: []string{"repasse", extrato.ProfissionalID}, estabelecimentoID: e.ID}
+		if extrato.ProfissionalNome != "" {
+			doc.titulo += " - " + extrato.ProfissionalNome
+		}
+		colunas := []exportacao.Coluna{
+			{Titulo: "Data e hora", Peso: 1.6}, {Titulo: "Procedimento", Peso: 2}, {Titulo: "Preço (R$)"},
+			{Titulo: "Regra", Peso: 1.4}, {Titulo: "Comissão (R$)"},
+		}
+		esc := h.iniciarExportacao(c, formato, doc, detalhesRepasse(periodo, loc), colunas)
+		if esc == nil {
+			return
+		}
+		for _, item := range extrato.Itens {
+			if err = esc.Linha(item.DataHora, item.Procedimento, item.Preco, item.Regra, item.Comissao); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			err = esc.Linha("Total", "", extrato.Faturado, "", extrato.Comissao)
+		}
+		terminarExportacao(c, esc, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"estabelecimento_id": e.ID,
+		"periodo":            periodo,
+		"extrato":            extrato,
+	})
+}
+
+// detalhesRepasse são as linhas de período e emissão dos extratos exportados
+func detalhesRepasse(periodo relatorios.Periodo, loc *time.Location) []string {
+	return []string{
+		"Período: " + descreverPeriodo(periodo),
+		"Emitido em " + time.Now().In(loc).Format("02/01/2006 15:04"),
+	}
+}
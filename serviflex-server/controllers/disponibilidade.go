@@ -0,0 +1,166 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"servico-api/agenda"
+	"servico-api/models"
+	"servico-api/storage"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	passoPadraoMin    = 15
+	passoMinimoMin    = 5
+	passoMaximoMin    = 240
+	diasPadrao        = 7
+	diasMaximoPeriodo = 31
+)
+
+// Disponibilidade retorna, dia a dia, os horários em que o procedimento ainda pode ser
+// agendado com o profissional, considerando o expediente, as exceções de horário e os
+// agendamentos existentes
+// @Summary Horários disponíveis do profissional
+// @Tags Profissional
+// @Produce json
+// @Param uid path string true "ID do profissional"
+// @Param procedimento query string true "ID ou nome do procedimento"
+// @Param de query string false "Data inicial (AAAA-MM-DD), padrão hoje"
+// @Param ate query string false "Data final (AAAA-MM-DD), padrão 6 dias após o início"
+// @Param passo query int false "Minutos entre os inícios sugeridos (padrão 15)"
+// @Success 200 {object} map[string]interface{}
+// @Router /profissionais/{uid}/disponibilidade [get]
+func (h *Handler) Disponibilidade(c *gin.Context) {
+	profID := c.Param("uid")
+	ctx := c.Request.Context()
+	loc, err := h.fusoDoProfissional(ctx, profID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar fuso horário do profissional"})
+		return
+	}
+	agora := time.Now().In(loc)
+
+	ref := c.Query("procedimento")
+	if ref == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Informe o procedimento"})
+		return
+	}
+
+	passo := passoPadraoMin
+	if v := c.Query("passo"); v != "" {
+		p, err := strconv.Atoi(v)
+		if err != nil || p < passoMinimoMin || p > passoMaximoMin {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Passo deve ser um número de minutos entre 5 e 240"})
+			return
+		}
+		passo = p
+	}
+
+	hoje := time.Date(agora.Year(), agora.Month(), agora.Day(), 0, 0, 0, 0, loc)
+	de, err := dataDoParametro(c.Query("de"), hoje, loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Data inicial inválida, use AAAA-MM-DD"})
+		return
+	}
+	ate, err := dataDoParametro(c.Query("ate"), de.AddDate(0, 0, diasPadrao-1), loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Data final inválida, use AAAA-MM-DD"})
+		return
+	}
+	if ate.Before(de) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Data final anterior à inicial"})
+		return
+	}
+	if ate.After(de.AddDate(0, 0, diasMaximoPeriodo-1)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "O período pode ter no máximo 31 dias"})
+		return
+	}
+
+	proc, err := h.procedimentoDoProfissional(ctx, profID, ref)
+	if errors.Is(err, storage.ErrNaoEncontrado) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Procedimento não encontrado para este profissional"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar procedimento"})
+		return
+	}
+	if proc.DuracaoMin <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Procedimento sem duração definida"})
+		return
+	}
+
+	horarios, err := h.repos.Horarios.ListarPorProfissional(ctx, profID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar horários"})
+		return
+	}
+	horariosPorDia := make(map[models.DiaSemana][]models.Horario)
+	for _, hr := range horarios {
+		horariosPorDia[hr.DiaSemana] = append(horariosPorDia[hr.DiaSemana], hr)
+	}
+
+	excecoes, err := h.repos.Excecoes.ListarPorProfissional(ctx, profID, de.Format("2006-01-02"), ate.Format("2006-01-02"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar exceções de horário"})
+		return
+	}
+
+	// Agendamentos iniciados pouco antes do período ainda podem ocupar o primeiro dia
+	ocupados, err := h.repos.Agendamentos.Listar(ctx, storage.FiltroAgendamento{
+		ProfissionalID: profID,
+		De:             de.Add(-storage.JanelaConflito),
+		Ate:            ate.AddDate(0, 0, 1),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar agendamentos"})
+		return
+	}
+
+	duracao := time.Duration(proc.DuracaoMin) * time.Minute
+	dias := []gin.H{}
+	for dia := de; !dia.After(ate); dia = dia.AddDate(0, 0, 1) {
+		diaSemana := models.DiaSemanaDe(dia.Weekday())
+		expediente := agenda.AplicarExcecoes(agenda.Expediente(horariosPorDia[diaSemana], dia), excecoes, dia)
+		dias = append(dias, gin.H{
+			"data":       dia.Format("2006-01-02"),
+			"dia_semana": diaSemana,
+			"horarios":   agenda.HorariosLivres(expediente, ocupados, duracao, time.Duration(passo)*time.Minute, agora),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"profissional_id": profID,
+		"procedimento": gin.H{
+			"id":          proc.ID,
+			"nome":        proc.Nome,
+			"duracao_min": proc.DuracaoMin,
+		},
+		"passo_min": passo,
+		"dias":      dias,
+	})
+}
+
+// procedimentoDoProfissional aceita o ID do procedimento ou, como em AgendarHorario, seu nome
+func (h *Handler) procedimentoDoProfissional(ctx context.Context, profID, ref string) (*models.Procedimento, error) {
+	proc, err := h.repos.Procedimentos.Buscar(ctx, ref)
+	if err == nil && proc.ProfissionalID == profID {
+		return proc, nil
+	}
+	if err != nil && !errors.Is(err, storage.ErrNaoEncontrado) {
+		return nil, err
+	}
+	return h.repos.Procedimentos.BuscarPorNome(ctx, profID, ref)
+}
+
+// dataDoParametro interpreta uma data AAAA-MM-DD no fuso informado, usando o padrão se vazia
+func dataDoParametro(valor string, padrao time.Time, loc *time.Location) (time.Time, error) {
+	if valor == "" {
+		return padrao, nil
+	}
+	return time.ParseInLocation("2006-01-02", valor, loc)
+}
@@ -1,11 +1,11 @@
 package controllers
 
 import (
-	"context"
-	"fmt"
 	"net/http"
-	"servico-api/config"
 	"servico-api/models"
+	"servico-api/relatorios"
+	"servico-api/storage"
+	"sort"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -21,17 +21,24 @@ import (
 // @Success 201 {object} map[string]string
 // @Failure 400 {object} map[string]string
 // @Router /estabelecimentos [post]
-func CriarEstabelecimento(c *gin.Context) {
+func (h *Handler) CriarEstabelecimento(c *gin.Context) {
 	var input models.EstabelecimentoInput
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos"})
 		return
 	}
+	if !politicaValida(c, input.PoliticaCancelamento) {
+		return
+	}
+	if _, err := models.CarregarFuso(input.FusoHorario); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Fuso horário inválido, use um nome IANA como America/Sao_Paulo"})
+		return
+	}
 
-	estabID := uuid.New().String()
-	uid := c.GetString("uid") // você pode usar middleware para injetar o uid do auth
+	uid := c.GetString("uid") // injetado por utils.AutenticacaoMiddleware
 
 	estab := models.Estabelecimento{
+		ID:             uuid.New().String(),
 		Nome:           input.Nome,
 		Descricao:      input.Descricao,
 		FotoURL:        input.FotoURL,
@@ -39,23 +46,18 @@ func CriarEstabelecimento(c *gin.Context) {
 		Localizacao:    input.Localizacao,
 		CriadoEm:       time.Now(),
 		ResponsavelUID: uid,
-	}
+		FusoHorario:    input.FusoHorario,
 
-	ctx := context.Background()
-	client, err := config.App.Firestore(ctx)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao conectar ao Firestore"})
-		return
+		PoliticaCancelamento: input.PoliticaCancelamento,
 	}
-	defer client.Close()
 
-	_, err = client.Collection("estabelecimentos").Doc(estabID).Set(ctx, estab)
-	if err != nil {
+	ctx := c.Request.Context()
+	if err := h.repos.Estabelecimentos.Salvar(ctx, estab); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao criar estabelecimento"})
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"id": estabID})
+	c.JSON(http.StatusCreated, gin.H{"id": estab.ID})
 }
 
 // EditarEstabelecimento atualiza os dados de um estabelecimento
@@ -68,7 +70,7 @@ func CriarEstabelecimento(c *gin.Context) {
 // @Success 200 {object} map[string]string
 // @Failure 400 {object} map[string]string
 // @Router /estabelecimentos/{id} [put]
-func EditarEstabelecimento(c *gin.Context) {
+func (h *Handler) EditarEstabelecimento(c *gin.Context) {
 	id := c.Param("id")
 
 	var input models.EstabelecimentoInput
@@ -76,34 +78,48 @@ func EditarEstabelecimento(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos"})
 		return
 	}
-
-	ctx := context.Background()
-	client, err := config.App.Firestore(ctx)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao conectar ao Firestore"})
+	if !politicaValida(c, input.PoliticaCancelamento) {
+		return
+	}
+	if _, err := models.CarregarFuso(input.FusoHorario); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Fuso horário inválido, use um nome IANA como America/Sao_Paulo"})
 		return
 	}
-	defer client.Close()
+
+	ctx := c.Request.Context()
 
 	// Garante que o estabelecimento existe
-	docRef := client.Collection("estabelecimentos").Doc(id)
-	_, err = docRef.Get(ctx)
+	atual, err := h.repos.Estabelecimentos.Buscar(ctx, id)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Estabelecimento não encontrado"})
 		return
 	}
 
+	// Mantém o responsável original: admins também podem editar sem assumir a posse
 	update := models.Estabelecimento{
+		ID:             id,
 		Nome:           input.Nome,
 		Descricao:      input.Descricao,
 		FotoURL:        input.FotoURL,
 		Categoria:      input.Categoria,
 		Localizacao:    input.Localizacao,
-		CriadoEm:       time.Now(),
-		ResponsavelUID: c.GetString("uid"), // opcionalmente, pode manter o antigo
+		CriadoEm:       atual.CriadoEm,
+		ResponsavelUID: atual.ResponsavelUID,
+		FusoHorario:    input.FusoHorario,
+
+		PoliticaCancelamento: input.PoliticaCancelamento,
+		Comissoes:            atual.Comissoes, // alteradas só por DefinirComissoes
+	}
+	// Fuso e política ausentes no pedido continuam os atuais; uma política com
+	// percentuais zerados deixa de cobrar taxas
+	if update.FusoHorario == "" {
+		update.FusoHorario = atual.FusoHorario
+	}
+	if update.PoliticaCancelamento == nil {
+		update.PoliticaCancelamento = atual.PoliticaCancelamento
 	}
 
-	if _, err := docRef.Set(ctx, update); err != nil {
+	if err := h.repos.Estabelecimentos.Salvar(ctx, update); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao atualizar"})
 		return
 	}
@@ -117,38 +133,33 @@ func EditarEstabelecimento(c *gin.Context) {
 // @Produce json
 // @Success 200 {array} models.Estabelecimento
 // @Router /estabelecimentos [get]
-func ListarEstabelecimentos(c *gin.Context) {
-	ctx := context.Background()
-	client, err := config.App.Firestore(ctx)
+func (h *Handler) ListarEstabelecimentos(c *gin.Context) {
+	ctx := c.Request.Context()
+	lista, err := h.repos.Estabelecimentos.Listar(ctx)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao conectar"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar dados"})
 		return
 	}
-	defer client.Close()
 
-	docs, err := client.Collection("estabelecimentos").Documents(ctx).GetAll()
+	ids := make([]string, len(lista))
+	for i, e := range lista {
+		ids[i] = e.ID
+	}
+	resumos, err := h.resumosPontuados(ctx, models.AlvoEstabelecimento, ids)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar dados"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar avaliações dos estabelecimentos"})
 		return
 	}
+	// Mais bem avaliados primeiro, pela média bayesiana
+	sort.SliceStable(lista, func(i, j int) bool {
+		return resumos[lista[i].ID].Pontuacao > resumos[lista[j].ID].Pontuacao
+	})
 
 	var estabelecimentos []map[string]interface{}
-	for _, doc := range docs {
-		var e models.Estabelecimento
-		if err := doc.DataTo(&e); err == nil {
-			// Criar um mapa que inclui o ID do documento
-			estabelecimentoComID := map[string]interface{}{
-				"id":             doc.Ref.ID,
-				"nome":           e.Nome,
-				"descricao":      e.Descricao,
-				"fotoURL":        e.FotoURL,
-				"categoria":      e.Categoria,
-				"localizacao":    e.Localizacao,
-				"criadoEm":       e.CriadoEm,
-				"responsavelUid": e.ResponsavelUID,
-			}
-			estabelecimentos = append(estabelecimentos, estabelecimentoComID)
-		}
+	for _, e := range lista {
+		item := estabelecimentoComID(e)
+		item["avaliacoes"] = resumos[e.ID]
+		estabelecimentos = append(estabelecimentos, item)
 	}
 
 	c.JSON(http.StatusOK, estabelecimentos)
@@ -162,28 +173,31 @@ func ListarEstabelecimentos(c *gin.Context) {
 // @Success 200 {object} models.Estabelecimento
 // @Failure 404 {object} map[string]string
 // @Router /estabelecimentos/{id} [get]
-func BuscarEstabelecimentoPorID(c *gin.Context) {
+func (h *Handler) BuscarEstabelecimentoPorID(c *gin.Context) {
 	id := c.Param("id")
-	ctx := context.Background()
-	client, err := config.App.Firestore(ctx)
+	ctx := c.Request.Context()
+
+	e, err := h.repos.Estabelecimentos.Buscar(ctx, id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao conectar"})
+		c.JSON(http.StatusNotFound, gin.H{"error": "Estabelecimento não encontrado"})
 		return
 	}
-	defer client.Close()
 
-	doc, err := client.Collection("estabelecimentos").Doc(id).Get(ctx)
-	if err != nil || !doc.Exists() {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Estabelecimento não encontrado"})
+	resumos, err := h.resumosPontuados(ctx, models.AlvoEstabelecimento, []string{id})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar avaliações do estabelecimento"})
 		return
 	}
 
-	var e models.Estabelecimento
-	doc.DataTo(&e)
+	resposta := estabelecimentoComID(*e)
+	resposta["avaliacoes"] = resumos[id]
+	c.JSON(http.StatusOK, resposta)
+}
 
-	// Retornar com ID incluído
-	estabelecimentoComID := map[string]interface{}{
-		"id":             doc.Ref.ID,
+// estabelecimentoComID monta a resposta JSON do estabelecimento incluindo o ID do documento
+func estabelecimentoComID(e models.Estabelecimento) map[string]interface{} {
+	return map[string]interface{}{
+		"id":             e.ID,
 		"nome":           e.Nome,
 		"descricao":      e.Descricao,
 		"fotoURL":        e.FotoURL,
@@ -191,169 +205,112 @@ func BuscarEstabelecimentoPorID(c *gin.Context) {
 		"localizacao":    e.Localizacao,
 		"criadoEm":       e.CriadoEm,
 		"responsavelUid": e.ResponsavelUID,
-	}
+		"fusoHorario":    e.FusoHorario,
 
-	c.JSON(http.StatusOK, estabelecimentoComID)
+		"politicaCancelamento": e.PoliticaCancelamento,
+	}
 }
-func RelatorioFaturamentoEstabelecimento(c *gin.Context) {
-	estabID := c.Param("id")
 
-	ctx := context.Background()
-	client, err := config.App.Firestore(ctx)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao conectar com Firestore"})
-		return
-	}
-	defer client.Close()
+// RelatorioFaturamentoEstabelecimento soma os atendimentos concluídos e as taxas de
+// cancelamento do estabelecimento. Sem período, cobre todo o histórico.
+// @Summary Faturamento do estabelecimento
+// @Tags Relatórios
+// @Produce json
+// @Param id path string true "ID do estabelecimento"
+// @Param de query string false "Data inicial (AAAA-MM-DD), no fuso do estabelecimento"
+// @Param ate query string false "Data final (AAAA-MM-DD), padrão hoje"
+// @Param agrupar query string false "dia, semana, mes, procedimento ou profissional"
+// @Param comparar query string false "periodo_anterior para comparar com o período imediatamente anterior"
+// @Param formato query string false "json (padrão), csv, xlsx ou pdf"
+// @Success 200 {object} map[string]interface{}
+// @Router /relatorios/estabelecimento/faturamento/{id} [get]
+func (h *Handler) RelatorioFaturamentoEstabelecimento(c *gin.Context) {
+	estabID := c.Param("id")
 
-	// Buscar todos os agendamentos do estabelecimento
-	agendamentos, err := client.Collection("agendamentos").
-		Where("estabelecimento_id", "==", estabID).
-		Documents(ctx).GetAll()
+	loc, err := h.fusoDoEstabelecimento(c.Request.Context(), estabID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar agendamentos"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar fuso horário do estabelecimento"})
 		return
 	}
 
-	if len(agendamentos) == 0 {
-		c.JSON(http.StatusOK, gin.H{"total_faturado": 0, "quantidade_agendamentos": 0})
+	// Taxas de cancelamento tardio e falta também entram no faturamento
+	fonte := h.fonteFaturamento(storage.FiltroAgendamento{EstabelecimentoID: estabID}, loc, true)
+	rel := h.gerarRelatorio(c, loc, relatorios.Historico, metricasFaturamentoComTaxas, fonte)
+	if rel == nil {
 		return
 	}
 
-	// Buscar todos os procedimentos do sistema (ou filtrar por estabelecimento se tiver esse vínculo)
-	procDocs, err := client.Collection("procedimentos").Documents(ctx).GetAll()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar procedimentos"})
-		return
+	campos := gin.H{"estabelecimento_id": estabID}
+	for _, m := range rel.Metricas {
+		campos[m] = rel.Total(m)
 	}
-
-	// Mapa com chave combinando profissional_id + nome do procedimento
-	procMap := make(map[string]float64)
-	for _, doc := range procDocs {
-		var p models.Procedimento
-		if err := doc.DataTo(&p); err == nil {
-			key := fmt.Sprintf("%s|%s", p.ProfissionalID, p.Nome)
-			procMap[key] = p.Preco
-		}
-	}
-
-	total := 0.0
-	for _, ag := range agendamentos {
-		profID := ag.Data()["profissional_id"].(string)
-		nomeProc := ag.Data()["procedimento"].(string)
-		key := fmt.Sprintf("%s|%s", profID, nomeProc)
-		if preco, ok := procMap[key]; ok {
-			total += preco
-		}
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"estabelecimento_id":      estabID,
-		"quantidade_agendamentos": len(agendamentos),
-		"total_faturado":          total,
-	})
+	campos["quantidade_agendamentos"] = int(rel.Total("quantidade_agendamentos"))
+	campos["quantidade_taxas"] = int(rel.Total("quantidade_taxas"))
+	h.responderRelatorio(c, loc, rel, documento{
+		titulo:            "Faturamento",
+		arquivo:           []string{"faturamento", estabID},
+		estabelecimentoID: estabID,
+	}, campos)
 }
-func RelatorioAvaliacoesPorEstabelecimento(c *gin.Context) {
+
+// RelatorioAvaliacoesPorEstabelecimento resume as avaliações visíveis do estabelecimento
+// @Summary Avaliações do estabelecimento
+// @Tags Relatórios
+// @Produce json
+// @Param id path string true "ID do estabelecimento"
+// @Param de query string false "Data inicial (AAAA-MM-DD), no fuso do estabelecimento"
+// @Param ate query string false "Data final (AAAA-MM-DD), padrão hoje"
+// @Param agrupar query string false "dia, semana, mes ou profissional"
+// @Param comparar query string false "periodo_anterior para comparar com o período imediatamente anterior"
+// @Param formato query string false "json (padrão), csv, xlsx ou pdf"
+// @Success 200 {object} map[string]interface{}
+// @Router /relatorios/avaliacoes/estabelecimento/{id} [get]
+func (h *Handler) RelatorioAvaliacoesPorEstabelecimento(c *gin.Context) {
 	estabID := c.Param("id")
+	ctx := c.Request.Context()
 
-	ctx := context.Background()
-	client, err := config.App.Firestore(ctx)
+	loc, err := h.fusoDoEstabelecimento(ctx, estabID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao conectar com Firestore"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar fuso horário do estabelecimento"})
 		return
 	}
-	defer client.Close()
-
-	// Buscar todas as avaliações do estabelecimento
-	docs, err := client.Collection("avaliacoes").
-		Where("estabelecimento_id", "==", estabID).
-		Documents(ctx).GetAll()
-
+	resumo, err := h.repos.Avaliacoes.BuscarResumo(ctx, models.AlvoEstabelecimento, estabID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar avaliações"})
 		return
 	}
-
-	if len(docs) == 0 {
-		c.JSON(http.StatusOK, gin.H{
-			"estabelecimento_id":    estabID,
-			"quantidade_avaliacoes": 0,
-			"media_nota":            0,
-			"avaliacoes":            []models.Avaliacao{},
-		})
-		return
-	}
-
-	var avaliacoes []models.Avaliacao
-	var total float64 = 0
-
-	for _, doc := range docs {
-		var a models.Avaliacao
-		if err := doc.DataTo(&a); err == nil {
-			total += float64(a.Nota)
-			avaliacoes = append(avaliacoes, a)
-		}
-	}
-
-	media := total / float64(len(avaliacoes))
-
-	c.JSON(http.StatusOK, gin.H{
-		"estabelecimento_id":    estabID,
-		"quantidade_avaliacoes": len(avaliacoes),
-		"media_nota":            media,
-		"avaliacoes":            avaliacoes,
-	})
-}
-func RelatorioAgendamentosPorMesEstabelecimento(c *gin.Context) {
-	estabID := c.Param("id")
-
-	ctx := context.Background()
-	client, err := config.App.Firestore(ctx)
+	avaliacoes, err := h.repos.Avaliacoes.ListarPorEstabelecimento(ctx, estabID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao conectar com Firestore"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar avaliações"})
 		return
 	}
-	defer client.Close()
 
-	// Últimos 12 meses
-	agora := time.Now()
-	inicio := agora.AddDate(0, -11, 0)
-	inicio = time.Date(inicio.Year(), inicio.Month(), 1, 0, 0, 0, 0, time.Local)
+	doc := documento{titulo: "Avaliações", arquivo: []string{"avaliacoes", estabID}, estabelecimentoID: estabID}
+	h.relatorioAvaliacoes(c, loc, avaliacoes, resumo, doc, gin.H{"estabelecimento_id": estabID})
+}
 
-	// Buscar agendamentos do estabelecimento
-	docs, err := client.Collection("agendamentos").
-		Where("estabelecimento_id", "==", estabID).
-		Where("data_hora", ">=", inicio).
-		Where("data_hora", "<=", agora).
-		Documents(ctx).GetAll()
+// RelatorioAgendamentosPorMesEstabelecimento conta os agendamentos do estabelecimento,
+// por padrão mês a mês nos últimos 12 meses
+// @Summary Agendamentos do estabelecimento
+// @Tags Relatórios
+// @Produce json
+// @Param id path string true "ID do estabelecimento"
+// @Param de query string false "Data inicial (AAAA-MM-DD), no fuso do estabelecimento"
+// @Param ate query string false "Data final (AAAA-MM-DD), padrão hoje; sem período, os últimos 12 meses por mês"
+// @Param agrupar query string false "dia, semana, mes, procedimento ou profissional"
+// @Param comparar query string false "periodo_anterior para comparar com o período imediatamente anterior"
+// @Param formato query string false "json (padrão), csv, xlsx ou pdf"
+// @Success 200 {object} map[string]interface{}
+// @Router /relatorios/agendamentos/estabelecimento/{id} [get]
+func (h *Handler) RelatorioAgendamentosPorMesEstabelecimento(c *gin.Context) {
+	estabID := c.Param("id")
 
+	loc, err := h.fusoDoEstabelecimento(c.Request.Context(), estabID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar agendamentos"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar fuso horário do estabelecimento"})
 		return
 	}
 
-	// Inicializa contagem
-	contagem := make(map[string]int)
-	for i := 0; i < 12; i++ {
-		mes := inicio.AddDate(0, i, 0)
-		key := fmt.Sprintf("%04d-%02d", mes.Year(), mes.Month())
-		contagem[key] = 0
-	}
-
-	// Conta agendamentos
-	for _, doc := range docs {
-		var ag models.Agendamento
-		if err := doc.DataTo(&ag); err == nil {
-			data := ag.DataHora.In(time.Local)
-			key := fmt.Sprintf("%04d-%02d", data.Year(), data.Month())
-			if _, ok := contagem[key]; ok {
-				contagem[key]++
-			}
-		}
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"estabelecimento_id":   estabID,
-		"agendamentos_por_mes": contagem,
-	})
+	doc := documento{titulo: "Agendamentos", arquivo: []string{"agendamentos", estabID}, estabelecimentoID: estabID}
+	h.relatorioAgendamentos(c, loc, storage.FiltroAgendamento{EstabelecimentoID: estabID}, doc, gin.H{"estabelecimento_id": estabID})
 }
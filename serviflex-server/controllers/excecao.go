@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"servico-api/models"
+	"servico-api/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// CriarExcecaoHorario registra uma folga, feriado, bloqueio parcial ou horário extra
+// @Summary Criar exceção de horário
+// @Tags Horários
+// @Accept json
+// @Produce json
+// @Param excecao body models.ExcecaoHorario true "Exceção à agenda semanal"
+// @Success 201 {object} models.ExcecaoHorario
+// @Failure 400 {object} map[string]string
+// @Router /horarios/excecoes [post]
+func (h *Handler) CriarExcecaoHorario(c *gin.Context) {
+	var excecao models.ExcecaoHorario
+	if err := c.ShouldBindJSON(&excecao); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos"})
+		return
+	}
+	if err := excecao.Validar(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	excecao.ID = uuid.New().String()
+	if err := h.repos.Excecoes.Salvar(c.Request.Context(), excecao); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao salvar exceção de horário"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, excecao)
+}
+
+// ListarExcecoesHorario lista as exceções de horário do profissional, opcionalmente
+// restritas ao período entre de e ate. Os motivos são pessoais: só o próprio profissional
+// e admins consultam.
+// @Summary Listar exceções de horário
+// @Tags Horários
+// @Produce json
+// @Param id path string true "ID do profissional"
+// @Param de query string false "Data inicial (AAAA-MM-DD)"
+// @Param ate query string false "Data final (AAAA-MM-DD)"
+// @Success 200 {array} models.ExcecaoHorario
+// @Failure 403 {object} map[string]string
+// @Router /horarios/{id}/excecoes [get]
+func (h *Handler) ListarExcecoesHorario(c *gin.Context) {
+	excecoes, err := h.repos.Excecoes.ListarPorProfissional(c.Request.Context(), c.Param("id"), c.Query("de"), c.Query("ate"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar exceções de horário"})
+		return
+	}
+	if excecoes == nil {
+		excecoes = []models.ExcecaoHorario{}
+	}
+	c.JSON(http.StatusOK, excecoes)
+}
+
+// EditarExcecaoHorario atualiza uma exceção de horário, mantendo o profissional original
+// @Summary Editar exceção de horário
+// @Tags Horários
+// @Accept json
+// @Produce json
+// @Param id path string true "ID da exceção"
+// @Param excecao body models.ExcecaoHorario true "Exceção atualizada"
+// @Success 200 {object} models.ExcecaoHorario
+// @Router /horarios/excecoes/{id} [put]
+func (h *Handler) EditarExcecaoHorario(c *gin.Context) {
+	id := c.Param("id")
+	var excecao models.ExcecaoHorario
+	if err := c.ShouldBindJSON(&excecao); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos"})
+		return
+	}
+	if err := excecao.Validar(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx := c.Request.Context()
+
+	atual, err := h.repos.Excecoes.Buscar(ctx, id)
+	if errors.Is(err, storage.ErrNaoEncontrado) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Exceção de horário não encontrada"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar exceção de horário"})
+		return
+	}
+
+	excecao.ID = id
+	excecao.ProfissionalID = atual.ProfissionalID
+	if err := h.repos.Excecoes.Salvar(ctx, excecao); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao atualizar exceção de horário"})
+		return
+	}
+	c.JSON(http.StatusOK, excecao)
+}
+
+// ExcluirExcecaoHorario remove uma exceção de horário
+// @Summary Excluir exceção de horário
+// @Tags Horários
+// @Produce json
+// @Param id path string true "ID da exceção"
+// @Success 200 {object} map[string]string
+// @Router /horarios/excecoes/{id} [delete]
+func (h *Handler) ExcluirExcecaoHorario(c *gin.Context) {
+	if err := h.repos.Excecoes.Excluir(c.Request.Context(), c.Param("id")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao excluir exceção de horário"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Exceção de horário excluída com sucesso"})
+}
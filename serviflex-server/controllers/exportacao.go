@@ -0,0 +1,261 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"servico-api/exportacao"
+	"servico-api/models"
+	"servico-api/relatorios"
+	"servico-api/storage"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Nomes das métricas e dos agrupamentos nas colunas dos arquivos exportados
+var (
+	rotulosMetricas = map[string]string{
+		"quantidade_agendamentos": "Agendamentos",
+		"total_servicos":          "Serviços (R$)",
+		"quantidade_taxas":        "Taxas cobradas",
+		"total_taxas":             "Total em taxas (R$)",
+		"total_faturado":          "Total faturado (R$)",
+		"quantidade_avaliacoes":   "Avaliações",
+		"media_nota":              "Nota média",
+	}
+	rotulosAgrupamentos = map[relatorios.Agrupamento]string{
+		relatorios.SemAgrupamento:      "Período",
+		relatorios.AgruparDia:          "Dia",
+		relatorios.AgruparSemana:       "Semana",
+		relatorios.AgruparMes:          "Mês",
+		relatorios.AgruparProcedimento: "Procedimento",
+		relatorios.AgruparProfissional: "Profissional",
+	}
+)
+
+// documento identifica um relatório ou listagem exportável. O cabeçalho do arquivo traz o
+// estabelecimento informado ou, sem ele, o estabelecimento do profissional.
+type documento struct {
+	titulo            string
+	arquivo           []string // partes do nome do arquivo
+	estabelecimentoID string
+	profissionalID    string
+}
+
+// formatoExportacao lê o parâmetro formato. Um valor inválido já responde 400 e devolve false.
+func formatoExportacao(c *gin.Context) (exportacao.Formato, bool) {
+	formato, err := exportacao.InterpretarFormato(c.Query("formato"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return formato, false
+	}
+	return formato, true
+}
+
+// estabelecimentoDoDocumento busca o estabelecimento do cabeçalho; nil quando não há
+func (h *Handler) estabelecimentoDoDocumento(ctx context.Context, doc documento) (*models.Estabelecimento, error) {
+	estabID := doc.estabelecimentoID
+	if estabID == "" && doc.profissionalID != "" {
+		p, err := h.repos.Usuarios.BuscarProfissional(ctx, doc.profissionalID)
+		if err != nil && !errors.Is(err, storage.ErrNaoEncontrado) {
+			return nil, err
+		}
+		if p != nil {
+			estabID = p.EstabelecimentoID
+		}
+	}
+	if estabID == "" {
+		return nil, nil
+	}
+	e, err := h.repos.Estabelecimentos.Buscar(ctx, estabID)
+	if errors.Is(err, storage.ErrNaoEncontrado) {
+		return nil, nil
+	}
+	return e, err
+}
+
+// iniciarExportacao prepara a resposta como anexo e começa o arquivo com o cabeçalho do
+// documento. Em caso de erro já escreve a resposta e devolve nil.
+func (h *Handler) iniciarExportacao(c *gin.Context, formato exportacao.Formato, doc documento, detalhes []string, colunas []exportacao.Coluna) exportacao.Escritor {
+	estab, err := h.estabelecimentoDoDocumento(c.Request.Context(), doc)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar estabelecimento"})
+		return nil
+	}
+
+	c.Header("Content-Type", formato.TipoConteudo())
+	c.Header("Content-Disposition", `attachment; filename="`+exportacao.NomeArquivo(formato, doc.arquivo...)+`"`)
+	c.Status(http.StatusOK)
+	cab := exportacao.Cabecalho{Estabelecimento: estab, Titulo: doc.titulo, Detalhes: detalhes}
+	esc, err := exportacao.NovoEscritor(formato, c.Writer, cab, colunas)
+	if err != nil {
+		terminarExportacao(c, nil, err)
+		return nil
+	}
+	return esc
+}
+
+// terminarExportacao fecha o arquivo. Enquanto nada foi enviado, um erro ainda vira uma
+// resposta 500; depois disso o arquivo já saiu incompleto e o erro só fica registrado.
+func terminarExportacao(c *gin.Context, esc exportacao.Escritor, err error) {
+	if err != nil && !c.Writer.Written() {
+		c.Writer.Header().Del("Content-Type")
+		c.Writer.Header().Del("Content-Disposition")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao exportar"})
+		return
+	}
+	if esc != nil {
+		if errFechar := esc.Fechar(); err == nil {
+			err = errFechar
+		}
+	}
+	if err != nil {
+		c.Error(err)
+		c.Abort()
+	}
+}
+
+// exportarRelatorio grava uma linha por grupo da série, o total e, na comparação, os
+// totais do período anterior e a variação percentual
+func (h *Handler) exportarRelatorio(c *gin.Context, formato exportacao.Formato, loc *time.Location, rel *relatorios.Relatorio, doc documento) {
+	colunas := []exportacao.Coluna{{Titulo: rotulosAgrupamentos[rel.Agrupar], Peso: 2}}
+	for _, m := range rel.Metricas {
+		colunas = append(colunas, exportacao.Coluna{Titulo: rotulosMetricas[m]})
+	}
+	detalhes := []string{"Período: " + descreverPeriodo(rel.Atual.Periodo)}
+	if rel.Anterior != nil {
+		detalhes = append(detalhes, "Comparado a: "+descreverPeriodo(rel.Anterior.Periodo))
+	}
+	detalhes = append(detalhes, "Emitido em "+time.Now().In(loc).Format("02/01/2006 15:04"))
+
+	esc := h.iniciarExportacao(c, formato, doc, detalhes, colunas)
+	if esc == nil {
+		return
+	}
+	linha := func(primeira string, valores []float64) error {
+		celulas := []any{primeira}
+		for i, m := range rel.Metricas {
+			celulas = append(celulas, celulaMetrica(m, valores[i]))
+		}
+		return esc.Linha(celulas...)
+	}
+
+	var err error
+	for _, l := range rel.Atual.Serie {
+		rotulo := l.Rotulo
+		if rotulo == "" {
+			rotulo = l.Chave
+		}
+		if err = linha(rotulo, l.Valores); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = linha("Total", rel.Atual.Totais)
+	}
+	if err == nil && rel.Anterior != nil {
+		err = linha("Período anterior", rel.Anterior.Totais)
+		if err == nil {
+			variacoes := rel.Variacoes()
+			celulas := []any{"Variação (%)"}
+			for _, m := range rel.Metricas {
+				celulas = append(celulas, variacoes[m].Percentual)
+			}
+			err = esc.Linha(celulas...)
+		}
+	}
+	terminarExportacao(c, esc, err)
+}
+
+// celulaMetrica mantém as contagens como números inteiros na planilha
+func celulaMetrica(metrica string, valor float64) any {
+	if strings.HasPrefix(metrica, "quantidade_") {
+		return int(valor)
+	}
+	return valor
+}
+
+// descreverPeriodo escreve o período com datas inclusivas, como na tela
+func descreverPeriodo(p relatorios.Periodo) string {
+	ate := p.Fim.AddDate(0, 0, -1).Format("02/01/2006")
+	if p.Aberto() {
+		return "todo o histórico até " + ate
+	}
+	return p.Inicio.Format("02/01/2006") + " a " + ate
+}
+
+// exportarAgendamentos grava os agendamentos do filtro conforme são lidos do repositório,
+// no horário local de cada profissional. A coluna "outro" traz o nome da outra parte do
+// atendimento: o profissional na agenda do cliente e o cliente na do profissional.
+func (h *Handler) exportarAgendamentos(c *gin.Context, formato exportacao.Formato, loc *time.Location, filtro storage.FiltroAgendamento, doc documento, outro string, nome func(ctx context.Context, ag models.Agendamento) (string, error)) {
+	colunas := []exportacao.Coluna{
+		{Titulo: "Data e hora", Peso: 1.6},
+		{Titulo: "Procedimento", Peso: 2},
+		{Titulo: outro, Peso: 2},
+		{Titulo: "Status"},
+		{Titulo: "Duração (min)"},
+		{Titulo: "Preço (R$)"},
+		{Titulo: "Taxa (R$)"},
+	}
+	detalhes := []string{"Emitido em " + time.Now().In(loc).Format("02/01/2006 15:04")}
+	esc := h.iniciarExportacao(c, formato, doc, detalhes, colunas)
+	if esc == nil {
+		return
+	}
+
+	ctx := c.Request.Context()
+	fusos := h.novosFusos()
+	err := h.repos.Agendamentos.Percorrer(ctx, filtro, func(ag models.Agendamento) error {
+		if err := fusos.localizar(ctx, &ag); err != nil {
+			return err
+		}
+		n, err := nome(ctx, ag)
+		if err != nil {
+			return err
+		}
+		var taxa *float64
+		if ag.Taxa != nil {
+			taxa = &ag.Taxa.Valor
+		}
+		return esc.Linha(ag.DataHora, ag.Procedimento, n, ag.StatusAtual(), ag.DuracaoMin, ag.Preco, taxa)
+	})
+	terminarExportacao(c, esc, err)
+}
+
+// nomesEmCache consulta cada ID uma só vez durante a exportação; IDs inexistentes ficam sem nome
+func nomesEmCache(buscar func(ctx context.Context, id string) (string, error)) func(ctx context.Context, id string) (string, error) {
+	nomes := make(map[string]string)
+	return func(ctx context.Context, id string) (string, error) {
+		if nome, ok := nomes[id]; ok {
+			return nome, nil
+		}
+		nome, err := buscar(ctx, id)
+		if errors.Is(err, storage.ErrNaoEncontrado) {
+			nome, err = "", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		nomes[id] = nome
+		return nome, nil
+	}
+}
+
+// nomeCliente e nomeProfissional servem de busca para nomesEmCache
+func (h *Handler) nomeCliente(ctx context.Context, id string) (string, error) {
+	cliente, err := h.repos.Usuarios.BuscarCliente(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	return cliente.Nome, nil
+}
+
+func (h *Handler) nomeProfissional(ctx context.Context, id string) (string, error) {
+	p, err := h.repos.Usuarios.BuscarProfissional(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	return p.Nome, nil
+}
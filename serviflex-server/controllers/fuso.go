@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"servico-api/models"
+	"servico-api/storage"
+	"time"
+)
+
+// fusoDoEstabelecimento devolve o fuso configurado no estabelecimento. Um estabelecimento
+// inexistente (ou ID vazio) usa models.FusoPadrao.
+func (h *Handler) fusoDoEstabelecimento(ctx context.Context, estabID string) (*time.Location, error) {
+	if estabID == "" {
+		return models.CarregarFuso("")
+	}
+	e, err := h.repos.Estabelecimentos.Buscar(ctx, estabID)
+	if errors.Is(err, storage.ErrNaoEncontrado) {
+		return models.CarregarFuso("")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return e.Fuso()
+}
+
+// estabelecimentoDoProfissional devolve o ID do estabelecimento em que o profissional
+// atende; vazio se o profissional não existe, não tem estabelecimento ou se o
+// estabelecimento do seu cadastro não o lista entre os vinculados. Só o aceite de convite
+// e RemoverProfissional mudam o vínculo, mas a lista do estabelecimento é quem decide.
+func (h *Handler) estabelecimentoDoProfissional(ctx context.Context, profID string) (string, error) {
+	p, err := h.repos.Usuarios.BuscarProfissional(ctx, profID)
+	if errors.Is(err, storage.ErrNaoEncontrado) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if p.EstabelecimentoID == "" {
+		return "", nil
+	}
+	vinculado, err := h.vinculadoAoEstabelecimento(ctx, p.EstabelecimentoID, profID)
+	if err != nil || !vinculado {
+		return "", err
+	}
+	return p.EstabelecimentoID, nil
+}
+
+// vinculadoAoEstabelecimento indica se o profissional está na lista de vinculados do estabelecimento
+func (h *Handler) vinculadoAoEstabelecimento(ctx context.Context, estabID, profID string) (bool, error) {
+	vinculos, err := h.repos.Estabelecimentos.ListarProfissionais(ctx, estabID)
+	if err != nil {
+		return false, err
+	}
+	for _, v := range vinculos {
+		if v.UID == profID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// fusoDoProfissional devolve o fuso herdado do estabelecimento do profissional
+func (h *Handler) fusoDoProfissional(ctx context.Context, profID string) (*time.Location, error) {
+	estabID, err := h.estabelecimentoDoProfissional(ctx, profID)
+	if err != nil {
+		return nil, err
+	}
+	return h.fusoDoEstabelecimento(ctx, estabID)
+}
+
+// fusosAgendamentos guarda o fuso de cada estabelecimento e profissional já consultado,
+// para localizar muitos agendamentos sem repetir as buscas
+type fusosAgendamentos struct {
+	h               *Handler
+	estabelecimento map[string]*time.Location
+	profissional    map[string]*time.Location
+}
+
+func (h *Handler) novosFusos() *fusosAgendamentos {
+	return &fusosAgendamentos{
+		h:               h,
+		estabelecimento: make(map[string]*time.Location),
+		profissional:    make(map[string]*time.Location),
+	}
+}
+
+// localizar converte o horário do agendamento para o fuso do estabelecimento em que foi
+// marcado, que não muda se o profissional trocar de estabelecimento depois. Agendamentos
+// antigos sem estabelecimento usam o fuso atual do profissional.
+func (f *fusosAgendamentos) localizar(ctx context.Context, ag *models.Agendamento) error {
+	cache, id, buscar := f.estabelecimento, ag.EstabelecimentoID, f.h.fusoDoEstabelecimento
+	if id == "" {
+		cache, id, buscar = f.profissional, ag.ProfissionalID, f.h.fusoDoProfissional
+	}
+	loc, ok := cache[id]
+	if !ok {
+		var err error
+		if loc, err = buscar(ctx, id); err != nil {
+			return err
+		}
+		cache[id] = loc
+	}
+	ag.DataHora = ag.DataHora.In(loc)
+	return nil
+}
+
+// localizarAgendamentos converte o horário de cada agendamento para o fuso em que foi
+// marcado, para que as respostas mostrem a hora local do atendimento
+func (h *Handler) localizarAgendamentos(ctx context.Context, agendamentos []models.Agendamento) error {
+	fusos := h.novosFusos()
+	for i := range agendamentos {
+		if err := fusos.localizar(ctx, &agendamentos[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+package controllers
+
+import "servico-api/storage"
+
+// Handler concentra os handlers HTTP da API e os repositórios que eles utilizam
+type Handler struct {
+	repos *storage.Repositorios
+}
+
+// NovoHandler cria os handlers a partir dos repositórios injetados
+func NovoHandler(repos *storage.Repositorios) *Handler {
+	return &Handler{repos: repos}
+}
@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"net/http"
+	"servico-api/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListarModeracaoAvaliacoes retorna a fila de moderação: avaliações denunciadas ainda
+// sem decisão de um admin, com as denúncias
+// @Summary Fila de moderação de avaliações
+// @Tags Admin
+// @Produce json
+// @Success 200 {array} models.Avaliacao
+// @Router /moderacao/avaliacoes [get]
+func (h *Handler) ListarModeracaoAvaliacoes(c *gin.Context) {
+	avaliacoes, err := h.repos.Avaliacoes.ListarEmModeracao(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar avaliações denunciadas"})
+		return
+	}
+	if avaliacoes == nil {
+		avaliacoes = []models.Avaliacao{}
+	}
+	c.JSON(http.StatusOK, avaliacoes)
+}
+
+// OcultarAvaliacao tira a avaliação dos relatórios e das médias até ser restaurada
+// @Summary Ocultar avaliação
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path string true "ID da avaliação"
+// @Param dados body models.MotivoInput true "Motivo da decisão"
+// @Success 200 {object} models.Avaliacao
+// @Router /moderacao/avaliacoes/{id}/ocultar [post]
+func (h *Handler) OcultarAvaliacao(c *gin.Context) {
+	h.moderarAvaliacao(c, models.ModeracaoOcultar)
+}
+
+// RestaurarAvaliacao republica uma avaliação oculta ou descarta as denúncias de uma publicada
+// @Summary Restaurar avaliação
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path string true "ID da avaliação"
+// @Param dados body models.MotivoInput true "Motivo da decisão"
+// @Success 200 {object} models.Avaliacao
+// @Router /moderacao/avaliacoes/{id}/restaurar [post]
+func (h *Handler) RestaurarAvaliacao(c *gin.Context) {
+	h.moderarAvaliacao(c, models.ModeracaoRestaurar)
+}
+
+// ExcluirAvaliacao remove a avaliação de forma definitiva. O registro é mantido com o
+// motivo, e o agendamento não pode ser avaliado de novo.
+// @Summary Excluir avaliação
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path string true "ID da avaliação"
+// @Param dados body models.MotivoInput true "Motivo da decisão"
+// @Success 200 {object} models.Avaliacao
+// @Router /moderacao/avaliacoes/{id} [delete]
+func (h *Handler) ExcluirAvaliacao(c *gin.Context) {
+	h.moderarAvaliacao(c, models.ModeracaoExcluir)
+}
+
+// moderarAvaliacao registra a decisão do admin autenticado, que sempre exige um motivo
+func (h *Handler) moderarAvaliacao(c *gin.Context, acao string) {
+	motivo, ok := motivoObrigatorio(c)
+	if !ok {
+		return
+	}
+
+	decisao := models.AcaoModeracao{Acao: acao, Motivo: motivo, PorUID: c.GetString("uid"), Em: time.Now()}
+	avaliacao, err := h.repos.Avaliacoes.Atualizar(c.Request.Context(), c.Param("id"), func(a *models.Avaliacao) error {
+		return a.Moderar(decisao)
+	})
+	h.responderAvaliacao(c, avaliacao, err, true)
+}
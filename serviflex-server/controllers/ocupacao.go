@@ -0,0 +1,357 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"servico-api/agenda"
+	"servico-api/exportacao"
+	"servico-api/models"
+	"servico-api/relatorios"
+	"servico-api/storage"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	diasPadraoOcupacao   = 28
+	diasPadraoOciosidade = 7
+	diasMaximoOcupacao   = 92
+	minimoPadraoOcioso   = 30
+	minimoMaximoOcioso   = 480
+)
+
+// OcupacaoProfissional é a ocupação de um profissional do estabelecimento no período
+type OcupacaoProfissional struct {
+	ProfissionalID   string `json:"profissional_id"`
+	ProfissionalNome string `json:"profissional_nome"`
+	agenda.Ocupacao
+}
+
+// OcupacaoDiaSemana é a ocupação somada de todas as datas de um dia da semana
+type OcupacaoDiaSemana struct {
+	DiaSemana models.DiaSemana `json:"dia_semana"`
+	agenda.Ocupacao
+}
+
+// OcupacaoHora é uma célula do mapa de calor: um dia da semana numa hora do dia
+type OcupacaoHora struct {
+	DiaSemana models.DiaSemana `json:"dia_semana"`
+	Hora      int              `json:"hora"`
+	agenda.Ocupacao
+}
+
+// HorarioOcioso é um trecho do expediente sem nenhum atendimento marcado
+type HorarioOcioso struct {
+	ProfissionalID   string    `json:"profissional_id"`
+	ProfissionalNome string    `json:"profissional_nome"`
+	Inicio           time.Time `json:"inicio"`
+	Fim              time.Time `json:"fim"`
+	DuracaoMin       int       `json:"duracao_min"`
+}
+
+// periodoOcupacao interpreta de e ate no fuso do estabelecimento, com os dias padrão
+// contados a partir de inicio. A ocupação não agrupa nem compara e precisa de um período
+// fechado de até 92 dias. Em caso de erro já escreve a resposta.
+func periodoOcupacao(c *gin.Context, loc *time.Location, inicio time.Time, dias int) (relatorios.Periodo, bool) {
+	hoje := time.Now().In(loc)
+	padrao := relatorios.Consulta{Periodo: relatorios.Periodo{Inicio: inicio, Fim: inicio.AddDate(0, 0, dias)}}
+	consulta, err := relatorios.Interpretar(c.Query, padrao, hoje)
+	switch {
+	case err != nil:
+	case consulta.Agrupar != relatorios.SemAgrupamento || consulta.Comparar:
+		err = errors.New("relatórios de ocupação não aceitam agrupar nem comparar")
+	case consulta.Periodo.Aberto():
+		err = errors.New("informe a data inicial (de)")
+	case consulta.Periodo.Inicio.AddDate(0, 0, diasMaximoOcupacao).Before(consulta.Periodo.Fim):
+		err = fmt.Errorf("o período pode ter no máximo %d dias", diasMaximoOcupacao)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return relatorios.Periodo{}, false
+	}
+	return consulta.Periodo, true
+}
+
+// meiaNoite devolve o início do dia de t
+func meiaNoite(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// percorrerExpedientes chama visitar para cada dia do período de cada profissional, com o
+// expediente do dia (horários, pausas e exceções) e os agendamentos do profissional no
+// estabelecimento em torno do período
+func (h *Handler) percorrerExpedientes(ctx context.Context, estabID string, periodo relatorios.Periodo, profissionais []models.ProfissionalEstabelecimento,
+	visitar func(p models.ProfissionalEstabelecimento, expediente []agenda.Intervalo, agendamentos []models.Agendamento)) error {
+	// Agendamentos iniciados na véspera ainda podem ocupar o primeiro dia
+	todos, err := h.repos.Agendamentos.Listar(ctx, storage.FiltroAgendamento{
+		EstabelecimentoID: estabID,
+		De:                periodo.Inicio.Add(-storage.JanelaConflito),
+		Ate:               periodo.Ultimo(),
+	})
+	if err != nil {
+		return err
+	}
+	porProfissional := make(map[string][]models.Agendamento)
+	for _, ag := range todos {
+		porProfissional[ag.ProfissionalID] = append(porProfissional[ag.ProfissionalID], ag)
+	}
+
+	de, ate := periodo.Inicio.Format("2006-01-02"), periodo.Ultimo().Format("2006-01-02")
+	for _, p := range profissionais {
+		horarios, err := h.repos.Horarios.ListarPorProfissional(ctx, p.UID)
+		if err != nil {
+			return err
+		}
+		horariosPorDia := make(map[models.DiaSemana][]models.Horario)
+		for _, hr := range horarios {
+			horariosPorDia[hr.DiaSemana] = append(horariosPorDia[hr.DiaSemana], hr)
+		}
+		excecoes, err := h.repos.Excecoes.ListarPorProfissional(ctx, p.UID, de, ate)
+		if err != nil {
+			return err
+		}
+		for dia := periodo.Inicio; dia.Before(periodo.Fim); dia = dia.AddDate(0, 0, 1) {
+			expediente := agenda.AplicarExcecoes(agenda.Expediente(horariosPorDia[models.DiaSemanaDe(dia.Weekday())], dia), excecoes, dia)
+			visitar(p, expediente, porProfissional[p.UID])
+		}
+	}
+	return nil
+}
+
+// profissionaisComNome lista os profissionais vinculados ao estabelecimento, com o nome
+// preenchido quando o vínculo não o traz
+func (h *Handler) profissionaisComNome(ctx context.Context, estabID string) ([]models.ProfissionalEstabelecimento, error) {
+	vinculos, err := h.repos.Estabelecimentos.ListarProfissionais(ctx, estabID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range vinculos {
+		if vinculos[i].Nome != "" {
+			continue
+		}
+		if vinculos[i].Nome, err = h.nomeProfissional(ctx, vinculos[i].UID); err != nil && !errors.Is(err, storage.ErrNaoEncontrado) {
+			return nil, err
+		}
+	}
+	return vinculos, nil
+}
+
+// RelatorioOcupacaoEstabelecimento compara o expediente cadastrado dos profissionais com
+// o tempo tomado pelos agendamentos no período: taxa de ocupação total, por profissional,
+// por dia da semana e por dia da semana e hora (mapa de calor). Agendamentos cancelados ou
+// com falta não ocupam a agenda.
+// @Summary Ocupação do estabelecimento
+// @Tags Relatórios
+// @Produce json
+// @Param id path string true "ID do estabelecimento"
+// @Param de query string false "Data inicial (AAAA-MM-DD), padrão 27 dias antes de hoje"
+// @Param ate query string false "Data final (AAAA-MM-DD), padrão hoje"
+// @Param formato query string false "json (padrão), csv, xlsx ou pdf"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Router /relatorios/ocupacao/estabelecimento/{id} [get]
+func (h *Handler) RelatorioOcupacaoEstabelecimento(c *gin.Context) {
+	formato, ok := formatoExportacao(c)
+	if !ok {
+		return
+	}
+	e, loc, ok := h.estabelecimentoDoRepasse(c)
+	if !ok {
+		return
+	}
+	hoje := meiaNoite(time.Now().In(loc))
+	periodo, ok := periodoOcupacao(c, loc, hoje.AddDate(0, 0, 1-diasPadraoOcupacao), diasPadraoOcupacao)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	profissionais, err := h.profissionaisComNome(ctx, e.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar profissionais"})
+		return
+	}
+	var total agenda.MapaOcupacao
+	porProfissional := make(map[string]*agenda.MapaOcupacao, len(profissionais))
+	err = h.percorrerExpedientes(ctx, e.ID, periodo, profissionais, func(p models.ProfissionalEstabelecimento, expediente []agenda.Intervalo, ags []models.Agendamento) {
+		if porProfissional[p.UID] == nil {
+			porProfissional[p.UID] = &agenda.MapaOcupacao{}
+		}
+		porProfissional[p.UID].Somar(expediente, ags)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao calcular ocupação"})
+		return
+	}
+
+	linhas := make([]OcupacaoProfissional, 0, len(profissionais))
+	for _, p := range profissionais {
+		m := porProfissional[p.UID]
+		if m == nil {
+			m = &agenda.MapaOcupacao{}
+		}
+		total.Juntar(*m)
+		linhas = append(linhas, OcupacaoProfissional{ProfissionalID: p.UID, ProfissionalNome: p.Nome, Ocupacao: m.Total})
+	}
+	sort.SliceStable(linhas, func(i, j int) bool { return linhas[i].Taxa > linhas[j].Taxa })
+
+	if formato != exportacao.FormatoJSON {
+		doc := documento{titulo: "Ocupação dos profissionais", arquivo: []string{"ocupacao", e.ID}, estabelecimentoID: e.ID}
+		colunas := []exportacao.Coluna{
+			{Titulo: "Profissional", Peso: 2}, {Titulo: "Expediente (h)"}, {Titulo: "Ocupado (h)"},
+			{Titulo: "Ocioso (h)"}, {Titulo: "Ocupação (%)"},
+		}
+		esc := h.iniciarExportacao(c, formato, doc, detalhesRepasse(periodo, loc), colunas)
+		if esc == nil {
+			return
+		}
+		horas := func(min int) float64 { return float64(min) / 60 }
+		for _, l := range append(linhas, OcupacaoProfissional{ProfissionalNome: "Total", Ocupacao: total.Total}) {
+			if err = esc.Linha(l.ProfissionalNome, horas(l.CapacidadeMin), horas(l.OcupadoMin), horas(l.OciosoMin), l.Taxa); err != nil {
+				break
+			}
+		}
+		terminarExportacao(c, esc, err)
+		return
+	}
+
+	dias := make([]OcupacaoDiaSemana, 0, len(total.DiaSemana))
+	var mapa []OcupacaoHora
+	for d, o := range total.DiaSemana {
+		dia := models.DiaSemana(d + 1)
+		dias = append(dias, OcupacaoDiaSemana{DiaSemana: dia, Ocupacao: o})
+		for hora, celula := range total.DiaEHora[d] {
+			if celula.CapacidadeMin > 0 {
+				mapa = append(mapa, OcupacaoHora{DiaSemana: dia, Hora: hora, Ocupacao: celula})
+			}
+		}
+	}
+	if mapa == nil {
+		mapa = []OcupacaoHora{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"estabelecimento_id": e.ID,
+		"periodo":            periodo,
+		"ocupacao":           total.Total,
+		"profissionais":      linhas,
+		"dias_semana":        dias,
+		"mapa_calor":         mapa,
+	})
+}
+
+// RelatorioOciosidadeEstabelecimento lista os trechos do expediente dos profissionais sem
+// nenhum atendimento marcado que durem ao menos o mínimo pedido, em ordem de início. Por
+// padrão cobre os próximos 7 dias, para o estabelecimento encontrar vagas a preencher.
+// @Summary Horários ociosos do estabelecimento
+// @Tags Relatórios
+// @Produce json
+// @Param id path string true "ID do estabelecimento"
+// @Param de query string false "Data inicial (AAAA-MM-DD), padrão hoje"
+// @Param ate query string false "Data final (AAAA-MM-DD), padrão 6 dias após hoje"
+// @Param minimo query int false "Duração mínima do trecho ocioso em minutos (padrão 30)"
+// @Param profissional query string false "Restringe a um profissional vinculado"
+// @Param formato query string false "json (padrão), csv, xlsx ou pdf"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Router /relatorios/ociosidade/estabelecimento/{id} [get]
+func (h *Handler) RelatorioOciosidadeEstabelecimento(c *gin.Context) {
+	formato, ok := formatoExportacao(c)
+	if !ok {
+		return
+	}
+	minimo := minimoPadraoOcioso
+	if v := c.Query("minimo"); v != "" {
+		m, err := strconv.Atoi(v)
+		if err != nil || m < passoMinimoMin || m > minimoMaximoOcioso {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Mínimo deve ser um número de minutos entre 5 e 480"})
+			return
+		}
+		minimo = m
+	}
+	e, loc, ok := h.estabelecimentoDoRepasse(c)
+	if !ok {
+		return
+	}
+	periodo, ok := periodoOcupacao(c, loc, meiaNoite(time.Now().In(loc)), diasPadraoOciosidade)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	profissionais, err := h.profissionaisComNome(ctx, e.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar profissionais"})
+		return
+	}
+	if profID := c.Query("profissional"); profID != "" {
+		var escolhido []models.ProfissionalEstabelecimento
+		for _, p := range profissionais {
+			if p.UID == profID {
+				escolhido = append(escolhido, p)
+			}
+		}
+		if escolhido == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Profissional " + profID + " não está vinculado ao estabelecimento"})
+			return
+		}
+		profissionais = escolhido
+	}
+
+	ociosos := []HorarioOcioso{}
+	totalMin := 0
+	err = h.percorrerExpedientes(ctx, e.ID, periodo, profissionais, func(p models.ProfissionalEstabelecimento, expediente []agenda.Intervalo, ags []models.Agendamento) {
+		for _, livre := range agenda.Livres(expediente, ags) {
+			duracao := int(livre.Fim.Sub(livre.Inicio) / time.Minute)
+			if duracao < minimo {
+				continue
+			}
+			ociosos = append(ociosos, HorarioOcioso{
+				ProfissionalID:   p.UID,
+				ProfissionalNome: p.Nome,
+				Inicio:           livre.Inicio,
+				Fim:              livre.Fim,
+				DuracaoMin:       duracao,
+			})
+			totalMin += duracao
+		}
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao calcular horários ociosos"})
+		return
+	}
+	sort.SliceStable(ociosos, func(i, j int) bool { return ociosos[i].Inicio.Before(ociosos[j].Inicio) })
+
+	if formato != exportacao.FormatoJSON {
+		doc := documento{titulo: "Horários ociosos", arquivo: []string{"ociosidade", e.ID}, estabelecimentoID: e.ID}
+		colunas := []exportacao.Coluna{
+			{Titulo: "Profissional", Peso: 2}, {Titulo: "Início", Peso: 1.6}, {Titulo: "Fim", Peso: 1.6}, {Titulo: "Duração (min)"},
+		}
+		detalhes := append(detalhesRepasse(periodo, loc), fmt.Sprintf("Trechos de ao menos %d minutos", minimo))
+		esc := h.iniciarExportacao(c, formato, doc, detalhes, colunas)
+		if esc == nil {
+			return
+		}
+		for _, o := range ociosos {
+			if err = esc.Linha(o.ProfissionalNome, o.Inicio, o.Fim, o.DuracaoMin); err != nil {
+				break
+			}
+		}
+		terminarExportacao(c, esc, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"estabelecimento_id": e.ID,
+		"periodo":            periodo,
+		"minimo_min":         minimo,
+		"quantidade":         len(ociosos),
+		"ocioso_min":         totalMin,
+		"horarios_ociosos":   ociosos,
+	})
+}
@@ -1,15 +1,29 @@
 package controllers
 
 import (
-	"context"
+	"fmt"
 	"net/http"
-	"servico-api/config"
 	"servico-api/models"
+	"servico-api/storage"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
+// duracaoMaximaMin é a maior duração aceita: a busca de conflitos só olha JanelaConflito para trás
+const duracaoMaximaMin = int(storage.JanelaConflito / time.Minute)
+
+// duracaoValida recusa procedimentos sem duração ou mais longos que duracaoMaximaMin. Em
+// caso de erro já escreve a resposta.
+func duracaoValida(c *gin.Context, duracaoMin int) bool {
+	if duracaoMin <= 0 || duracaoMin > duracaoMaximaMin {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Duração deve ser de 1 a %d minutos", duracaoMaximaMin)})
+		return false
+	}
+	return true
+}
+
 // CriarProcedimento adiciona novo procedimento para um profissional
 // @Summary Criar procedimento
 // @Tags Procedimentos
@@ -18,25 +32,20 @@ import (
 // @Param procedimento body models.Procedimento true "Procedimento"
 // @Success 201 {object} models.Procedimento
 // @Router /procedimentos [post]
-func CriarProcedimento(c *gin.Context) {
+func (h *Handler) CriarProcedimento(c *gin.Context) {
 	var proc models.Procedimento
 	if err := c.ShouldBindJSON(&proc); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos"})
 		return
 	}
-
-	proc.ID = uuid.New().String()
-
-	ctx := context.Background()
-	client, err := config.App.Firestore(ctx)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao conectar ao Firestore"})
+	if !duracaoValida(c, proc.DuracaoMin) || !politicaValida(c, proc.PoliticaCancelamento) {
 		return
 	}
-	defer client.Close()
 
-	_, err = client.Collection("procedimentos").Doc(proc.ID).Set(ctx, proc)
-	if err != nil {
+	proc.ID = uuid.New().String()
+
+	ctx := c.Request.Context()
+	if err := h.repos.Procedimentos.Salvar(ctx, proc); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao salvar procedimento"})
 		return
 	}
@@ -51,32 +60,16 @@ func CriarProcedimento(c *gin.Context) {
 // @Param id path string true "ID do profissional"
 // @Success 200 {array} models.Procedimento
 // @Router /procedimentos/{id} [get]
-func ListarProcedimentosPorProfissional(c *gin.Context) {
+func (h *Handler) ListarProcedimentosPorProfissional(c *gin.Context) {
 	profID := c.Param("id")
-	ctx := context.Background()
-	client, err := config.App.Firestore(ctx)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro na conexão com Firestore"})
-		return
-	}
-	defer client.Close()
+	ctx := c.Request.Context()
 
-	docs, err := client.Collection("procedimentos").
-		Where("profissional_id", "==", profID).
-		Documents(ctx).GetAll()
+	procedimentos, err := h.repos.Procedimentos.ListarPorProfissional(ctx, profID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar procedimentos"})
 		return
 	}
 
-	var procedimentos []models.Procedimento
-	for _, doc := range docs {
-		var proc models.Procedimento
-		if err := doc.DataTo(&proc); err == nil {
-			procedimentos = append(procedimentos, proc)
-		}
-	}
-
 	c.JSON(http.StatusOK, procedimentos)
 }
 
@@ -89,24 +82,20 @@ func ListarProcedimentosPorProfissional(c *gin.Context) {
 // @Param procedimento body models.Procedimento true "Procedimento atualizado"
 // @Success 200 {object} map[string]string
 // @Router /procedimentos/{id} [put]
-func AtualizarProcedimento(c *gin.Context) {
+func (h *Handler) AtualizarProcedimento(c *gin.Context) {
 	id := c.Param("id")
 	var proc models.Procedimento
 	if err := c.ShouldBindJSON(&proc); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos"})
 		return
 	}
-
-	ctx := context.Background()
-	client, err := config.App.Firestore(ctx)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro na conexão com Firestore"})
+	if !duracaoValida(c, proc.DuracaoMin) || !politicaValida(c, proc.PoliticaCancelamento) {
 		return
 	}
-	defer client.Close()
+	proc.ID = id
 
-	_, err = client.Collection("procedimentos").Doc(id).Set(ctx, proc)
-	if err != nil {
+	ctx := c.Request.Context()
+	if err := h.repos.Procedimentos.Salvar(ctx, proc); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao atualizar procedimento"})
 		return
 	}
@@ -121,18 +110,11 @@ func AtualizarProcedimento(c *gin.Context) {
 // @Param id path string true "ID do procedimento"
 // @Success 200 {object} map[string]string
 // @Router /procedimentos/{id} [delete]
-func DeletarProcedimento(c *gin.Context) {
+func (h *Handler) DeletarProcedimento(c *gin.Context) {
 	id := c.Param("id")
-	ctx := context.Background()
-	client, err := config.App.Firestore(ctx)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro na conexão com Firestore"})
-		return
-	}
-	defer client.Close()
+	ctx := c.Request.Context()
 
-	_, err = client.Collection("procedimentos").Doc(id).Delete(ctx)
-	if err != nil {
+	if err := h.repos.Procedimentos.Excluir(ctx, id); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao deletar procedimento"})
 		return
 	}
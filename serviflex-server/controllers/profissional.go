@@ -2,18 +2,22 @@ package controllers
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"net/http"
-	"servico-api/config"
+	"servico-api/exportacao"
 	"servico-api/models"
+	"servico-api/relatorios"
+	"servico-api/storage"
+	"sort"
 	"time"
 
-	"cloud.google.com/go/firestore"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
-// CriarHorario adiciona horários de atendimento para um profissional
+// CriarHorario adiciona um turno de atendimento em cada dia informado. Um dia pode ter
+// vários turnos, mas nenhum pode se sobrepor a outro já cadastrado; havendo sobreposição
+// nada é gravado.
 // @Summary Criar horários
 // @Tags Horários
 // @Accept json
@@ -21,47 +25,21 @@ import (
 // @Param horario body models.HorarioInput true "Horário de atendimento"
 // @Success 201 {object} map[string]interface{}
 // @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]interface{} "Turno sobreposto a outro já cadastrado"
 // @Router /horarios [post]
-func CriarHorario(c *gin.Context) {
+func (h *Handler) CriarHorario(c *gin.Context) {
 	var input models.HorarioInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos"})
-		return
-	}
-
-	ctx := context.Background()
-	client, err := config.App.Firestore(ctx)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro na conexão com Firestore"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": erroDadosHorario(err)})
 		return
 	}
-	defer client.Close()
-
-	existentes, err := client.Collection("horarios").
-		Where("profissional_id", "==", input.ProfissionalID).
-		Documents(ctx).GetAll()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao verificar horários existentes"})
+	if len(input.DiasSemana) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Informe ao menos um dia da semana"})
 		return
 	}
 
-	diasJaCadastrados := make(map[string]bool)
-	for _, doc := range existentes {
-		data := doc.Data()
-		if dia, ok := data["dia_semana"].(string); ok {
-			diasJaCadastrados[dia] = true
-		}
-	}
-
-	var criados []models.Horario
-	var ignorados []string
-
+	novos := make([]models.Horario, 0, len(input.DiasSemana))
 	for _, dia := range input.DiasSemana {
-		if diasJaCadastrados[dia] {
-			ignorados = append(ignorados, dia)
-			continue
-		}
-
 		horario := models.Horario{
 			ID:             uuid.New().String(),
 			ProfissionalID: input.ProfissionalID,
@@ -69,68 +47,85 @@ func CriarHorario(c *gin.Context) {
 			HoraInicio:     input.HoraInicio,
 			HoraFim:        input.HoraFim,
 			Disponivel:     true,
+			Pausas:         input.Pausas,
 		}
-
-		_, err := client.Collection("horarios").Doc(horario.ID).Set(ctx, horario)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao salvar horário"})
+		if err := horario.Validar(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
+		novos = append(novos, horario)
+	}
 
-		criados = append(criados, horario)
+	err := h.repos.Horarios.SalvarTurnos(c.Request.Context(), input.ProfissionalID, novos)
+	if !turnosSalvos(c, err, "Erro ao salvar horário") {
+		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"criados":   criados,
-		"ignorados": ignorados,
-	})
+	c.JSON(http.StatusCreated, gin.H{"criados": novos})
 }
 
 // EditarHorario permite atualizar um horário existente
-func EditarHorario(c *gin.Context) {
+func (h *Handler) EditarHorario(c *gin.Context) {
 	id := c.Param("id")
 	var input models.Horario
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": erroDadosHorario(err)})
 		return
 	}
-	ctx := context.Background()
-	client, err := config.App.Firestore(ctx)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao conectar com Firestore"})
-		return
-	}
-	defer client.Close()
+	ctx := c.Request.Context()
 
-	docRef := client.Collection("horarios").Doc(id)
-	_, err = docRef.Get(ctx)
+	atual, err := h.repos.Horarios.Buscar(ctx, id)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Horário não encontrado"})
 		return
 	}
 
-	// Atualiza apenas os campos principais
-	_, err = docRef.Set(ctx, input)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao atualizar horário"})
+	// Atualiza apenas os campos principais; o turno continua com o mesmo profissional
+	input.ID = id
+	input.ProfissionalID = atual.ProfissionalID
+	if err := input.Validar(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	err = h.repos.Horarios.SalvarTurnos(ctx, atual.ProfissionalID, []models.Horario{input})
+	if !turnosSalvos(c, err, "Erro ao atualizar horário") {
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Horário atualizado com sucesso"})
 }
 
+// turnosSalvos escreve o 409 de um turno sobreposto a outro do profissional ou o 500 de
+// uma falha na gravação; devolve true se err for nil
+func turnosSalvos(c *gin.Context, err error, erroGravacao string) bool {
+	var sobreposto *storage.TurnoSobreposto
+	switch {
+	case err == nil:
+		return true
+	case errors.As(err, &sobreposto):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":    "O turno se sobrepõe a outro horário já cadastrado",
+			"conflito": sobreposto.Existente,
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": erroGravacao})
+	}
+	return false
+}
+
+// erroDadosHorario explica a recusa do corpo quando o problema é o dia da semana
+func erroDadosHorario(err error) string {
+	if errors.Is(err, models.ErrDiaSemanaInvalido) {
+		return models.ErrDiaSemanaInvalido.Error()
+	}
+	return "Dados inválidos"
+}
+
 // ExcluirHorario remove um horário do profissional
-func ExcluirHorario(c *gin.Context) {
+func (h *Handler) ExcluirHorario(c *gin.Context) {
 	id := c.Param("id")
-	ctx := context.Background()
-	client, err := config.App.Firestore(ctx)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao conectar com Firestore"})
-		return
-	}
-	defer client.Close()
+	ctx := c.Request.Context()
 
-	_, err = client.Collection("horarios").Doc(id).Delete(ctx)
-	if err != nil {
+	if err := h.repos.Horarios.Excluir(ctx, id); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao excluir horário"})
 		return
 	}
@@ -144,54 +139,57 @@ func ExcluirHorario(c *gin.Context) {
 // @Param id path string true "ID do profissional"
 // @Success 200 {array} models.Horario
 // @Router /horarios/{id} [get]
-func ListarHorariosPorProfissional(c *gin.Context) {
+func (h *Handler) ListarHorariosPorProfissional(c *gin.Context) {
 	profissionalID := c.Param("id")
-	ctx := context.Background()
-	client, err := config.App.Firestore(ctx)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro na conexão com Firestore"})
-		return
-	}
-	defer client.Close()
+	ctx := c.Request.Context()
 
-	docs, err := client.Collection("horarios").Where("profissional_id", "==", profissionalID).Documents(ctx).GetAll()
+	horarios, err := h.repos.Horarios.ListarPorProfissional(ctx, profissionalID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar horários"})
 		return
 	}
 
-	var horarios []models.Horario
-	for _, doc := range docs {
-		var h models.Horario
-		if err := doc.DataTo(&h); err == nil {
-			horarios = append(horarios, h)
-		}
-	}
-
 	c.JSON(http.StatusOK, horarios)
 }
 
-// ListarAgendamentosPorProfissional lista os agendamentos de um profissional
+// ListarAgendamentosPorProfissional lista os agendamentos de um profissional. Com o
+// parâmetro formato, exporta a agenda como arquivo com o cabeçalho do estabelecimento.
 // @Summary Listar agendamentos por profissional
 // @Tags Agendamentos
 // @Produce json
 // @Param id path string true "ID do profissional"
+// @Param formato query string false "json (padrão), csv, xlsx ou pdf"
 // @Success 200 {array} models.Agendamento
 // @Router /agendamentos/profissional/{id} [get]
-func ListarAgendamentosPorProfissional(c *gin.Context) {
+func (h *Handler) ListarAgendamentosPorProfissional(c *gin.Context) {
 	profissionalID := c.Param("id")
-	ctx := context.Background()
-	client, err := config.App.Firestore(ctx)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro na conexão com Firestore"})
+	ctx := c.Request.Context()
+
+	formato, ok := formatoExportacao(c)
+	if !ok {
+		return
+	}
+	if formato != exportacao.FormatoJSON {
+		loc, err := h.fusoDoProfissional(ctx, profissionalID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar fuso horário do profissional"})
+			return
+		}
+		doc := documento{titulo: "Agenda", arquivo: []string{"agenda", profissionalID}, profissionalID: profissionalID}
+		if nome, err := h.nomeProfissional(ctx, profissionalID); err == nil && nome != "" {
+			doc.titulo += " de " + nome
+		}
+		nomes := nomesEmCache(h.nomeCliente)
+		h.exportarAgendamentos(c, formato, loc, storage.FiltroAgendamento{ProfissionalID: profissionalID}, doc, "Cliente", func(ctx context.Context, ag models.Agendamento) (string, error) {
+			return nomes(ctx, ag.ClienteID)
+		})
 		return
 	}
-	defer client.Close()
 
-	agDocs, err := client.Collection("agendamentos").
-		Where("profissional_id", "==", profissionalID).
-		OrderBy("data_hora", firestore.Asc).
-		Documents(ctx).GetAll()
+	lista, err := h.repos.Agendamentos.Listar(ctx, storage.FiltroAgendamento{ProfissionalID: profissionalID})
+	if err == nil {
+		err = h.localizarAgendamentos(ctx, lista)
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar agendamentos"})
 		return
@@ -204,19 +202,10 @@ func ListarAgendamentosPorProfissional(c *gin.Context) {
 
 	var agendamentos []AgendamentoComCliente
 
-	for _, doc := range agDocs {
-		var ag models.Agendamento
-		if err := doc.DataTo(&ag); err != nil {
-			continue
-		}
-
-		clienteDoc, err := client.Collection("clientes").Doc(ag.ClienteID).Get(ctx)
+	for _, ag := range lista {
 		nomeCliente := ""
-		if err == nil && clienteDoc.Exists() {
-			dadosCliente := clienteDoc.Data()
-			if nome, ok := dadosCliente["nome"].(string); ok {
-				nomeCliente = nome
-			}
+		if cliente, err := h.repos.Usuarios.BuscarCliente(ctx, ag.ClienteID); err == nil {
+			nomeCliente = cliente.Nome
 		}
 
 		agendamentos = append(agendamentos, AgendamentoComCliente{
@@ -236,7 +225,7 @@ func ListarAgendamentosPorProfissional(c *gin.Context) {
 // @Param dados body models.VinculoProfissionalInput true "IDs do profissional e estabelecimento"
 // @Success 201 {object} map[string]string
 // @Router /estabelecimentos/profissionais/convidar [post]
-func ConvidarProfissional(c *gin.Context) {
+func (h *Handler) ConvidarProfissional(c *gin.Context) {
 	var input models.VinculoProfissionalInput
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Dados inválidos"})
@@ -244,6 +233,7 @@ func ConvidarProfissional(c *gin.Context) {
 	}
 
 	notificacao := models.Notificacao{
+		ID:                uuid.New().String(),
 		ParaUID:           input.ProfissionalUID,
 		Tipo:              "convite_estabelecimento",
 		Mensagem:          "Você foi convidado para o estabelecimento",
@@ -253,16 +243,8 @@ func ConvidarProfissional(c *gin.Context) {
 		CriadoEm:          time.Now(),
 	}
 
-	ctx := context.Background()
-	client, err := config.App.Firestore(ctx)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro no Firestore"})
-		return
-	}
-	defer client.Close()
-
-	_, err = client.Collection("notificacoes").Doc(uuid.New().String()).Set(ctx, notificacao)
-	if err != nil {
+	ctx := c.Request.Context()
+	if err := h.repos.Notificacoes.Criar(ctx, notificacao); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao enviar notificação"})
 		return
 	}
@@ -279,7 +261,7 @@ func ConvidarProfissional(c *gin.Context) {
 // @Param body body map[string]string true "resposta: aceito | recusado"
 // @Success 200 {object} map[string]string
 // @Router /estabelecimentos/profissionais/notificacao/{id} [post]
-func AceitarOuRecusarConvite(c *gin.Context) {
+func (h *Handler) AceitarOuRecusarConvite(c *gin.Context) {
 	notifID := c.Param("id")
 	var body map[string]string
 	if err := c.ShouldBindJSON(&body); err != nil {
@@ -293,28 +275,19 @@ func AceitarOuRecusarConvite(c *gin.Context) {
 		return
 	}
 
-	ctx := context.Background()
-	client, err := config.App.Firestore(ctx)
+	ctx := c.Request.Context()
+
+	notif, err := h.repos.Notificacoes.Buscar(ctx, notifID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro no Firestore"})
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notificação não encontrada"})
 		return
 	}
-	defer client.Close()
 
-	doc, err := client.Collection("notificacoes").Doc(notifID).Get(ctx)
-	if err != nil || !doc.Exists() {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Notificação não encontrada"})
+	if err := h.repos.Notificacoes.Responder(ctx, notifID, resposta); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao registrar resposta"})
 		return
 	}
 
-	var notif models.Notificacao
-	doc.DataTo(&notif)
-
-	client.Collection("notificacoes").Doc(notifID).Update(ctx, []firestore.Update{
-		{Path: "respondido", Value: true},
-		{Path: "resposta", Value: resposta},
-	})
-
 	if resposta == "aceito" {
 		vinculo := models.ProfissionalEstabelecimento{
 			UID:          notif.ParaUID,
@@ -322,12 +295,15 @@ func AceitarOuRecusarConvite(c *gin.Context) {
 			Status:       "ativo",
 			AdicionadoEm: time.Now(),
 		}
-		client.Collection("estabelecimentos").Doc(notif.EstabelecimentoID).
-			Collection("profissionais").Doc(notif.ParaUID).Set(ctx, vinculo)
+		if err := h.repos.Estabelecimentos.VincularProfissional(ctx, notif.EstabelecimentoID, vinculo); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao vincular profissional"})
+			return
+		}
 
-		client.Collection("profissionais").Doc(notif.ParaUID).Update(ctx, []firestore.Update{
-			{Path: "estabelecimentoId", Value: notif.EstabelecimentoID},
-		})
+		if err := h.repos.Usuarios.DefinirEstabelecimentoProfissional(ctx, notif.ParaUID, notif.EstabelecimentoID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao vincular profissional"})
+			return
+		}
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Resposta registrada com sucesso"})
@@ -340,44 +316,17 @@ func AceitarOuRecusarConvite(c *gin.Context) {
 // @Param uid path string true "UID do profissional"
 // @Success 200 {array} models.Notificacao
 // @Router /profissionais/{uid}/convites-pendentes [get]
-func ListarConvitesPendentes(c *gin.Context) {
-    uid := c.Param("uid")
-    ctx := context.Background()
-    client, err := config.App.Firestore(ctx)
-    if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao conectar"})
-        return
-    }
-    defer client.Close()
-
-    docs, err := client.Collection("notificacoes").
-        Where("paraUid", "==", uid).
-        Where("tipo", "==", "convite_estabelecimento").
-        Where("respondido", "==", false).
-        Documents(ctx).GetAll()
-    if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar convites"})
-        return
-    }
-
-    // Struct de resposta incluindo o ID da notificação
-    type NotificacaoComID struct {
-        ID                string      `json:"id"`
-        models.Notificacao
-    }
-
-    var convites []NotificacaoComID
-    for _, doc := range docs {
-        var n models.Notificacao
-        if err := doc.DataTo(&n); err == nil {
-            convites = append(convites, NotificacaoComID{
-                ID:           doc.Ref.ID,
-                Notificacao:  n,
-            })
-        }
-    }
-
-    c.JSON(http.StatusOK, convites)
+func (h *Handler) ListarConvitesPendentes(c *gin.Context) {
+	uid := c.Param("uid")
+	ctx := c.Request.Context()
+
+	convites, err := h.repos.Notificacoes.ListarConvitesPendentes(ctx, uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar convites"})
+		return
+	}
+
+	c.JSON(http.StatusOK, convites)
 }
 
 // RemoverProfissional remove o vínculo de um profissional com o estabelecimento
@@ -388,23 +337,21 @@ func ListarConvitesPendentes(c *gin.Context) {
 // @Param profId path string true "ID do profissional"
 // @Success 200 {object} map[string]string
 // @Router /estabelecimentos/{estId}/profissionais/{profId} [delete]
-func RemoverProfissional(c *gin.Context) {
+func (h *Handler) RemoverProfissional(c *gin.Context) {
 	estID := c.Param("estId")
 	profID := c.Param("profId")
 
-	ctx := context.Background()
-	client, err := config.App.Firestore(ctx)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro no Firestore"})
+	ctx := c.Request.Context()
+
+	if err := h.repos.Estabelecimentos.DesvincularProfissional(ctx, estID, profID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao remover profissional"})
 		return
 	}
-	defer client.Close()
 
-	client.Collection("estabelecimentos").Doc(estID).Collection("profissionais").Doc(profID).Delete(ctx)
-
-	client.Collection("profissionais").Doc(profID).Update(ctx, []firestore.Update{
-		{Path: "estabelecimentoId", Value: ""},
-	})
+	if err := h.repos.Usuarios.DefinirEstabelecimentoProfissional(ctx, profID, ""); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao remover profissional"})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Profissional removido com sucesso"})
 }
@@ -416,238 +363,168 @@ func RemoverProfissional(c *gin.Context) {
 // @Param id path string true "ID do estabelecimento"
 // @Success 200 {array} models.ProfissionalEstabelecimento
 // @Router /estabelecimentos/{id}/profissionais [get]
-func ListarProfissionaisDoEstabelecimento(c *gin.Context) {
+func (h *Handler) ListarProfissionaisDoEstabelecimento(c *gin.Context) {
 	estID := c.Param("id")
+	ctx := c.Request.Context()
 
-	ctx := context.Background()
-	client, err := config.App.Firestore(ctx)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao conectar"})
-		return
-	}
-	defer client.Close()
-
-	docs, err := client.Collection("estabelecimentos").Doc(estID).Collection("profissionais").Documents(ctx).GetAll()
+	lista, err := h.repos.Estabelecimentos.ListarProfissionais(ctx, estID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar profissionais"})
 		return
 	}
 
-	var lista []models.ProfissionalEstabelecimento
-	for _, doc := range docs {
-		var p models.ProfissionalEstabelecimento
-		if err := doc.DataTo(&p); err == nil {
-			lista = append(lista, p)
-		}
-	}
-
 	c.JSON(http.StatusOK, lista)
 }
 
-func RelatorioFaturamentoProfissional(c *gin.Context) {
+// RelatorioFaturamentoProfissional soma os atendimentos concluídos do profissional. Sem
+// período, cobre todo o histórico.
+// @Summary Faturamento do profissional
+// @Tags Relatórios
+// @Produce json
+// @Param id path string true "ID do profissional"
+// @Param de query string false "Data inicial (AAAA-MM-DD), no fuso do estabelecimento do profissional"
+// @Param ate query string false "Data final (AAAA-MM-DD), padrão hoje"
+// @Param agrupar query string false "dia, semana, mes, procedimento ou profissional"
+// @Param comparar query string false "periodo_anterior para comparar com o período imediatamente anterior"
+// @Param formato query string false "json (padrão), csv, xlsx ou pdf"
+// @Success 200 {object} map[string]interface{}
+// @Router /relatorios/profissional/faturamento/{id} [get]
+func (h *Handler) RelatorioFaturamentoProfissional(c *gin.Context) {
 	profID := c.Param("id")
 
-	ctx := context.Background()
-	client, err := config.App.Firestore(ctx)
+	loc, err := h.fusoDoProfissional(c.Request.Context(), profID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro na conexão com Firestore"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar fuso horário do profissional"})
 		return
 	}
-	defer client.Close()
 
-	// Buscar agendamentos do profissional
-	agendamentos, err := client.Collection("agendamentos").
-		Where("profissional_id", "==", profID).
-		Documents(ctx).GetAll()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar agendamentos"})
-		return
-	}
-
-	if len(agendamentos) == 0 {
-		c.JSON(http.StatusOK, gin.H{"total_faturado": 0, "quantidade_agendamentos": 0})
-		return
-	}
-
-	// Buscar todos os procedimentos do profissional
-	procDocs, err := client.Collection("procedimentos").
-		Where("profissional_id", "==", profID).
-		Documents(ctx).GetAll()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar procedimentos"})
+	fonte := h.fonteFaturamento(storage.FiltroAgendamento{ProfissionalID: profID}, loc, false)
+	rel := h.gerarRelatorio(c, loc, relatorios.Historico, metricasFaturamento, fonte)
+	if rel == nil {
 		return
 	}
 
-	precos := make(map[string]float64)
-	for _, doc := range procDocs {
-		var p models.Procedimento
-		if err := doc.DataTo(&p); err == nil {
-			precos[p.Nome] = p.Preco
-		}
-	}
-
-	total := 0.0
-	for _, ag := range agendamentos {
-		nomeProc := ag.Data()["procedimento"].(string)
-		if preco, ok := precos[nomeProc]; ok {
-			total += preco
-		}
-	}
-
-	c.JSON(http.StatusOK, gin.H{
+	doc := documento{titulo: "Faturamento do profissional", arquivo: []string{"faturamento", profID}, profissionalID: profID}
+	h.responderRelatorio(c, loc, rel, doc, gin.H{
 		"profissional_id":         profID,
-		"quantidade_agendamentos": len(agendamentos),
-		"total_faturado":          total,
+		"quantidade_agendamentos": int(rel.Total("quantidade_agendamentos")),
+		"total_faturado":          rel.Total("total_faturado"),
 	})
 }
-func RelatorioAvaliacoesPorProfissional(c *gin.Context) {
+
+// RelatorioAvaliacoesPorProfissional resume as avaliações visíveis do profissional
+// @Summary Avaliações do profissional
+// @Tags Relatórios
+// @Produce json
+// @Param id path string true "ID do profissional"
+// @Param de query string false "Data inicial (AAAA-MM-DD), no fuso do estabelecimento do profissional"
+// @Param ate query string false "Data final (AAAA-MM-DD), padrão hoje"
+// @Param agrupar query string false "dia, semana ou mes"
+// @Param comparar query string false "periodo_anterior para comparar com o período imediatamente anterior"
+// @Param formato query string false "json (padrão), csv, xlsx ou pdf"
+// @Success 200 {object} map[string]interface{}
+// @Router /relatorios/avaliacoes/profissional/{id} [get]
+func (h *Handler) RelatorioAvaliacoesPorProfissional(c *gin.Context) {
 	profID := c.Param("id")
+	ctx := c.Request.Context()
 
-	ctx := context.Background()
-	client, err := config.App.Firestore(ctx)
+	loc, err := h.fusoDoProfissional(ctx, profID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao conectar com Firestore"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar fuso horário do profissional"})
 		return
 	}
-	defer client.Close()
-
-	// Buscar todas as avaliações do profissional
-	docs, err := client.Collection("avaliacoes").
-		Where("profissional_id", "==", profID).
-		Documents(ctx).GetAll()
+	resumo, err := h.repos.Avaliacoes.BuscarResumo(ctx, models.AlvoProfissional, profID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar avaliações"})
 		return
 	}
-
-	if len(docs) == 0 {
-		c.JSON(http.StatusOK, gin.H{
-			"profissional_id":       profID,
-			"quantidade_avaliacoes": 0,
-			"media_nota":            0,
-			"avaliacoes":            []models.Avaliacao{},
-		})
-		return
-	}
-
-	totalNotas := 0.0
-	var avaliacoes []models.Avaliacao
-
-	for _, doc := range docs {
-		var a models.Avaliacao
-		if err := doc.DataTo(&a); err == nil {
-			totalNotas += float64(a.Nota)
-			avaliacoes = append(avaliacoes, a)
-		}
-	}
-
-	media := totalNotas / float64(len(avaliacoes))
-
-	c.JSON(http.StatusOK, gin.H{
-		"profissional_id":       profID,
-		"quantidade_avaliacoes": len(avaliacoes),
-		"media_nota":            media,
-		"avaliacoes":            avaliacoes,
-	})
-}
-func RelatorioAgendamentosPorMesProfissional(c *gin.Context) {
-	profID := c.Param("id")
-
-	ctx := context.Background()
-	client, err := config.App.Firestore(ctx)
+	avaliacoes, err := h.repos.Avaliacoes.ListarPorProfissional(ctx, profID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao conectar com Firestore"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar avaliações"})
 		return
 	}
-	defer client.Close()
 
-	// Definir intervalo: últimos 12 meses
-	agora := time.Now()
-	inicio := agora.AddDate(0, -11, 0) // 11 meses atrás
-	inicio = time.Date(inicio.Year(), inicio.Month(), 1, 0, 0, 0, 0, time.Local)
+	doc := documento{titulo: "Avaliações do profissional", arquivo: []string{"avaliacoes", profID}, profissionalID: profID}
+	h.relatorioAvaliacoes(c, loc, avaliacoes, resumo, doc, gin.H{"profissional_id": profID})
+}
 
-	// Buscar agendamentos do profissional dentro do intervalo
-	docs, err := client.Collection("agendamentos").
-		Where("profissional_id", "==", profID).
-		Where("data_hora", ">=", inicio).
-		Where("data_hora", "<=", agora).
-		Documents(ctx).GetAll()
+// RelatorioAgendamentosPorMesProfissional conta os agendamentos do profissional, por
+// padrão mês a mês nos últimos 12 meses
+// @Summary Agendamentos do profissional
+// @Tags Relatórios
+// @Produce json
+// @Param id path string true "ID do profissional"
+// @Param de query string false "Data inicial (AAAA-MM-DD), no fuso do estabelecimento do profissional"
+// @Param ate query string false "Data final (AAAA-MM-DD), padrão hoje; sem período, os últimos 12 meses por mês"
+// @Param agrupar query string false "dia, semana, mes, procedimento ou profissional"
+// @Param comparar query string false "periodo_anterior para comparar com o período imediatamente anterior"
+// @Param formato query string false "json (padrão), csv, xlsx ou pdf"
+// @Success 200 {object} map[string]interface{}
+// @Router /relatorios/agendamentos/profissional/{id} [get]
+func (h *Handler) RelatorioAgendamentosPorMesProfissional(c *gin.Context) {
+	profID := c.Param("id")
 
+	// Os períodos são contados no fuso do estabelecimento do profissional
+	loc, err := h.fusoDoProfissional(c.Request.Context(), profID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar agendamentos"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar fuso horário do profissional"})
 		return
 	}
 
-	// Inicializar contagem mensal
-	contagem := make(map[string]int)
-	for i := 0; i < 12; i++ {
-		mes := inicio.AddDate(0, i, 0)
-		key := fmt.Sprintf("%04d-%02d", mes.Year(), mes.Month())
-		contagem[key] = 0
-	}
-
-	// Contar agendamentos por mês
-	for _, doc := range docs {
-		var ag models.Agendamento
-		if err := doc.DataTo(&ag); err == nil {
-			data := ag.DataHora.In(time.Local)
-			key := fmt.Sprintf("%04d-%02d", data.Year(), data.Month())
-			if _, ok := contagem[key]; ok {
-				contagem[key]++
-			}
-		}
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"profissional_id":      profID,
-		"agendamentos_por_mes": contagem,
-	})
+	doc := documento{titulo: "Agendamentos do profissional", arquivo: []string{"agendamentos", profID}, profissionalID: profID}
+	h.relatorioAgendamentos(c, loc, storage.FiltroAgendamento{ProfissionalID: profID}, doc, gin.H{"profissional_id": profID})
 }
 
-// ListarProfissionais retorna todos os profissionais
-func ListarProfissionais(c *gin.Context) {
-	ctx := context.Background()
-	client, err := config.App.Firestore(ctx)
+// ListarProfissionais retorna todos os profissionais, ordenados pela pontuação das avaliações
+func (h *Handler) ListarProfissionais(c *gin.Context) {
+	ctx := c.Request.Context()
+	profissionais, err := h.repos.Usuarios.ListarProfissionais(ctx)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao conectar"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar profissionais"})
 		return
 	}
-	defer client.Close()
 
-	docs, err := client.Collection("profissionais").Documents(ctx).GetAll()
+	ids := make([]string, len(profissionais))
+	for i, p := range profissionais {
+		ids[i] = p.ID
+	}
+	resumos, err := h.resumosPontuados(ctx, models.AlvoProfissional, ids)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar profissionais"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar avaliações dos profissionais"})
 		return
 	}
 
-	var profissionais []models.Profissional
-	for _, doc := range docs {
-		var p models.Profissional
-		if err := doc.DataTo(&p); err == nil {
-			profissionais = append(profissionais, p)
-		}
+	for i := range profissionais {
+		resumo := resumos[profissionais[i].ID]
+		profissionais[i].Senha = ""
+		profissionais[i].Avaliacoes = &resumo
 	}
+	// Mais bem avaliados primeiro, pela média bayesiana
+	sort.SliceStable(profissionais, func(i, j int) bool {
+		return profissionais[i].Avaliacoes.Pontuacao > profissionais[j].Avaliacoes.Pontuacao
+	})
 	c.JSON(http.StatusOK, profissionais)
 }
 
 // BuscarProfissionalPorID retorna um profissional por ID
-func BuscarProfissionalPorID(c *gin.Context) {
+func (h *Handler) BuscarProfissionalPorID(c *gin.Context) {
 	uid := c.Param("uid")
-	ctx := context.Background()
-	client, err := config.App.Firestore(ctx)
+	ctx := c.Request.Context()
+
+	p, err := h.repos.Usuarios.BuscarProfissional(ctx, uid)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao conectar"})
+		c.JSON(http.StatusNotFound, gin.H{"error": "Profissional não encontrado"})
 		return
 	}
-	defer client.Close()
 
-	doc, err := client.Collection("profissionais").Doc(uid).Get(ctx)
-	if err != nil || !doc.Exists() {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Profissional não encontrado"})
+	resumos, err := h.resumosPontuados(ctx, models.AlvoProfissional, []string{uid})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar avaliações do profissional"})
 		return
 	}
+	resumo := resumos[uid]
 
-	var p models.Profissional
-	doc.DataTo(&p)
+	p.Senha = ""
+	p.Avaliacoes = &resumo
 	c.JSON(http.StatusOK, p)
 }
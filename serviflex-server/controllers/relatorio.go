@@ -0,0 +1,247 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"servico-api/exportacao"
+	"servico-api/models"
+	"servico-api/relatorios"
+	"servico-api/storage"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Métricas de cada relatório, na ordem das colunas
+var (
+	metricasAgendamentos        = []string{"quantidade_agendamentos"}
+	metricasFaturamento         = []string{"quantidade_agendamentos", "total_faturado"}
+	metricasFaturamentoComTaxas = []string{"quantidade_agendamentos", "total_servicos", "quantidade_taxas", "total_taxas", "total_faturado"}
+	metricasAvaliacoes          = []string{"quantidade_avaliacoes", "media_nota"}
+)
+
+// mesesRelatorioAgendamentos é o período padrão do relatório de agendamentos
+const mesesRelatorioAgendamentos = 12
+
+// errAgrupamentoIndisponivel é devolvido pelas fontes que não sabem agrupar como pedido
+var errAgrupamentoIndisponivel = errors.New("agrupamento indisponível neste relatório")
+
+// gerarRelatorio interpreta de, ate, agrupar e comparar no fuso loc e executa o relatório
+// sobre a fonte. padrao dá o período e o agrupamento usados sem parâmetros. O formato da
+// exportação é validado aqui, antes de qualquer consulta. Em caso de erro já escreve a
+// resposta e devolve nil.
+func (h *Handler) gerarRelatorio(c *gin.Context, loc *time.Location, padrao func(hoje time.Time) relatorios.Consulta, metricas []string, fonte relatorios.Fonte) *relatorios.Relatorio {
+	if _, ok := formatoExportacao(c); !ok {
+		return nil
+	}
+	hoje := time.Now().In(loc)
+	consulta, err := relatorios.Interpretar(c.Query, padrao(hoje), hoje)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil
+	}
+
+	ctx := c.Request.Context()
+	rel, err := relatorios.Gerar(ctx, consulta, metricas, fonte)
+	if errors.Is(err, errAgrupamentoIndisponivel) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Agrupamento por " + string(consulta.Agrupar) + " indisponível neste relatório"})
+		return nil
+	}
+	if err == nil && consulta.Agrupar == relatorios.AgruparProfissional {
+		err = h.rotularProfissionais(ctx, rel)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao gerar relatório"})
+		return nil
+	}
+	return rel
+}
+
+// responderRelatorio escreve o relatório com os campos próprios de cada endpoint ou, com
+// o parâmetro formato, o exporta como arquivo identificado por doc
+func (h *Handler) responderRelatorio(c *gin.Context, loc *time.Location, rel *relatorios.Relatorio, doc documento, campos gin.H) {
+	if formato, _ := formatoExportacao(c); formato != exportacao.FormatoJSON {
+		h.exportarRelatorio(c, formato, loc, rel, doc)
+		return
+	}
+	resposta := gin.H(rel.Campos())
+	for k, v := range campos {
+		resposta[k] = v
+	}
+	c.JSON(http.StatusOK, resposta)
+}
+
+// rotularProfissionais usa o nome de cada profissional como rótulo da série agrupada por profissional
+func (h *Handler) rotularProfissionais(ctx context.Context, rel *relatorios.Relatorio) error {
+	series := [][]relatorios.Linha{rel.Atual.Serie}
+	if rel.Anterior != nil {
+		series = append(series, rel.Anterior.Serie)
+	}
+	nomes := make(map[string]string)
+	for _, serie := range series {
+		for i := range serie {
+			id := serie[i].Chave
+			nome, ok := nomes[id]
+			if !ok {
+				p, err := h.repos.Usuarios.BuscarProfissional(ctx, id)
+				if err != nil && !errors.Is(err, storage.ErrNaoEncontrado) {
+					return err
+				}
+				if p != nil {
+					nome = p.Nome
+				}
+				nomes[id] = nome
+			}
+			serie[i].Rotulo = nome
+		}
+	}
+	return nil
+}
+
+// noPeriodo restringe o filtro aos agendamentos do período
+func noPeriodo(filtro storage.FiltroAgendamento, p relatorios.Periodo) storage.FiltroAgendamento {
+	filtro.De = p.Inicio
+	filtro.Ate = p.Ultimo()
+	return filtro
+}
+
+// fonteAgendamentos conta os agendamentos do filtro, de qualquer status
+func (h *Handler) fonteAgendamentos(filtro storage.FiltroAgendamento, loc *time.Location) relatorios.Fonte {
+	return func(ctx context.Context, p relatorios.Periodo, agrupar relatorios.Agrupamento) ([]relatorios.Linha, error) {
+		resumos, err := h.repos.Relatorios.Faturamento(ctx, noPeriodo(filtro, p), agrupar, loc)
+		if err != nil {
+			return nil, err
+		}
+		tabela := relatorios.NovaTabela(len(metricasAgendamentos))
+		for _, r := range resumos {
+			tabela.Linha(r.Chave, r.Rotulo).Valores[0] = float64(r.Quantidade)
+		}
+		return tabela.Linhas(), nil
+	}
+}
+
+// fonteFaturamento soma o preço dos atendimentos concluídos do filtro. Com taxas, soma
+// também as taxas de cancelamento tardio e falta, e o total faturado inclui as duas
+// partes (métricas em metricasFaturamentoComTaxas); sem, usa metricasFaturamento.
+func (h *Handler) fonteFaturamento(filtro storage.FiltroAgendamento, loc *time.Location, taxas bool) relatorios.Fonte {
+	return func(ctx context.Context, p relatorios.Periodo, agrupar relatorios.Agrupamento) ([]relatorios.Linha, error) {
+		f := noPeriodo(filtro, p)
+		f.Status = models.StatusConcluido // só atendimentos realizados geram faturamento
+		servicos, err := h.repos.Relatorios.Faturamento(ctx, f, agrupar, loc)
+		if err != nil {
+			return nil, err
+		}
+		if !taxas {
+			tabela := relatorios.NovaTabela(len(metricasFaturamento))
+			for _, r := range servicos {
+				linha := tabela.Linha(r.Chave, r.Rotulo)
+				linha.Valores[0], linha.Valores[1] = float64(r.Quantidade), r.Total
+			}
+			return tabela.Linhas(), nil
+		}
+
+		cobradas, err := h.repos.Relatorios.Taxas(ctx, noPeriodo(filtro, p), agrupar, loc)
+		if err != nil {
+			return nil, err
+		}
+		tabela := relatorios.NovaTabela(len(metricasFaturamentoComTaxas))
+		for _, r := range servicos {
+			linha := tabela.Linha(r.Chave, r.Rotulo)
+			linha.Valores[0], linha.Valores[1] = float64(r.Quantidade), r.Total
+		}
+		for _, r := range cobradas {
+			linha := tabela.Linha(r.Chave, r.Rotulo)
+			linha.Valores[2], linha.Valores[3] = float64(r.Quantidade), r.Total
+		}
+		linhas := tabela.Linhas()
+		for i := range linhas {
+			linhas[i].Valores[4] = linhas[i].Valores[1] + linhas[i].Valores[3]
+		}
+		return linhas, nil
+	}
+}
+
+// fonteAvaliacoes calcula quantidade e média das avaliações visíveis, já carregadas, pela
+// data de envio. Os totais de todo o histórico vêm do resumo mantido a cada avaliação.
+func fonteAvaliacoes(avaliacoes []models.Avaliacao, resumo models.ResumoAvaliacoes, loc *time.Location) relatorios.Fonte {
+	return func(_ context.Context, p relatorios.Periodo, agrupar relatorios.Agrupamento) ([]relatorios.Linha, error) {
+		if agrupar == relatorios.SemAgrupamento && p.Aberto() {
+			return []relatorios.Linha{{Valores: []float64{float64(resumo.Quantidade), resumo.Media}}}, nil
+		}
+		if agrupar == relatorios.AgruparProcedimento {
+			return nil, errAgrupamentoIndisponivel
+		}
+
+		var chaves []string
+		grupos := make(map[string][]models.Avaliacao)
+		for _, a := range avaliacoes {
+			if !a.Visivel() || !p.Contem(a.Data) {
+				continue
+			}
+			chave := agrupar.Chave(a.Data.In(loc))
+			if agrupar == relatorios.AgruparProfissional {
+				chave = a.ProfissionalID
+			}
+			if _, ok := grupos[chave]; !ok {
+				chaves = append(chaves, chave)
+			}
+			grupos[chave] = append(grupos[chave], a)
+		}
+
+		linhas := make([]relatorios.Linha, 0, len(chaves))
+		for _, chave := range chaves {
+			r := models.ResumirAvaliacoes(grupos[chave])
+			linhas = append(linhas, relatorios.Linha{Chave: chave, Valores: []float64{float64(r.Quantidade), r.Media}})
+		}
+		return linhas, nil
+	}
+}
+
+// relatorioAvaliacoes responde o relatório de avaliações já carregadas do profissional ou
+// do estabelecimento: a consulta comum, o histograma e as avaliações visíveis do período
+func (h *Handler) relatorioAvaliacoes(c *gin.Context, loc *time.Location, avaliacoes []models.Avaliacao, resumo models.ResumoAvaliacoes, doc documento, campos gin.H) {
+	rel := h.gerarRelatorio(c, loc, relatorios.Historico, metricasAvaliacoes, fonteAvaliacoes(avaliacoes, resumo, loc))
+	if rel == nil {
+		return
+	}
+
+	// Histograma e lista acompanham o período pedido; sem período, o histograma vem do resumo
+	var doPeriodo []models.Avaliacao
+	for _, a := range avaliacoes {
+		if rel.Atual.Periodo.Contem(a.Data) {
+			doPeriodo = append(doPeriodo, a)
+		}
+	}
+	histograma := resumo.ComHistograma().Histograma
+	if !rel.Atual.Periodo.Aberto() {
+		histograma = models.ResumirAvaliacoes(doPeriodo).Histograma
+	}
+
+	campos["quantidade_avaliacoes"] = int(rel.Total("quantidade_avaliacoes"))
+	campos["media_nota"] = rel.Total("media_nota")
+	campos["histograma"] = histograma
+	campos["avaliacoes"] = avaliacoesPublicas(doPeriodo)
+	h.responderRelatorio(c, loc, rel, doc, campos)
+}
+
+// relatorioAgendamentos responde a contagem de agendamentos do filtro, por padrão dos
+// últimos 12 meses. Agrupado por mês, mantém também o mapa agendamentos_por_mes.
+func (h *Handler) relatorioAgendamentos(c *gin.Context, loc *time.Location, filtro storage.FiltroAgendamento, doc documento, campos gin.H) {
+	padrao := func(hoje time.Time) relatorios.Consulta {
+		return relatorios.UltimosMeses(hoje, mesesRelatorioAgendamentos)
+	}
+	rel := h.gerarRelatorio(c, loc, padrao, metricasAgendamentos, h.fonteAgendamentos(filtro, loc))
+	if rel == nil {
+		return
+	}
+	if rel.Agrupar == relatorios.AgruparMes {
+		porMes := make(map[string]int, len(rel.Atual.Serie))
+		for _, l := range rel.Atual.Serie {
+			porMes[l.Chave] = int(l.Valores[0])
+		}
+		campos["agendamentos_por_mes"] = porMes
+	}
+	campos["quantidade_agendamentos"] = int(rel.Total("quantidade_agendamentos"))
+	h.responderRelatorio(c, loc, rel, doc, campos)
+}
@@ -1,11 +1,8 @@
 package controllers
 
 import (
-	"context"
 	"net/http"
-	"servico-api/config"
 
-	"cloud.google.com/go/firestore"
 	"github.com/gin-gonic/gin"
 )
 
@@ -25,7 +22,7 @@ type ImagemInput struct {
 // @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /upload/{tipo}/{id} [put]
-func SetImagemURL(c *gin.Context) {
+func (h *Handler) SetImagemURL(c *gin.Context) {
 	tipo := c.Param("tipo")
 	id := c.Param("id")
 
@@ -35,30 +32,20 @@ func SetImagemURL(c *gin.Context) {
 		return
 	}
 
-	ctx := context.Background()
-	client, err := config.App.Firestore(ctx)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao conectar com Firestore"})
-		return
-	}
-	defer client.Close()
+	ctx := c.Request.Context()
 
-	col := ""
+	var err error
 	switch tipo {
 	case "profissional":
-		col = "profissionais"
+		err = h.repos.Usuarios.AtualizarImagemProfissional(ctx, id, input.ImagemURL)
 	case "procedimento":
-		col = "procedimentos"
+		err = h.repos.Procedimentos.AtualizarImagem(ctx, id, input.ImagemURL)
 	default:
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Tipo inválido"})
 		return
 	}
-
-	_, err = client.Collection(col).Doc(id).Update(ctx, []firestore.Update{
-		{Path: "imagem_url", Value: input.ImagemURL},
-	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao atualizar imagem no Firestore"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao atualizar imagem"})
 		return
 	}
 
@@ -0,0 +1,51 @@
+package exportacao
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+)
+
+// escritorCSV grava CSV separado por ponto e vírgula, que o Excel em português abre
+// direto em colunas, com BOM UTF-8 para os acentos
+type escritorCSV struct {
+	w *csv.Writer
+}
+
+func novoCSV(w io.Writer, colunas []Coluna) (*escritorCSV, error) {
+	if _, err := io.WriteString(w, "\ufeff"); err != nil {
+		return nil, err
+	}
+	e := &escritorCSV{w: csv.NewWriter(w)}
+	e.w.Comma = ';'
+	titulos := make([]string, len(colunas))
+	for i, c := range colunas {
+		titulos[i] = c.Titulo
+	}
+	return e, e.w.Write(titulos)
+}
+
+func (e *escritorCSV) Linha(celulas ...any) error {
+	registro := make([]string, len(celulas))
+	for i, c := range celulas {
+		registro[i] = texto(c)
+		if _, ok := c.(string); ok {
+			registro[i] = semFormula(registro[i])
+		}
+	}
+	return e.w.Write(registro)
+}
+
+// semFormula prefixa com apóstrofo o texto que a planilha interpretaria como fórmula,
+// como um nome de cliente ou procedimento começando com "="; números não passam por aqui
+func semFormula(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func (e *escritorCSV) Fechar() error {
+	e.w.Flush()
+	return e.w.Error()
+}
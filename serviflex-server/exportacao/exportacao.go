@@ -0,0 +1,174 @@
+// Package exportacao grava relatórios e listagens em CSV, XLSX e PDF. Os escritores
+// recebem uma linha por vez e a enviam ao io.Writer sem acumular o arquivo na memória, de
+// modo que exportações grandes podem ir direto para a resposta HTTP. Só a biblioteca
+// padrão é usada: o XLSX é um pacote OOXML mínimo e o PDF usa as fontes padrão.
+package exportacao
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"servico-api/models"
+)
+
+// Formato é o tipo de arquivo da exportação
+type Formato string
+
+const (
+	FormatoJSON Formato = "" // resposta JSON normal, sem exportação
+	FormatoCSV  Formato = "csv"
+	FormatoXLSX Formato = "xlsx"
+	FormatoPDF  Formato = "pdf"
+)
+
+// ErrFormatoInvalido recusa formatos desconhecidos
+var ErrFormatoInvalido = errors.New("formato deve ser json, csv, xlsx ou pdf")
+
+// InterpretarFormato lê o parâmetro formato; vazio ou "json" significam a resposta JSON
+func InterpretarFormato(valor string) (Formato, error) {
+	switch f := Formato(strings.ToLower(valor)); f {
+	case FormatoCSV, FormatoXLSX, FormatoPDF:
+		return f, nil
+	case FormatoJSON, "json":
+		return FormatoJSON, nil
+	}
+	return FormatoJSON, ErrFormatoInvalido
+}
+
+// TipoConteudo devolve o Content-Type do arquivo
+func (f Formato) TipoConteudo() string {
+	switch f {
+	case FormatoCSV:
+		return "text/csv; charset=utf-8"
+	case FormatoXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case FormatoPDF:
+		return "application/pdf"
+	}
+	return "application/json; charset=utf-8"
+}
+
+// Coluna descreve uma coluna da exportação. Peso é a largura relativa da coluna no PDF;
+// zero vale 1.
+type Coluna struct {
+	Titulo string
+	Peso   float64
+}
+
+// Cabecalho identifica o documento. O estabelecimento, quando houver, abre a planilha e o
+// PDF com nome e endereço; o CSV traz só os dados, para ser importado por outros sistemas.
+type Cabecalho struct {
+	Estabelecimento *models.Estabelecimento
+	Titulo          string
+	Detalhes        []string // linhas complementares, como o período e a data de emissão
+}
+
+// linhas devolve o cabeçalho linha a linha: nome e endereço do estabelecimento, título e detalhes
+func (c Cabecalho) linhas() (estabelecimento []string, titulo string, detalhes []string) {
+	if e := c.Estabelecimento; e != nil {
+		estabelecimento = append(estabelecimento, e.Nome)
+		endereco := e.Localizacao.Endereco
+		if cidade := strings.Trim(e.Localizacao.Cidade+" - "+e.Localizacao.UF, " -"); cidade != "" {
+			if endereco != "" {
+				endereco += ", "
+			}
+			endereco += cidade
+		}
+		if endereco != "" {
+			estabelecimento = append(estabelecimento, endereco)
+		}
+	}
+	return estabelecimento, c.Titulo, c.Detalhes
+}
+
+// Escritor grava as linhas da exportação em sequência. As células podem ser string, int,
+// float64, *float64 (nil fica vazio) ou time.Time; números ficam numéricos na planilha.
+// Fechar termina o arquivo e precisa ser chamado mesmo depois de um erro de Linha.
+type Escritor interface {
+	Linha(celulas ...any) error
+	Fechar() error
+}
+
+// NovoEscritor começa a exportação no formato pedido, já com o cabeçalho e os títulos das colunas
+func NovoEscritor(formato Formato, w io.Writer, cab Cabecalho, colunas []Coluna) (Escritor, error) {
+	switch formato {
+	case FormatoCSV:
+		return novoCSV(w, colunas)
+	case FormatoXLSX:
+		return novoXLSX(w, cab, colunas)
+	case FormatoPDF:
+		return novoPDF(w, cab, colunas)
+	}
+	return nil, ErrFormatoInvalido
+}
+
+// NomeArquivo monta o nome do arquivo para o Content-Disposition, só com caracteres seguros
+func NomeArquivo(formato Formato, partes ...string) string {
+	var b strings.Builder
+	for _, parte := range partes {
+		if parte == "" {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteByte('-')
+		}
+		for _, r := range parte {
+			switch {
+			case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+				b.WriteRune(r)
+			default:
+				b.WriteByte('_')
+			}
+		}
+	}
+	return b.String() + "." + string(formato)
+}
+
+// texto formata a célula para CSV e PDF, com vírgula decimal como nas planilhas em português
+func texto(celula any) string {
+	switch v := celula.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return decimal(v)
+	case *float64:
+		if v == nil {
+			return ""
+		}
+		return decimal(*v)
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return v.Format("02/01/2006 15:04")
+	}
+	return fmt.Sprint(celula)
+}
+
+// numero devolve o valor numérico da célula, se ela for um número
+func numero(celula any) (valor float64, inteiro bool, ok bool) {
+	switch v := celula.(type) {
+	case int:
+		return float64(v), true, true
+	case float64:
+		return v, false, true
+	case *float64:
+		if v != nil {
+			return *v, false, true
+		}
+	}
+	return 0, false, false
+}
+
+// decimal formata com duas casas e vírgula, como valores em reais
+func decimal(v float64) string {
+	return strings.Replace(strconv.FormatFloat(v, 'f', 2, 64), ".", ",", 1)
+}
@@ -0,0 +1,130 @@
+package exportacao
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"servico-api/models"
+)
+
+var cabecalho = Cabecalho{
+	Estabelecimento: &models.Estabelecimento{
+		Nome:        "Salão (Centro)",
+		Localizacao: models.Endereco{Endereco: "Rua A, 10", Cidade: "Campinas", UF: "SP"},
+	},
+	Titulo:   "Faturamento",
+	Detalhes: []string{"Período: 01/01/2025 a 31/01/2025"},
+}
+
+var colunas = []Coluna{{Titulo: "Dia", Peso: 2}, {Titulo: "Agendamentos"}, {Titulo: "Total (R$)"}}
+
+func exportar(t *testing.T, formato Formato, linhas int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	esc, err := NovoEscritor(formato, &buf, cabecalho, colunas)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < linhas; i++ {
+		if err := esc.Linha("Corte; barba", i, 1234.5); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := esc.Linha(time.Date(2025, 1, 2, 9, 30, 0, 0, time.UTC), nil, (*float64)(nil)); err != nil {
+		t.Fatal(err)
+	}
+	if err := esc.Fechar(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestCSV(t *testing.T) {
+	got := string(exportar(t, FormatoCSV, 1))
+	want := "\ufeffDia;Agendamentos;Total (R$)\n\"Corte; barba\";0;1234,50\n02/01/2025 09:30;;\n"
+	if got != want {
+		t.Errorf("CSV inesperado:\n%q\nesperado:\n%q", got, want)
+	}
+}
+
+func TestCSVSemFormulas(t *testing.T) {
+	var buf bytes.Buffer
+	esc, err := NovoEscritor(FormatoCSV, &buf, cabecalho, colunas)
+	if err != nil {
+		t.Fatal(err)
+	}
+	esc.Linha("=HYPERLINK(\"x\")", "@SOMA(A1)", -12.5)
+	esc.Linha("+55 19", "-corte", "Corte - barba")
+	if err := esc.Fechar(); err != nil {
+		t.Fatal(err)
+	}
+	want := "\ufeffDia;Agendamentos;Total (R$)\n\"'=HYPERLINK(\"\"x\"\")\";'@SOMA(A1);-12,50\n'+55 19;'-corte;Corte - barba\n"
+	if got := buf.String(); got != want {
+		t.Errorf("CSV inesperado:\n%q\nesperado:\n%q", got, want)
+	}
+}
+
+func TestXLSX(t *testing.T) {
+	arquivo := exportar(t, FormatoXLSX, 2)
+	z, err := zip.NewReader(bytes.NewReader(arquivo), int64(len(arquivo)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var folha string
+	for _, f := range z.File {
+		if f.Name == "xl/worksheets/sheet1.xml" {
+			r, _ := f.Open()
+			b, _ := io.ReadAll(r)
+			folha = string(b)
+		}
+	}
+	for _, trecho := range []string{
+		`<t xml:space="preserve">Salão (Centro)</t>`,
+		`Rua A, 10, Campinas - SP`,
+		`<c r="B7" s="0"><v>0</v></c><c r="C7" s="2"><v>1234.5</v></c>`,
+		`<c r="A9" s="4"><v>45659.395833333336</v></c>`,
+	} {
+		if !strings.Contains(folha, trecho) {
+			t.Errorf("planilha sem %q:\n%s", trecho, folha)
+		}
+	}
+}
+
+func TestPDF(t *testing.T) {
+	arquivo := exportar(t, FormatoPDF, 120)
+	if !bytes.HasPrefix(arquivo, []byte("%PDF-1.4")) || !bytes.HasSuffix(arquivo, []byte("%%EOF\n")) {
+		t.Fatal("PDF sem assinatura ou fim de arquivo")
+	}
+	if !bytes.Contains(arquivo, []byte("(Sal\xe3o \\(Centro\\))")) {
+		t.Error("nome do estabelecimento não foi convertido para WinAnsi e escapado")
+	}
+	if m := regexp.MustCompile(`/Count (\d+)`).FindSubmatch(arquivo); m == nil || string(m[1]) == "1" {
+		t.Error("120 linhas deveriam ocupar mais de uma página")
+	}
+
+	// Cada entrada da tabela xref aponta para o início do seu objeto
+	m := regexp.MustCompile(`startxref\n(\d+)`).FindSubmatch(arquivo)
+	inicio, _ := strconv.Atoi(string(m[1]))
+	entradas := strings.Split(string(arquivo[inicio:]), "\n")[3:]
+	for n, entrada := range entradas {
+		if strings.HasPrefix(entrada, "trailer") {
+			break
+		}
+		offset, _ := strconv.Atoi(entrada[:10])
+		if objeto := strconv.Itoa(n+1) + " 0 obj"; !bytes.HasPrefix(arquivo[offset:], []byte(objeto)) {
+			t.Errorf("xref do objeto %d aponta para %q", n+1, arquivo[offset:offset+10])
+		}
+	}
+}
+
+func TestNomeArquivo(t *testing.T) {
+	if got := NomeArquivo(FormatoPDF, "faturamento", "abc/../d é", ""); got != "faturamento-abc____d__.pdf" {
+		t.Errorf("nome inesperado: %s", got)
+	}
+}
@@ -0,0 +1,319 @@
+package exportacao
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Layout do PDF, em pontos (1/72 pol.). Páginas A4, deitadas quando há muitas colunas.
+const (
+	pdfLarguraA4      = 595.0
+	pdfAlturaA4       = 842.0
+	pdfMargem         = 36.0
+	pdfCorpo          = 9.0
+	pdfEntrelinha     = 13.0
+	pdfColunasRetrato = 5
+)
+
+// Objetos fixos do documento; as páginas vêm a partir de pdfPrimeiraPagina
+const (
+	pdfCatalogo = iota + 1
+	pdfPaginas
+	pdfFonte
+	pdfFonteNegrito
+	pdfPrimeiraPagina
+)
+
+// escritorPDF monta uma página por vez e a grava assim que ela enche: só a página atual
+// fica na memória. O índice de páginas e a tabela xref vão no final, como o formato permite.
+type escritorPDF struct {
+	w        *contador
+	cab      Cabecalho
+	colunas  []Coluna
+	larguras []float64
+
+	largura, altura float64
+	offsets         map[int]int64
+	paginas         []int
+	proximo         int
+
+	pagina *bytes.Buffer
+	y      float64
+	err    error
+}
+
+// contador registra quantos bytes já foram escritos, para a tabela xref
+type contador struct {
+	w io.Writer
+	n int64
+}
+
+func (c *contador) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func novoPDF(w io.Writer, cab Cabecalho, colunas []Coluna) (*escritorPDF, error) {
+	e := &escritorPDF{
+		w:       &contador{w: w},
+		cab:     cab,
+		colunas: colunas,
+		largura: pdfLarguraA4,
+		altura:  pdfAlturaA4,
+		offsets: make(map[int]int64),
+		proximo: pdfPrimeiraPagina,
+	}
+	if len(colunas) > pdfColunasRetrato {
+		e.largura, e.altura = e.altura, e.largura
+	}
+
+	var pesos float64
+	for _, c := range colunas {
+		pesos += peso(c)
+	}
+	util := e.largura - 2*pdfMargem
+	for _, c := range colunas {
+		e.larguras = append(e.larguras, util*peso(c)/pesos)
+	}
+
+	fmt.Fprint(e.w, "%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	e.objeto(pdfCatalogo, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pdfPaginas))
+	e.objeto(pdfFonte, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	e.objeto(pdfFonteNegrito, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	e.novaPagina()
+	return e, e.err
+}
+
+func peso(c Coluna) float64 {
+	if c.Peso <= 0 {
+		return 1
+	}
+	return c.Peso
+}
+
+func (e *escritorPDF) Linha(celulas ...any) error {
+	if e.y-pdfEntrelinha < pdfMargem+pdfEntrelinha {
+		e.fecharPagina()
+		e.novaPagina()
+	}
+	e.celulas(false, celulas)
+	return e.err
+}
+
+func (e *escritorPDF) Fechar() error {
+	e.fecharPagina()
+
+	kids := make([]string, len(e.paginas))
+	for i, p := range e.paginas {
+		kids[i] = fmt.Sprintf("%d 0 R", p)
+	}
+	e.objeto(pdfPaginas, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(e.paginas)))
+
+	inicioXref := e.w.n
+	fmt.Fprintf(e.w, "xref\n0 %d\n0000000000 65535 f \n", e.proximo)
+	for n := 1; n < e.proximo; n++ {
+		fmt.Fprintf(e.w, "%010d 00000 n \n", e.offsets[n])
+	}
+	_, err := fmt.Fprintf(e.w, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", e.proximo, pdfCatalogo, inicioXref)
+	if e.err != nil {
+		return e.err
+	}
+	return err
+}
+
+// objeto grava o objeto n e guarda sua posição para a tabela xref
+func (e *escritorPDF) objeto(n int, conteudo string) {
+	if e.err != nil {
+		return
+	}
+	e.offsets[n] = e.w.n
+	_, e.err = fmt.Fprintf(e.w, "%d 0 obj\n%s\nendobj\n", n, conteudo)
+}
+
+// novaPagina começa a página com o cabeçalho (completo só na primeira) e os títulos das colunas
+func (e *escritorPDF) novaPagina() {
+	e.pagina = &bytes.Buffer{}
+	e.y = e.altura - pdfMargem
+
+	estabelecimento, titulo, detalhes := e.cab.linhas()
+	if len(e.paginas) == 0 {
+		for i, l := range estabelecimento {
+			if i == 0 {
+				e.texto(pdfMargem, 14, true, l)
+				e.y -= 18
+				continue
+			}
+			e.texto(pdfMargem, pdfCorpo, false, l)
+			e.y -= pdfEntrelinha
+		}
+		if len(estabelecimento) > 0 {
+			e.y -= 6
+		}
+		if titulo != "" {
+			e.texto(pdfMargem, 12, true, titulo)
+			e.y -= 16
+		}
+		for _, d := range detalhes {
+			e.texto(pdfMargem, pdfCorpo, false, d)
+			e.y -= pdfEntrelinha
+		}
+	} else if titulo != "" {
+		e.texto(pdfMargem, pdfCorpo, true, titulo)
+		e.y -= pdfEntrelinha
+	}
+	e.y -= 6
+
+	titulos := make([]any, len(e.colunas))
+	for i, c := range e.colunas {
+		titulos[i] = c.Titulo
+	}
+	e.celulas(true, titulos)
+	fmt.Fprintf(e.pagina, "0.5 w %.2f %.2f m %.2f %.2f l S\n", pdfMargem, e.y+pdfEntrelinha-3, e.largura-pdfMargem, e.y+pdfEntrelinha-3)
+}
+
+// celulas escreve uma linha da tabela; números ficam alinhados à direita
+func (e *escritorPDF) celulas(negrito bool, celulas []any) {
+	x := pdfMargem
+	for i, c := range celulas {
+		if i >= len(e.larguras) {
+			break
+		}
+		largura := e.larguras[i]
+		s := cortar(texto(c), largura-4, pdfCorpo, negrito)
+		if _, _, ok := numero(c); ok {
+			e.texto(x+largura-4-larguraTexto(s, pdfCorpo, negrito), pdfCorpo, negrito, s)
+		} else {
+			e.texto(x, pdfCorpo, negrito, s)
+		}
+		x += largura
+	}
+	e.y -= pdfEntrelinha
+}
+
+// fecharPagina grava o conteúdo e o objeto da página atual, com o número no rodapé
+func (e *escritorPDF) fecharPagina() {
+	if e.pagina == nil {
+		return
+	}
+	rodape := fmt.Sprintf("Página %d", len(e.paginas)+1)
+	e.y = pdfMargem - pdfEntrelinha
+	e.texto(e.largura-pdfMargem-larguraTexto(rodape, 8, false), 8, false, rodape)
+
+	conteudo, pagina := e.proximo, e.proximo+1
+	e.proximo += 2
+	e.objeto(conteudo, fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", e.pagina.Len(), e.pagina.String()))
+	e.objeto(pagina, fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %.0f %.0f] /Contents %d 0 R /Resources << /Font << /F1 %d 0 R /F2 %d 0 R >> >> >>",
+		pdfPaginas, e.largura, e.altura, conteudo, pdfFonte, pdfFonteNegrito))
+	e.paginas = append(e.paginas, pagina)
+	e.pagina = nil
+}
+
+// texto posiciona uma linha de texto na altura atual
+func (e *escritorPDF) texto(x, tamanho float64, negrito bool, s string) {
+	fonte := "F1"
+	if negrito {
+		fonte = "F2"
+	}
+	fmt.Fprintf(e.pagina, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", fonte, tamanho, x, e.y, escaparPDF(s))
+}
+
+// escaparPDF converte o texto para WinAnsiEncoding e escapa os delimitadores de string
+func escaparPDF(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		c, ok := winAnsi(r)
+		if !ok {
+			c = '?'
+		}
+		switch c {
+		case '(', ')', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			if c < 32 {
+				c = ' '
+			}
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// winAnsi devolve o byte do caractere na codificação das fontes padrão do PDF. Cobre o
+// Latin-1, que tem todos os acentos do português, e a pontuação tipográfica mais comum.
+func winAnsi(r rune) (byte, bool) {
+	switch {
+	case r < 0x80 || (r >= 0xA0 && r <= 0xFF):
+		return byte(r), true
+	}
+	switch r {
+	case '€':
+		return 0x80, true
+	case '…':
+		return 0x85, true
+	case '‘':
+		return 0x91, true
+	case '’':
+		return 0x92, true
+	case '“':
+		return 0x93, true
+	case '”':
+		return 0x94, true
+	case '•':
+		return 0x95, true
+	case '–':
+		return 0x96, true
+	case '—':
+		return 0x97, true
+	}
+	return 0, false
+}
+
+// cortar encurta o texto com reticências até caber na largura
+func cortar(s string, largura, tamanho float64, negrito bool) string {
+	if larguraTexto(s, tamanho, negrito) <= largura {
+		return s
+	}
+	runas := []rune(s)
+	for len(runas) > 0 {
+		runas = runas[:len(runas)-1]
+		if curto := string(runas) + "…"; larguraTexto(curto, tamanho, negrito) <= largura {
+			return curto
+		}
+	}
+	return ""
+}
+
+// larguraTexto estima a largura do texto na Helvetica pelas métricas da fonte; letras
+// acentuadas medem como a letra base e a versão negrito é cerca de 5% mais larga
+func larguraTexto(s string, tamanho float64, negrito bool) float64 {
+	var milesimos int
+	for _, r := range s {
+		switch {
+		case r >= 32 && r < 127:
+			milesimos += larguraHelvetica[r-32]
+		case r == '…':
+			milesimos += 1000
+		default:
+			milesimos += 556
+		}
+	}
+	largura := float64(milesimos) * tamanho / 1000
+	if negrito {
+		largura *= 1.05
+	}
+	return largura
+}
+
+// larguraHelvetica são as larguras (em milésimos do tamanho) dos caracteres ASCII 32 a 126
+var larguraHelvetica = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
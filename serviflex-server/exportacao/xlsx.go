@@ -0,0 +1,149 @@
+package exportacao
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Partes fixas do pacote XLSX. A planilha usa strings inline, dispensando a tabela de
+// strings compartilhadas, que exigiria conhecer todas as linhas antes de gravar.
+const (
+	xlsxTipos = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/><Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/></Types>`
+	xlsxRelacoes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+	xlsxPasta = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Relatório" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	xlsxRelacoesPasta = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/></Relationships>`
+	// Estilos: 0 normal, 1 negrito, 2 número com duas casas, 3 título, 4 data e hora
+	xlsxEstilos = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><numFmts count="1"><numFmt numFmtId="164" formatCode="dd/mm/yyyy hh:mm"/></numFmts><fonts count="3"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="14"/><name val="Calibri"/></font></fonts><fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills><borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders><cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs><cellXfs count="5"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/><xf numFmtId="4" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/><xf numFmtId="0" fontId="2" fillId="0" borderId="0" xfId="0" applyFont="1"/><xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/></cellXfs></styleSheet>`
+)
+
+const (
+	estiloNormal = iota
+	estiloNegrito
+	estiloDecimal
+	estiloTitulo
+	estiloDataHora
+)
+
+// escritorXLSX grava a planilha como a última parte do pacote zip, linha a linha
+type escritorXLSX struct {
+	zip   *zip.Writer
+	folha *bufio.Writer
+	linha int
+}
+
+func novoXLSX(w io.Writer, cab Cabecalho, colunas []Coluna) (*escritorXLSX, error) {
+	z := zip.NewWriter(w)
+	for _, parte := range []struct{ nome, conteudo string }{
+		{"[Content_Types].xml", xlsxTipos},
+		{"_rels/.rels", xlsxRelacoes},
+		{"xl/workbook.xml", xlsxPasta},
+		{"xl/_rels/workbook.xml.rels", xlsxRelacoesPasta},
+		{"xl/styles.xml", xlsxEstilos},
+	} {
+		f, err := z.Create(parte.nome)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, parte.conteudo); err != nil {
+			return nil, err
+		}
+	}
+	f, err := z.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	e := &escritorXLSX{zip: z, folha: bufio.NewWriter(f)}
+	e.folha.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" +
+		`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+
+	estabelecimento, titulo, detalhes := cab.linhas()
+	for i, l := range estabelecimento {
+		estilo := estiloNormal
+		if i == 0 {
+			estilo = estiloTitulo
+		}
+		e.gravar(estilo, l)
+	}
+	if titulo != "" {
+		e.gravar(estiloNegrito, titulo)
+	}
+	for _, d := range detalhes {
+		e.gravar(estiloNormal, d)
+	}
+	if e.linha > 0 {
+		e.linha++ // linha em branco antes da tabela
+	}
+	titulos := make([]any, len(colunas))
+	for i, c := range colunas {
+		titulos[i] = c.Titulo
+	}
+	return e, e.gravar(estiloNegrito, titulos...)
+}
+
+func (e *escritorXLSX) Linha(celulas ...any) error {
+	return e.gravar(estiloNormal, celulas...)
+}
+
+// gravar escreve a próxima linha; números e datas ficam como valores, não texto
+func (e *escritorXLSX) gravar(estilo int, celulas ...any) error {
+	e.linha++
+	linha := strconv.Itoa(e.linha)
+	e.folha.WriteString(`<row r="` + linha + `">`)
+	for i, c := range celulas {
+		ref := nomeColuna(i) + linha
+		if v, inteiro, ok := numero(c); ok {
+			s := estilo
+			if !inteiro && estilo == estiloNormal {
+				s = estiloDecimal
+			}
+			e.folha.WriteString(`<c r="` + ref + `" s="` + strconv.Itoa(s) + `"><v>` + strconv.FormatFloat(v, 'f', -1, 64) + `</v></c>`)
+			continue
+		}
+		if t, ok := c.(time.Time); ok && !t.IsZero() {
+			e.folha.WriteString(`<c r="` + ref + `" s="` + strconv.Itoa(estiloDataHora) + `"><v>` + strconv.FormatFloat(serialExcel(t), 'f', -1, 64) + `</v></c>`)
+			continue
+		}
+		s := texto(c)
+		if s == "" {
+			continue
+		}
+		e.folha.WriteString(`<c r="` + ref + `" s="` + strconv.Itoa(estilo) + `" t="inlineStr"><is><t xml:space="preserve">`)
+		xml.EscapeText(e.folha, []byte(s))
+		e.folha.WriteString(`</t></is></c>`)
+	}
+	_, err := e.folha.WriteString("</row>")
+	return err
+}
+
+func (e *escritorXLSX) Fechar() error {
+	e.folha.WriteString(`</sheetData></worksheet>`)
+	if err := e.folha.Flush(); err != nil {
+		return err
+	}
+	return e.zip.Close()
+}
+
+// nomeColuna converte o índice (a partir de 0) no nome da coluna: A, B, ..., Z, AA, ...
+func nomeColuna(i int) string {
+	nome := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		nome = string(rune('A'+(i-1)%26)) + nome
+	}
+	return nome
+}
+
+// serialExcel converte a data e hora de parede de t no número de dias desde 30/12/1899,
+// como o Excel guarda datas
+func serialExcel(t time.Time) float64 {
+	parede := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+	return parede.Sub(time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)).Hours() / 24
+}
@@ -0,0 +1,104 @@
+package migracoes
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/firestore"
+)
+
+// camposRenomeados mapeia, por coleção, os nomes antigos em snake_case para os nomes em
+// camelCase das tags firestore dos modelos. Em horarios, a troca vale também dentro das pausas.
+var camposRenomeados = map[string]map[string]string{
+	"agendamentos": {
+		"cliente_id": "clienteId", "profissional_id": "profissionalId", "estabelecimento_id": "estabelecimentoId",
+		"procedimento_id": "procedimentoId", "data_hora": "dataHora", "duracao_min": "duracaoMin",
+	},
+	"avaliacoes": {
+		"cliente_id": "clienteId", "profissional_id": "profissionalId", "estabelecimento_id": "estabelecimentoId",
+	},
+	"horarios": {
+		"profissional_id": "profissionalId", "dia_semana": "diaSemana", "hora_inicio": "horaInicio", "hora_fim": "horaFim",
+	},
+	"excecoes_horario": {
+		"profissional_id": "profissionalId", "data_inicio": "dataInicio", "data_fim": "dataFim",
+		"hora_inicio": "horaInicio", "hora_fim": "horaFim",
+	},
+	"procedimentos": {
+		"profissional_id": "profissionalId", "duracao_min": "duracaoMin", "imagem_url": "imagemUrl",
+		"politica_cancelamento": "politicaCancelamento",
+	},
+	"profissionais": {"imagem_url": "imagemUrl"},
+}
+
+// MigrarCamposFirestore reescreve os documentos gravados com nomes de campo em snake_case
+// para o esquema em camelCase dos modelos. Se o documento já tiver o campo novo, ele
+// prevalece e o antigo só é removido.
+func MigrarCamposFirestore(ctx context.Context, client *firestore.Client) (int, error) {
+	alterados := 0
+	for colecao, renomear := range camposRenomeados {
+		docs, err := client.Collection(colecao).Documents(ctx).GetAll()
+		if err != nil {
+			return alterados, fmt.Errorf("erro ao ler %s: %w", colecao, err)
+		}
+		for _, doc := range docs {
+			updates := camposParaRenomear(doc.Data(), renomear)
+			if len(updates) == 0 {
+				continue
+			}
+			if _, err := doc.Ref.Update(ctx, updates); err != nil {
+				return alterados, fmt.Errorf("erro ao atualizar %s/%s: %w", colecao, doc.Ref.ID, err)
+			}
+			alterados++
+		}
+	}
+	return alterados, nil
+}
+
+// camposParaRenomear monta as atualizações que levam o documento ao esquema novo
+func camposParaRenomear(dados map[string]interface{}, renomear map[string]string) []firestore.Update {
+	var updates []firestore.Update
+	for campo, valor := range dados {
+		novo, ok := renomear[campo]
+		if !ok {
+			// Listas de mapas, como as pausas dos horários, são regravadas inteiras
+			if lista, mudou := renomearNaLista(valor, renomear); mudou {
+				updates = append(updates, firestore.Update{Path: campo, Value: lista})
+			}
+			continue
+		}
+		if _, existe := dados[novo]; !existe {
+			lista, _ := renomearNaLista(valor, renomear)
+			updates = append(updates, firestore.Update{Path: novo, Value: lista})
+		}
+		updates = append(updates, firestore.Update{Path: campo, Value: firestore.Delete})
+	}
+	return updates
+}
+
+// renomearNaLista aplica a troca de nomes nos mapas de uma lista; outros valores voltam
+// como estão. mudou indica se algum campo foi renomeado.
+func renomearNaLista(valor interface{}, renomear map[string]string) (interface{}, bool) {
+	lista, ok := valor.([]interface{})
+	if !ok {
+		return valor, false
+	}
+	mudou := false
+	nova := make([]interface{}, len(lista))
+	for i, item := range lista {
+		nova[i] = item
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		copia := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			if novo, ok := renomear[k]; ok {
+				k, mudou = novo, true
+			}
+			copia[k] = v
+		}
+		nova[i] = copia
+	}
+	return nova, mudou
+}
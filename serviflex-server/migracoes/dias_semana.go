@@ -0,0 +1,40 @@
+package migracoes
+
+import (
+	"context"
+	"fmt"
+	"servico-api/models"
+
+	"cloud.google.com/go/firestore"
+)
+
+// MigrarDiasSemana converte o dia da semana dos horários gravados como texto ("Terça",
+// "terca") para o número ISO-8601 em "diaSemana". Documentos com um nome que não
+// corresponde a nenhum dia não são alterados e interrompem a migração, para correção manual.
+func MigrarDiasSemana(ctx context.Context, client *firestore.Client) (int, error) {
+	docs, err := client.Collection("horarios").Documents(ctx).GetAll()
+	if err != nil {
+		return 0, fmt.Errorf("erro ao ler horarios: %w", err)
+	}
+
+	alterados := 0
+	for _, doc := range docs {
+		texto, ok := campo(doc.Data(), "diaSemana", "dia_semana").(string)
+		if !ok {
+			continue // já numérico
+		}
+		dia, err := models.ParseDiaSemana(texto)
+		if err != nil {
+			return alterados, fmt.Errorf("horarios/%s: dia_semana %q não reconhecido", doc.Ref.ID, texto)
+		}
+		updates := []firestore.Update{{Path: "diaSemana", Value: int(dia)}}
+		if _, antigo := doc.Data()["dia_semana"]; antigo {
+			updates = append(updates, firestore.Update{Path: "dia_semana", Value: firestore.Delete})
+		}
+		if _, err := doc.Ref.Update(ctx, updates); err != nil {
+			return alterados, fmt.Errorf("erro ao atualizar horarios/%s: %w", doc.Ref.ID, err)
+		}
+		alterados++
+	}
+	return alterados, nil
+}
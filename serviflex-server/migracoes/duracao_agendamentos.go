@@ -0,0 +1,51 @@
+package migracoes
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/firestore"
+)
+
+// MigrarDuracaoAgendamentos copia para cada agendamento sem "duracaoMin" a duração do
+// procedimento de mesmo nome do profissional, usada na detecção de conflitos de horário.
+// Agendamentos cujo procedimento não existe mais ficam como estão.
+func MigrarDuracaoAgendamentos(ctx context.Context, client *firestore.Client) (int, error) {
+	procs, err := client.Collection("procedimentos").Documents(ctx).GetAll()
+	if err != nil {
+		return 0, fmt.Errorf("erro ao ler procedimentos: %w", err)
+	}
+	duracoes := make(map[string]int64)
+	for _, doc := range procs {
+		dados := doc.Data()
+		profissional, _ := campo(dados, "profissionalId", "profissional_id").(string)
+		nome, _ := dados["nome"].(string)
+		if duracao, ok := campo(dados, "duracaoMin", "duracao_min").(int64); ok {
+			duracoes[profissional+"|"+nome] = duracao
+		}
+	}
+
+	docs, err := client.Collection("agendamentos").Documents(ctx).GetAll()
+	if err != nil {
+		return 0, fmt.Errorf("erro ao ler agendamentos: %w", err)
+	}
+
+	alterados := 0
+	for _, doc := range docs {
+		dados := doc.Data()
+		if atual, _ := dados["duracaoMin"].(int64); atual > 0 {
+			continue
+		}
+		profissional, _ := dados["profissionalId"].(string)
+		procedimento, _ := dados["procedimento"].(string)
+		duracao, ok := duracoes[profissional+"|"+procedimento]
+		if !ok {
+			continue
+		}
+		if _, err := doc.Ref.Update(ctx, []firestore.Update{{Path: "duracaoMin", Value: duracao}}); err != nil {
+			return alterados, fmt.Errorf("erro ao atualizar agendamentos/%s: %w", doc.Ref.ID, err)
+		}
+		alterados++
+	}
+	return alterados, nil
+}
@@ -0,0 +1,44 @@
+// Package migracoes reúne as migrações de dados executadas sob demanda pelo comando cmd/migrar
+package migracoes
+
+import (
+	"context"
+	"sort"
+
+	"cloud.google.com/go/firestore"
+)
+
+// Migracao executa uma migração e devolve quantos documentos foram alterados
+type Migracao func(ctx context.Context, client *firestore.Client) (int, error)
+
+// Disponiveis lista as migrações que podem ser executadas, indexadas pelo nome
+var Disponiveis = map[string]Migracao{
+	"senhas":                    MigrarSenhas,
+	"duracao_agendamentos":      MigrarDuracaoAgendamentos,
+	"status_agendamentos":       MigrarStatusAgendamentos,
+	"dias_semana":               MigrarDiasSemana,
+	"procedimento_agendamentos": MigrarProcedimentoAgendamentos,
+	"campos_firestore":          MigrarCamposFirestore,
+	"resumos_avaliacoes":        MigrarResumosAvaliacoes,
+}
+
+// Nomes devolve os nomes das migrações disponíveis em ordem alfabética
+func Nomes() []string {
+	nomes := make([]string, 0, len(Disponiveis))
+	for nome := range Disponiveis {
+		nomes = append(nomes, nome)
+	}
+	sort.Strings(nomes)
+	return nomes
+}
+
+// campo lê o primeiro dos nomes presente no documento. As migrações mais antigas podem
+// rodar antes ou depois de campos_firestore, então aceitam o nome atual e o antigo.
+func campo(dados map[string]interface{}, nomes ...string) interface{} {
+	for _, nome := range nomes {
+		if valor, ok := dados[nome]; ok {
+			return valor
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package migracoes
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/firestore"
+)
+
+// MigrarProcedimentoAgendamentos liga cada agendamento sem "procedimentoId" ao procedimento
+// de mesmo nome do profissional e copia o preço atual dele como preço da reserva (e a
+// duração, se ainda faltar). Agendamentos cujo procedimento não existe mais ficam como estão.
+func MigrarProcedimentoAgendamentos(ctx context.Context, client *firestore.Client) (int, error) {
+	procs, err := client.Collection("procedimentos").Documents(ctx).GetAll()
+	if err != nil {
+		return 0, fmt.Errorf("erro ao ler procedimentos: %w", err)
+	}
+	porNome := make(map[string]*firestore.DocumentSnapshot)
+	for _, doc := range procs {
+		dados := doc.Data()
+		profissional, _ := campo(dados, "profissionalId", "profissional_id").(string)
+		nome, _ := dados["nome"].(string)
+		porNome[profissional+"|"+nome] = doc
+	}
+
+	docs, err := client.Collection("agendamentos").Documents(ctx).GetAll()
+	if err != nil {
+		return 0, fmt.Errorf("erro ao ler agendamentos: %w", err)
+	}
+
+	alterados := 0
+	for _, doc := range docs {
+		dados := doc.Data()
+		if atual, _ := dados["procedimentoId"].(string); atual != "" {
+			continue
+		}
+		profissional, _ := dados["profissionalId"].(string)
+		procedimento, _ := dados["procedimento"].(string)
+		proc, ok := porNome[profissional+"|"+procedimento]
+		if !ok {
+			continue
+		}
+
+		updates := []firestore.Update{
+			{Path: "procedimentoId", Value: proc.Ref.ID},
+			{Path: "preco", Value: numero(proc.Data()["preco"])},
+		}
+		if duracao, _ := dados["duracaoMin"].(int64); duracao <= 0 {
+			updates = append(updates, firestore.Update{Path: "duracaoMin", Value: int64(numero(campo(proc.Data(), "duracaoMin", "duracao_min")))})
+		}
+		if _, err := doc.Ref.Update(ctx, updates); err != nil {
+			return alterados, fmt.Errorf("erro ao atualizar agendamentos/%s: %w", doc.Ref.ID, err)
+		}
+		alterados++
+	}
+	return alterados, nil
+}
+
+// numero lê um campo numérico que o Firestore pode devolver como int64 ou float64
+func numero(v interface{}) float64 {
+	switch n := v.(type) {
+	case int64:
+		return float64(n)
+	case float64:
+		return n
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,83 @@
+package migracoes
+
+import (
+	"context"
+	"fmt"
+	"servico-api/models"
+	"servico-api/storage"
+	"strconv"
+
+	"cloud.google.com/go/firestore"
+)
+
+// MigrarResumosAvaliacoes refaz do zero os resumos de notas (quantidade, soma, média e
+// histograma) de cada profissional e estabelecimento e os resumos gerais, a partir das
+// avaliações visíveis. Depois dela, a API mantém os resumos a cada gravação. Os caminhos
+// seguem firestoredb.AvaliacaoRepository: o resumo geral vai inteiro para o fragmento "0"
+// e os demais fragmentos são apagados.
+func MigrarResumosAvaliacoes(ctx context.Context, client *firestore.Client) (int, error) {
+	docs, err := client.Collection("avaliacoes").Documents(ctx).GetAll()
+	if err != nil {
+		return 0, fmt.Errorf("erro ao ler avaliacoes: %w", err)
+	}
+
+	resumos := make(map[storage.AlvoResumo]models.ResumoAvaliacoes)
+	for _, doc := range docs {
+		var a models.Avaliacao
+		if err := doc.DataTo(&a); err != nil {
+			return 0, fmt.Errorf("avaliacoes/%s: %w", doc.Ref.ID, err)
+		}
+		for _, alvo := range storage.AlvosDaAvaliacao(a) {
+			resumo := resumos[alvo]
+			resumo.Aplicar(nil, &a)
+			resumos[alvo] = resumo.ComHistograma()
+		}
+	}
+
+	alterados := 0
+	for alvo, resumo := range resumos {
+		var err error
+		if alvo.ID == "" {
+			err = gravarResumoGeral(ctx, client, alvo.Alvo, resumo)
+		} else {
+			ref := client.Collection(alvo.Alvo).Doc(alvo.ID).Collection("resumos").Doc("avaliacoes")
+			if _, err = ref.Set(ctx, resumo); err != nil {
+				err = fmt.Errorf("erro ao gravar %s: %w", ref.Path, err)
+			}
+		}
+		if err != nil {
+			return alterados, err
+		}
+		alterados++
+	}
+	return alterados, nil
+}
+
+// gravarResumoGeral substitui os fragmentos do resumo geral do tipo de alvo por um só
+func gravarResumoGeral(ctx context.Context, client *firestore.Client, alvo string, resumo models.ResumoAvaliacoes) error {
+	fragmentos := client.Collection("resumos_avaliacoes").Doc(alvo).Collection("fragmentos")
+	docs, err := fragmentos.Documents(ctx).GetAll()
+	if err != nil {
+		return fmt.Errorf("erro ao ler %s: %w", fragmentos.Path, err)
+	}
+	for _, doc := range docs {
+		if _, err := doc.Ref.Delete(ctx); err != nil {
+			return fmt.Errorf("erro ao apagar %s: %w", doc.Ref.Path, err)
+		}
+	}
+
+	histograma := make(map[string]int, len(resumo.Histograma))
+	for i, n := range resumo.Histograma {
+		histograma[strconv.Itoa(i+1)] = n
+	}
+	ref := fragmentos.Doc("0")
+	_, err = ref.Set(ctx, map[string]interface{}{
+		"quantidade": resumo.Quantidade,
+		"soma":       resumo.Soma,
+		"histograma": histograma,
+	})
+	if err != nil {
+		return fmt.Errorf("erro ao gravar %s: %w", ref.Path, err)
+	}
+	return nil
+}
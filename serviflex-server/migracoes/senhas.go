@@ -0,0 +1,38 @@
+package migracoes
+
+import (
+	"context"
+	"fmt"
+	"servico-api/utils"
+
+	"cloud.google.com/go/firestore"
+)
+
+// MigrarSenhas substitui as senhas ainda armazenadas em texto puro por hashes bcrypt.
+// Documentos que já possuem hash são ignorados, então a migração pode ser repetida com segurança.
+func MigrarSenhas(ctx context.Context, client *firestore.Client) (int, error) {
+	alterados := 0
+	for _, colecao := range []string{"clientes", "profissionais", "admin"} {
+		docs, err := client.Collection(colecao).Documents(ctx).GetAll()
+		if err != nil {
+			return alterados, fmt.Errorf("erro ao ler %s: %w", colecao, err)
+		}
+
+		for _, doc := range docs {
+			senha, _ := doc.Data()["senha"].(string)
+			if senha == "" || utils.SenhaEhHash(senha) {
+				continue
+			}
+
+			hash, err := utils.HashSenha(senha)
+			if err != nil {
+				return alterados, fmt.Errorf("erro ao gerar hash de %s/%s: %w", colecao, doc.Ref.ID, err)
+			}
+			if _, err := doc.Ref.Update(ctx, []firestore.Update{{Path: "senha", Value: hash}}); err != nil {
+				return alterados, fmt.Errorf("erro ao atualizar %s/%s: %w", colecao, doc.Ref.ID, err)
+			}
+			alterados++
+		}
+	}
+	return alterados, nil
+}
@@ -0,0 +1,37 @@
+package migracoes
+
+import (
+	"context"
+	"fmt"
+	"servico-api/models"
+	"time"
+
+	"cloud.google.com/go/firestore"
+)
+
+// MigrarStatusAgendamentos define o status dos agendamentos criados antes do ciclo de vida:
+// os que já passaram viram "concluido", mantendo-os no faturamento, e os futuros ficam "pendente".
+func MigrarStatusAgendamentos(ctx context.Context, client *firestore.Client) (int, error) {
+	docs, err := client.Collection("agendamentos").Documents(ctx).GetAll()
+	if err != nil {
+		return 0, fmt.Errorf("erro ao ler agendamentos: %w", err)
+	}
+
+	agora := time.Now()
+	alterados := 0
+	for _, doc := range docs {
+		dados := doc.Data()
+		if atual, _ := dados["status"].(string); atual != "" {
+			continue
+		}
+		status := models.StatusPendente
+		if dataHora, ok := dados["dataHora"].(time.Time); ok && dataHora.Before(agora) {
+			status = models.StatusConcluido
+		}
+		if _, err := doc.Ref.Update(ctx, []firestore.Update{{Path: "status", Value: status}}); err != nil {
+			return alterados, fmt.Errorf("erro ao atualizar agendamentos/%s: %w", doc.Ref.ID, err)
+		}
+		alterados++
+	}
+	return alterados, nil
+}
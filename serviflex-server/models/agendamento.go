@@ -2,11 +2,34 @@ package models
 
 import "time"
 
+// Agendamento guarda uma cópia do nome, preço e duração do procedimento no momento da
+// reserva: editar ou renomear o procedimento depois não altera agendamentos já feitos.
 type Agendamento struct {
-    ID                string    `firestore:"id" json:"id"`
-    ClienteID         string    `firestore:"clienteId" json:"cliente_id"`
-    ProfissionalID    string    `firestore:"profissionalId" json:"profissional_id"`
-    EstabelecimentoID string    `firestore:"estabelecimentoId" json:"estabelecimento_id"`
-    Procedimento      string    `firestore:"procedimento" json:"procedimento"`
-    DataHora          time.Time `firestore:"dataHora" json:"data_hora"`
+    ID                string            `firestore:"id" json:"id"`
+    ClienteID         string            `firestore:"clienteId" json:"cliente_id"`
+    ProfissionalID    string            `firestore:"profissionalId" json:"profissional_id"`
+    EstabelecimentoID string            `firestore:"estabelecimentoId" json:"estabelecimento_id"`
+    ProcedimentoID    string            `firestore:"procedimentoId" json:"procedimento_id"`
+    Procedimento      string            `firestore:"procedimento" json:"procedimento"` // nome copiado do procedimento ao agendar
+    Preco             float64           `firestore:"preco" json:"preco"`               // copiado do procedimento ao agendar
+    DataHora          time.Time         `firestore:"dataHora" json:"data_hora"`
+    DuracaoMin        int               `firestore:"duracaoMin" json:"duracao_min"` // copiada do procedimento ao agendar
+    Status            string            `firestore:"status" json:"status"`
+    Historico         []TransicaoStatus `firestore:"historico" json:"historico"`
+    Taxa              *TaxaCancelamento `firestore:"taxa,omitempty" json:"taxa,omitempty"` // cobrança por cancelamento tardio ou falta
+}
+
+// Fim devolve o horário em que o atendimento termina. Registros antigos, sem duração,
+// ocupam ao menos um minuto.
+func (a Agendamento) Fim() time.Time {
+    if a.DuracaoMin <= 0 {
+        return a.DataHora.Add(time.Minute)
+    }
+    return a.DataHora.Add(time.Duration(a.DuracaoMin) * time.Minute)
+}
+
+// Sobrepoe indica se os intervalos dos dois agendamentos se cruzam. Um atendimento que
+// termina exatamente quando o outro começa não conflita.
+func (a Agendamento) Sobrepoe(outro Agendamento) bool {
+    return a.DataHora.Before(outro.Fim()) && outro.DataHora.Before(a.Fim())
 }
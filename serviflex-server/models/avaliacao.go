@@ -1,12 +1,140 @@
 package models
 
-import "time"
+import (
+	"errors"
+	"math"
+	"time"
+)
 
+// PrazoAvaliacao é quanto tempo depois do atendimento o cliente ainda pode avaliá-lo
+const PrazoAvaliacao = 30 * 24 * time.Hour
+
+// JanelaEdicaoAvaliacao é quanto tempo depois de enviada a avaliação ainda pode ser editada
+const JanelaEdicaoAvaliacao = 7 * 24 * time.Hour
+
+// Alvos dos resumos de avaliação
+const (
+	AlvoProfissional    = "profissionais"
+	AlvoEstabelecimento = "estabelecimentos"
+)
+
+// Avaliacao é a nota dada pelo cliente a um atendimento concluído. O ID é o do
+// agendamento avaliado, o que garante uma avaliação por atendimento; avaliações
+// anteriores à API podem não ter agendamento.
 type Avaliacao struct {
-	ProfissionalID    string    `firestore:"profissionalId"`
-	ClienteID         string    `firestore:"clienteId"`
-	EstabelecimentoID string    `firestore:"estabelecimentoId"`
-	Nota              float64   `firestore:"nota"`
-	Comentario        string    `firestore:"comentario"`
-	Data              time.Time `firestore:"data"`
+	ID                string     `json:"id" firestore:"-"`
+	AgendamentoID     string     `json:"agendamento_id,omitempty" firestore:"agendamentoId,omitempty"`
+	ProfissionalID    string     `json:"profissional_id" firestore:"profissionalId"`
+	ClienteID         string     `json:"cliente_id" firestore:"clienteId"`
+	EstabelecimentoID string     `json:"estabelecimento_id" firestore:"estabelecimentoId"`
+	Nota              float64    `json:"nota" firestore:"nota"`
+	Comentario        string     `json:"comentario" firestore:"comentario"`
+	Data              time.Time  `json:"data" firestore:"data"`
+	EditadaEm         *time.Time `json:"editada_em,omitempty" firestore:"editadaEm,omitempty"`
+
+	Situacao    string              `json:"situacao,omitempty" firestore:"situacao,omitempty"`
+	Resposta    *RespostaAvaliacao  `json:"resposta,omitempty" firestore:"resposta,omitempty"`
+	Denuncias   []DenunciaAvaliacao `json:"denuncias,omitempty" firestore:"denuncias,omitempty"`
+	EmModeracao bool                `json:"em_moderacao,omitempty" firestore:"emModeracao"`
+	Moderacao   []AcaoModeracao     `json:"moderacao,omitempty" firestore:"moderacao,omitempty"`
+}
+
+// AvaliacaoInput é o corpo de criação e edição de uma avaliação
+type AvaliacaoInput struct {
+	AgendamentoID string  `json:"agendamento_id"`
+	Nota          float64 `json:"nota"`
+	Comentario    string  `json:"comentario"`
+}
+
+// Validar exige nota inteira de 1 a 5 e comentário de até 1000 caracteres
+func (a AvaliacaoInput) Validar() error {
+	if a.Nota < 1 || a.Nota > 5 || a.Nota != math.Trunc(a.Nota) {
+		return errors.New("nota deve ser um número inteiro de 1 a 5")
+	}
+	if len([]rune(a.Comentario)) > 1000 {
+		return errors.New("comentário deve ter no máximo 1000 caracteres")
+	}
+	return nil
+}
+
+// Editavel indica se a avaliação ainda está dentro da janela de edição
+func (a Avaliacao) Editavel(agora time.Time) bool {
+	return agora.Before(a.Data.Add(JanelaEdicaoAvaliacao))
+}
+
+// PesoBayesiano é quantas avaliações com a média geral são somadas a cada alvo na
+// pontuação usada para ordenar as listagens: com poucas avaliações, a pontuação fica
+// perto da média geral e só se afasta dela à medida que as avaliações se acumulam
+const PesoBayesiano = 5
+
+// NotaNeutra é a média de referência da pontuação enquanto não houver nenhuma avaliação
+const NotaNeutra = 3.0
+
+// ResumoAvaliacoes é o agregado de notas visíveis do profissional ou do estabelecimento,
+// mantido a cada avaliação criada, editada ou moderada
+type ResumoAvaliacoes struct {
+	Quantidade int     `json:"quantidade" firestore:"quantidade"`
+	Soma       float64 `json:"soma" firestore:"soma"`
+	Histograma []int   `json:"histograma" firestore:"histograma"` // quantidade de notas 1 a 5
+	Media      float64 `json:"media" firestore:"media"`
+
+	// Pontuacao é a média bayesiana, preenchida nas listagens ordenadas
+	Pontuacao float64 `json:"pontuacao,omitempty" firestore:"-"`
+}
+
+// Aplicar atualiza o resumo com a mudança de uma avaliação: retira a contribuição de
+// antes e soma a de depois. Use antes nil para avaliações novas. Só avaliações visíveis
+// contam.
+func (r *ResumoAvaliacoes) Aplicar(antes, depois *Avaliacao) {
+	if antes != nil && antes.Visivel() {
+		r.contar(antes.Nota, -1)
+	}
+	if depois != nil && depois.Visivel() {
+		r.contar(depois.Nota, 1)
+	}
+}
+
+func (r *ResumoAvaliacoes) contar(nota float64, sinal int) {
+	*r = r.ComHistograma()
+	r.Quantidade += sinal
+	r.Soma += float64(sinal) * nota
+	r.Histograma[Estrelas(nota)-1] += sinal
+	r.Media = 0
+	if r.Quantidade > 0 {
+		r.Media = r.Soma / float64(r.Quantidade)
+	}
+}
+
+// ComHistograma devolve o resumo com as cinco faixas do histograma, inclusive quando zerado
+func (r ResumoAvaliacoes) ComHistograma() ResumoAvaliacoes {
+	if len(r.Histograma) < 5 {
+		r.Histograma = append(r.Histograma, make([]int, 5-len(r.Histograma))...)
+	}
+	return r
+}
+
+// Estrelas arredonda a nota para a faixa de 1 a 5 do histograma; avaliações anteriores à
+// API podem ter notas fracionadas
+func Estrelas(nota float64) int {
+	return int(math.Max(1, math.Min(5, math.Round(nota))))
+}
+
+// Bayesiana devolve a média ajustada pela média geral do tipo de alvo, que desempata
+// alvos com poucas avaliações em favor dos mais bem avaliados de forma consistente
+func (r ResumoAvaliacoes) Bayesiana(geral ResumoAvaliacoes) float64 {
+	referencia := NotaNeutra
+	if geral.Quantidade > 0 {
+		referencia = geral.Media
+	}
+	return (PesoBayesiano*referencia + r.Soma) / float64(PesoBayesiano+r.Quantidade)
+}
+
+// ResumirAvaliacoes calcula o resumo do zero, ignorando as avaliações ocultas ou
+// excluídas pela moderação
+func ResumirAvaliacoes(avaliacoes []Avaliacao) ResumoAvaliacoes {
+	resumo := ResumoAvaliacoes{}.ComHistograma()
+	for i := range avaliacoes {
+		resumo.Aplicar(nil, &avaliacoes[i])
+	}
+	return resumo
 }
@@ -0,0 +1,80 @@
+package models
+
+import "testing"
+
+func TestValidarAvaliacao(t *testing.T) {
+	for _, nota := range []float64{1, 3, 5} {
+		if err := (AvaliacaoInput{Nota: nota}).Validar(); err != nil {
+			t.Errorf("nota %v deveria ser aceita: %v", nota, err)
+		}
+	}
+	for _, nota := range []float64{0, 4.5, 6} {
+		if err := (AvaliacaoInput{Nota: nota}).Validar(); err == nil {
+			t.Errorf("nota %v deveria ser recusada", nota)
+		}
+	}
+}
+
+func TestResumirAvaliacoes(t *testing.T) {
+	if r := ResumirAvaliacoes(nil); r.Quantidade != 0 || r.Media != 0 {
+		t.Errorf("resumo vazio inesperado: %+v", r)
+	}
+	r := ResumirAvaliacoes([]Avaliacao{{Nota: 5}, {Nota: 4}, {Nota: 3}})
+	if r.Quantidade != 3 || r.Media != 4 {
+		t.Errorf("resumo inesperado: %+v", r)
+	}
+}
+
+func TestModerarAvaliacao(t *testing.T) {
+	var a Avaliacao
+	if err := a.Moderar(AcaoModeracao{Acao: ModeracaoRestaurar}); err != ErrModeracaoInvalida {
+		t.Errorf("restaurar avaliação publicada sem denúncias deveria falhar, obtive %v", err)
+	}
+	if err := a.Denunciar(DenunciaAvaliacao{PorUID: "u1"}); err != nil || !a.EmModeracao {
+		t.Fatalf("denúncia não colocou a avaliação na fila: %v", err)
+	}
+	if err := a.Moderar(AcaoModeracao{Acao: ModeracaoOcultar}); err != nil || a.Visivel() || a.EmModeracao {
+		t.Fatalf("ocultar falhou: %v %+v", err, a)
+	}
+	if err := a.Denunciar(DenunciaAvaliacao{PorUID: "u2"}); err != ErrModeracaoInvalida {
+		t.Errorf("avaliação oculta não deveria aceitar denúncias, obtive %v", err)
+	}
+	if err := a.Moderar(AcaoModeracao{Acao: ModeracaoExcluir}); err != nil || a.SituacaoAtual() != SituacaoExcluida {
+		t.Fatalf("excluir falhou: %v %+v", err, a)
+	}
+	if err := a.Moderar(AcaoModeracao{Acao: ModeracaoRestaurar}); err != ErrModeracaoInvalida {
+		t.Errorf("exclusão deveria ser definitiva, obtive %v", err)
+	}
+	if len(a.Moderacao) != 2 {
+		t.Errorf("histórico de moderação inesperado: %+v", a.Moderacao)
+	}
+}
+
+func TestAplicarAvaliacao(t *testing.T) {
+	var r ResumoAvaliacoes
+	antes := Avaliacao{Nota: 2}
+	r.Aplicar(nil, &antes)
+	depois := Avaliacao{Nota: 5}
+	r.Aplicar(&antes, &depois)
+	if r.Quantidade != 1 || r.Media != 5 || r.Histograma[1] != 0 || r.Histograma[4] != 1 {
+		t.Fatalf("edição não atualizou o resumo: %+v", r)
+	}
+	oculta := depois
+	oculta.Situacao = SituacaoOculta
+	r.Aplicar(&depois, &oculta)
+	if r.Quantidade != 0 || r.Media != 0 || r.Histograma[4] != 0 {
+		t.Errorf("avaliação oculta não deveria contar: %+v", r)
+	}
+}
+
+func TestBayesiana(t *testing.T) {
+	if p := (ResumoAvaliacoes{}).Bayesiana(ResumoAvaliacoes{}); p != NotaNeutra {
+		t.Errorf("sem avaliações a pontuação deveria ser a nota neutra, obtive %v", p)
+	}
+	geral := ResumirAvaliacoes([]Avaliacao{{Nota: 4}, {Nota: 4}, {Nota: 4}, {Nota: 4}})
+	uma := ResumirAvaliacoes([]Avaliacao{{Nota: 5}})
+	muitas := ResumirAvaliacoes([]Avaliacao{{Nota: 5}, {Nota: 5}, {Nota: 5}, {Nota: 5}, {Nota: 5}, {Nota: 5}})
+	if !(muitas.Bayesiana(geral) > uma.Bayesiana(geral) && uma.Bayesiana(geral) > geral.Media) {
+		t.Errorf("pontuações fora de ordem: uma %v, muitas %v", uma.Bayesiana(geral), muitas.Bayesiana(geral))
+	}
+}
@@ -0,0 +1,199 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Tipos de regra de comissão
+const (
+	ComissaoPercentual = "percentual" // percentual do preço de cada atendimento
+	ComissaoFixa       = "fixa"       // valor fixo por atendimento
+	ComissaoEscalonada = "escalonada" // percentual conforme a faixa de faturamento atingida no período
+)
+
+// FaixaComissao vale quando o faturamento do profissional no período, nos atendimentos
+// cobertos pela regra, chega a APartirDe
+type FaixaComissao struct {
+	APartirDe  float64 `json:"a_partir_de" firestore:"aPartirDe"`
+	Percentual float64 `json:"percentual" firestore:"percentual"`
+}
+
+// RegraComissao define quanto do preço de um atendimento concluído fica com o
+// profissional; o restante é do estabelecimento. ProfissionalID e ProcedimentoID
+// restringem a regra; vazios, valem para todos. Entre as regras que cobrem um atendimento
+// vale a mais específica: profissional e procedimento, depois só procedimento, só
+// profissional e, por fim, a regra geral do estabelecimento.
+type RegraComissao struct {
+	ProfissionalID string          `json:"profissional_id,omitempty" firestore:"profissionalId,omitempty"`
+	ProcedimentoID string          `json:"procedimento_id,omitempty" firestore:"procedimentoId,omitempty"`
+	Tipo           string          `json:"tipo" firestore:"tipo"`
+	Percentual     float64         `json:"percentual,omitempty" firestore:"percentual,omitempty"` // percentual
+	Valor          float64         `json:"valor,omitempty" firestore:"valor,omitempty"`           // fixa
+	Faixas         []FaixaComissao `json:"faixas,omitempty" firestore:"faixas,omitempty"`         // escalonada, em ordem crescente
+}
+
+// Validar confere o tipo e os valores da regra
+func (r RegraComissao) Validar() error {
+	switch r.Tipo {
+	case ComissaoPercentual:
+		if r.Percentual < 0 || r.Percentual > 100 {
+			return errors.New("percentual deve estar entre 0 e 100")
+		}
+	case ComissaoFixa:
+		if r.Valor < 0 {
+			return errors.New("valor fixo não pode ser negativo")
+		}
+	case ComissaoEscalonada:
+		if len(r.Faixas) == 0 || r.Faixas[0].APartirDe != 0 {
+			return errors.New("comissão escalonada precisa de faixas, a primeira a partir de 0")
+		}
+		for i, f := range r.Faixas {
+			if f.Percentual < 0 || f.Percentual > 100 {
+				return errors.New("percentual deve estar entre 0 e 100")
+			}
+			if i > 0 && f.APartirDe <= r.Faixas[i-1].APartirDe {
+				return errors.New("faixas devem estar em ordem crescente de faturamento")
+			}
+		}
+	default:
+		return errors.New("tipo de comissão deve ser percentual, fixa ou escalonada")
+	}
+	return nil
+}
+
+// ValidarRegrasComissao valida cada regra e recusa duas regras com o mesmo alcance
+func ValidarRegrasComissao(regras []RegraComissao) error {
+	alcances := make(map[[2]string]bool, len(regras))
+	for _, r := range regras {
+		if err := r.Validar(); err != nil {
+			return err
+		}
+		alcance := [2]string{r.ProfissionalID, r.ProcedimentoID}
+		if alcances[alcance] {
+			return errors.New("mais de uma regra para o mesmo profissional e procedimento")
+		}
+		alcances[alcance] = true
+	}
+	return nil
+}
+
+// Descricao resume a regra para os extratos, ex.: "40%", "R$ 25,00 fixo"
+func (r RegraComissao) Descricao() string {
+	switch r.Tipo {
+	case ComissaoPercentual:
+		return strings.Replace(fmt.Sprintf("%g%%", r.Percentual), ".", ",", 1)
+	case ComissaoFixa:
+		return "R$ " + strings.Replace(fmt.Sprintf("%.2f", r.Valor), ".", ",", 1) + " fixo"
+	case ComissaoEscalonada:
+		return fmt.Sprintf("escalonada (%d faixas)", len(r.Faixas))
+	}
+	return ""
+}
+
+// comissao calcula a parte do profissional num atendimento, arredondada aos centavos;
+// faturado é o total do período coberto pela regra, usado para escolher a faixa. O valor
+// fixo é limitado ao preço, para que o estabelecimento nunca fique negativo.
+func (r RegraComissao) comissao(preco, faturado float64) float64 {
+	var valor float64
+	switch r.Tipo {
+	case ComissaoPercentual:
+		valor = preco * r.Percentual / 100
+	case ComissaoFixa:
+		valor = math.Min(r.Valor, preco)
+	case ComissaoEscalonada:
+		valor = preco * r.percentualFaixa(faturado) / 100
+	}
+	return math.Round(valor*100) / 100
+}
+
+// percentualFaixa devolve o percentual da maior faixa atingida pelo faturamento
+func (r RegraComissao) percentualFaixa(faturamento float64) float64 {
+	percentual := 0.0
+	for _, f := range r.Faixas {
+		if faturamento >= f.APartirDe {
+			percentual = f.Percentual
+		}
+	}
+	return percentual
+}
+
+// RegraComissaoAplicavel devolve a regra mais específica que cobre o atendimento, ou nil
+func RegraComissaoAplicavel(regras []RegraComissao, ag Agendamento) *RegraComissao {
+	var escolhida *RegraComissao
+	melhor := -1
+	for i, r := range regras {
+		if (r.ProfissionalID != "" && r.ProfissionalID != ag.ProfissionalID) ||
+			(r.ProcedimentoID != "" && r.ProcedimentoID != ag.ProcedimentoID) {
+			continue
+		}
+		especificidade := 0
+		if r.ProcedimentoID != "" {
+			especificidade += 2
+		}
+		if r.ProfissionalID != "" {
+			especificidade++
+		}
+		if especificidade > melhor {
+			escolhida, melhor = &regras[i], especificidade
+		}
+	}
+	return escolhida
+}
+
+// ItemRepasse é um atendimento concluído no extrato, com a comissão do profissional
+type ItemRepasse struct {
+	AgendamentoID string    `json:"agendamento_id"`
+	DataHora      time.Time `json:"data_hora"`
+	Procedimento  string    `json:"procedimento"`
+	Preco         float64   `json:"preco"`
+	Regra         string    `json:"regra"` // vazio quando nenhuma regra cobre o atendimento
+	Comissao      float64   `json:"comissao"`
+}
+
+// ExtratoRepasse soma o que um profissional faturou no período e quanto recebe de comissão
+type ExtratoRepasse struct {
+	ProfissionalID   string        `json:"profissional_id"`
+	ProfissionalNome string        `json:"profissional_nome"`
+	Quantidade       int           `json:"quantidade_atendimentos"`
+	Faturado         float64       `json:"total_faturado"`
+	Comissao         float64       `json:"total_comissao"`
+	Estabelecimento  float64       `json:"total_estabelecimento"` // faturado menos comissão
+	SemRegra         int           `json:"atendimentos_sem_regra"`
+	Itens            []ItemRepasse `json:"itens,omitempty"`
+}
+
+// CalcularRepasse monta o extrato de um profissional a partir dos seus atendimentos
+// concluídos no período, em ordem. Na regra escalonada, a faixa é escolhida pelo total
+// faturado no período nos atendimentos cobertos por ela e vale para todos eles.
+// Atendimentos sem regra não geram comissão e ficam contados em SemRegra.
+func CalcularRepasse(regras []RegraComissao, profissionalID string, agendamentos []Agendamento) ExtratoRepasse {
+	aplicaveis := make([]*RegraComissao, len(agendamentos))
+	faturadoPorRegra := make(map[*RegraComissao]float64)
+	for i, ag := range agendamentos {
+		aplicaveis[i] = RegraComissaoAplicavel(regras, ag)
+		if aplicaveis[i] != nil {
+			faturadoPorRegra[aplicaveis[i]] += ag.Preco
+		}
+	}
+
+	extrato := ExtratoRepasse{ProfissionalID: profissionalID, Itens: make([]ItemRepasse, 0, len(agendamentos))}
+	for i, ag := range agendamentos {
+		item := ItemRepasse{AgendamentoID: ag.ID, DataHora: ag.DataHora, Procedimento: ag.Procedimento, Preco: ag.Preco}
+		if r := aplicaveis[i]; r != nil {
+			item.Regra = r.Descricao()
+			item.Comissao = r.comissao(ag.Preco, faturadoPorRegra[r])
+		} else {
+			extrato.SemRegra++
+		}
+		extrato.Quantidade++
+		extrato.Faturado += ag.Preco
+		extrato.Comissao += item.Comissao
+		extrato.Itens = append(extrato.Itens, item)
+	}
+	extrato.Estabelecimento = extrato.Faturado - extrato.Comissao
+	return extrato
+}
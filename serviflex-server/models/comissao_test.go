@@ -0,0 +1,74 @@
+package models
+
+import "testing"
+
+func TestRegraComissaoAplicavel(t *testing.T) {
+	regras := []RegraComissao{
+		{Tipo: ComissaoPercentual, Percentual: 40},
+		{ProfissionalID: "p1", Tipo: ComissaoPercentual, Percentual: 50},
+		{ProcedimentoID: "corte", Tipo: ComissaoFixa, Valor: 15},
+		{ProfissionalID: "p1", ProcedimentoID: "corte", Tipo: ComissaoFixa, Valor: 20},
+	}
+	for _, caso := range []struct {
+		profissional, procedimento string
+		esperada                   int
+	}{
+		{"p2", "barba", 0},
+		{"p1", "barba", 1},
+		{"p2", "corte", 2},
+		{"p1", "corte", 3},
+	} {
+		r := RegraComissaoAplicavel(regras, Agendamento{ProfissionalID: caso.profissional, ProcedimentoID: caso.procedimento})
+		if r != &regras[caso.esperada] {
+			t.Errorf("%s/%s: regra %+v, esperada %+v", caso.profissional, caso.procedimento, r, regras[caso.esperada])
+		}
+	}
+	if RegraComissaoAplicavel(regras[1:2], Agendamento{ProfissionalID: "p2"}) != nil {
+		t.Error("regra de outro profissional não deveria valer")
+	}
+}
+
+func TestCalcularRepasse(t *testing.T) {
+	regras := []RegraComissao{
+		{ProcedimentoID: "corte", Tipo: ComissaoEscalonada, Faixas: []FaixaComissao{{0, 30}, {100, 50}}},
+		{ProcedimentoID: "barba", Tipo: ComissaoFixa, Valor: 10},
+	}
+	ags := []Agendamento{
+		{ProcedimentoID: "corte", Preco: 60},
+		{ProcedimentoID: "corte", Preco: 40},
+		{ProcedimentoID: "barba", Preco: 25},
+		{ProcedimentoID: "unha", Preco: 35},
+	}
+
+	// Os cortes somam 100 e atingem a segunda faixa, que vale para os dois
+	e := CalcularRepasse(regras, "p1", ags)
+	if e.Quantidade != 4 || e.Faturado != 160 || e.Comissao != 30+20+10 || e.Estabelecimento != 100 || e.SemRegra != 1 {
+		t.Errorf("extrato inesperado: %+v", e)
+	}
+
+	e = CalcularRepasse(regras, "p1", ags[1:])
+	if e.Itens[0].Comissao != 12 {
+		t.Errorf("corte abaixo da segunda faixa deveria usar 30%%: %+v", e.Itens[0])
+	}
+}
+
+func TestComissaoFixaLimitadaAoPreco(t *testing.T) {
+	regras := []RegraComissao{{Tipo: ComissaoFixa, Valor: 30}}
+	e := CalcularRepasse(regras, "p1", []Agendamento{{Preco: 20}, {Preco: 50}})
+	if e.Itens[0].Comissao != 20 || e.Comissao != 50 || e.Estabelecimento != 20 {
+		t.Errorf("comissão fixa acima do preço deveria ficar no preço: %+v", e)
+	}
+}
+
+func TestValidarRegrasComissao(t *testing.T) {
+	for _, regras := range [][]RegraComissao{
+		{{Tipo: "bonus"}},
+		{{Tipo: ComissaoPercentual, Percentual: 120}},
+		{{Tipo: ComissaoEscalonada, Faixas: []FaixaComissao{{10, 30}}}},
+		{{Tipo: ComissaoPercentual, Percentual: 40}, {Tipo: ComissaoFixa, Valor: 10}},
+	} {
+		if ValidarRegrasComissao(regras) == nil {
+			t.Errorf("regras %+v deveriam ser recusadas", regras)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DiaSemana é o dia da semana no padrão ISO-8601: 1 é segunda-feira e 7 é domingo.
+// Trafega na API e é gravado como número.
+type DiaSemana int
+
+const (
+	Segunda DiaSemana = iota + 1
+	Terca
+	Quarta
+	Quinta
+	Sexta
+	Sabado
+	Domingo
+)
+
+// ErrDiaSemanaInvalido indica um dia da semana fora de 1–7 ou um nome não reconhecido
+var ErrDiaSemanaInvalido = errors.New("dia da semana inválido, use 1 (segunda) a 7 (domingo)")
+
+var nomesDiaSemana = [...]string{"", "Segunda", "Terça", "Quarta", "Quinta", "Sexta", "Sábado", "Domingo"}
+
+// apelidosDiaSemana são os nomes aceitos na leitura, já em minúsculas. Qualquer outro
+// texto é recusado para que o turno não fique gravado num dia que nunca casa.
+var apelidosDiaSemana = map[string]DiaSemana{
+	"segunda": Segunda, "segunda-feira": Segunda, "seg": Segunda,
+	"terça": Terca, "terca": Terca, "terça-feira": Terca, "terca-feira": Terca, "ter": Terca,
+	"quarta": Quarta, "quarta-feira": Quarta, "qua": Quarta,
+	"quinta": Quinta, "quinta-feira": Quinta, "qui": Quinta,
+	"sexta": Sexta, "sexta-feira": Sexta, "sex": Sexta,
+	"sábado": Sabado, "sabado": Sabado, "sáb": Sabado, "sab": Sabado,
+	"domingo": Domingo, "dom": Domingo,
+}
+
+// DiaSemanaDe converte o dia da semana do pacote time, que começa no domingo
+func DiaSemanaDe(d time.Weekday) DiaSemana {
+	if d == time.Sunday {
+		return Domingo
+	}
+	return DiaSemana(d)
+}
+
+// Valido indica se o dia está entre segunda (1) e domingo (7)
+func (d DiaSemana) Valido() bool {
+	return d >= Segunda && d <= Domingo
+}
+
+// String devolve o nome do dia em português, como "Terça"
+func (d DiaSemana) String() string {
+	if !d.Valido() {
+		return "DiaSemana(" + strconv.Itoa(int(d)) + ")"
+	}
+	return nomesDiaSemana[d]
+}
+
+// ParseDiaSemana interpreta o número ISO ("2") ou um dos nomes aceitos ("Terça", "terca",
+// "ter", "terça-feira"), sem diferenciar maiúsculas
+func ParseDiaSemana(s string) (DiaSemana, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if n, err := strconv.Atoi(s); err == nil {
+		if d := DiaSemana(n); d.Valido() {
+			return d, nil
+		}
+		return 0, ErrDiaSemanaInvalido
+	}
+	if d, ok := apelidosDiaSemana[s]; ok {
+		return d, nil
+	}
+	return 0, ErrDiaSemanaInvalido
+}
+
+// UnmarshalJSON aceita o número ISO e, para clientes antigos, os nomes aceitos por ParseDiaSemana
+func (d *DiaSemana) UnmarshalJSON(dados []byte) error {
+	var n int
+	if err := json.Unmarshal(dados, &n); err == nil {
+		if !DiaSemana(n).Valido() {
+			return ErrDiaSemanaInvalido
+		}
+		*d = DiaSemana(n)
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(dados, &s); err != nil {
+		return ErrDiaSemanaInvalido
+	}
+	dia, err := ParseDiaSemana(s)
+	if err != nil {
+		return err
+	}
+	*d = dia
+	return nil
+}
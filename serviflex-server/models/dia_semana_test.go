@@ -0,0 +1,49 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestParseDiaSemana(t *testing.T) {
+	aceitos := map[string]DiaSemana{
+		"1": Segunda, "Segunda": Segunda, "segunda-feira": Segunda,
+		"Terça": Terca, "terca": Terca, "Terca": Terca, " TER ": Terca,
+		"Sábado": Sabado, "sabado": Sabado, "7": Domingo, "dom": Domingo,
+	}
+	for entrada, esperado := range aceitos {
+		if d, err := ParseDiaSemana(entrada); err != nil || d != esperado {
+			t.Errorf("ParseDiaSemana(%q) = %v, %v; esperado %v", entrada, d, err, esperado)
+		}
+	}
+	for _, invalido := range []string{"", "0", "8", "terçaa", "Tuesday", "segundas"} {
+		if _, err := ParseDiaSemana(invalido); err == nil {
+			t.Errorf("ParseDiaSemana(%q) deveria falhar", invalido)
+		}
+	}
+}
+
+func TestDiaSemanaJSON(t *testing.T) {
+	var input HorarioInput
+	if err := json.Unmarshal([]byte(`{"dias_semana": [2, "quarta", "Sábado"]}`), &input); err != nil {
+		t.Fatal(err)
+	}
+	if len(input.DiasSemana) != 3 || input.DiasSemana[0] != Terca || input.DiasSemana[1] != Quarta || input.DiasSemana[2] != Sabado {
+		t.Fatalf("dias interpretados errado: %v", input.DiasSemana)
+	}
+	if err := json.Unmarshal([]byte(`{"dias_semana": [8]}`), &input); err == nil {
+		t.Error("8 não é um dia ISO válido")
+	}
+
+	saida, _ := json.Marshal(Horario{DiaSemana: Domingo})
+	var h map[string]interface{}
+	json.Unmarshal(saida, &h)
+	if h["dia_semana"] != float64(7) {
+		t.Errorf("dia_semana deveria sair como número ISO, obtive %v", h["dia_semana"])
+	}
+
+	if DiaSemanaDe(time.Sunday) != Domingo || DiaSemanaDe(time.Monday) != Segunda {
+		t.Error("conversão de time.Weekday incorreta")
+	}
+}
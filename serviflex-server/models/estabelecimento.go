@@ -3,6 +3,7 @@ package models
 import "time"
 
 type Estabelecimento struct {
+	ID             string    `firestore:"-"` // ID do documento
 	Nome           string    `firestore:"nome"`
 	Descricao      string    `firestore:"descricao"`
 	FotoURL        string    `firestore:"fotoURL"`
@@ -10,6 +11,10 @@ type Estabelecimento struct {
 	Localizacao    Endereco  `firestore:"localizacao"`
 	CriadoEm       time.Time `firestore:"criadoEm"`
 	ResponsavelUID string    `firestore:"responsavelUid"`
+	FusoHorario    string    `firestore:"fusoHorario"` // nome IANA; vazio usa FusoPadrao
+
+	PoliticaCancelamento *PoliticaCancelamento `firestore:"politicaCancelamento,omitempty"`
+	Comissoes            []RegraComissao       `firestore:"comissoes,omitempty"` // regras de repasse aos profissionais
 }
 
 type Endereco struct {
@@ -24,6 +29,9 @@ type EstabelecimentoInput struct {
 	FotoURL     string   `json:"fotoURL"`
 	Categoria   string   `json:"categoria"`
 	Localizacao Endereco `json:"localizacao" binding:"required"`
+	FusoHorario string   `json:"fuso_horario"` // ex.: America/Sao_Paulo; na edição, vazio mantém o atual
+
+	PoliticaCancelamento *PoliticaCancelamento `json:"politica_cancelamento"` // opcional; na edição, ausente mantém a atual
 }
 
 // VinculoProfissionalInput representa a requisição para adicionar um profissional a um estabelecimento.
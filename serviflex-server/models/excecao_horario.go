@@ -0,0 +1,66 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// Tipos de exceção à agenda semanal
+const (
+	ExcecaoBloqueio = "bloqueio" // folga, férias, feriado ou compromisso: fecha o período
+	ExcecaoExtra    = "extra"    // expediente avulso além dos horários da semana
+)
+
+// ExcecaoHorario altera a agenda semanal do profissional em datas específicas. Sem
+// HoraInicio/HoraFim o bloqueio vale para o dia inteiro; com elas, só para aquele
+// período de cada dia entre DataInicio e DataFim.
+type ExcecaoHorario struct {
+	ID             string `json:"id,omitempty" firestore:"id,omitempty"`
+	ProfissionalID string `json:"profissional_id" firestore:"profissionalId"`
+	Tipo           string `json:"tipo" firestore:"tipo"`
+	DataInicio     string `json:"data_inicio" firestore:"dataInicio"` // AAAA-MM-DD
+	DataFim        string `json:"data_fim" firestore:"dataFim"`       // AAAA-MM-DD, inclusiva
+	HoraInicio     string `json:"hora_inicio,omitempty" firestore:"horaInicio"`
+	HoraFim        string `json:"hora_fim,omitempty" firestore:"horaFim"`
+	Motivo         string `json:"motivo,omitempty" firestore:"motivo"`
+}
+
+// DiaInteiro indica se a exceção não tem horário, valendo para o dia todo
+func (e ExcecaoHorario) DiaInteiro() bool {
+	return e.HoraInicio == "" && e.HoraFim == ""
+}
+
+// Abrange indica se a exceção vale na data informada (AAAA-MM-DD)
+func (e ExcecaoHorario) Abrange(data string) bool {
+	return e.DataInicio <= data && data <= e.DataFim
+}
+
+// Validar confere tipo, datas e horários. Uma DataFim vazia passa a ser igual à DataInicio.
+func (e *ExcecaoHorario) Validar() error {
+	if e.Tipo != ExcecaoBloqueio && e.Tipo != ExcecaoExtra {
+		return errors.New("tipo deve ser bloqueio ou extra")
+	}
+	if e.DataFim == "" {
+		e.DataFim = e.DataInicio
+	}
+	inicio, err := time.Parse("2006-01-02", e.DataInicio)
+	if err != nil {
+		return errors.New("data_inicio inválida, use AAAA-MM-DD")
+	}
+	fim, err := time.Parse("2006-01-02", e.DataFim)
+	if err != nil {
+		return errors.New("data_fim inválida, use AAAA-MM-DD")
+	}
+	if fim.Before(inicio) {
+		return errors.New("data_fim anterior a data_inicio")
+	}
+
+	if e.DiaInteiro() {
+		if e.Tipo == ExcecaoExtra {
+			return errors.New("horário extra precisa de hora_inicio e hora_fim")
+		}
+		return nil
+	}
+	_, _, err = periodo(e.HoraInicio, e.HoraFim)
+	return err
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	_ "time/tzdata" // embute a base IANA: o servidor não depende do zoneinfo do sistema
+)
+
+// FusoPadrao vale para estabelecimentos sem fuso definido e para profissionais sem estabelecimento
+const FusoPadrao = "America/Sao_Paulo"
+
+// CarregarFuso interpreta um nome IANA (ex.: "America/Manaus"); vazio significa FusoPadrao
+func CarregarFuso(nome string) (*time.Location, error) {
+	if nome == "" {
+		nome = FusoPadrao
+	}
+	if nome == "Local" {
+		// time.LoadLocation aceita "Local", que faria o resultado depender do servidor
+		return nil, errors.New("fuso deve ser um nome IANA, como America/Sao_Paulo")
+	}
+	return time.LoadLocation(nome)
+}
+
+// Fuso devolve o fuso em que o estabelecimento funciona
+func (e Estabelecimento) Fuso() (*time.Location, error) {
+	return CarregarFuso(e.FusoHorario)
+}
@@ -1,16 +1,93 @@
 package models
 
+import (
+	"errors"
+	"time"
+)
+
+// Horario é um turno de atendimento semanal. Um profissional pode ter vários turnos no
+// mesmo dia (ex.: 08:00–12:00 e 14:00–19:00), desde que não se sobreponham.
 type Horario struct {
-	ID             string `json:"id,omitempty" firestore:"id,omitempty"`
-	ProfissionalID string `json:"profissional_id" firestore:"profissional_id"`
-	DiaSemana      string `json:"dia_semana" firestore:"dia_semana"`
-	HoraInicio     string `json:"hora_inicio" firestore:"hora_inicio"`
-	HoraFim        string `json:"hora_fim" firestore:"hora_fim"`
-	Disponivel     bool   `json:"disponivel" firestore:"disponivel"`
+	ID             string    `json:"id,omitempty" firestore:"id,omitempty"`
+	ProfissionalID string    `json:"profissional_id" firestore:"profissionalId"`
+	DiaSemana      DiaSemana `json:"dia_semana" firestore:"diaSemana"` // ISO-8601: 1 = segunda ... 7 = domingo
+	HoraInicio     string    `json:"hora_inicio" firestore:"horaInicio"`
+	HoraFim        string    `json:"hora_fim" firestore:"horaFim"`
+	Disponivel     bool      `json:"disponivel" firestore:"disponivel"`
+	Pausas         []Pausa   `json:"pausas,omitempty" firestore:"pausas,omitempty"` // intervalos sem atendimento dentro do turno
+}
+
+// Pausa é um intervalo dentro do turno em que não há atendimento, como o almoço
+type Pausa struct {
+	HoraInicio string `json:"hora_inicio" firestore:"horaInicio"`
+	HoraFim    string `json:"hora_fim" firestore:"horaFim"`
 }
+
 type HorarioInput struct {
-	ProfissionalID string   `json:"profissional_id"`
-	DiasSemana     []string `json:"dias_semana"`
-	HoraInicio     string   `json:"hora_inicio"`
-	HoraFim        string   `json:"hora_fim"`
+	ProfissionalID string      `json:"profissional_id"`
+	DiasSemana     []DiaSemana `json:"dias_semana"`
+	HoraInicio     string      `json:"hora_inicio"`
+	HoraFim        string      `json:"hora_fim"`
+	Pausas         []Pausa     `json:"pausas"`
+}
+
+// Validar confere se o turno tem dia da semana e horas válidos e se as pausas ficam dentro dele sem se cruzarem
+func (h Horario) Validar() error {
+	if !h.DiaSemana.Valido() {
+		return ErrDiaSemanaInvalido
+	}
+	inicio, fim, err := periodo(h.HoraInicio, h.HoraFim)
+	if err != nil {
+		return err
+	}
+	pausas := make([][2]time.Time, 0, len(h.Pausas))
+	for _, p := range h.Pausas {
+		pi, pf, err := periodo(p.HoraInicio, p.HoraFim)
+		if err != nil {
+			return errors.New("pausa inválida: " + err.Error())
+		}
+		if pi.Before(inicio) || pf.After(fim) {
+			return errors.New("pausa fora do turno")
+		}
+		for _, outra := range pausas {
+			if pi.Before(outra[1]) && outra[0].Before(pf) {
+				return errors.New("pausas sobrepostas")
+			}
+		}
+		pausas = append(pausas, [2]time.Time{pi, pf})
+	}
+	return nil
+}
+
+// Sobrepoe indica se os dois turnos caem no mesmo dia e têm horas em comum. Turnos que
+// apenas se encostam (12:00–14:00 e 14:00–18:00) não se sobrepõem.
+func (h Horario) Sobrepoe(outro Horario) bool {
+	if h.DiaSemana != outro.DiaSemana {
+		return false
+	}
+	ai, af, err := periodo(h.HoraInicio, h.HoraFim)
+	if err != nil {
+		return false
+	}
+	bi, bf, err := periodo(outro.HoraInicio, outro.HoraFim)
+	if err != nil {
+		return false
+	}
+	return ai.Before(bf) && bi.Before(af)
+}
+
+// periodo interpreta um par de horas "15:04" exigindo que o fim seja posterior ao início
+func periodo(inicio, fim string) (time.Time, time.Time, error) {
+	i, err := time.Parse("15:04", inicio)
+	if err != nil {
+		return i, i, errors.New("hora_inicio inválida, use HH:MM")
+	}
+	f, err := time.Parse("15:04", fim)
+	if err != nil {
+		return i, f, errors.New("hora_fim inválida, use HH:MM")
+	}
+	if !f.After(i) {
+		return i, f, errors.New("hora_fim deve ser posterior a hora_inicio")
+	}
+	return i, f, nil
 }
@@ -0,0 +1,126 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// Situações de uma avaliação diante da moderação. Avaliações sem situação estão publicadas.
+const (
+	SituacaoPublicada = "publicada"
+	SituacaoOculta    = "oculta"
+	SituacaoExcluida  = "excluida"
+)
+
+// Ações da fila de moderação
+const (
+	ModeracaoOcultar   = "ocultar"
+	ModeracaoRestaurar = "restaurar"
+	ModeracaoExcluir   = "excluir"
+)
+
+var (
+	// ErrModeracaoInvalida indica que a situação atual da avaliação não permite a ação
+	ErrModeracaoInvalida = errors.New("a situação atual da avaliação não permite esta ação")
+	// ErrJaRespondida impede uma segunda resposta pública à mesma avaliação
+	ErrJaRespondida = errors.New("avaliação já respondida")
+	// ErrJaDenunciada impede que o mesmo usuário denuncie a avaliação duas vezes
+	ErrJaDenunciada = errors.New("avaliação já denunciada por este usuário")
+)
+
+// RespostaAvaliacao é a resposta pública do profissional ou do responsável pelo estabelecimento
+type RespostaAvaliacao struct {
+	Texto  string    `json:"texto" firestore:"texto"`
+	PorUID string    `json:"por_uid" firestore:"porUid"`
+	Em     time.Time `json:"em" firestore:"em"`
+}
+
+// DenunciaAvaliacao registra quem apontou abuso na avaliação e por quê
+type DenunciaAvaliacao struct {
+	PorUID string    `json:"por_uid" firestore:"porUid"`
+	Motivo string    `json:"motivo" firestore:"motivo"`
+	Em     time.Time `json:"em" firestore:"em"`
+}
+
+// AcaoModeracao registra uma decisão de admin sobre a avaliação
+type AcaoModeracao struct {
+	Acao   string    `json:"acao" firestore:"acao"`
+	Motivo string    `json:"motivo" firestore:"motivo"`
+	PorUID string    `json:"por_uid" firestore:"porUid"`
+	Em     time.Time `json:"em" firestore:"em"`
+}
+
+// RespostaInput é o corpo da resposta pública a uma avaliação
+type RespostaInput struct {
+	Texto string `json:"texto"`
+}
+
+// SituacaoAtual devolve a situação da avaliação; as anteriores à moderação estão publicadas
+func (a Avaliacao) SituacaoAtual() string {
+	if a.Situacao == "" {
+		return SituacaoPublicada
+	}
+	return a.Situacao
+}
+
+// Visivel indica se a avaliação aparece nos relatórios e entra nas médias
+func (a Avaliacao) Visivel() bool {
+	return a.SituacaoAtual() == SituacaoPublicada
+}
+
+// Publica devolve a avaliação sem as denúncias e o histórico de moderação, que só os admins veem
+func (a Avaliacao) Publica() Avaliacao {
+	a.Denuncias = nil
+	a.Moderacao = nil
+	a.EmModeracao = false
+	return a
+}
+
+// Responder grava a única resposta pública da avaliação
+func (a *Avaliacao) Responder(r RespostaAvaliacao) error {
+	if !a.Visivel() {
+		return ErrModeracaoInvalida
+	}
+	if a.Resposta != nil {
+		return ErrJaRespondida
+	}
+	a.Resposta = &r
+	return nil
+}
+
+// Denunciar registra a denúncia e coloca a avaliação na fila de moderação
+func (a *Avaliacao) Denunciar(d DenunciaAvaliacao) error {
+	if !a.Visivel() {
+		return ErrModeracaoInvalida
+	}
+	for _, existente := range a.Denuncias {
+		if existente.PorUID == d.PorUID {
+			return ErrJaDenunciada
+		}
+	}
+	a.Denuncias = append(a.Denuncias, d)
+	a.EmModeracao = true
+	return nil
+}
+
+// Moderar aplica a decisão do admin e tira a avaliação da fila. Ocultar vale para
+// avaliações publicadas; restaurar republica uma oculta ou, numa publicada denunciada,
+// descarta as denúncias; excluir é definitivo.
+func (a *Avaliacao) Moderar(m AcaoModeracao) error {
+	situacao := a.SituacaoAtual()
+	var para string
+	switch {
+	case m.Acao == ModeracaoOcultar && situacao == SituacaoPublicada:
+		para = SituacaoOculta
+	case m.Acao == ModeracaoRestaurar && (situacao == SituacaoOculta || (situacao == SituacaoPublicada && a.EmModeracao)):
+		para = SituacaoPublicada
+	case m.Acao == ModeracaoExcluir && situacao != SituacaoExcluida:
+		para = SituacaoExcluida
+	default:
+		return ErrModeracaoInvalida
+	}
+	a.Situacao = para
+	a.EmModeracao = false
+	a.Moderacao = append(a.Moderacao, m)
+	return nil
+}
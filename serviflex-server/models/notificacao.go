@@ -3,6 +3,7 @@ package models
 import "time"
 
 type Notificacao struct {
+	ID                string    `json:"id" firestore:"-"` // ID do documento
 	ParaUID           string    `firestore:"paraUid"`
 	Tipo              string    `firestore:"tipo"` // "convite_estabelecimento"
 	Mensagem          string    `firestore:"mensagem"`
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// Motivos das taxas geradas pela política de cancelamento
+const (
+	TaxaCancelamentoTardio = "cancelamento_tardio"
+	TaxaNaoComparecimento  = "nao_comparecimento"
+)
+
+// PoliticaCancelamento define até quando o cliente pode cancelar sem custo e quanto paga
+// depois disso. Os percentuais (0 a 100) incidem sobre o preço do procedimento.
+// Ex.: {24, 50, 100} = grátis até 24h antes, 50% depois e falta cobrada integralmente.
+type PoliticaCancelamento struct {
+	AntecedenciaHoras           int     `json:"antecedencia_horas" firestore:"antecedenciaHoras"`
+	PercentualTardio            float64 `json:"percentual_tardio" firestore:"percentualTardio"`
+	PercentualNaoComparecimento float64 `json:"percentual_nao_comparecimento" firestore:"percentualNaoComparecimento"`
+}
+
+// TaxaCancelamento é a cobrança gerada ao cancelar tarde ou faltar a um agendamento
+type TaxaCancelamento struct {
+	Motivo     string    `json:"motivo" firestore:"motivo"`
+	Percentual float64   `json:"percentual" firestore:"percentual"`
+	Valor      float64   `json:"valor" firestore:"valor"`
+	CriadaEm   time.Time `json:"criada_em" firestore:"criadaEm"`
+}
+
+// Validar confere se a antecedência e os percentuais estão dentro dos limites
+func (p PoliticaCancelamento) Validar() error {
+	if p.AntecedenciaHoras < 0 {
+		return errors.New("antecedência não pode ser negativa")
+	}
+	if p.PercentualTardio < 0 || p.PercentualTardio > 100 ||
+		p.PercentualNaoComparecimento < 0 || p.PercentualNaoComparecimento > 100 {
+		return errors.New("percentuais devem estar entre 0 e 100")
+	}
+	return nil
+}
+
+// Taxa calcula a cobrança devida pela última transição do agendamento: cancelamento
+// dentro do prazo de antecedência ou não comparecimento. Devolve nil quando não há taxa.
+func (p PoliticaCancelamento) Taxa(ag Agendamento, preco float64) *TaxaCancelamento {
+	if len(ag.Historico) == 0 {
+		return nil
+	}
+	ultima := ag.Historico[len(ag.Historico)-1]
+
+	taxa := TaxaCancelamento{CriadaEm: ultima.Em}
+	switch {
+	case ultima.Para == StatusNaoCompareceu:
+		taxa.Motivo, taxa.Percentual = TaxaNaoComparecimento, p.PercentualNaoComparecimento
+	case ultima.Para == StatusCancelado && ag.DataHora.Sub(ultima.Em) < time.Duration(p.AntecedenciaHoras)*time.Hour:
+		taxa.Motivo, taxa.Percentual = TaxaCancelamentoTardio, p.PercentualTardio
+	default:
+		return nil
+	}
+
+	taxa.Valor = preco * taxa.Percentual / 100
+	if taxa.Valor <= 0 {
+		return nil
+	}
+	return &taxa
+}
+
+// PoliticaAplicavel devolve a política do procedimento, se houver, ou a do estabelecimento
+func PoliticaAplicavel(estab *Estabelecimento, proc *Procedimento) *PoliticaCancelamento {
+	if proc != nil && proc.PoliticaCancelamento != nil {
+		return proc.PoliticaCancelamento
+	}
+	if estab != nil {
+		return estab.PoliticaCancelamento
+	}
+	return nil
+}
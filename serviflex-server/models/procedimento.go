@@ -2,10 +2,13 @@ package models
 
 type Procedimento struct {
 	ID             string  `json:"id,omitempty" firestore:"id,omitempty"`
-	ProfissionalID string  `json:"profissional_id" firestore:"profissional_id"`
+	ProfissionalID string  `json:"profissional_id" firestore:"profissionalId"`
 	Nome           string  `json:"nome" firestore:"nome"`
 	Descricao      string  `json:"descricao" firestore:"descricao"`
 	Preco          float64 `json:"preco" firestore:"preco"`
-	DuracaoMin     int     `json:"duracao_min" firestore:"duracao_min"`
-	ImagemURL      string  `json:"imagem_url,omitempty" firestore:"imagem_url,omitempty"`
+	DuracaoMin     int     `json:"duracao_min" firestore:"duracaoMin"`
+	ImagemURL      string  `json:"imagem_url,omitempty" firestore:"imagemUrl,omitempty"`
+
+	// PoliticaCancelamento substitui a política do estabelecimento para este procedimento
+	PoliticaCancelamento *PoliticaCancelamento `json:"politica_cancelamento,omitempty" firestore:"politicaCancelamento,omitempty"`
 }
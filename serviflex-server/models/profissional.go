@@ -6,11 +6,14 @@ type Profissional struct {
 	ID                string    `json:"id,omitempty" firestore:"id,omitempty"`
 	Nome              string    `json:"nome" firestore:"nome"`
 	Email             string    `json:"email" firestore:"email"`
-	Senha             string    `json:"senha" firestore:"senha"`
-	ImagemURL         string    `json:"imagem_url,omitempty" firestore:"imagem_url,omitempty"`
+	Senha             string    `json:"senha,omitempty" firestore:"senha"`
+	ImagemURL         string    `json:"imagem_url,omitempty" firestore:"imagemUrl,omitempty"`
 	Telefone          string    `json:"telefone,omitempty" firestore:"telefone,omitempty"`
 	EstabelecimentoID string    `json:"estabelecimentoId,omitempty" firestore:"estabelecimentoId,omitempty"`
 	CriadoEm          time.Time `json:"criadoEm" firestore:"criadoEm"`
+
+	// Avaliacoes é preenchido nas respostas com o resumo guardado pelo AvaliacaoRepository
+	Avaliacoes *ResumoAvaliacoes `json:"avaliacoes,omitempty" firestore:"-"`
 }
 
 type ProfissionalEstabelecimento struct {
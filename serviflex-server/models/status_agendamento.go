@@ -0,0 +1,113 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// Estados do ciclo de vida de um agendamento
+const (
+	StatusPendente      = "pendente"
+	StatusConfirmado    = "confirmado"
+	StatusConcluido     = "concluido"
+	StatusCancelado     = "cancelado"
+	StatusNaoCompareceu = "nao_compareceu"
+)
+
+// ErrTransicaoInvalida indica que o agendamento não pode passar do estado atual ao pedido
+var ErrTransicaoInvalida = errors.New("transição de status inválida")
+
+// transicoes lista, para cada estado, os estados seguintes permitidos. Concluído,
+// cancelado e não compareceu são finais.
+var transicoes = map[string][]string{
+	StatusPendente:   {StatusConfirmado, StatusCancelado},
+	StatusConfirmado: {StatusConcluido, StatusCancelado, StatusNaoCompareceu},
+}
+
+// TransicaoStatus registra quem mudou o status de um agendamento e quando
+type TransicaoStatus struct {
+	De       string     `json:"de,omitempty" firestore:"de"`
+	Para     string     `json:"para" firestore:"para"`
+	PorUID   string     `json:"por_uid" firestore:"porUid"`
+	PorTipo  string     `json:"por_tipo" firestore:"porTipo"`
+	Em       time.Time  `json:"em" firestore:"em"`
+	Motivo   string     `json:"motivo,omitempty" firestore:"motivo,omitempty"`
+	DataHora *time.Time `json:"data_hora,omitempty" firestore:"dataHora,omitempty"` // novo horário, nos reagendamentos
+}
+
+// StatusAtual devolve o status do agendamento; registros anteriores ao ciclo de vida
+// não têm status e são tratados como pendentes
+func (a Agendamento) StatusAtual() string {
+	if a.Status == "" {
+		return StatusPendente
+	}
+	return a.Status
+}
+
+// Ocupa indica se o agendamento ainda ocupa a agenda do profissional
+func (a Agendamento) Ocupa() bool {
+	s := a.StatusAtual()
+	return s != StatusCancelado && s != StatusNaoCompareceu
+}
+
+// PodeReagendar indica se o agendamento ainda pode mudar de horário
+func (a Agendamento) PodeReagendar() bool {
+	s := a.StatusAtual()
+	return s == StatusPendente || s == StatusConfirmado
+}
+
+// Transicionar muda o status e registra a transição no histórico
+func (a *Agendamento) Transicionar(para string, t TransicaoStatus) error {
+	de := a.StatusAtual()
+	permitido := false
+	for _, s := range transicoes[de] {
+		if s == para {
+			permitido = true
+			break
+		}
+	}
+	if !permitido {
+		return ErrTransicaoInvalida
+	}
+	a.registrar(de, para, t)
+	return nil
+}
+
+// Reagendar move o agendamento para um novo horário. Como o profissional precisa
+// confirmar de novo, o status volta a pendente.
+func (a *Agendamento) Reagendar(dataHora time.Time, duracaoMin int, t TransicaoStatus) error {
+	if !a.PodeReagendar() {
+		return ErrTransicaoInvalida
+	}
+	t.DataHora = &dataHora
+	a.registrar(a.StatusAtual(), StatusPendente, t)
+	a.DataHora = dataHora
+	a.DuracaoMin = duracaoMin
+	return nil
+}
+
+// Iniciar define o status de um agendamento recém-criado. Taxas só nascem das
+// transições, então uma taxa vinda no pedido é descartada.
+func (a *Agendamento) Iniciar(t TransicaoStatus) {
+	a.Historico = nil
+	a.Taxa = nil
+	a.registrar("", StatusPendente, t)
+}
+
+func (a *Agendamento) registrar(de, para string, t TransicaoStatus) {
+	t.De = de
+	t.Para = para
+	a.Status = para
+	a.Historico = append(a.Historico, t)
+}
+
+// MotivoInput é o corpo opcional das transições de status
+type MotivoInput struct {
+	Motivo string `json:"motivo"`
+}
+
+// ReagendamentoInput representa o pedido de mudança de horário de um agendamento
+type ReagendamentoInput struct {
+	DataHora time.Time `json:"data_hora" binding:"required"`
+	Motivo   string    `json:"motivo"`
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTransicoesDeStatus(t *testing.T) {
+	casos := []struct {
+		de, para string
+		ok       bool
+	}{
+		{"", StatusConfirmado, true}, // registros antigos contam como pendentes
+		{StatusPendente, StatusCancelado, true},
+		{StatusPendente, StatusConcluido, false},
+		{StatusConfirmado, StatusConcluido, true},
+		{StatusConfirmado, StatusNaoCompareceu, true},
+		{StatusConfirmado, StatusCancelado, true},
+		{StatusConcluido, StatusCancelado, false},
+		{StatusCancelado, StatusConfirmado, false},
+		{StatusNaoCompareceu, StatusConcluido, false},
+	}
+	for _, caso := range casos {
+		ag := Agendamento{Status: caso.de}
+		err := ag.Transicionar(caso.para, TransicaoStatus{PorUID: "u-1"})
+		if caso.ok != (err == nil) {
+			t.Errorf("%q → %q: erro %v", caso.de, caso.para, err)
+			continue
+		}
+		if err != nil {
+			if !errors.Is(err, ErrTransicaoInvalida) || ag.Status != caso.de || len(ag.Historico) != 0 {
+				t.Errorf("%q → %q: transição recusada alterou o agendamento: %+v", caso.de, caso.para, ag)
+			}
+			continue
+		}
+		de := Agendamento{Status: caso.de}.StatusAtual()
+		h := ag.Historico[0]
+		if ag.Status != caso.para || h.De != de || h.Para != caso.para || h.PorUID != "u-1" {
+			t.Errorf("%q → %q: histórico inesperado %+v", caso.de, caso.para, ag.Historico)
+		}
+	}
+}
+
+func TestReagendar(t *testing.T) {
+	antes := time.Date(2025, 3, 10, 10, 0, 0, 0, time.UTC)
+	depois := antes.Add(2 * time.Hour)
+
+	ag := Agendamento{DataHora: antes, DuracaoMin: 30}
+	ag.Iniciar(TransicaoStatus{})
+	if err := ag.Transicionar(StatusConfirmado, TransicaoStatus{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ag.Reagendar(depois, 45, TransicaoStatus{Motivo: "pedido do cliente"}); err != nil {
+		t.Fatal(err)
+	}
+	ultima := ag.Historico[len(ag.Historico)-1]
+	if ag.Status != StatusPendente || !ag.DataHora.Equal(depois) || ag.DuracaoMin != 45 {
+		t.Fatalf("reagendamento não aplicado: %+v", ag)
+	}
+	if len(ag.Historico) != 3 || ultima.De != StatusConfirmado || ultima.DataHora == nil || !ultima.DataHora.Equal(depois) {
+		t.Fatalf("histórico inesperado: %+v", ag.Historico)
+	}
+
+	ag.Status = StatusCancelado
+	if err := ag.Reagendar(antes, 30, TransicaoStatus{}); !errors.Is(err, ErrTransicaoInvalida) {
+		t.Fatalf("agendamento cancelado não deveria ser reagendado, erro %v", err)
+	}
+	if ag.Ocupa() {
+		t.Fatal("agendamento cancelado não deveria ocupar a agenda")
+	}
+}
@@ -6,11 +6,11 @@ type Usuario struct {
 	ID                string    `json:"id" firestore:"id"`
 	Nome              string    `json:"nome" firestore:"nome"`
 	Email             string    `json:"email" firestore:"email"`
-	Senha             string    `json:"senha" firestore:"senha"`
+	Senha             string    `json:"senha,omitempty" firestore:"senha"`
 	Tipo              string    `json:"tipo,omitempty"`
 	Telefone          string    `json:"telefone" firestore:"telefone"`
 	FotoURL           string    `json:"fotoUrl" firestore:"fotoURL"`
-	ImagemURL         string    `json:"imagem_url" firestore:"imagem_url"`
+	ImagemURL         string    `json:"imagem_url" firestore:"imagemUrl"`
 	EstabelecimentoID string    `json:"estabelecimentoId" firestore:"estabelecimentoId,omitempty"` // só se for profissional
 	CriadoEm          time.Time `json:"criadoEm" firestore:"criadoEm"`
 }
@@ -0,0 +1,408 @@
+// Package relatorios é o motor comum dos endpoints /relatorios: interpreta o período, o
+// agrupamento e a comparação pedidos, monta a série com zeros nos intervalos sem movimento
+// e calcula a variação em relação ao período anterior. Os valores de cada relatório vêm de
+// uma Fonte, que consulta os repositórios.
+package relatorios
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Agrupamento define como a série do relatório quebra os valores do período
+type Agrupamento string
+
+const (
+	SemAgrupamento      Agrupamento = ""
+	AgruparDia          Agrupamento = "dia"
+	AgruparSemana       Agrupamento = "semana"
+	AgruparMes          Agrupamento = "mes"
+	AgruparProcedimento Agrupamento = "procedimento"
+	AgruparProfissional Agrupamento = "profissional"
+)
+
+// CompararPeriodoAnterior é o único valor aceito no parâmetro comparar
+const CompararPeriodoAnterior = "periodo_anterior"
+
+// MaximoPontos limita a quantidade de intervalos de uma série temporal
+const MaximoPontos = 366
+
+var (
+	// ErrComparacaoSemPeriodo recusa a comparação de um período sem data inicial
+	ErrComparacaoSemPeriodo = errors.New("para comparar com o período anterior informe a data inicial (de)")
+	// ErrSerieLonga recusa séries temporais com mais de MaximoPontos intervalos
+	ErrSerieLonga = fmt.Errorf("a série pode ter no máximo %d pontos, use um agrupamento maior", MaximoPontos)
+)
+
+// Temporal indica se o agrupamento quebra o período em intervalos de tempo
+func (a Agrupamento) Temporal() bool {
+	return a == AgruparDia || a == AgruparSemana || a == AgruparMes
+}
+
+// Chave devolve o intervalo em que t cai, já no fuso do relatório: "2006-01-02" por dia,
+// "2006-W01" pela semana ISO e "2006-01" por mês. As chaves ordenam cronologicamente.
+func (a Agrupamento) Chave(t time.Time) string {
+	switch a {
+	case AgruparDia:
+		return t.Format("2006-01-02")
+	case AgruparSemana:
+		ano, semana := t.ISOWeek()
+		return fmt.Sprintf("%04d-W%02d", ano, semana)
+	case AgruparMes:
+		return fmt.Sprintf("%04d-%02d", t.Year(), t.Month())
+	}
+	return ""
+}
+
+// Periodo é um intervalo de dias inteiros no fuso do relatório: [Inicio, Fim). Inicio
+// zero significa desde o primeiro registro.
+type Periodo struct {
+	Inicio time.Time
+	Fim    time.Time
+}
+
+// Aberto indica se o período não tem data inicial
+func (p Periodo) Aberto() bool {
+	return p.Inicio.IsZero()
+}
+
+// Contem indica se t está dentro do período
+func (p Periodo) Contem(t time.Time) bool {
+	return (p.Aberto() || !t.Before(p.Inicio)) && t.Before(p.Fim)
+}
+
+// Ultimo devolve o último instante do período, para filtros com limite final inclusivo
+func (p Periodo) Ultimo() time.Time {
+	return p.Fim.Add(-time.Nanosecond)
+}
+
+// Anterior devolve o período imediatamente antes deste, de mesma duração. Períodos de
+// meses inteiros comparam com a mesma quantidade de meses inteiros.
+func (p Periodo) Anterior() Periodo {
+	if p.Inicio.Day() == 1 && p.Fim.Day() == 1 {
+		meses := (p.Fim.Year()-p.Inicio.Year())*12 + int(p.Fim.Month()-p.Inicio.Month())
+		return Periodo{Inicio: p.Inicio.AddDate(0, -meses, 0), Fim: p.Inicio}
+	}
+	dias := diasEntre(p.Inicio, p.Fim)
+	return Periodo{Inicio: p.Inicio.AddDate(0, 0, -dias), Fim: p.Inicio}
+}
+
+// MarshalJSON expõe o período como datas inclusivas AAAA-MM-DD; "de" é nulo no período aberto
+func (p Periodo) MarshalJSON() ([]byte, error) {
+	var de *string
+	if !p.Aberto() {
+		s := p.Inicio.Format("2006-01-02")
+		de = &s
+	}
+	ate := p.Fim.AddDate(0, 0, -1).Format("2006-01-02")
+	return json.Marshal(map[string]*string{"de": de, "ate": &ate})
+}
+
+// chaves lista os intervalos de um período fechado, em ordem
+func (p Periodo) chaves(agrupar Agrupamento) []string {
+	var chaves []string
+	for dia := p.Inicio; dia.Before(p.Fim); dia = dia.AddDate(0, 0, 1) {
+		chave := agrupar.Chave(dia)
+		if len(chaves) == 0 || chaves[len(chaves)-1] != chave {
+			chaves = append(chaves, chave)
+		}
+	}
+	return chaves
+}
+
+// diasEntre conta os dias de calendário entre duas meias-noites do mesmo fuso, sem se
+// confundir com os dias de 23 ou 25 horas das mudanças de horário de verão
+func diasEntre(inicio, fim time.Time) int {
+	a := time.Date(inicio.Year(), inicio.Month(), inicio.Day(), 0, 0, 0, 0, time.UTC)
+	b := time.Date(fim.Year(), fim.Month(), fim.Day(), 0, 0, 0, 0, time.UTC)
+	return int(b.Sub(a).Hours() / 24)
+}
+
+// Consulta é o que foi pedido ao relatório
+type Consulta struct {
+	Periodo  Periodo
+	Agrupar  Agrupamento
+	Comparar bool
+}
+
+// Interpretar lê os parâmetros de e ate (AAAA-MM-DD, inclusivos, no fuso de hoje),
+// agrupar e comparar. Sem de nem ate vale o período padrão do relatório; só com de, o
+// período vai até hoje; só com ate, começa no primeiro registro. O agrupamento padrão
+// também é o do relatório.
+func Interpretar(parametro func(string) string, padrao Consulta, hoje time.Time) (Consulta, error) {
+	consulta := padrao
+	loc := hoje.Location()
+	amanha := time.Date(hoje.Year(), hoje.Month(), hoje.Day()+1, 0, 0, 0, 0, loc)
+
+	de, ate := parametro("de"), parametro("ate")
+	if de != "" || ate != "" {
+		consulta.Periodo = Periodo{Fim: amanha}
+	}
+	if de != "" {
+		inicio, err := time.ParseInLocation("2006-01-02", de, loc)
+		if err != nil {
+			return Consulta{}, errors.New("data inicial inválida, use AAAA-MM-DD")
+		}
+		consulta.Periodo.Inicio = inicio
+	}
+	if ate != "" {
+		fim, err := time.ParseInLocation("2006-01-02", ate, loc)
+		if err != nil {
+			return Consulta{}, errors.New("data final inválida, use AAAA-MM-DD")
+		}
+		consulta.Periodo.Fim = fim.AddDate(0, 0, 1)
+	}
+	if !consulta.Periodo.Aberto() && !consulta.Periodo.Inicio.Before(consulta.Periodo.Fim) {
+		return Consulta{}, errors.New("data final anterior à inicial")
+	}
+
+	if v := parametro("agrupar"); v != "" {
+		consulta.Agrupar = Agrupamento(v)
+		switch consulta.Agrupar {
+		case AgruparDia, AgruparSemana, AgruparMes, AgruparProcedimento, AgruparProfissional:
+		default:
+			return Consulta{}, errors.New("agrupar deve ser dia, semana, mes, procedimento ou profissional")
+		}
+	}
+	if consulta.Agrupar.Temporal() && !consulta.Periodo.Aberto() && len(consulta.Periodo.chaves(consulta.Agrupar)) > MaximoPontos {
+		return Consulta{}, ErrSerieLonga
+	}
+
+	switch parametro("comparar") {
+	case "":
+	case CompararPeriodoAnterior:
+		consulta.Comparar = true
+	default:
+		return Consulta{}, errors.New("comparar aceita apenas periodo_anterior")
+	}
+	if consulta.Comparar && consulta.Periodo.Aberto() {
+		return Consulta{}, ErrComparacaoSemPeriodo
+	}
+	return consulta, nil
+}
+
+// Linha é um grupo da série com os valores das métricas, na ordem de Relatorio.Metricas.
+// Rotulo é o nome legível do grupo, quando a chave é um ID.
+type Linha struct {
+	Chave   string
+	Rotulo  string
+	Valores []float64
+}
+
+// Fonte calcula as linhas de um período com o agrupamento pedido. Sem agrupamento devolve
+// no máximo uma linha, a dos totais; grupos sem movimento podem ser omitidos.
+type Fonte func(ctx context.Context, periodo Periodo, agrupar Agrupamento) ([]Linha, error)
+
+// Resultado são os totais e a série de um período
+type Resultado struct {
+	Periodo Periodo
+	Totais  []float64
+	Serie   []Linha
+}
+
+// Relatorio é o resultado de uma consulta: o período pedido e, na comparação, o anterior
+type Relatorio struct {
+	Metricas []string
+	Agrupar  Agrupamento
+	Atual    Resultado
+	Anterior *Resultado
+}
+
+// Gerar executa a consulta sobre a fonte
+func Gerar(ctx context.Context, consulta Consulta, metricas []string, fonte Fonte) (*Relatorio, error) {
+	atual, err := resultado(ctx, consulta.Periodo, consulta.Agrupar, metricas, fonte)
+	if err != nil {
+		return nil, err
+	}
+	rel := &Relatorio{Metricas: metricas, Agrupar: consulta.Agrupar, Atual: atual}
+	if consulta.Comparar {
+		anterior, err := resultado(ctx, consulta.Periodo.Anterior(), consulta.Agrupar, metricas, fonte)
+		if err != nil {
+			return nil, err
+		}
+		rel.Anterior = &anterior
+	}
+	return rel, nil
+}
+
+func resultado(ctx context.Context, periodo Periodo, agrupar Agrupamento, metricas []string, fonte Fonte) (Resultado, error) {
+	r := Resultado{Periodo: periodo, Totais: make([]float64, len(metricas))}
+	totais, err := fonte(ctx, periodo, SemAgrupamento)
+	if err != nil {
+		return Resultado{}, err
+	}
+	if len(totais) > 0 {
+		copy(r.Totais, totais[0].Valores)
+	}
+	if agrupar == SemAgrupamento {
+		return r, nil
+	}
+
+	linhas, err := fonte(ctx, periodo, agrupar)
+	if err != nil {
+		return Resultado{}, err
+	}
+	if !agrupar.Temporal() {
+		// Grupos por ID: os de maior valor na primeira métrica primeiro
+		sort.SliceStable(linhas, func(i, j int) bool {
+			if linhas[i].Valores[0] != linhas[j].Valores[0] {
+				return linhas[i].Valores[0] > linhas[j].Valores[0]
+			}
+			return linhas[i].Chave < linhas[j].Chave
+		})
+		r.Serie = linhas
+		return r, nil
+	}
+	if periodo.Aberto() {
+		sort.Slice(linhas, func(i, j int) bool { return linhas[i].Chave < linhas[j].Chave })
+		r.Serie = linhas
+		return r, nil
+	}
+
+	// Série temporal completa, com zero nos intervalos sem movimento
+	porChave := make(map[string]Linha, len(linhas))
+	for _, l := range linhas {
+		porChave[l.Chave] = l
+	}
+	for _, chave := range periodo.chaves(agrupar) {
+		l, ok := porChave[chave]
+		if !ok {
+			l = Linha{Chave: chave, Valores: make([]float64, len(metricas))}
+		}
+		r.Serie = append(r.Serie, l)
+	}
+	return r, nil
+}
+
+// Total devolve o total da métrica no período pedido
+func (r *Relatorio) Total(metrica string) float64 {
+	for i, m := range r.Metricas {
+		if m == metrica {
+			return r.Atual.Totais[i]
+		}
+	}
+	return 0
+}
+
+// Variacao é a diferença de uma métrica para o período anterior. Percentual é nulo
+// quando o período anterior é zero.
+type Variacao struct {
+	Absoluta   float64  `json:"absoluta"`
+	Percentual *float64 `json:"percentual"`
+}
+
+// Variacoes compara os totais do período pedido com os do anterior
+func (r *Relatorio) Variacoes() map[string]Variacao {
+	if r.Anterior == nil {
+		return nil
+	}
+	variacoes := make(map[string]Variacao, len(r.Metricas))
+	for i, m := range r.Metricas {
+		atual, anterior := r.Atual.Totais[i], r.Anterior.Totais[i]
+		v := Variacao{Absoluta: atual - anterior}
+		if anterior != 0 {
+			p := (atual - anterior) / anterior * 100
+			v.Percentual = &p
+		}
+		variacoes[m] = v
+	}
+	return variacoes
+}
+
+// Campos devolve o relatório no formato das respostas JSON: periodo, agrupar, totais,
+// serie e, na comparação, comparacao com o período anterior e as variações
+func (r *Relatorio) Campos() map[string]any {
+	campos := map[string]any{
+		"periodo": r.Atual.Periodo,
+		"totais":  r.valores(r.Atual.Totais),
+	}
+	if r.Agrupar != SemAgrupamento {
+		campos["agrupar"] = r.Agrupar
+		campos["serie"] = r.serie(r.Atual.Serie)
+	}
+	if r.Anterior != nil {
+		anterior := map[string]any{
+			"periodo":  r.Anterior.Periodo,
+			"totais":   r.valores(r.Anterior.Totais),
+			"variacao": r.Variacoes(),
+		}
+		if r.Agrupar != SemAgrupamento {
+			anterior["serie"] = r.serie(r.Anterior.Serie)
+		}
+		campos["comparacao"] = anterior
+	}
+	return campos
+}
+
+func (r *Relatorio) valores(valores []float64) map[string]float64 {
+	m := make(map[string]float64, len(r.Metricas))
+	for i, metrica := range r.Metricas {
+		m[metrica] = valores[i]
+	}
+	return m
+}
+
+func (r *Relatorio) serie(linhas []Linha) []map[string]any {
+	serie := make([]map[string]any, 0, len(linhas))
+	for _, l := range linhas {
+		ponto := map[string]any{"chave": l.Chave}
+		if l.Rotulo != "" {
+			ponto["rotulo"] = l.Rotulo
+		}
+		for i, metrica := range r.Metricas {
+			ponto[metrica] = l.Valores[i]
+		}
+		serie = append(serie, ponto)
+	}
+	return serie
+}
+
+// Tabela acumula os valores das métricas por chave, na ordem em que as chaves aparecem.
+// Ajuda as fontes a juntar numa linha só os resultados de consultas diferentes.
+type Tabela struct {
+	metricas int
+	linhas   []Linha
+	indice   map[string]int
+}
+
+// NovaTabela cria uma tabela para a quantidade de métricas informada
+func NovaTabela(metricas int) *Tabela {
+	return &Tabela{metricas: metricas, indice: make(map[string]int)}
+}
+
+// Linha devolve a linha da chave, criando-a zerada se ainda não existir. Um rótulo não
+// vazio substitui o anterior.
+func (t *Tabela) Linha(chave, rotulo string) *Linha {
+	i, ok := t.indice[chave]
+	if !ok {
+		i = len(t.linhas)
+		t.indice[chave] = i
+		t.linhas = append(t.linhas, Linha{Chave: chave, Valores: make([]float64, t.metricas)})
+	}
+	if rotulo != "" {
+		t.linhas[i].Rotulo = rotulo
+	}
+	return &t.linhas[i]
+}
+
+// Linhas devolve as linhas acumuladas
+func (t *Tabela) Linhas() []Linha {
+	return t.linhas
+}
+
+// Historico é a consulta padrão dos relatórios que cobrem todos os registros até hoje
+func Historico(hoje time.Time) Consulta {
+	return Consulta{Periodo: Periodo{Fim: time.Date(hoje.Year(), hoje.Month(), hoje.Day()+1, 0, 0, 0, 0, hoje.Location())}}
+}
+
+// UltimosMeses é a consulta padrão por mês que cobre o mês atual e os meses-1 anteriores
+func UltimosMeses(hoje time.Time, meses int) Consulta {
+	consulta := Historico(hoje)
+	consulta.Periodo.Inicio = time.Date(hoje.Year(), hoje.Month()-time.Month(meses-1), 1, 0, 0, 0, 0, hoje.Location())
+	consulta.Agrupar = AgruparMes
+	return consulta
+}
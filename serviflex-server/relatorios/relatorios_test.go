@@ -0,0 +1,77 @@
+package relatorios
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func parametros(valores map[string]string) func(string) string {
+	return func(nome string) string { return valores[nome] }
+}
+
+func TestInterpretar(t *testing.T) {
+	loc, _ := time.LoadLocation("America/Sao_Paulo")
+	hoje := time.Date(2025, 3, 15, 10, 0, 0, 0, loc)
+
+	padrao, err := Interpretar(parametros(nil), UltimosMeses(hoje, 12), hoje)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !padrao.Periodo.Inicio.Equal(time.Date(2024, 4, 1, 0, 0, 0, 0, loc)) || padrao.Agrupar != AgruparMes {
+		t.Errorf("consulta padrão inesperada: %+v", padrao)
+	}
+
+	c, err := Interpretar(parametros(map[string]string{"de": "2025-02-01", "ate": "2025-02-28", "comparar": "periodo_anterior"}), Historico(hoje), hoje)
+	if err != nil {
+		t.Fatal(err)
+	}
+	anterior := c.Periodo.Anterior()
+	if !anterior.Inicio.Equal(time.Date(2025, 1, 1, 0, 0, 0, 0, loc)) || !anterior.Fim.Equal(c.Periodo.Inicio) {
+		t.Errorf("mês anterior inesperado: %+v", anterior)
+	}
+
+	c, _ = Interpretar(parametros(map[string]string{"de": "2025-03-10", "ate": "2025-03-16"}), Historico(hoje), hoje)
+	if anterior := c.Periodo.Anterior(); !anterior.Inicio.Equal(time.Date(2025, 3, 3, 0, 0, 0, 0, loc)) {
+		t.Errorf("semana anterior inesperada: %+v", anterior)
+	}
+
+	for _, invalido := range []map[string]string{
+		{"de": "15/03/2025"},
+		{"de": "2025-03-10", "ate": "2025-03-01"},
+		{"agrupar": "ano"},
+		{"comparar": "ano_anterior"},
+		{"comparar": "periodo_anterior"}, // sem data inicial
+		{"de": "2020-01-01", "agrupar": "dia"},
+	} {
+		if _, err := Interpretar(parametros(invalido), Historico(hoje), hoje); err == nil {
+			t.Errorf("parâmetros %v deveriam ser recusados", invalido)
+		}
+	}
+}
+
+func TestGerar(t *testing.T) {
+	inicio := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	consulta := Consulta{Periodo: Periodo{Inicio: inicio, Fim: inicio.AddDate(0, 0, 3)}, Agrupar: AgruparDia, Comparar: true}
+	fonte := func(_ context.Context, p Periodo, agrupar Agrupamento) ([]Linha, error) {
+		if p.Inicio.Before(inicio) {
+			return []Linha{{Chave: agrupar.Chave(p.Inicio), Valores: []float64{2}}}, nil
+		}
+		return []Linha{{Chave: agrupar.Chave(inicio.AddDate(0, 0, 1)), Valores: []float64{3}}}, nil
+	}
+
+	rel, err := Gerar(context.Background(), consulta, []string{"quantidade"}, fonte)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rel.Atual.Serie) != 3 || rel.Atual.Serie[0].Valores[0] != 0 || rel.Atual.Serie[1].Valores[0] != 3 {
+		t.Errorf("série sem os dias zerados: %+v", rel.Atual.Serie)
+	}
+	if rel.Anterior == nil || rel.Anterior.Serie[0].Chave != "2024-12-29" {
+		t.Fatalf("período anterior inesperado: %+v", rel.Anterior)
+	}
+	v := rel.Variacoes()["quantidade"]
+	if v.Absoluta != 1 || v.Percentual == nil || *v.Percentual != 50 {
+		t.Errorf("variação inesperada: %+v", v)
+	}
+}
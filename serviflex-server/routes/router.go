@@ -1,89 +1,211 @@
 package routes
 
 import (
+	"servico-api/autorizacao"
 	"servico-api/controllers"
+	"servico-api/storage"
 	"servico-api/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
-// SetupRoutes configura todas as rotas principais da API
-func SetupRoutes(router *gin.Engine) {
+// Políticas de acesso reutilizadas pelas rotas. Admins podem agir sobre qualquer recurso.
+var apenasAdmin = autorizacao.Exigir(autorizacao.Admin)
+
+func proprioUsuario(origem autorizacao.Origem) gin.HandlerFunc {
+	return autorizacao.Exigir(autorizacao.Algum(autorizacao.Admin, autorizacao.Proprio(origem)))
+}
+
+func proprioProfissional(origem autorizacao.Origem) gin.HandlerFunc {
+	return autorizacao.Exigir(autorizacao.Algum(
+		autorizacao.Admin,
+		autorizacao.Todas(autorizacao.Tipo(autorizacao.TipoProfissional), autorizacao.Proprio(origem)),
+	))
+}
+
+func donoEstabelecimento(h *controllers.Handler, origem autorizacao.Origem) gin.HandlerFunc {
+	return autorizacao.Exigir(autorizacao.Algum(
+		autorizacao.Admin,
+		autorizacao.Dono(origem, h.ResponsavelEstabelecimento),
+	))
+}
+
+func donoProcedimento(h *controllers.Handler) gin.HandlerFunc {
+	return autorizacao.Exigir(autorizacao.Algum(
+		autorizacao.Admin,
+		autorizacao.Todas(
+			autorizacao.Tipo(autorizacao.TipoProfissional),
+			autorizacao.Dono(autorizacao.Param("id"), h.ProfissionalDoProcedimento),
+		),
+	))
+}
+
+func donoHorario(h *controllers.Handler) gin.HandlerFunc {
+	return autorizacao.Exigir(autorizacao.Algum(
+		autorizacao.Admin,
+		autorizacao.Dono(autorizacao.Param("id"), h.ProfissionalDoHorario),
+	))
+}
+
+func donoExcecao(h *controllers.Handler) gin.HandlerFunc {
+	return autorizacao.Exigir(autorizacao.Algum(
+		autorizacao.Admin,
+		autorizacao.Dono(autorizacao.Param("id"), h.ProfissionalDaExcecao),
+	))
+}
+
+// participanteAgendamento libera o cliente e o profissional do agendamento
+func participanteAgendamento(h *controllers.Handler) gin.HandlerFunc {
+	return autorizacao.Exigir(autorizacao.Algum(
+		autorizacao.Admin,
+		autorizacao.Dono(autorizacao.Param("id"), h.ClienteDoAgendamento),
+		autorizacao.Dono(autorizacao.Param("id"), h.ProfissionalDoAgendamento),
+	))
+}
+
+func profissionalDoAgendamento(h *controllers.Handler) gin.HandlerFunc {
+	return autorizacao.Exigir(autorizacao.Algum(
+		autorizacao.Admin,
+		autorizacao.Dono(autorizacao.Param("id"), h.ProfissionalDoAgendamento),
+	))
+}
+
+// SetupRoutes configura todas as rotas principais da API sobre os repositórios informados
+func SetupRoutes(router *gin.Engine, repos *storage.Repositorios) {
+	h := controllers.NovoHandler(repos)
 	api := router.Group("/api")
 
-	SetupAuthRoutes(api)
-	SetupClienteRoutes(api)
-	SetupProfissionalRoutes(api)
-	SetupProcedimentoRoutes(api)
-	SetupHorarioRoutes(api)
-	SetupAgendamentoRoutes(api)
-	SetupUploadRoutes(api)
-	SetupEstabelecimentoRoutes(api)
-	SetupAdminRoutes(api) // ADICIONE ESTA LINHA
+	// Rotas públicas
+	SetupAuthRoutes(api, utils.NovoAuth(repos.Usuarios))
 
+	// Demais rotas exigem token de acesso
+	protegidas := api.Group("", utils.AutenticacaoMiddleware())
+	SetupClienteRoutes(protegidas, h)
+	SetupProfissionalRoutes(protegidas, h)
+	SetupProcedimentoRoutes(protegidas, h)
+	SetupHorarioRoutes(protegidas, h)
+	SetupAgendamentoRoutes(protegidas, h)
+	SetupUploadRoutes(protegidas, h)
+	SetupEstabelecimentoRoutes(protegidas, h)
+	SetupAvaliacaoRoutes(protegidas, h)
+	SetupAdminRoutes(protegidas.Group("", apenasAdmin), h)
 }
 
-func SetupAuthRoutes(rg *gin.RouterGroup) {
-	rg.POST("/login", utils.Login)
-	rg.POST("/cadastro", utils.CadastrarUsuario)
+func SetupAuthRoutes(rg *gin.RouterGroup, auth *utils.Auth) {
+	rg.POST("/login", auth.Login)
+	rg.POST("/cadastro", auth.CadastrarUsuario)
 }
 
-func SetupClienteRoutes(rg *gin.RouterGroup) {
-	rg.GET("/agendamentos/cliente/:id", controllers.ListarAgendamentosPorCliente)
-	rg.PUT("/usuarios/:id", controllers.EditarUsuario) // Agora requer ?tipo=clientes|profissionais|admin
+func SetupClienteRoutes(rg *gin.RouterGroup, h *controllers.Handler) {
+	rg.GET("/agendamentos/cliente/:id", proprioUsuario(autorizacao.Param("id")), h.ListarAgendamentosPorCliente)
+	rg.PUT("/usuarios/:id", proprioUsuario(autorizacao.Param("id")), h.EditarUsuario) // Agora requer ?tipo=clientes|profissionais|admin
 }
 
-func SetupEstabelecimentoRoutes(rg *gin.RouterGroup) {
-	rg.POST("/estabelecimentos", controllers.CriarEstabelecimento)
-	rg.PUT("/estabelecimentos/:id", controllers.EditarEstabelecimento)
-	rg.GET("/estabelecimentos", controllers.ListarEstabelecimentos)
-	rg.GET("/estabelecimentos/:id", controllers.BuscarEstabelecimentoPorID)
-	rg.GET("/relatorios/estabelecimento/faturamento/:id", controllers.RelatorioFaturamentoEstabelecimento)
-	rg.GET("/relatorios/avaliacoes/estabelecimento/:id", controllers.RelatorioAvaliacoesPorEstabelecimento)
-	rg.GET("/relatorios/agendamentos/estabelecimento/:id", controllers.RelatorioAgendamentosPorMesEstabelecimento)
-	rg.POST("/estabelecimentos/profissionais/convidar", controllers.ConvidarProfissional)
-	rg.POST("/estabelecimentos/profissionais/notificacao/:id", controllers.AceitarOuRecusarConvite)
-	rg.DELETE("/estabelecimentos/:estId/profissionais/:profId", controllers.RemoverProfissional)
-	rg.GET("/estabelecimentos/:id/profissionais", controllers.ListarProfissionaisDoEstabelecimento)
+func SetupEstabelecimentoRoutes(rg *gin.RouterGroup, h *controllers.Handler) {
+	rg.POST("/estabelecimentos", h.CriarEstabelecimento)
+	rg.PUT("/estabelecimentos/:id", donoEstabelecimento(h, autorizacao.Param("id")), h.EditarEstabelecimento)
+	rg.GET("/estabelecimentos", h.ListarEstabelecimentos)
+	rg.GET("/estabelecimentos/:id", h.BuscarEstabelecimentoPorID)
+	rg.GET("/relatorios/estabelecimento/faturamento/:id", donoEstabelecimento(h, autorizacao.Param("id")), h.RelatorioFaturamentoEstabelecimento)
+	rg.GET("/relatorios/avaliacoes/estabelecimento/:id", h.RelatorioAvaliacoesPorEstabelecimento)
+	rg.GET("/relatorios/agendamentos/estabelecimento/:id", donoEstabelecimento(h, autorizacao.Param("id")), h.RelatorioAgendamentosPorMesEstabelecimento)
+	rg.GET("/relatorios/ocupacao/estabelecimento/:id", donoEstabelecimento(h, autorizacao.Param("id")), h.RelatorioOcupacaoEstabelecimento)
+	rg.GET("/relatorios/ociosidade/estabelecimento/:id", donoEstabelecimento(h, autorizacao.Param("id")), h.RelatorioOciosidadeEstabelecimento)
+	rg.POST("/estabelecimentos/profissionais/convidar", donoEstabelecimento(h, autorizacao.CampoJSON("estabelecimento_id")), h.ConvidarProfissional)
+	rg.POST("/estabelecimentos/profissionais/notificacao/:id", autorizacao.Exigir(autorizacao.Dono(autorizacao.Param("id"), h.DestinatarioNotificacao)), h.AceitarOuRecusarConvite)
+	rg.DELETE("/estabelecimentos/:estId/profissionais/:profId", donoEstabelecimento(h, autorizacao.Param("estId")), h.RemoverProfissional)
+	rg.GET("/estabelecimentos/:id/profissionais", h.ListarProfissionaisDoEstabelecimento)
+
+	// Comissões: o responsável define as regras e vê todos os repasses; cada profissional vê o próprio extrato
+	rg.GET("/estabelecimentos/:id/comissoes", donoEstabelecimento(h, autorizacao.Param("id")), h.ListarComissoes)
+	rg.PUT("/estabelecimentos/:id/comissoes", donoEstabelecimento(h, autorizacao.Param("id")), h.DefinirComissoes)
+	rg.GET("/relatorios/repasses/estabelecimento/:id", donoEstabelecimento(h, autorizacao.Param("id")), h.RelatorioRepassesEstabelecimento)
+	rg.GET("/relatorios/repasses/estabelecimento/:id/profissional/:profId", autorizacao.Exigir(autorizacao.Algum(
+		autorizacao.Admin,
+		autorizacao.Dono(autorizacao.Param("id"), h.ResponsavelEstabelecimento),
+		autorizacao.Proprio(autorizacao.Param("profId")),
+	)), h.RelatorioRepasseProfissional)
 }
 
-func SetupProfissionalRoutes(rg *gin.RouterGroup) {
-	rg.GET("/agendamentos/profissional/:id", controllers.ListarAgendamentosPorProfissional)
-	rg.GET("/horarios/:id", controllers.ListarHorariosPorProfissional)
-	rg.GET("/profissionais/:uid/convites-pendentes", controllers.ListarConvitesPendentes)
-	rg.GET("/profissionais", controllers.ListarProfissionais)         // NOVA ROTA
-	rg.GET("/profissionais/:uid", controllers.BuscarProfissionalPorID) // NOVA ROTA
-	rg.GET("/relatorios/profissional/faturamento/:id", controllers.RelatorioFaturamentoProfissional)
-	rg.GET("/relatorios/avaliacoes/profissional/:id", controllers.RelatorioAvaliacoesPorProfissional)
-	rg.GET("/relatorios/agendamentos/profissional/:id", controllers.RelatorioAgendamentosPorMesProfissional)
+func SetupProfissionalRoutes(rg *gin.RouterGroup, h *controllers.Handler) {
+	rg.GET("/agendamentos/profissional/:id", proprioUsuario(autorizacao.Param("id")), h.ListarAgendamentosPorProfissional)
+	rg.GET("/horarios/:id", h.ListarHorariosPorProfissional)
+	rg.GET("/profissionais/:uid/convites-pendentes", proprioUsuario(autorizacao.Param("uid")), h.ListarConvitesPendentes)
+	rg.GET("/profissionais", h.ListarProfissionais)          // NOVA ROTA
+	rg.GET("/profissionais/:uid", h.BuscarProfissionalPorID) // NOVA ROTA
+	rg.GET("/profissionais/:uid/disponibilidade", h.Disponibilidade)
+	rg.GET("/relatorios/profissional/faturamento/:id", proprioUsuario(autorizacao.Param("id")), h.RelatorioFaturamentoProfissional)
+	rg.GET("/relatorios/avaliacoes/profissional/:id", h.RelatorioAvaliacoesPorProfissional)
+	rg.GET("/relatorios/agendamentos/profissional/:id", proprioUsuario(autorizacao.Param("id")), h.RelatorioAgendamentosPorMesProfissional)
 
 }
 
-func SetupProcedimentoRoutes(rg *gin.RouterGroup) {
-	rg.POST("/procedimentos", controllers.CriarProcedimento)
-	rg.GET("/procedimentos/:id", controllers.ListarProcedimentosPorProfissional)
-	rg.PUT("/procedimentos/:id", controllers.AtualizarProcedimento)
-	rg.DELETE("/procedimentos/:id", controllers.DeletarProcedimento)
+func SetupProcedimentoRoutes(rg *gin.RouterGroup, h *controllers.Handler) {
+	rg.POST("/procedimentos", proprioProfissional(autorizacao.CampoJSON("profissional_id")), h.CriarProcedimento)
+	rg.GET("/procedimentos/:id", h.ListarProcedimentosPorProfissional)
+	rg.PUT("/procedimentos/:id", donoProcedimento(h), proprioProfissional(autorizacao.CampoJSON("profissional_id")), h.AtualizarProcedimento)
+	rg.DELETE("/procedimentos/:id", donoProcedimento(h), h.DeletarProcedimento)
 }
 
-func SetupHorarioRoutes(rg *gin.RouterGroup) {
-	rg.POST("/horarios", controllers.CriarHorario)
-	rg.PUT("/horarios/:id", controllers.EditarHorario)   // NOVA ROTA
-	rg.DELETE("/horarios/:id", controllers.ExcluirHorario) // NOVA ROTA
+func SetupHorarioRoutes(rg *gin.RouterGroup, h *controllers.Handler) {
+	rg.POST("/horarios", proprioProfissional(autorizacao.CampoJSON("profissional_id")), h.CriarHorario)
+	rg.PUT("/horarios/:id", donoHorario(h), h.EditarHorario)     // NOVA ROTA
+	rg.DELETE("/horarios/:id", donoHorario(h), h.ExcluirHorario) // NOVA ROTA
+
+	rg.POST("/horarios/excecoes", proprioProfissional(autorizacao.CampoJSON("profissional_id")), h.CriarExcecaoHorario)
+	rg.GET("/horarios/:id/excecoes", proprioUsuario(autorizacao.Param("id")), h.ListarExcecoesHorario) // clientes veem só a disponibilidade
+	rg.PUT("/horarios/excecoes/:id", donoExcecao(h), h.EditarExcecaoHorario)
+	rg.DELETE("/horarios/excecoes/:id", donoExcecao(h), h.ExcluirExcecaoHorario)
 }
 
-func SetupUploadRoutes(rg *gin.RouterGroup) {
-	rg.PUT("/upload/:tipo/:id", controllers.SetImagemURL) // tipo = profissional | procedimento
+func SetupUploadRoutes(rg *gin.RouterGroup, h *controllers.Handler) {
+	rg.PUT("/upload/:tipo/:id", autorizacao.Exigir(autorizacao.Algum(
+		autorizacao.Admin,
+		autorizacao.ConformeParam("tipo", map[string]autorizacao.Regra{
+			"profissional": autorizacao.Proprio(autorizacao.Param("id")),
+			"procedimento": autorizacao.Dono(autorizacao.Param("id"), h.ProfissionalDoProcedimento),
+		}),
+	)), h.SetImagemURL) // tipo = profissional | procedimento
 }
 
-func SetupAgendamentoRoutes(rg *gin.RouterGroup) {
-	rg.POST("/agendamentos", controllers.AgendarHorario)
+func SetupAgendamentoRoutes(rg *gin.RouterGroup, h *controllers.Handler) {
+	rg.POST("/agendamentos", autorizacao.Exigir(autorizacao.Algum(
+		autorizacao.Admin,
+		autorizacao.Todas(autorizacao.Tipo(autorizacao.TipoCliente), autorizacao.Proprio(autorizacao.CampoJSON("cliente_id"))),
+	)), h.AgendarHorario)
+	rg.GET("/agendamentos/:id", participanteAgendamento(h), h.BuscarAgendamento)
+	rg.POST("/agendamentos/:id/confirmar", profissionalDoAgendamento(h), h.ConfirmarAgendamento)
+	rg.POST("/agendamentos/:id/concluir", profissionalDoAgendamento(h), h.ConcluirAgendamento)
+	rg.POST("/agendamentos/:id/nao-compareceu", profissionalDoAgendamento(h), h.MarcarNaoComparecimento)
+	rg.POST("/agendamentos/:id/cancelar", participanteAgendamento(h), h.CancelarAgendamento)
+	rg.POST("/agendamentos/:id/reagendar", participanteAgendamento(h), h.ReagendarAgendamento)
 }
 
-func SetupAdminRoutes(rg *gin.RouterGroup) {
-	rg.GET("/admins", controllers.ListarAdmins)
-	rg.GET("/admins/:id", controllers.BuscarAdminPorID)
-	rg.POST("/admins", controllers.CriarAdmin)
-	rg.PUT("/admins/:id", controllers.EditarAdmin)
-	rg.DELETE("/admins/:id", controllers.ExcluirAdmin)
+// SetupAvaliacaoRoutes registra as avaliações, que só o cliente do agendamento escreve e
+// só o profissional ou o responsável pelo estabelecimento respondem. Qualquer usuário denuncia.
+func SetupAvaliacaoRoutes(rg *gin.RouterGroup, h *controllers.Handler) {
+	rg.POST("/avaliacoes", autorizacao.Exigir(autorizacao.Todas(
+		autorizacao.Tipo(autorizacao.TipoCliente),
+		autorizacao.Dono(autorizacao.CampoJSON("agendamento_id"), h.ClienteDoAgendamento),
+	)), h.CriarAvaliacao)
+	rg.PUT("/avaliacoes/:id", autorizacao.Exigir(autorizacao.Dono(autorizacao.Param("id"), h.ClienteDaAvaliacao)), h.EditarAvaliacao)
+	rg.POST("/avaliacoes/:id/resposta", autorizacao.Exigir(autorizacao.Algum(
+		autorizacao.Dono(autorizacao.Param("id"), h.ProfissionalDaAvaliacao),
+		autorizacao.Dono(autorizacao.Param("id"), h.ResponsavelDaAvaliacao),
+	)), h.ResponderAvaliacao)
+	rg.POST("/avaliacoes/:id/denunciar", h.DenunciarAvaliacao)
+}
+
+// SetupAdminRoutes registra as rotas de administração; o grupo recebido já exige tipo admin
+func SetupAdminRoutes(rg *gin.RouterGroup, h *controllers.Handler) {
+	rg.GET("/admins", h.ListarAdmins)
+	rg.GET("/admins/:id", h.BuscarAdminPorID)
+	rg.POST("/admins", h.CriarAdmin)
+	rg.PUT("/admins/:id", h.EditarAdmin)
+	rg.DELETE("/admins/:id", h.ExcluirAdmin)
+
+	rg.GET("/moderacao/avaliacoes", h.ListarModeracaoAvaliacoes)
+	rg.POST("/moderacao/avaliacoes/:id/ocultar", h.OcultarAvaliacao)
+	rg.POST("/moderacao/avaliacoes/:id/restaurar", h.RestaurarAvaliacao)
+	rg.DELETE("/moderacao/avaliacoes/:id", h.ExcluirAvaliacao)
 }
@@ -0,0 +1,1596 @@
+package routes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"servico-api/agenda"
+	"servico-api/config"
+	"servico-api/controllers"
+	"servico-api/exportacao"
+	"servico-api/models"
+	"servico-api/relatorios"
+	"servico-api/storage"
+	"servico-api/storage/memoria"
+	"servico-api/utils"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IDs dos registros criados por novoAmbiente
+const (
+	clienteID        = "cli-1"
+	profissionalID   = "prof-1"
+	outroProfID      = "prof-2"
+	adminID          = "adm-1"
+	estabID          = "est-1"
+	procedimentoID   = "proc-1"
+	horarioID        = "hor-1"
+	agendamentoID    = "ag-1"
+	notificacaoID    = "notif-1"
+	excecaoID        = "exc-1"
+	avaliacaoID      = "av-1" // avaliação anterior à API, sem agendamento
+	senhaPadrao      = "123456"
+	emailCliente     = "joao@cliente.com"
+	emailAdmin       = "admin@serviflex.com"
+	nomeProcedimento = "Corte"
+)
+
+var senhaHash string
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	config.JWTSecret = []byte("segredo-de-teste")
+
+	var err error
+	if senhaHash, err = utils.HashSenha(senhaPadrao); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// proximaSegunda devolve a próxima segunda-feira às 10h no fuso do estabelecimento do
+// cenário, que não define fuso e usa models.FusoPadrao
+func proximaSegunda() time.Time {
+	return proximaSegundaEm(fuso(""))
+}
+
+// proximaSegundaEm devolve a próxima segunda-feira às 10h no fuso informado
+func proximaSegundaEm(loc *time.Location) time.Time {
+	d := time.Now().In(loc).AddDate(0, 0, 1)
+	for d.Weekday() != time.Monday {
+		d = d.AddDate(0, 0, 1)
+	}
+	return time.Date(d.Year(), d.Month(), d.Day(), 10, 0, 0, 0, loc)
+}
+
+// novoAmbiente cria a API sobre repositórios em memória populados com um cenário básico
+func novoAmbiente(t *testing.T) (*gin.Engine, *storage.Repositorios) {
+	t.Helper()
+	ctx := context.Background()
+	repos := memoria.NovosRepositorios()
+	agora := time.Now()
+
+	deve := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("erro ao popular repositórios: %v", err)
+		}
+	}
+
+	deve(repos.Usuarios.SalvarCliente(ctx, models.Cliente{ID: clienteID, Nome: "João Cliente", Email: emailCliente, Senha: senhaHash, CriadoEm: agora}))
+	deve(repos.Usuarios.SalvarProfissional(ctx, models.Profissional{ID: profissionalID, Nome: "Maria Silva", Email: "maria@exemplo.com", Senha: senhaHash, EstabelecimentoID: estabID, CriadoEm: agora}))
+	deve(repos.Usuarios.SalvarProfissional(ctx, models.Profissional{ID: outroProfID, Nome: "Ana Souza", Email: "ana@exemplo.com", Senha: senhaHash, CriadoEm: agora}))
+	deve(repos.Usuarios.SalvarAdmin(ctx, models.Admin{ID: adminID, Nome: "Admin", Email: emailAdmin, Senha: senhaHash, Tipo: "admin", CriadoEm: agora}))
+	deve(repos.Estabelecimentos.Salvar(ctx, models.Estabelecimento{
+		ID:             estabID,
+		Nome:           "Studio da Beleza",
+		Categoria:      "Beleza",
+		Localizacao:    models.Endereco{Endereco: "Rua das Flores, 123", Cidade: "Uberlândia", UF: "MG"},
+		CriadoEm:       agora,
+		ResponsavelUID: profissionalID,
+	}))
+	deve(repos.Estabelecimentos.VincularProfissional(ctx, estabID, models.ProfissionalEstabelecimento{UID: profissionalID, Status: "ativo", AdicionadoEm: agora}))
+	deve(repos.Procedimentos.Salvar(ctx, models.Procedimento{ID: procedimentoID, ProfissionalID: profissionalID, Nome: nomeProcedimento, Preco: 50, DuracaoMin: 30}))
+	deve(repos.Horarios.Salvar(ctx, models.Horario{ID: horarioID, ProfissionalID: profissionalID, DiaSemana: models.Segunda, HoraInicio: "08:00", HoraFim: "18:00", Disponivel: true}))
+	deve(repos.Agendamentos.Criar(ctx, models.Agendamento{
+		ID:                agendamentoID,
+		ClienteID:         clienteID,
+		ProfissionalID:    profissionalID,
+		EstabelecimentoID: estabID,
+		ProcedimentoID:    procedimentoID,
+		Procedimento:      nomeProcedimento,
+		Preco:             50,
+		DataHora:          agora.AddDate(0, 0, -7),
+		Status:            models.StatusConcluido,
+	}))
+	deve(repos.Notificacoes.Criar(ctx, models.Notificacao{
+		ID:                notificacaoID,
+		ParaUID:           outroProfID,
+		Tipo:              "convite_estabelecimento",
+		Mensagem:          "Você foi convidado para o estabelecimento",
+		EstabelecimentoID: estabID,
+		CriadoEm:          agora,
+	}))
+	deve(repos.Avaliacoes.Criar(ctx, models.Avaliacao{
+		ID:                avaliacaoID,
+		ProfissionalID:    profissionalID,
+		ClienteID:         clienteID,
+		EstabelecimentoID: estabID,
+		Nota:              4,
+		Data:              agora,
+	}))
+
+	r := gin.New()
+	SetupRoutes(r, repos)
+	return r, repos
+}
+
+// quem identifica o chamador de um caso de teste; UID vazio significa requisição sem token
+type quem struct {
+	UID  string
+	Tipo string
+}
+
+var (
+	anonimo      = quem{}
+	cliente      = quem{clienteID, "clientes"}
+	profissional = quem{profissionalID, "profissionais"}
+	outroProf    = quem{outroProfID, "profissionais"}
+	admin        = quem{adminID, "admin"}
+)
+
+func requisicao(t *testing.T, r http.Handler, metodo, url string, chamador quem, corpo interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	var leitor *bytes.Reader
+	if corpo != nil {
+		dados, err := json.Marshal(corpo)
+		if err != nil {
+			t.Fatalf("erro ao serializar corpo: %v", err)
+		}
+		leitor = bytes.NewReader(dados)
+	} else {
+		leitor = bytes.NewReader(nil)
+	}
+
+	req := httptest.NewRequest(metodo, url, leitor)
+	req.Header.Set("Content-Type", "application/json")
+	if chamador.UID != "" {
+		token, err := utils.GerarToken(chamador.UID, chamador.Tipo)
+		if err != nil {
+			t.Fatalf("erro ao gerar token: %v", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+type casoRota struct {
+	nome      string
+	metodo    string
+	rota      string // padrão registrado no gin, usado para checar a cobertura
+	url       string
+	chamador  quem
+	corpo     interface{}
+	status    int
+	preparar  func(t *testing.T, repos *storage.Repositorios) // ajustes no cenário antes da requisição
+	verificar func(t *testing.T, w *httptest.ResponseRecorder, repos *storage.Repositorios)
+}
+
+func casosRotas() []casoRota {
+	agendar := map[string]interface{}{
+		"cliente_id":         clienteID,
+		"profissional_id":    profissionalID,
+		"estabelecimento_id": estabID,
+		"procedimento":       nomeProcedimento,
+		"data_hora":          proximaSegunda(),
+	}
+	procedimento := map[string]interface{}{"profissional_id": profissionalID, "nome": "Barba", "preco": 30, "duracao_min": 20}
+	estabelecimento := map[string]interface{}{"nome": "Novo Studio", "localizacao": map[string]string{"cidade": "Uberlândia"}}
+	avaliar := map[string]interface{}{"agendamento_id": agendamentoID, "nota": 5, "comentario": "Ótimo atendimento"}
+	denunciar := map[string]string{"motivo": "Linguagem ofensiva"}
+	moderar := map[string]string{"motivo": "Conteúdo ofensivo"}
+
+	return []casoRota{
+		// Autenticação
+		{nome: "login válido", metodo: "POST", rota: "/api/login", url: "/api/login", chamador: anonimo,
+			corpo: map[string]string{"email": emailCliente, "senha": senhaPadrao}, status: http.StatusOK,
+			verificar: func(t *testing.T, w *httptest.ResponseRecorder, _ *storage.Repositorios) {
+				var resp struct {
+					Token   string                 `json:"token"`
+					Usuario map[string]interface{} `json:"usuario"`
+				}
+				decodificar(t, w, &resp)
+				claims, err := utils.ValidarToken(resp.Token)
+				if err != nil || claims.UID != clienteID || claims.Tipo != "clientes" {
+					t.Fatalf("token inválido: %+v, %v", claims, err)
+				}
+				if _, ok := resp.Usuario["senha"]; ok {
+					t.Fatal("resposta de login não deve conter a senha")
+				}
+			}},
+		{nome: "login com senha errada", metodo: "POST", rota: "/api/login", url: "/api/login", chamador: anonimo,
+			corpo: map[string]string{"email": emailCliente, "senha": "errada"}, status: http.StatusUnauthorized},
+		{nome: "cadastro de cliente", metodo: "POST", rota: "/api/cadastro", url: "/api/cadastro", chamador: anonimo,
+			corpo: map[string]string{"nome": "Novo", "email": "novo@cliente.com", "senha": "abc123", "tipo": "clientes"}, status: http.StatusCreated,
+			verificar: func(t *testing.T, _ *httptest.ResponseRecorder, repos *storage.Repositorios) {
+				u, err := repos.Usuarios.BuscarPorEmail(context.Background(), "clientes", "novo@cliente.com")
+				if err != nil || !utils.VerificarSenha(u.Senha, "abc123") {
+					t.Fatalf("senha não foi salva com hash: %v", err)
+				}
+			}},
+		{nome: "cadastro de profissional não escolhe estabelecimento", metodo: "POST", rota: "/api/cadastro", url: "/api/cadastro", chamador: anonimo,
+			corpo: map[string]string{"nome": "Nova", "email": "nova@exemplo.com", "senha": "abc123", "tipo": "profissionais", "estabelecimentoId": estabID}, status: http.StatusCreated,
+			verificar: func(t *testing.T, _ *httptest.ResponseRecorder, repos *storage.Repositorios) {
+				u, err := repos.Usuarios.BuscarPorEmail(context.Background(), "profissionais", "nova@exemplo.com")
+				if err != nil || u.EstabelecimentoID != "" {
+					t.Fatalf("profissional se vinculou no cadastro: %+v, %v", u, err)
+				}
+			}},
+		{nome: "cadastro com email repetido", metodo: "POST", rota: "/api/cadastro", url: "/api/cadastro", chamador: anonimo,
+			corpo: map[string]string{"nome": "João", "email": emailCliente, "senha": "abc123", "tipo": "clientes"}, status: http.StatusConflict},
+		{nome: "cadastro de admin é recusado", metodo: "POST", rota: "/api/cadastro", url: "/api/cadastro", chamador: anonimo,
+			corpo: map[string]string{"nome": "X", "email": "x@x.com", "senha": "abc123", "tipo": "admin"}, status: http.StatusBadRequest},
+
+		// Cliente
+		{nome: "agendamentos do cliente", metodo: "GET", rota: "/api/agendamentos/cliente/:id", url: "/api/agendamentos/cliente/" + clienteID, chamador: cliente, status: http.StatusOK,
+			verificar: esperarTamanho(1)},
+		{nome: "agendamentos do cliente em XLSX", metodo: "GET", rota: "/api/agendamentos/cliente/:id", url: "/api/agendamentos/cliente/" + clienteID + "?formato=xlsx", chamador: cliente, status: http.StatusOK,
+			verificar: func(t *testing.T, w *httptest.ResponseRecorder, _ *storage.Repositorios) {
+				if w.Header().Get("Content-Type") != exportacao.FormatoXLSX.TipoConteudo() || !strings.HasPrefix(w.Body.String(), "PK") {
+					t.Fatalf("planilha inesperada: %v", w.Header())
+				}
+			}},
+		{nome: "agendamentos do cliente no fuso em que foram marcados", metodo: "GET", rota: "/api/agendamentos/cliente/:id", url: "/api/agendamentos/cliente/" + clienteID, chamador: cliente, status: http.StatusOK,
+			preparar: func(t *testing.T, repos *storage.Repositorios) {
+				comFuso("Asia/Tokyo")(t, repos)
+				// O profissional deixa o estabelecimento depois do atendimento
+				ctx := context.Background()
+				repos.Estabelecimentos.DesvincularProfissional(ctx, estabID, profissionalID)
+				repos.Usuarios.DefinirEstabelecimentoProfissional(ctx, profissionalID, "")
+			},
+			verificar: func(t *testing.T, w *httptest.ResponseRecorder, _ *storage.Repositorios) {
+				var lista []models.Agendamento
+				decodificar(t, w, &lista)
+				if _, offset := lista[0].DataHora.Zone(); offset != 9*60*60 {
+					t.Fatalf("horário não exibido no fuso do estabelecimento do agendamento: %v", lista[0].DataHora)
+				}
+			}},
+		{nome: "agendamentos de outro cliente", metodo: "GET", rota: "/api/agendamentos/cliente/:id", url: "/api/agendamentos/cliente/" + clienteID, chamador: outroProf, status: http.StatusForbidden},
+		{nome: "agendamentos sem token", metodo: "GET", rota: "/api/agendamentos/cliente/:id", url: "/api/agendamentos/cliente/" + clienteID, chamador: anonimo, status: http.StatusUnauthorized},
+		{nome: "editar o próprio usuário", metodo: "PUT", rota: "/api/usuarios/:id", url: "/api/usuarios/" + clienteID + "?tipo=clientes", chamador: cliente,
+			corpo: map[string]string{"nome": "João Editado", "email": emailCliente}, status: http.StatusOK,
+			verificar: func(t *testing.T, _ *httptest.ResponseRecorder, repos *storage.Repositorios) {
+				cl, _ := repos.Usuarios.BuscarCliente(context.Background(), clienteID)
+				if cl.Nome != "João Editado" || !utils.VerificarSenha(cl.Senha, senhaPadrao) {
+					t.Fatalf("cliente não atualizado corretamente: %+v", cl)
+				}
+			}},
+		{nome: "profissional não troca o próprio estabelecimento", metodo: "PUT", rota: "/api/usuarios/:id", url: "/api/usuarios/" + outroProfID + "?tipo=profissionais", chamador: outroProf,
+			corpo: map[string]string{"nome": "Ana Souza", "email": "ana@exemplo.com", "estabelecimentoId": estabID}, status: http.StatusOK,
+			verificar: func(t *testing.T, _ *httptest.ResponseRecorder, repos *storage.Repositorios) {
+				p, _ := repos.Usuarios.BuscarProfissional(context.Background(), outroProfID)
+				if p.EstabelecimentoID != "" {
+					t.Fatalf("profissional se vinculou sem convite: %+v", p)
+				}
+			}},
+		{nome: "editar perfil sem estabelecimento mantém o vínculo", metodo: "PUT", rota: "/api/usuarios/:id", url: "/api/usuarios/" + profissionalID + "?tipo=profissionais", chamador: profissional,
+			corpo: map[string]string{"nome": "Maria Editada", "email": "maria@exemplo.com"}, status: http.StatusOK,
+			verificar: func(t *testing.T, _ *httptest.ResponseRecorder, repos *storage.Repositorios) {
+				p, _ := repos.Usuarios.BuscarProfissional(context.Background(), profissionalID)
+				if p.Nome != "Maria Editada" || p.EstabelecimentoID != estabID {
+					t.Fatalf("profissional não atualizado corretamente: %+v", p)
+				}
+			}},
+		{nome: "editar outro usuário", metodo: "PUT", rota: "/api/usuarios/:id", url: "/api/usuarios/" + profissionalID + "?tipo=profissionais", chamador: cliente,
+			corpo: map[string]string{"nome": "Invasor"}, status: http.StatusForbidden},
+
+		// Estabelecimentos
+		{nome: "criar estabelecimento", metodo: "POST", rota: "/api/estabelecimentos", url: "/api/estabelecimentos", chamador: outroProf,
+			corpo: estabelecimento, status: http.StatusCreated},
+		{nome: "editar estabelecimento como responsável", metodo: "PUT", rota: "/api/estabelecimentos/:id", url: "/api/estabelecimentos/" + estabID, chamador: profissional,
+			corpo: estabelecimento, status: http.StatusOK,
+			verificar: func(t *testing.T, _ *httptest.ResponseRecorder, repos *storage.Repositorios) {
+				e, _ := repos.Estabelecimentos.Buscar(context.Background(), estabID)
+				if e.Nome != "Novo Studio" || e.ResponsavelUID != profissionalID {
+					t.Fatalf("estabelecimento não atualizado corretamente: %+v", e)
+				}
+			}},
+		{nome: "editar estabelecimento sem fuso e política mantém os atuais", metodo: "PUT", rota: "/api/estabelecimentos/:id", url: "/api/estabelecimentos/" + estabID, chamador: profissional,
+			corpo: estabelecimento, status: http.StatusOK,
+			preparar: func(t *testing.T, repos *storage.Repositorios) {
+				comFuso("America/New_York")(t, repos)
+				e, _ := repos.Estabelecimentos.Buscar(context.Background(), estabID)
+				e.PoliticaCancelamento = &models.PoliticaCancelamento{AntecedenciaHoras: 24, PercentualTardio: 50}
+				repos.Estabelecimentos.Salvar(context.Background(), *e)
+			},
+			verificar: func(t *testing.T, _ *httptest.ResponseRecorder, repos *storage.Repositorios) {
+				e, _ := repos.Estabelecimentos.Buscar(context.Background(), estabID)
+				if e.Nome != "Novo Studio" || e.FusoHorario != "America/New_York" || e.PoliticaCancelamento == nil || e.PoliticaCancelamento.PercentualTardio != 50 {
+					t.Fatalf("edição descartou fuso ou política: %+v", e)
+				}
+			}},
+		{nome: "criar estabelecimento com política de cancelamento inválida", metodo: "POST", rota: "/api/estabelecimentos", url: "/api/estabelecimentos", chamador: outroProf,
+			corpo: comCampo(estabelecimento, "politica_cancelamento", map[string]interface{}{"antecedencia_horas": 24, "percentual_tardio": 150}), status: http.StatusBadRequest},
+		{nome: "criar estabelecimento com fuso inválido", metodo: "POST", rota: "/api/estabelecimentos", url: "/api/estabelecimentos", chamador: outroProf,
+			corpo: comCampo(estabelecimento, "fuso_horario", "America/Atlantida"), status: http.StatusBadRequest},
+		{nome: "editar estabelecimento de outro", metodo: "PUT", rota: "/api/estabelecimentos/:id", url: "/api/estabelecimentos/" + estabID, chamador: outroProf,
+			corpo: estabelecimento, status: http.StatusForbidden},
+		{nome: "editar estabelecimento como admin", metodo: "PUT", rota: "/api/estabelecimentos/:id", url: "/api/estabelecimentos/" + estabID, chamador: admin,
+			corpo: estabelecimento, status: http.StatusOK},
+		{nome: "editar estabelecimento inexistente", metodo: "PUT", rota: "/api/estabelecimentos/:id", url: "/api/estabelecimentos/nao-existe", chamador: profissional,
+			corpo: estabelecimento, status: http.StatusNotFound},
+		{nome: "listar estabelecimentos", metodo: "GET", rota: "/api/estabelecimentos", url: "/api/estabelecimentos", chamador: cliente, status: http.StatusOK,
+			verificar: esperarTamanho(1)},
+		{nome: "buscar estabelecimento", metodo: "GET", rota: "/api/estabelecimentos/:id", url: "/api/estabelecimentos/" + estabID, chamador: cliente, status: http.StatusOK},
+		{nome: "buscar estabelecimento inexistente", metodo: "GET", rota: "/api/estabelecimentos/:id", url: "/api/estabelecimentos/nao-existe", chamador: cliente, status: http.StatusNotFound},
+		{nome: "faturamento do estabelecimento", metodo: "GET", rota: "/api/relatorios/estabelecimento/faturamento/:id", url: "/api/relatorios/estabelecimento/faturamento/" + estabID, chamador: profissional, status: http.StatusOK,
+			verificar: esperarCampo("total_faturado", 50.0)},
+		{nome: "faturamento ignora agendamentos não concluídos", metodo: "GET", rota: "/api/relatorios/estabelecimento/faturamento/:id", url: "/api/relatorios/estabelecimento/faturamento/" + estabID, chamador: profissional, status: http.StatusOK,
+			preparar:  ajustarAgendamento(models.StatusCancelado, time.Now().AddDate(0, 0, -7)),
+			verificar: esperarCampo("total_faturado", 0.0)},
+		{nome: "faturamento do estabelecimento com taxa de cancelamento", metodo: "GET", rota: "/api/relatorios/estabelecimento/faturamento/:id", url: "/api/relatorios/estabelecimento/faturamento/" + estabID, chamador: profissional, status: http.StatusOK,
+			preparar: func(t *testing.T, repos *storage.Repositorios) {
+				repos.Agendamentos.Criar(context.Background(), models.Agendamento{
+					ID: "ag-cancelado", ClienteID: clienteID, ProfissionalID: profissionalID, EstabelecimentoID: estabID,
+					Procedimento: nomeProcedimento, DataHora: time.Now().AddDate(0, 0, -1), Status: models.StatusCancelado,
+					Taxa: &models.TaxaCancelamento{Motivo: models.TaxaCancelamentoTardio, Percentual: 50, Valor: 25},
+				})
+			},
+			verificar: func(t *testing.T, w *httptest.ResponseRecorder, _ *storage.Repositorios) {
+				var resumo struct {
+					Servicos float64 `json:"total_servicos"`
+					Taxas    float64 `json:"total_taxas"`
+					Total    float64 `json:"total_faturado"`
+				}
+				decodificar(t, w, &resumo)
+				if resumo.Servicos != 50 || resumo.Taxas != 25 || resumo.Total != 75 {
+					t.Fatalf("faturamento inesperado: %+v", resumo)
+				}
+			}},
+		{nome: "faturamento do estabelecimento por terceiro", metodo: "GET", rota: "/api/relatorios/estabelecimento/faturamento/:id", url: "/api/relatorios/estabelecimento/faturamento/" + estabID, chamador: cliente, status: http.StatusForbidden},
+		{nome: "avaliações do estabelecimento", metodo: "GET", rota: "/api/relatorios/avaliacoes/estabelecimento/:id", url: "/api/relatorios/avaliacoes/estabelecimento/" + estabID, chamador: cliente, status: http.StatusOK,
+			verificar: esperarCampo("media_nota", 4.0)},
+		{nome: "agendamentos por mês do estabelecimento", metodo: "GET", rota: "/api/relatorios/agendamentos/estabelecimento/:id", url: "/api/relatorios/agendamentos/estabelecimento/" + estabID, chamador: profissional, status: http.StatusOK},
+		{nome: "agendamentos por mês no fuso do estabelecimento", metodo: "GET", rota: "/api/relatorios/agendamentos/estabelecimento/:id", url: "/api/relatorios/agendamentos/estabelecimento/" + estabID, chamador: profissional, status: http.StatusOK,
+			preparar: func(t *testing.T, repos *storage.Repositorios) {
+				comFuso("Asia/Tokyo")(t, repos)
+				// 1h antes da virada do mês em Tóquio: ainda é o mês anterior lá, mas já é o mês atual em UTC
+				ajustarAgendamento(models.StatusConcluido, inicioDoMes("Asia/Tokyo").Add(-time.Hour))(t, repos)
+			},
+			verificar: func(t *testing.T, w *httptest.ResponseRecorder, _ *storage.Repositorios) {
+				var corpo struct {
+					PorMes map[string]int `json:"agendamentos_por_mes"`
+				}
+				decodificar(t, w, &corpo)
+				inicio := inicioDoMes("Asia/Tokyo")
+				anterior, atual := relatorios.AgruparMes.Chave(inicio.AddDate(0, -1, 0)), relatorios.AgruparMes.Chave(inicio)
+				if corpo.PorMes[anterior] != 1 || corpo.PorMes[atual] != 0 {
+					t.Fatalf("agendamento contado no mês errado: %v", corpo.PorMes)
+				}
+			}},
+		{nome: "faturamento por procedimento comparado ao período anterior", metodo: "GET", rota: "/api/relatorios/estabelecimento/faturamento/:id", chamador: profissional, status: http.StatusOK,
+			url: "/api/relatorios/estabelecimento/faturamento/" + estabID + "?de=" + diaLocal(-10) + "&ate=" + diaLocal(0) + "&agrupar=procedimento&comparar=periodo_anterior",
+			verificar: func(t *testing.T, w *httptest.ResponseRecorder, _ *storage.Repositorios) {
+				var corpo struct {
+					Serie []struct {
+						Chave  string  `json:"chave"`
+						Rotulo string  `json:"rotulo"`
+						Total  float64 `json:"total_faturado"`
+					} `json:"serie"`
+					Comparacao struct {
+						Variacao map[string]struct {
+							Absoluta   float64  `json:"absoluta"`
+							Percentual *float64 `json:"percentual"`
+						} `json:"variacao"`
+					} `json:"comparacao"`
+				}
+				decodificar(t, w, &corpo)
+				if len(corpo.Serie) != 1 || corpo.Serie[0].Chave != procedimentoID || corpo.Serie[0].Rotulo != nomeProcedimento || corpo.Serie[0].Total != 50 {
+					t.Fatalf("série por procedimento inesperada: %s", w.Body.String())
+				}
+				if v := corpo.Comparacao.Variacao["total_faturado"]; v.Absoluta != 50 || v.Percentual != nil {
+					t.Fatalf("variação inesperada: %s", w.Body.String())
+				}
+			}},
+		{nome: "faturamento fora do período", metodo: "GET", rota: "/api/relatorios/estabelecimento/faturamento/:id", chamador: profissional, status: http.StatusOK,
+			url:       "/api/relatorios/estabelecimento/faturamento/" + estabID + "?de=" + diaLocal(-3),
+			verificar: esperarCampo("total_faturado", 0.0)},
+		{nome: "agendamentos por dia", metodo: "GET", rota: "/api/relatorios/agendamentos/estabelecimento/:id", chamador: profissional, status: http.StatusOK,
+			url: "/api/relatorios/agendamentos/estabelecimento/" + estabID + "?de=" + diaLocal(-7) + "&ate=" + diaLocal(0) + "&agrupar=dia",
+			verificar: func(t *testing.T, w *httptest.ResponseRecorder, _ *storage.Repositorios) {
+				var corpo struct {
+					Serie []struct {
+						Chave      string  `json:"chave"`
+						Quantidade float64 `json:"quantidade_agendamentos"`
+					} `json:"serie"`
+				}
+				decodificar(t, w, &corpo)
+				if len(corpo.Serie) != 8 || corpo.Serie[0].Chave != diaLocal(-7) || corpo.Serie[0].Quantidade != 1 {
+					t.Fatalf("série diária inesperada: %s", w.Body.String())
+				}
+			}},
+		{nome: "relatório com agrupamento inválido", metodo: "GET", rota: "/api/relatorios/agendamentos/estabelecimento/:id", chamador: profissional, status: http.StatusBadRequest,
+			url: "/api/relatorios/agendamentos/estabelecimento/" + estabID + "?agrupar=ano"},
+		{nome: "comparação sem data inicial", metodo: "GET", rota: "/api/relatorios/estabelecimento/faturamento/:id", chamador: profissional, status: http.StatusBadRequest,
+			url: "/api/relatorios/estabelecimento/faturamento/" + estabID + "?comparar=periodo_anterior"},
+		{nome: "avaliações por procedimento", metodo: "GET", rota: "/api/relatorios/avaliacoes/estabelecimento/:id", chamador: cliente, status: http.StatusBadRequest,
+			url: "/api/relatorios/avaliacoes/estabelecimento/" + estabID + "?agrupar=procedimento"},
+		{nome: "faturamento exportado em CSV", metodo: "GET", rota: "/api/relatorios/estabelecimento/faturamento/:id", chamador: profissional, status: http.StatusOK,
+			url: "/api/relatorios/estabelecimento/faturamento/" + estabID + "?agrupar=procedimento&formato=csv",
+			verificar: func(t *testing.T, w *httptest.ResponseRecorder, _ *storage.Repositorios) {
+				if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/csv") || !strings.Contains(w.Header().Get("Content-Disposition"), "faturamento-"+estabID+".csv") {
+					t.Fatalf("cabeçalhos inesperados: %v", w.Header())
+				}
+				linhas := strings.Split(strings.TrimPrefix(w.Body.String(), "\ufeff"), "\n")
+				if linhas[0] != "Procedimento;Agendamentos;Serviços (R$);Taxas cobradas;Total em taxas (R$);Total faturado (R$)" ||
+					!strings.HasPrefix(linhas[1], nomeProcedimento+";1;50,00") || !strings.HasPrefix(linhas[2], "Total;") {
+					t.Fatalf("CSV inesperado:\n%s", w.Body.String())
+				}
+			}},
+		{nome: "relatório com formato inválido", metodo: "GET", rota: "/api/relatorios/agendamentos/profissional/:id", chamador: profissional, status: http.StatusBadRequest,
+			url: "/api/relatorios/agendamentos/profissional/" + profissionalID + "?formato=doc"},
+		// Comissões
+		{nome: "definir comissões", metodo: "PUT", rota: "/api/estabelecimentos/:id/comissoes", url: "/api/estabelecimentos/" + estabID + "/comissoes", chamador: profissional,
+			corpo: map[string]interface{}{"regras": []map[string]interface{}{
+				{"tipo": "percentual", "percentual": 40},
+				{"profissional_id": profissionalID, "procedimento_id": procedimentoID, "tipo": "fixa", "valor": 20},
+			}}, status: http.StatusOK,
+			verificar: func(t *testing.T, _ *httptest.ResponseRecorder, repos *storage.Repositorios) {
+				e, _ := repos.Estabelecimentos.Buscar(context.Background(), estabID)
+				if len(e.Comissoes) != 2 || e.Comissoes[1].Valor != 20 {
+					t.Fatalf("regras não salvas: %+v", e.Comissoes)
+				}
+			}},
+		{nome: "comissão escalonada com faixas fora de ordem", metodo: "PUT", rota: "/api/estabelecimentos/:id/comissoes", url: "/api/estabelecimentos/" + estabID + "/comissoes", chamador: profissional,
+			corpo: map[string]interface{}{"regras": []map[string]interface{}{
+				{"tipo": "escalonada", "faixas": []map[string]float64{{"a_partir_de": 0, "percentual": 30}, {"a_partir_de": 0, "percentual": 50}}},
+			}}, status: http.StatusBadRequest},
+		{nome: "comissão de profissional não vinculado", metodo: "PUT", rota: "/api/estabelecimentos/:id/comissoes", url: "/api/estabelecimentos/" + estabID + "/comissoes", chamador: profissional,
+			corpo: map[string]interface{}{"regras": []map[string]interface{}{{"profissional_id": outroProfID, "tipo": "percentual", "percentual": 50}}}, status: http.StatusBadRequest},
+		{nome: "definir comissões de outro estabelecimento", metodo: "PUT", rota: "/api/estabelecimentos/:id/comissoes", url: "/api/estabelecimentos/" + estabID + "/comissoes", chamador: outroProf,
+			corpo: map[string]interface{}{"regras": []map[string]interface{}{}}, status: http.StatusForbidden},
+		{nome: "listar comissões", metodo: "GET", rota: "/api/estabelecimentos/:id/comissoes", url: "/api/estabelecimentos/" + estabID + "/comissoes", chamador: profissional, status: http.StatusOK,
+			preparar: comRegras(models.RegraComissao{Tipo: models.ComissaoPercentual, Percentual: 40}),
+			verificar: func(t *testing.T, w *httptest.ResponseRecorder, _ *storage.Repositorios) {
+				var corpo struct {
+					Regras []models.RegraComissao `json:"regras"`
+				}
+				decodificar(t, w, &corpo)
+				if len(corpo.Regras) != 1 || corpo.Regras[0].Percentual != 40 {
+					t.Fatalf("regras inesperadas: %s", w.Body.String())
+				}
+			}},
+		{nome: "repasses do estabelecimento", metodo: "GET", rota: "/api/relatorios/repasses/estabelecimento/:id", chamador: profissional, status: http.StatusOK,
+			url:      "/api/relatorios/repasses/estabelecimento/" + estabID + "?de=" + diaLocal(-10),
+			preparar: comRegras(models.RegraComissao{Tipo: models.ComissaoPercentual, Percentual: 40}),
+			verificar: func(t *testing.T, w *httptest.ResponseRecorder, _ *storage.Repositorios) {
+				var corpo struct {
+					Profissionais   []models.ExtratoRepasse `json:"profissionais"`
+					Comissao        float64                 `json:"total_comissao"`
+					Estabelecimento float64                 `json:"total_estabelecimento"`
+				}
+				decodificar(t, w, &corpo)
+				if len(corpo.Profissionais) != 1 || corpo.Profissionais[0].ProfissionalNome != "Maria Silva" || corpo.Comissao != 20 || corpo.Estabelecimento != 30 {
+					t.Fatalf("repasses inesperados: %s", w.Body.String())
+				}
+			}},
+		{nome: "repasses com agrupamento", metodo: "GET", rota: "/api/relatorios/repasses/estabelecimento/:id", chamador: profissional, status: http.StatusBadRequest,
+			url: "/api/relatorios/repasses/estabelecimento/" + estabID + "?agrupar=dia"},
+		{nome: "extrato do profissional com comissão escalonada", metodo: "GET", rota: "/api/relatorios/repasses/estabelecimento/:id/profissional/:profId", chamador: profissional, status: http.StatusOK,
+			url: "/api/relatorios/repasses/estabelecimento/" + estabID + "/profissional/" + profissionalID + "?de=" + diaLocal(-10),
+			preparar: comRegras(models.RegraComissao{Tipo: models.ComissaoEscalonada, Faixas: []models.FaixaComissao{
+				{APartirDe: 0, Percentual: 30}, {APartirDe: 40, Percentual: 50},
+			}}),
+			verificar: func(t *testing.T, w *httptest.ResponseRecorder, _ *storage.Repositorios) {
+				var corpo struct {
+					Extrato models.ExtratoRepasse `json:"extrato"`
+				}
+				decodificar(t, w, &corpo)
+				if len(corpo.Extrato.Itens) != 1 || corpo.Extrato.Itens[0].Comissao != 25 || corpo.Extrato.Comissao != 25 {
+					t.Fatalf("extrato inesperado: %s", w.Body.String())
+				}
+			}},
+		{nome: "extrato do próprio profissional em CSV", metodo: "GET", rota: "/api/relatorios/repasses/estabelecimento/:id/profissional/:profId", chamador: outroProf, status: http.StatusOK,
+			url:      "/api/relatorios/repasses/estabelecimento/" + estabID + "/profissional/" + outroProfID + "?formato=csv",
+			preparar: vincular(outroProfID),
+			verificar: func(t *testing.T, w *httptest.ResponseRecorder, _ *storage.Repositorios) {
+				if !strings.HasSuffix(w.Body.String(), "Total;;0,00;;0,00\n") {
+					t.Fatalf("CSV inesperado: %q", w.Body.String())
+				}
+			}},
+		{nome: "extrato em estabelecimento sem vínculo", metodo: "GET", rota: "/api/relatorios/repasses/estabelecimento/:id/profissional/:profId", chamador: outroProf, status: http.StatusNotFound,
+			url: "/api/relatorios/repasses/estabelecimento/" + estabID + "/profissional/" + outroProfID + "?formato=pdf"},
+		{nome: "extrato de profissional que deixou o estabelecimento", metodo: "GET", rota: "/api/relatorios/repasses/estabelecimento/:id/profissional/:profId", chamador: admin, status: http.StatusOK,
+			url: "/api/relatorios/repasses/estabelecimento/" + estabID + "/profissional/" + profissionalID + "?de=" + diaLocal(-10),
+			preparar: func(t *testing.T, repos *storage.Repositorios) {
+				repos.Estabelecimentos.DesvincularProfissional(context.Background(), estabID, profissionalID)
+			},
+			verificar: func(t *testing.T, w *httptest.ResponseRecorder, _ *storage.Repositorios) {
+				var corpo struct {
+					Extrato models.ExtratoRepasse `json:"extrato"`
+				}
+				decodificar(t, w, &corpo)
+				if corpo.Extrato.Quantidade != 1 {
+					t.Fatalf("extrato inesperado: %s", w.Body.String())
+				}
+			}},
+		{nome: "extrato de outro profissional", metodo: "GET", rota: "/api/relatorios/repasses/estabelecimento/:id/profissional/:profId", chamador: outroProf, status: http.StatusForbidden,
+			url: "/api/relatorios/repasses/estabelecimento/" + estabID + "/profissional/" + profissionalID},
+		{nome: "ocupação do estabelecimento", metodo: "GET", rota: "/api/relatorios/ocupacao/estabelecimento/:id", chamador: profissional, status: http.StatusOK,
+			url: "/api/relatorios/ocupacao/estabelecimento/" + estabID + "?de=" + diaLocal(-6) + "&ate=" + diaLocal(0),
+			verificar: func(t *testing.T, w *httptest.ResponseRecorder, _ *storage.Repositorios) {
+				var corpo struct {
+					Ocupacao      agenda.Ocupacao                    `json:"ocupacao"`
+					Profissionais []controllers.OcupacaoProfissional `json:"profissionais"`
+					DiasSemana    []controllers.OcupacaoDiaSemana    `json:"dias_semana"`
+					MapaCalor     []controllers.OcupacaoHora         `json:"mapa_calor"`
+				}
+				decodificar(t, w, &corpo)
+				// Uma segunda-feira de 08:00 às 18:00 no período: dez células no mapa de calor
+				if corpo.Ocupacao.CapacidadeMin != 600 || len(corpo.Profissionais) != 1 || corpo.Profissionais[0].ProfissionalNome != "Maria Silva" ||
+					len(corpo.DiasSemana) != 7 || corpo.DiasSemana[0].CapacidadeMin != 600 || len(corpo.MapaCalor) != 10 || corpo.MapaCalor[0].Hora != 8 {
+					t.Fatalf("ocupação inesperada: %s", w.Body.String())
+				}
+			}},
+		{nome: "ocupação de outro estabelecimento", metodo: "GET", rota: "/api/relatorios/ocupacao/estabelecimento/:id", chamador: outroProf, status: http.StatusForbidden,
+			url: "/api/relatorios/ocupacao/estabelecimento/" + estabID},
+		{nome: "ocupação com período longo demais", metodo: "GET", rota: "/api/relatorios/ocupacao/estabelecimento/:id", chamador: profissional, status: http.StatusBadRequest,
+			url: "/api/relatorios/ocupacao/estabelecimento/" + estabID + "?de=" + diaLocal(-100)},
+		{nome: "horários ociosos do estabelecimento", metodo: "GET", rota: "/api/relatorios/ociosidade/estabelecimento/:id", chamador: profissional, status: http.StatusOK,
+			url: "/api/relatorios/ociosidade/estabelecimento/" + estabID + "?de=" + diaLocal(-6) + "&ate=" + diaLocal(0) + "&minimo=60",
+			verificar: func(t *testing.T, w *httptest.ResponseRecorder, _ *storage.Repositorios) {
+				var corpo struct {
+					Ociosos []controllers.HorarioOcioso `json:"horarios_ociosos"`
+				}
+				decodificar(t, w, &corpo)
+				if len(corpo.Ociosos) != 1 || corpo.Ociosos[0].DuracaoMin != 600 || corpo.Ociosos[0].ProfissionalID != profissionalID {
+					t.Fatalf("horários ociosos inesperados: %s", w.Body.String())
+				}
+			}},
+		{nome: "horários ociosos em CSV", metodo: "GET", rota: "/api/relatorios/ociosidade/estabelecimento/:id", chamador: profissional, status: http.StatusOK,
+			url: "/api/relatorios/ociosidade/estabelecimento/" + estabID + "?de=" + diaLocal(-6) + "&ate=" + diaLocal(0) + "&formato=csv",
+			verificar: func(t *testing.T, w *httptest.ResponseRecorder, _ *storage.Repositorios) {
+				if corpo := w.Body.String(); !strings.HasPrefix(corpo, "\ufeffProfissional;Início;Fim;Duração (min)\n") || !strings.HasSuffix(corpo, ";600\n") {
+					t.Fatalf("CSV inesperado: %q", corpo)
+				}
+			}},
+		{nome: "horários ociosos com mínimo inválido", metodo: "GET", rota: "/api/relatorios/ociosidade/estabelecimento/:id", chamador: profissional, status: http.StatusBadRequest,
+			url: "/api/relatorios/ociosidade/estabelecimento/" + estabID + "?minimo=2"},
+		{nome: "horários ociosos de profissional não vinculado", metodo: "GET", rota: "/api/relatorios/ociosidade/estabelecimento/:id", chamador: profissional, status: http.StatusBadRequest,
+			url: "/api/relatorios/ociosidade/estabelecimento/" + estabID + "?profissional=" + outroProfID},
+		{nome: "convidar profissional", metodo: "POST", rota: "/api/estabelecimentos/profissionais/convidar", url: "/api/estabelecimentos/profissionais/convidar", chamador: profissional,
+			corpo: map[string]string{"estabelecimento_id": estabID, "profissional_uid": outroProfID}, status: http.StatusCreated},
+		{nome: "convidar para estabelecimento de outro", metodo: "POST", rota: "/api/estabelecimentos/profissionais/convidar", url: "/api/estabelecimentos/profissionais/convidar", chamador: outroProf,
+			corpo: map[string]string{"estabelecimento_id": estabID, "profissional_uid": outroProfID}, status: http.StatusForbidden},
+		{nome: "aceitar convite", metodo: "POST", rota: "/api/estabelecimentos/profissionais/notificacao/:id", url: "/api/estabelecimentos/profissionais/notificacao/" + notificacaoID, chamador: outroProf,
+			corpo: map[string]string{"resposta": "aceito"}, status: http.StatusOK,
+			verificar: func(t *testing.T, _ *httptest.ResponseRecorder, repos *storage.Repositorios) {
+				p, _ := repos.Usuarios.BuscarProfissional(context.Background(), outroProfID)
+				vinculos, _ := repos.Estabelecimentos.ListarProfissionais(context.Background(), estabID)
+				if p.EstabelecimentoID != estabID || len(vinculos) != 2 {
+					t.Fatalf("profissional não vinculado: %+v, %d vínculos", p, len(vinculos))
+				}
+			}},
+		{nome: "responder convite de outro", metodo: "POST", rota: "/api/estabelecimentos/profissionais/notificacao/:id", url: "/api/estabelecimentos/profissionais/notificacao/" + notificacaoID, chamador: profissional,
+			corpo: map[string]string{"resposta": "aceito"}, status: http.StatusForbidden},
+		{nome: "remover profissional", metodo: "DELETE", rota: "/api/estabelecimentos/:estId/profissionais/:profId", url: "/api/estabelecimentos/" + estabID + "/profissionais/" + profissionalID, chamador: profissional, status: http.StatusOK},
+		{nome: "remover profissional de outro estabelecimento", metodo: "DELETE", rota: "/api/estabelecimentos/:estId/profissionais/:profId", url: "/api/estabelecimentos/" + estabID + "/profissionais/" + profissionalID, chamador: outroProf, status: http.StatusForbidden},
+		{nome: "profissionais do estabelecimento", metodo: "GET", rota: "/api/estabelecimentos/:id/profissionais", url: "/api/estabelecimentos/" + estabID + "/profissionais", chamador: cliente, status: http.StatusOK,
+			verificar: esperarTamanho(1)},
+
+		// Profissionais
+		{nome: "agenda do profissional em PDF", metodo: "GET", rota: "/api/agendamentos/profissional/:id", url: "/api/agendamentos/profissional/" + profissionalID + "?formato=pdf", chamador: profissional, status: http.StatusOK,
+			verificar: func(t *testing.T, w *httptest.ResponseRecorder, _ *storage.Repositorios) {
+				corpo := w.Body.String()
+				if w.Header().Get("Content-Type") != "application/pdf" || !strings.HasPrefix(corpo, "%PDF") || !strings.HasSuffix(corpo, "%%EOF\n") {
+					t.Fatalf("PDF inesperado: %v", w.Header())
+				}
+				if !strings.Contains(corpo, "(Studio da Beleza)") || !strings.Contains(corpo, "(Agenda de Maria Silva)") {
+					t.Fatal("PDF sem o cabeçalho do estabelecimento")
+				}
+			}},
+		{nome: "agendamentos do profissional", metodo: "GET", rota: "/api/agendamentos/profissional/:id", url: "/api/agendamentos/profissional/" + profissionalID, chamador: profissional, status: http.StatusOK,
+			verificar: func(t *testing.T, w *httptest.ResponseRecorder, _ *storage.Repositorios) {
+				var lista []map[string]interface{}
+				decodificar(t, w, &lista)
+				if len(lista) != 1 || lista[0]["cliente_nome"] != "João Cliente" {
+					t.Fatalf("lista inesperada: %v", lista)
+				}
+			}},
+		{nome: "horários do profissional", metodo: "GET", rota: "/api/horarios/:id", url: "/api/horarios/" + profissionalID, chamador: cliente, status: http.StatusOK,
+			verificar: esperarTamanho(1)},
+		{nome: "convites pendentes", metodo: "GET", rota: "/api/profissionais/:uid/convites-pendentes", url: "/api/profissionais/" + outroProfID + "/convites-pendentes", chamador: outroProf, status: http.StatusOK,
+			verificar: esperarTamanho(1)},
+		{nome: "convites pendentes de outro", metodo: "GET", rota: "/api/profissionais/:uid/convites-pendentes", url: "/api/profissionais/" + outroProfID + "/convites-pendentes", chamador: profissional, status: http.StatusForbidden},
+		{nome: "disponibilidade do profissional", metodo: "GET", rota: "/api/profissionais/:uid/disponibilidade", chamador: cliente, status: http.StatusOK,
+			url: "/api/profissionais/" + profissionalID + "/disponibilidade?procedimento=" + procedimentoID + "&passo=60&de=" + proximaSegunda().Format("2006-01-02") + "&ate=" + proximaSegunda().Format("2006-01-02"),
+			preparar: func(t *testing.T, repos *storage.Repositorios) {
+				repos.Agendamentos.Criar(context.Background(), models.Agendamento{
+					ID: "ag-ocupado", ClienteID: clienteID, ProfissionalID: profissionalID,
+					Procedimento: nomeProcedimento, DataHora: proximaSegunda(), DuracaoMin: 30,
+				})
+			},
+			verificar: func(t *testing.T, w *httptest.ResponseRecorder, _ *storage.Repositorios) {
+				var corpo struct {
+					Dias []struct {
+						Horarios []time.Time `json:"horarios"`
+					} `json:"dias"`
+				}
+				decodificar(t, w, &corpo)
+				// Expediente 08–18 com passo de 1h: 08:00 a 17:00, menos as 10:00 ocupadas
+				if len(corpo.Dias) != 1 || len(corpo.Dias[0].Horarios) != 9 {
+					t.Fatalf("disponibilidade inesperada: %s", w.Body.String())
+				}
+				for _, h := range corpo.Dias[0].Horarios {
+					if h.Equal(proximaSegunda()) {
+						t.Fatal("horário ocupado não deveria ser oferecido")
+					}
+				}
+			}},
+		{nome: "disponibilidade com exceções de horário", metodo: "GET", rota: "/api/profissionais/:uid/disponibilidade", chamador: cliente, status: http.StatusOK,
+			url: "/api/profissionais/" + profissionalID + "/disponibilidade?procedimento=" + procedimentoID + "&passo=60&de=" + proximaSegunda().Format("2006-01-02") + "&ate=" + proximaSegunda().AddDate(0, 0, 1).Format("2006-01-02"),
+			preparar: func(t *testing.T, repos *storage.Repositorios) {
+				segunda := proximaSegunda().Format("2006-01-02")
+				comExcecao(models.ExcecaoHorario{Tipo: models.ExcecaoBloqueio, DataInicio: segunda, DataFim: segunda, HoraInicio: "12:00", HoraFim: "18:00"})(t, repos)
+				terca := proximaSegunda().AddDate(0, 0, 1).Format("2006-01-02")
+				comExcecao(models.ExcecaoHorario{ID: "exc-2", Tipo: models.ExcecaoExtra, DataInicio: terca, DataFim: terca, HoraInicio: "09:00", HoraFim: "11:00"})(t, repos)
+			},
+			verificar: func(t *testing.T, w *httptest.ResponseRecorder, _ *storage.Repositorios) {
+				var corpo struct {
+					Dias []struct {
+						Horarios []time.Time `json:"horarios"`
+					} `json:"dias"`
+				}
+				decodificar(t, w, &corpo)
+				// Segunda só de manhã (08:00 a 11:00) e terça, sem expediente fixo, com o extra (09:00 e 10:00)
+				if len(corpo.Dias) != 2 || len(corpo.Dias[0].Horarios) != 4 || len(corpo.Dias[1].Horarios) != 2 {
+					t.Fatalf("disponibilidade inesperada: %s", w.Body.String())
+				}
+			}},
+		{nome: "disponibilidade por nome do procedimento", metodo: "GET", rota: "/api/profissionais/:uid/disponibilidade", chamador: cliente, status: http.StatusOK,
+			url: "/api/profissionais/" + profissionalID + "/disponibilidade?procedimento=" + nomeProcedimento, verificar: esperarCampo("passo_min", 15.0)},
+		{nome: "disponibilidade sem procedimento", metodo: "GET", rota: "/api/profissionais/:uid/disponibilidade", chamador: cliente, status: http.StatusBadRequest,
+			url: "/api/profissionais/" + profissionalID + "/disponibilidade"},
+		{nome: "disponibilidade com procedimento de outro", metodo: "GET", rota: "/api/profissionais/:uid/disponibilidade", chamador: cliente, status: http.StatusNotFound,
+			url: "/api/profissionais/" + outroProfID + "/disponibilidade?procedimento=" + procedimentoID},
+		{nome: "disponibilidade com período longo", metodo: "GET", rota: "/api/profissionais/:uid/disponibilidade", chamador: cliente, status: http.StatusBadRequest,
+			url: "/api/profissionais/" + profissionalID + "/disponibilidade?procedimento=" + procedimentoID + "&de=2025-01-01&ate=2025-03-01"},
+		{nome: "listar profissionais", metodo: "GET", rota: "/api/profissionais", url: "/api/profissionais", chamador: cliente, status: http.StatusOK,
+			verificar: func(t *testing.T, w *httptest.ResponseRecorder, _ *storage.Repositorios) {
+				if strings.Contains(w.Body.String(), "senha") {
+					t.Fatal("listagem de profissionais não deve conter senhas")
+				}
+			}},
+		{nome: "listar profissionais pela pontuação", metodo: "GET", rota: "/api/profissionais", url: "/api/profissionais", chamador: cliente, status: http.StatusOK,
+			preparar: func(t *testing.T, repos *storage.Repositorios) {
+				// Um 5 contra o 4 do cenário: o outro profissional passa à frente
+				err := repos.Avaliacoes.Criar(context.Background(), models.Avaliacao{
+					ID: "av-2", ProfissionalID: outroProfID, ClienteID: clienteID, Nota: 5, Data: time.Now(),
+				})
+				if err != nil {
+					t.Fatalf("erro ao criar avaliação: %v", err)
+				}
+			},
+			verificar: func(t *testing.T, w *httptest.ResponseRecorder, _ *storage.Repositorios) {
+				var lista []models.Profissional
+				if err := json.Unmarshal(w.Body.Bytes(), &lista); err != nil || len(lista) == 0 {
+					t.Fatalf("resposta inesperada: %s", w.Body.String())
+				}
+				if lista[0].ID != outroProfID || lista[0].Avaliacoes == nil || lista[0].Avaliacoes.Pontuacao <= 4.5 {
+					t.Fatalf("profissional mais bem avaliado deveria vir primeiro: %s", w.Body.String())
+				}
+			}},
+		{nome: "buscar profissional", metodo: "GET", rota: "/api/profissionais/:uid", url: "/api/profissionais/" + profissionalID, chamador: cliente, status: http.StatusOK},
+		{nome: "buscar profissional inexistente", metodo: "GET", rota: "/api/profissionais/:uid", url: "/api/profissionais/nao-existe", chamador: cliente, status: http.StatusNotFound},
+		{nome: "faturamento do profissional", metodo: "GET", rota: "/api/relatorios/profissional/faturamento/:id", url: "/api/relatorios/profissional/faturamento/" + profissionalID, chamador: profissional, status: http.StatusOK,
+			verificar: esperarCampo("total_faturado", 50.0)},
+		{nome: "faturamento mantém o preço da reserva", metodo: "GET", rota: "/api/relatorios/profissional/faturamento/:id", url: "/api/relatorios/profissional/faturamento/" + profissionalID, chamador: profissional, status: http.StatusOK,
+			preparar: func(t *testing.T, repos *storage.Repositorios) {
+				// Renomear e reajustar o procedimento não pode mexer no faturamento já realizado
+				repos.Procedimentos.Salvar(context.Background(), models.Procedimento{ID: procedimentoID, ProfissionalID: profissionalID, Nome: "Corte Premium", Preco: 80, DuracaoMin: 30})
+			},
+			verificar: esperarCampo("total_faturado", 50.0)},
+		{nome: "avaliações do profissional", metodo: "GET", rota: "/api/relatorios/avaliacoes/profissional/:id", url: "/api/relatorios/avaliacoes/profissional/" + profissionalID, chamador: cliente, status: http.StatusOK,
+			verificar: esperarCampo("quantidade_avaliacoes", 1.0)},
+		{nome: "agendamentos por mês do profissional", metodo: "GET", rota: "/api/relatorios/agendamentos/profissional/:id", url: "/api/relatorios/agendamentos/profissional/" + profissionalID, chamador: profissional, status: http.StatusOK},
+
+		// Procedimentos
+		{nome: "criar procedimento", metodo: "POST", rota: "/api/procedimentos", url: "/api/procedimentos", chamador: profissional, corpo: procedimento, status: http.StatusCreated},
+		{nome: "criar procedimento para outro", metodo: "POST", rota: "/api/procedimentos", url: "/api/procedimentos", chamador: outroProf, corpo: procedimento, status: http.StatusForbidden},
+		{nome: "criar procedimento sem duração", metodo: "POST", rota: "/api/procedimentos", url: "/api/procedimentos", chamador: profissional,
+			corpo: comCampo(procedimento, "duracao_min", 0), status: http.StatusBadRequest},
+		{nome: "criar procedimento com duração negativa", metodo: "POST", rota: "/api/procedimentos", url: "/api/procedimentos", chamador: profissional,
+			corpo: comCampo(procedimento, "duracao_min", -30), status: http.StatusBadRequest},
+		{nome: "listar procedimentos", metodo: "GET", rota: "/api/procedimentos/:id", url: "/api/procedimentos/" + profissionalID, chamador: cliente, status: http.StatusOK,
+			verificar: esperarTamanho(1)},
+		{nome: "atualizar procedimento", metodo: "PUT", rota: "/api/procedimentos/:id", url: "/api/procedimentos/" + procedimentoID, chamador: profissional, corpo: procedimento, status: http.StatusOK},
+		{nome: "atualizar procedimento com mais de um dia", metodo: "PUT", rota: "/api/procedimentos/:id", url: "/api/procedimentos/" + procedimentoID, chamador: profissional,
+			corpo: comCampo(procedimento, "duracao_min", 1441), status: http.StatusBadRequest},
+		{nome: "atualizar procedimento de outro", metodo: "PUT", rota: "/api/procedimentos/:id", url: "/api/procedimentos/" + procedimentoID, chamador: outroProf,
+			corpo: map[string]interface{}{"profissional_id": outroProfID, "nome": "Barba"}, status: http.StatusForbidden},
+		{nome: "deletar procedimento", metodo: "DELETE", rota: "/api/procedimentos/:id", url: "/api/procedimentos/" + procedimentoID, chamador: profissional, status: http.StatusOK},
+		{nome: "deletar procedimento inexistente", metodo: "DELETE", rota: "/api/procedimentos/:id", url: "/api/procedimentos/nao-existe", chamador: profissional, status: http.StatusNotFound},
+
+		// Horários
+		{nome: "criar horário", metodo: "POST", rota: "/api/horarios", url: "/api/horarios", chamador: profissional,
+			corpo: map[string]interface{}{"profissional_id": profissionalID, "dias_semana": []int{2, 3}, "hora_inicio": "09:00", "hora_fim": "17:00",
+				"pausas": []map[string]string{{"hora_inicio": "12:00", "hora_fim": "13:00"}}}, status: http.StatusCreated,
+			verificar: func(t *testing.T, _ *httptest.ResponseRecorder, repos *storage.Repositorios) {
+				terca, _ := repos.Horarios.ListarPorDia(context.Background(), profissionalID, models.Terca)
+				if len(terca) != 1 || len(terca[0].Pausas) != 1 {
+					t.Fatalf("turno de terça não salvo com a pausa: %+v", terca)
+				}
+			}},
+		{nome: "criar segundo turno no mesmo dia", metodo: "POST", rota: "/api/horarios", url: "/api/horarios", chamador: profissional,
+			corpo: map[string]interface{}{"profissional_id": profissionalID, "dias_semana": []int{1}, "hora_inicio": "18:00", "hora_fim": "21:00"}, status: http.StatusCreated,
+			verificar: func(t *testing.T, _ *httptest.ResponseRecorder, repos *storage.Repositorios) {
+				segunda, _ := repos.Horarios.ListarPorDia(context.Background(), profissionalID, models.Segunda)
+				if len(segunda) != 2 {
+					t.Fatalf("esperava 2 turnos na segunda, obtive %+v", segunda)
+				}
+			}},
+		{nome: "criar turno sobreposto", metodo: "POST", rota: "/api/horarios", url: "/api/horarios", chamador: profissional,
+			corpo: map[string]interface{}{"profissional_id": profissionalID, "dias_semana": []int{2, 1}, "hora_inicio": "17:00", "hora_fim": "20:00"}, status: http.StatusConflict,
+			verificar: func(t *testing.T, _ *httptest.ResponseRecorder, repos *storage.Repositorios) {
+				if terca, _ := repos.Horarios.ListarPorDia(context.Background(), profissionalID, models.Terca); len(terca) != 0 {
+					t.Fatal("nenhum turno deveria ser gravado quando um dia conflita")
+				}
+			}},
+		{nome: "criar turno com pausa fora do horário", metodo: "POST", rota: "/api/horarios", url: "/api/horarios", chamador: profissional,
+			corpo: map[string]interface{}{"profissional_id": profissionalID, "dias_semana": []int{2}, "hora_inicio": "09:00", "hora_fim": "12:00",
+				"pausas": []map[string]string{{"hora_inicio": "12:00", "hora_fim": "13:00"}}}, status: http.StatusBadRequest},
+		{nome: "criar horário com nome do dia sem acento", metodo: "POST", rota: "/api/horarios", url: "/api/horarios", chamador: profissional,
+			corpo: map[string]interface{}{"profissional_id": profissionalID, "dias_semana": []string{"terca"}, "hora_inicio": "09:00", "hora_fim": "12:00"}, status: http.StatusCreated,
+			verificar: func(t *testing.T, _ *httptest.ResponseRecorder, repos *storage.Repositorios) {
+				if terca, _ := repos.Horarios.ListarPorDia(context.Background(), profissionalID, models.Terca); len(terca) != 1 {
+					t.Fatalf("\"terca\" deveria ser gravado como terça-feira: %+v", terca)
+				}
+			}},
+		{nome: "criar horário com dia desconhecido", metodo: "POST", rota: "/api/horarios", url: "/api/horarios", chamador: profissional,
+			corpo: map[string]interface{}{"profissional_id": profissionalID, "dias_semana": []interface{}{2, "tercinha"}, "hora_inicio": "09:00", "hora_fim": "12:00"}, status: http.StatusBadRequest},
+		{nome: "criar horário com dia fora do padrão ISO", metodo: "POST", rota: "/api/horarios", url: "/api/horarios", chamador: profissional,
+			corpo: map[string]interface{}{"profissional_id": profissionalID, "dias_semana": []int{0}, "hora_inicio": "09:00", "hora_fim": "12:00"}, status: http.StatusBadRequest},
+		{nome: "criar horário de outro", metodo: "POST", rota: "/api/horarios", url: "/api/horarios", chamador: outroProf,
+			corpo: map[string]interface{}{"profissional_id": profissionalID, "dias_semana": []int{2}}, status: http.StatusForbidden},
+		{nome: "editar horário", metodo: "PUT", rota: "/api/horarios/:id", url: "/api/horarios/" + horarioID, chamador: profissional,
+			corpo: map[string]interface{}{"profissional_id": profissionalID, "dia_semana": 1, "hora_inicio": "07:00", "hora_fim": "12:00"}, status: http.StatusOK},
+		{nome: "editar horário sobrepondo outro turno", metodo: "PUT", rota: "/api/horarios/:id", url: "/api/horarios/" + horarioID, chamador: profissional,
+			corpo: map[string]interface{}{"dia_semana": 1, "hora_inicio": "07:00", "hora_fim": "20:00"}, status: http.StatusConflict,
+			preparar: func(t *testing.T, repos *storage.Repositorios) {
+				repos.Horarios.Salvar(context.Background(), models.Horario{ID: "hor-2", ProfissionalID: profissionalID, DiaSemana: models.Segunda, HoraInicio: "19:00", HoraFim: "21:00", Disponivel: true})
+			}},
+		{nome: "agendar na pausa do almoço", metodo: "POST", rota: "/api/agendamentos", url: "/api/agendamentos", chamador: cliente, status: http.StatusBadRequest,
+			corpo: comCampo(agendar, "data_hora", proximaSegunda().Add(2*time.Hour)),
+			preparar: func(t *testing.T, repos *storage.Repositorios) {
+				repos.Horarios.Salvar(context.Background(), models.Horario{ID: horarioID, ProfissionalID: profissionalID, DiaSemana: models.Segunda, HoraInicio: "08:00", HoraFim: "18:00", Disponivel: true,
+					Pausas: []models.Pausa{{HoraInicio: "12:00", HoraFim: "13:00"}}})
+			}},
+		{nome: "editar horário de outro", metodo: "PUT", rota: "/api/horarios/:id", url: "/api/horarios/" + horarioID, chamador: outroProf,
+			corpo: map[string]interface{}{"profissional_id": outroProfID}, status: http.StatusForbidden},
+		{nome: "excluir horário", metodo: "DELETE", rota: "/api/horarios/:id", url: "/api/horarios/" + horarioID, chamador: profissional, status: http.StatusOK},
+		{nome: "criar exceção de horário", metodo: "POST", rota: "/api/horarios/excecoes", url: "/api/horarios/excecoes", chamador: profissional,
+			corpo: map[string]string{"profissional_id": profissionalID, "tipo": "bloqueio", "data_inicio": "2025-12-24", "data_fim": "2025-12-26", "motivo": "Natal"}, status: http.StatusCreated},
+		{nome: "criar exceção de horário de outro", metodo: "POST", rota: "/api/horarios/excecoes", url: "/api/horarios/excecoes", chamador: outroProf,
+			corpo: map[string]string{"profissional_id": profissionalID, "tipo": "bloqueio", "data_inicio": "2025-12-24"}, status: http.StatusForbidden},
+		{nome: "criar horário extra sem horas", metodo: "POST", rota: "/api/horarios/excecoes", url: "/api/horarios/excecoes", chamador: profissional,
+			corpo: map[string]string{"profissional_id": profissionalID, "tipo": "extra", "data_inicio": "2025-12-20"}, status: http.StatusBadRequest},
+		{nome: "listar exceções de horário", metodo: "GET", rota: "/api/horarios/:id/excecoes", url: "/api/horarios/" + profissionalID + "/excecoes?de=2025-12-01&ate=2025-12-31", chamador: profissional, status: http.StatusOK,
+			preparar: func(t *testing.T, repos *storage.Repositorios) {
+				comExcecao(models.ExcecaoHorario{Tipo: models.ExcecaoBloqueio, DataInicio: "2025-11-28", DataFim: "2025-12-02"})(t, repos)
+				comExcecao(models.ExcecaoHorario{ID: "exc-2", Tipo: models.ExcecaoBloqueio, DataInicio: "2026-01-01", DataFim: "2026-01-01"})(t, repos)
+			},
+			verificar: esperarTamanho(1)},
+		{nome: "listar exceções de horário como cliente", metodo: "GET", rota: "/api/horarios/:id/excecoes", url: "/api/horarios/" + profissionalID + "/excecoes", chamador: cliente, status: http.StatusForbidden},
+		{nome: "editar exceção de horário", metodo: "PUT", rota: "/api/horarios/excecoes/:id", url: "/api/horarios/excecoes/" + excecaoID, chamador: profissional,
+			corpo: map[string]string{"profissional_id": outroProfID, "tipo": "bloqueio", "data_inicio": "2025-12-24", "hora_inicio": "12:00", "hora_fim": "18:00"}, status: http.StatusOK,
+			preparar: comExcecao(models.ExcecaoHorario{Tipo: models.ExcecaoBloqueio, DataInicio: "2025-12-24", DataFim: "2025-12-24"}),
+			verificar: func(t *testing.T, _ *httptest.ResponseRecorder, repos *storage.Repositorios) {
+				e, _ := repos.Excecoes.Buscar(context.Background(), excecaoID)
+				if e.ProfissionalID != profissionalID || e.HoraInicio != "12:00" || e.DataFim != "2025-12-24" {
+					t.Fatalf("exceção não atualizada corretamente: %+v", e)
+				}
+			}},
+		{nome: "editar exceção de horário de outro", metodo: "PUT", rota: "/api/horarios/excecoes/:id", url: "/api/horarios/excecoes/" + excecaoID, chamador: outroProf,
+			corpo: map[string]string{"tipo": "bloqueio", "data_inicio": "2025-12-24"}, status: http.StatusForbidden,
+			preparar: comExcecao(models.ExcecaoHorario{Tipo: models.ExcecaoBloqueio, DataInicio: "2025-12-24", DataFim: "2025-12-24"})},
+		{nome: "editar exceção de horário inexistente", metodo: "PUT", rota: "/api/horarios/excecoes/:id", url: "/api/horarios/excecoes/nao-existe", chamador: admin,
+			corpo: map[string]string{"tipo": "bloqueio", "data_inicio": "2025-12-24"}, status: http.StatusNotFound},
+		{nome: "excluir exceção de horário", metodo: "DELETE", rota: "/api/horarios/excecoes/:id", url: "/api/horarios/excecoes/" + excecaoID, chamador: profissional, status: http.StatusOK,
+			preparar: comExcecao(models.ExcecaoHorario{Tipo: models.ExcecaoBloqueio, DataInicio: "2025-12-24", DataFim: "2025-12-24"})},
+
+		// Upload
+		{nome: "imagem do próprio perfil", metodo: "PUT", rota: "/api/upload/:tipo/:id", url: "/api/upload/profissional/" + profissionalID, chamador: profissional,
+			corpo: map[string]string{"imagem_url": "https://exemplo.com/foto.jpg"}, status: http.StatusOK},
+		{nome: "imagem do procedimento", metodo: "PUT", rota: "/api/upload/:tipo/:id", url: "/api/upload/procedimento/" + procedimentoID, chamador: profissional,
+			corpo: map[string]string{"imagem_url": "https://exemplo.com/corte.jpg"}, status: http.StatusOK},
+		{nome: "imagem de outro profissional", metodo: "PUT", rota: "/api/upload/:tipo/:id", url: "/api/upload/profissional/" + profissionalID, chamador: outroProf,
+			corpo: map[string]string{"imagem_url": "https://exemplo.com/foto.jpg"}, status: http.StatusForbidden},
+		{nome: "imagem com tipo inválido", metodo: "PUT", rota: "/api/upload/:tipo/:id", url: "/api/upload/cliente/" + clienteID, chamador: cliente,
+			corpo: map[string]string{"imagem_url": "https://exemplo.com/foto.jpg"}, status: http.StatusBadRequest},
+
+		// Agendamentos
+		{nome: "agendar horário", metodo: "POST", rota: "/api/agendamentos", url: "/api/agendamentos", chamador: cliente, corpo: agendar, status: http.StatusCreated,
+			verificar: esperarCampo("duracao_min", 30.0)},
+		{nome: "agendar sem estabelecimento usa o do profissional", metodo: "POST", rota: "/api/agendamentos", url: "/api/agendamentos", chamador: cliente,
+			corpo: semCampo(agendar, "estabelecimento_id"), status: http.StatusCreated,
+			verificar: esperarCampo("estabelecimento_id", estabID)},
+		{nome: "agendar com estabelecimento sem vínculo no cadastro", metodo: "POST", rota: "/api/agendamentos", url: "/api/agendamentos", chamador: cliente,
+			corpo: semCampo(agendar, "estabelecimento_id"), status: http.StatusCreated,
+			preparar: func(t *testing.T, repos *storage.Repositorios) {
+				// O cadastro ainda aponta para o estabelecimento, mas ele não lista mais o profissional
+				repos.Estabelecimentos.DesvincularProfissional(context.Background(), estabID, profissionalID)
+			},
+			verificar: esperarCampo("estabelecimento_id", "")},
+		{nome: "agendar em estabelecimento de outro", metodo: "POST", rota: "/api/agendamentos", url: "/api/agendamentos", chamador: cliente,
+			corpo: comCampo(agendar, "estabelecimento_id", "est-outro"), status: http.StatusBadRequest},
+		{nome: "agendar horário sobreposto", metodo: "POST", rota: "/api/agendamentos", url: "/api/agendamentos", chamador: cliente, corpo: agendar, status: http.StatusConflict,
+			preparar: func(t *testing.T, repos *storage.Repositorios) {
+				// Outro cliente já ocupa 09:45–10:15; o pedido das 10:00 cruza esse intervalo
+				repos.Agendamentos.Criar(context.Background(), models.Agendamento{
+					ID: "ag-existente", ClienteID: "cli-2", ProfissionalID: profissionalID,
+					Procedimento: nomeProcedimento, DataHora: proximaSegunda().Add(-15 * time.Minute), DuracaoMin: 30,
+				})
+			},
+			verificar: func(t *testing.T, w *httptest.ResponseRecorder, _ *storage.Repositorios) {
+				var corpo struct {
+					Conflito struct{ Inicio, Fim time.Time } `json:"conflito"`
+				}
+				decodificar(t, w, &corpo)
+				if !corpo.Conflito.Fim.Equal(proximaSegunda().Add(15 * time.Minute)) {
+					t.Fatalf("intervalo conflitante inesperado: %+v", corpo.Conflito)
+				}
+			}},
+		{nome: "agendar logo após outro atendimento", metodo: "POST", rota: "/api/agendamentos", url: "/api/agendamentos", chamador: cliente, corpo: agendar, status: http.StatusCreated,
+			preparar: func(t *testing.T, repos *storage.Repositorios) {
+				repos.Agendamentos.Criar(context.Background(), models.Agendamento{
+					ID: "ag-anterior", ClienteID: "cli-2", ProfissionalID: profissionalID,
+					Procedimento: nomeProcedimento, DataHora: proximaSegunda().Add(-30 * time.Minute), DuracaoMin: 30,
+				})
+			}},
+		{nome: "agendar em nome de outro cliente", metodo: "POST", rota: "/api/agendamentos", url: "/api/agendamentos", chamador: quem{"cli-2", "clientes"}, corpo: agendar, status: http.StatusForbidden},
+		{nome: "agendar fora do expediente", metodo: "POST", rota: "/api/agendamentos", url: "/api/agendamentos", chamador: cliente,
+			corpo: comCampo(agendar, "data_hora", proximaSegunda().Add(9*time.Hour)), status: http.StatusBadRequest},
+		{nome: "agendar procedimento inexistente", metodo: "POST", rota: "/api/agendamentos", url: "/api/agendamentos", chamador: cliente,
+			corpo: comCampo(agendar, "procedimento", "Inexistente"), status: http.StatusBadRequest},
+		{nome: "agendar pelo ID do procedimento", metodo: "POST", rota: "/api/agendamentos", url: "/api/agendamentos", chamador: cliente, status: http.StatusCreated,
+			corpo: comCampo(comCampo(agendar, "procedimento", ""), "procedimento_id", procedimentoID),
+			verificar: func(t *testing.T, w *httptest.ResponseRecorder, _ *storage.Repositorios) {
+				var ag models.Agendamento
+				decodificar(t, w, &ag)
+				if ag.ProcedimentoID != procedimentoID || ag.Procedimento != nomeProcedimento || ag.Preco != 50 || ag.DuracaoMin != 30 {
+					t.Fatalf("procedimento não copiado para o agendamento: %+v", ag)
+				}
+			}},
+		{nome: "agendar procedimento de outro profissional", metodo: "POST", rota: "/api/agendamentos", url: "/api/agendamentos", chamador: cliente, status: http.StatusBadRequest,
+			corpo: comCampo(agendar, "procedimento_id", "proc-outro"),
+			preparar: func(t *testing.T, repos *storage.Repositorios) {
+				repos.Procedimentos.Salvar(context.Background(), models.Procedimento{ID: "proc-outro", ProfissionalID: outroProfID, Nome: "Barba", Preco: 30, DuracaoMin: 20})
+			}},
+		{nome: "agendar no fuso do estabelecimento", metodo: "POST", rota: "/api/agendamentos", url: "/api/agendamentos", chamador: cliente, status: http.StatusCreated,
+			corpo:    comCampo(agendar, "data_hora", proximaSegundaEm(fuso("America/New_York")).UTC()),
+			preparar: comFuso("America/New_York"),
+			verificar: func(t *testing.T, w *httptest.ResponseRecorder, _ *storage.Repositorios) {
+				var ag models.Agendamento
+				decodificar(t, w, &ag)
+				_, esperado := proximaSegundaEm(fuso("America/New_York")).Zone()
+				if _, offset := ag.DataHora.Zone(); offset != esperado || ag.DataHora.Hour() != 10 {
+					t.Fatalf("horário não exibido no fuso do estabelecimento: %v", ag.DataHora)
+				}
+			}},
+		{nome: "agendar fora do expediente no fuso do estabelecimento", metodo: "POST", rota: "/api/agendamentos", url: "/api/agendamentos", chamador: cliente, status: http.StatusBadRequest,
+			// 07:30 em Nova York já é horário comercial em São Paulo, mas não no estabelecimento
+			corpo:    comCampo(agendar, "data_hora", proximaSegundaEm(fuso("America/New_York")).Add(-150*time.Minute)),
+			preparar: comFuso("America/New_York")},
+		{nome: "agendar em dia de folga", metodo: "POST", rota: "/api/agendamentos", url: "/api/agendamentos", chamador: cliente, corpo: agendar, status: http.StatusBadRequest,
+			preparar: comExcecao(models.ExcecaoHorario{Tipo: models.ExcecaoBloqueio, DataInicio: proximaSegunda().AddDate(0, 0, -3).Format("2006-01-02"), DataFim: proximaSegunda().Format("2006-01-02"), Motivo: "Férias"})},
+		{nome: "agendar em horário extra", metodo: "POST", rota: "/api/agendamentos", url: "/api/agendamentos", chamador: cliente, status: http.StatusCreated,
+			corpo:    comCampo(agendar, "data_hora", proximaSegunda().Add(9*time.Hour)),
+			preparar: comExcecao(models.ExcecaoHorario{Tipo: models.ExcecaoExtra, DataInicio: proximaSegunda().Format("2006-01-02"), DataFim: proximaSegunda().Format("2006-01-02"), HoraInicio: "18:00", HoraFim: "20:00"})},
+		{nome: "buscar agendamento", metodo: "GET", rota: "/api/agendamentos/:id", url: "/api/agendamentos/" + agendamentoID, chamador: cliente, status: http.StatusOK,
+			verificar: esperarCampo("status", models.StatusConcluido)},
+		{nome: "buscar agendamento de terceiro", metodo: "GET", rota: "/api/agendamentos/:id", url: "/api/agendamentos/" + agendamentoID, chamador: outroProf, status: http.StatusForbidden},
+		{nome: "buscar agendamento inexistente", metodo: "GET", rota: "/api/agendamentos/:id", url: "/api/agendamentos/nao-existe", chamador: admin, status: http.StatusNotFound},
+		{nome: "confirmar agendamento", metodo: "POST", rota: "/api/agendamentos/:id/confirmar", url: "/api/agendamentos/" + agendamentoID + "/confirmar", chamador: profissional, status: http.StatusOK,
+			preparar: ajustarAgendamento(models.StatusPendente, proximaSegunda()),
+			verificar: func(t *testing.T, w *httptest.ResponseRecorder, _ *storage.Repositorios) {
+				var ag models.Agendamento
+				decodificar(t, w, &ag)
+				ultima := ag.Historico[len(ag.Historico)-1]
+				if ag.Status != models.StatusConfirmado || ultima.De != models.StatusPendente || ultima.PorUID != profissionalID {
+					t.Fatalf("transição não registrada: status %q, histórico %+v", ag.Status, ag.Historico)
+				}
+			}},
+		{nome: "confirmar agendamento pelo cliente", metodo: "POST", rota: "/api/agendamentos/:id/confirmar", url: "/api/agendamentos/" + agendamentoID + "/confirmar", chamador: cliente, status: http.StatusForbidden,
+			preparar: ajustarAgendamento(models.StatusPendente, proximaSegunda())},
+		{nome: "confirmar agendamento concluído", metodo: "POST", rota: "/api/agendamentos/:id/confirmar", url: "/api/agendamentos/" + agendamentoID + "/confirmar", chamador: profissional, status: http.StatusConflict},
+		{nome: "concluir agendamento", metodo: "POST", rota: "/api/agendamentos/:id/concluir", url: "/api/agendamentos/" + agendamentoID + "/concluir", chamador: profissional, status: http.StatusOK,
+			preparar:  ajustarAgendamento(models.StatusConfirmado, time.Now().Add(-time.Hour)),
+			verificar: esperarCampo("status", models.StatusConcluido)},
+		{nome: "concluir agendamento futuro", metodo: "POST", rota: "/api/agendamentos/:id/concluir", url: "/api/agendamentos/" + agendamentoID + "/concluir", chamador: profissional, status: http.StatusBadRequest,
+			preparar: ajustarAgendamento(models.StatusConfirmado, proximaSegunda())},
+		{nome: "registrar não comparecimento", metodo: "POST", rota: "/api/agendamentos/:id/nao-compareceu", url: "/api/agendamentos/" + agendamentoID + "/nao-compareceu", chamador: profissional, status: http.StatusOK,
+			preparar:  ajustarAgendamento(models.StatusConfirmado, time.Now().Add(-time.Hour)),
+			verificar: esperarCampo("status", models.StatusNaoCompareceu)},
+		{nome: "não comparecimento com política do procedimento", metodo: "POST", rota: "/api/agendamentos/:id/nao-compareceu", url: "/api/agendamentos/" + agendamentoID + "/nao-compareceu", chamador: profissional, status: http.StatusOK,
+			preparar: func(t *testing.T, repos *storage.Repositorios) {
+				comPolitica(models.PoliticaCancelamento{PercentualNaoComparecimento: 100})(t, repos)
+				repos.Procedimentos.Salvar(context.Background(), models.Procedimento{
+					ID: procedimentoID, ProfissionalID: profissionalID, Nome: nomeProcedimento, Preco: 50, DuracaoMin: 30,
+					PoliticaCancelamento: &models.PoliticaCancelamento{PercentualNaoComparecimento: 40},
+				})
+				ajustarAgendamento(models.StatusConfirmado, time.Now().Add(-time.Hour))(t, repos)
+			},
+			verificar: func(t *testing.T, w *httptest.ResponseRecorder, _ *storage.Repositorios) {
+				var ag models.Agendamento
+				decodificar(t, w, &ag)
+				if ag.Taxa == nil || ag.Taxa.Motivo != models.TaxaNaoComparecimento || ag.Taxa.Valor != 20 {
+					t.Fatalf("taxa de não comparecimento inesperada: %+v", ag.Taxa)
+				}
+			}},
+		{nome: "registrar não comparecimento pelo cliente", metodo: "POST", rota: "/api/agendamentos/:id/nao-compareceu", url: "/api/agendamentos/" + agendamentoID + "/nao-compareceu", chamador: cliente, status: http.StatusForbidden},
+		{nome: "cancelar agendamento", metodo: "POST", rota: "/api/agendamentos/:id/cancelar", url: "/api/agendamentos/" + agendamentoID + "/cancelar", chamador: cliente,
+			corpo: map[string]string{"motivo": "Imprevisto"}, status: http.StatusOK,
+			preparar: ajustarAgendamento(models.StatusConfirmado, proximaSegunda()),
+			verificar: func(t *testing.T, w *httptest.ResponseRecorder, _ *storage.Repositorios) {
+				var ag models.Agendamento
+				decodificar(t, w, &ag)
+				if ag.Status != models.StatusCancelado || ag.Historico[len(ag.Historico)-1].Motivo != "Imprevisto" {
+					t.Fatalf("cancelamento não registrado: %+v", ag)
+				}
+			}},
+		{nome: "cancelar agendamento em cima da hora", metodo: "POST", rota: "/api/agendamentos/:id/cancelar", url: "/api/agendamentos/" + agendamentoID + "/cancelar", chamador: cliente, status: http.StatusOK,
+			preparar: func(t *testing.T, repos *storage.Repositorios) {
+				comPolitica(models.PoliticaCancelamento{AntecedenciaHoras: 24, PercentualTardio: 50, PercentualNaoComparecimento: 100})(t, repos)
+				ajustarAgendamento(models.StatusConfirmado, time.Now().Add(2*time.Hour))(t, repos)
+			},
+			verificar: func(t *testing.T, w *httptest.ResponseRecorder, _ *storage.Repositorios) {
+				var ag models.Agendamento
+				decodificar(t, w, &ag)
+				if ag.Taxa == nil || ag.Taxa.Motivo != models.TaxaCancelamentoTardio || ag.Taxa.Valor != 25 {
+					t.Fatalf("taxa de cancelamento inesperada: %+v", ag.Taxa)
+				}
+			}},
+		{nome: "cancelar em cima da hora agendamento sem estabelecimento", metodo: "POST", rota: "/api/agendamentos/:id/cancelar", url: "/api/agendamentos/" + agendamentoID + "/cancelar", chamador: cliente, status: http.StatusOK,
+			preparar: func(t *testing.T, repos *storage.Repositorios) {
+				comPolitica(models.PoliticaCancelamento{AntecedenciaHoras: 24, PercentualTardio: 50})(t, repos)
+				ajustarAgendamento(models.StatusConfirmado, time.Now().Add(2*time.Hour))(t, repos)
+				repos.Agendamentos.Atualizar(context.Background(), agendamentoID, func(ag *models.Agendamento) error {
+					ag.EstabelecimentoID = ""
+					return nil
+				})
+			},
+			verificar: func(t *testing.T, w *httptest.ResponseRecorder, _ *storage.Repositorios) {
+				var ag models.Agendamento
+				decodificar(t, w, &ag)
+				if ag.Taxa == nil || ag.Taxa.Valor != 25 {
+					t.Fatalf("política do estabelecimento do profissional não aplicada: %+v", ag.Taxa)
+				}
+			}},
+		{nome: "cancelar em cima da hora com procedimento removido", metodo: "POST", rota: "/api/agendamentos/:id/cancelar", url: "/api/agendamentos/" + agendamentoID + "/cancelar", chamador: cliente, status: http.StatusOK,
+			preparar: func(t *testing.T, repos *storage.Repositorios) {
+				comPolitica(models.PoliticaCancelamento{AntecedenciaHoras: 24, PercentualTardio: 50})(t, repos)
+				ajustarAgendamento(models.StatusConfirmado, time.Now().Add(2*time.Hour))(t, repos)
+				repos.Procedimentos.Excluir(context.Background(), procedimentoID)
+			},
+			verificar: func(t *testing.T, w *httptest.ResponseRecorder, _ *storage.Repositorios) {
+				var ag models.Agendamento
+				decodificar(t, w, &ag)
+				if ag.Taxa == nil || ag.Taxa.Valor != 25 {
+					t.Fatalf("política do estabelecimento não aplicada: %+v", ag.Taxa)
+				}
+			}},
+		{nome: "cancelar agendamento dentro do prazo", metodo: "POST", rota: "/api/agendamentos/:id/cancelar", url: "/api/agendamentos/" + agendamentoID + "/cancelar", chamador: cliente, status: http.StatusOK,
+			preparar: func(t *testing.T, repos *storage.Repositorios) {
+				comPolitica(models.PoliticaCancelamento{AntecedenciaHoras: 24, PercentualTardio: 50})(t, repos)
+				ajustarAgendamento(models.StatusConfirmado, time.Now().Add(48*time.Hour))(t, repos)
+			},
+			verificar: esperarCampo("taxa", nil)},
+		{nome: "cancelamento pelo profissional não gera taxa", metodo: "POST", rota: "/api/agendamentos/:id/cancelar", url: "/api/agendamentos/" + agendamentoID + "/cancelar", chamador: profissional, status: http.StatusOK,
+			preparar: func(t *testing.T, repos *storage.Repositorios) {
+				comPolitica(models.PoliticaCancelamento{AntecedenciaHoras: 24, PercentualTardio: 50})(t, repos)
+				ajustarAgendamento(models.StatusConfirmado, time.Now().Add(2*time.Hour))(t, repos)
+			},
+			verificar: esperarCampo("taxa", nil)},
+		{nome: "cancelar agendamento de terceiro", metodo: "POST", rota: "/api/agendamentos/:id/cancelar", url: "/api/agendamentos/" + agendamentoID + "/cancelar", chamador: quem{"cli-2", "clientes"}, status: http.StatusForbidden},
+		{nome: "cancelar agendamento concluído", metodo: "POST", rota: "/api/agendamentos/:id/cancelar", url: "/api/agendamentos/" + agendamentoID + "/cancelar", chamador: cliente, status: http.StatusConflict},
+		{nome: "reagendar", metodo: "POST", rota: "/api/agendamentos/:id/reagendar", url: "/api/agendamentos/" + agendamentoID + "/reagendar", chamador: cliente,
+			corpo: map[string]interface{}{"data_hora": proximaSegunda().Add(2 * time.Hour)}, status: http.StatusOK,
+			preparar:  ajustarAgendamento(models.StatusConfirmado, proximaSegunda()),
+			verificar: esperarCampo("status", models.StatusPendente)},
+		{nome: "reagendar depois de o profissional mudar de estabelecimento", metodo: "POST", rota: "/api/agendamentos/:id/reagendar", url: "/api/agendamentos/" + agendamentoID + "/reagendar", chamador: cliente,
+			corpo: map[string]interface{}{"data_hora": proximaSegundaEm(fuso("Asia/Tokyo"))}, status: http.StatusOK,
+			preparar: func(t *testing.T, repos *storage.Repositorios) {
+				ajustarAgendamento(models.StatusConfirmado, proximaSegunda())(t, repos)
+				ctx := context.Background()
+				repos.Estabelecimentos.Salvar(ctx, models.Estabelecimento{ID: "est-2", Nome: "Studio Tóquio", ResponsavelUID: outroProfID, FusoHorario: "Asia/Tokyo"})
+				repos.Estabelecimentos.DesvincularProfissional(ctx, estabID, profissionalID)
+				repos.Estabelecimentos.VincularProfissional(ctx, "est-2", models.ProfissionalEstabelecimento{UID: profissionalID, Status: "ativo", AdicionadoEm: time.Now()})
+				repos.Usuarios.DefinirEstabelecimentoProfissional(ctx, profissionalID, "est-2")
+			},
+			verificar: func(t *testing.T, w *httptest.ResponseRecorder, repos *storage.Repositorios) {
+				var ag models.Agendamento
+				decodificar(t, w, &ag)
+				if _, offset := ag.DataHora.Zone(); offset != 9*60*60 {
+					t.Fatalf("horário validado em Tóquio exibido em outro fuso: %v", ag.DataHora)
+				}
+				gravado, _ := repos.Agendamentos.Buscar(context.Background(), agendamentoID)
+				if gravado.EstabelecimentoID != "est-2" {
+					t.Fatalf("agendamento continua no estabelecimento %q", gravado.EstabelecimentoID)
+				}
+			}},
+		{nome: "reagendar com procedimento removido", metodo: "POST", rota: "/api/agendamentos/:id/reagendar", url: "/api/agendamentos/" + agendamentoID + "/reagendar", chamador: cliente,
+			corpo: map[string]interface{}{"data_hora": proximaSegunda().Add(2 * time.Hour)}, status: http.StatusOK,
+			preparar: func(t *testing.T, repos *storage.Repositorios) {
+				ajustarAgendamento(models.StatusConfirmado, proximaSegunda())(t, repos)
+				repos.Procedimentos.Excluir(context.Background(), procedimentoID)
+			},
+			verificar: esperarCampo("duracao_min", 30.0)},
+		{nome: "reagendar fora do expediente", metodo: "POST", rota: "/api/agendamentos/:id/reagendar", url: "/api/agendamentos/" + agendamentoID + "/reagendar", chamador: cliente,
+			corpo: map[string]interface{}{"data_hora": proximaSegunda().Add(9 * time.Hour)}, status: http.StatusBadRequest,
+			preparar: ajustarAgendamento(models.StatusPendente, proximaSegunda())},
+		{nome: "reagendar sobre outro atendimento", metodo: "POST", rota: "/api/agendamentos/:id/reagendar", url: "/api/agendamentos/" + agendamentoID + "/reagendar", chamador: cliente,
+			corpo: map[string]interface{}{"data_hora": proximaSegunda().Add(2 * time.Hour)}, status: http.StatusConflict,
+			preparar: func(t *testing.T, repos *storage.Repositorios) {
+				ajustarAgendamento(models.StatusPendente, proximaSegunda())(t, repos)
+				repos.Agendamentos.Criar(context.Background(), models.Agendamento{
+					ID: "ag-existente", ClienteID: "cli-2", ProfissionalID: profissionalID,
+					Procedimento: nomeProcedimento, DataHora: proximaSegunda().Add(2 * time.Hour), DuracaoMin: 30,
+				})
+			}},
+		{nome: "reagendar agendamento concluído", metodo: "POST", rota: "/api/agendamentos/:id/reagendar", url: "/api/agendamentos/" + agendamentoID + "/reagendar", chamador: cliente,
+			corpo: map[string]interface{}{"data_hora": proximaSegunda()}, status: http.StatusConflict},
+
+		// Avaliações
+		{nome: "avaliar atendimento concluído", metodo: "POST", rota: "/api/avaliacoes", url: "/api/avaliacoes", chamador: cliente,
+			corpo: avaliar, status: http.StatusCreated,
+			verificar: func(t *testing.T, w *httptest.ResponseRecorder, repos *storage.Repositorios) {
+				esperarCampo("id", agendamentoID)(t, w, repos)
+				for _, alvo := range []struct{ nome, id string }{{models.AlvoProfissional, profissionalID}, {models.AlvoEstabelecimento, estabID}} {
+					resumo, err := repos.Avaliacoes.BuscarResumo(context.Background(), alvo.nome, alvo.id)
+					if err != nil || resumo.Quantidade != 2 || resumo.Media != 4.5 {
+						t.Fatalf("resumo de %s inesperado: %+v %v", alvo.nome, resumo, err)
+					}
+				}
+			}},
+		{nome: "avaliar atendimento não concluído", metodo: "POST", rota: "/api/avaliacoes", url: "/api/avaliacoes", chamador: cliente,
+			corpo: avaliar, status: http.StatusConflict,
+			preparar: ajustarAgendamento(models.StatusConfirmado, time.Now().Add(-time.Hour))},
+		{nome: "avaliar atendimento fora do prazo", metodo: "POST", rota: "/api/avaliacoes", url: "/api/avaliacoes", chamador: cliente,
+			corpo: avaliar, status: http.StatusConflict,
+			preparar: ajustarAgendamento(models.StatusConcluido, time.Now().AddDate(0, 0, -31))},
+		{nome: "avaliar atendimento já avaliado", metodo: "POST", rota: "/api/avaliacoes", url: "/api/avaliacoes", chamador: cliente,
+			corpo: avaliar, status: http.StatusConflict, preparar: comAvaliacao(time.Now())},
+		{nome: "avaliar com nota inválida", metodo: "POST", rota: "/api/avaliacoes", url: "/api/avaliacoes", chamador: cliente,
+			corpo: comCampo(avaliar, "nota", 4.5), status: http.StatusBadRequest},
+		{nome: "avaliar atendimento de outro cliente", metodo: "POST", rota: "/api/avaliacoes", url: "/api/avaliacoes", chamador: quem{"cli-2", "clientes"},
+			corpo: avaliar, status: http.StatusForbidden},
+		{nome: "profissional não avalia atendimento", metodo: "POST", rota: "/api/avaliacoes", url: "/api/avaliacoes", chamador: profissional,
+			corpo: avaliar, status: http.StatusForbidden},
+		{nome: "editar avaliação", metodo: "PUT", rota: "/api/avaliacoes/:id", url: "/api/avaliacoes/" + agendamentoID, chamador: cliente,
+			corpo: map[string]interface{}{"nota": 2, "comentario": "Atrasou"}, status: http.StatusOK,
+			preparar: comAvaliacao(time.Now().Add(-time.Hour)),
+			verificar: func(t *testing.T, w *httptest.ResponseRecorder, repos *storage.Repositorios) {
+				var av models.Avaliacao
+				decodificar(t, w, &av)
+				if av.Nota != 2 || av.EditadaEm == nil {
+					t.Fatalf("edição não registrada: %+v", av)
+				}
+				if resumo, _ := repos.Avaliacoes.BuscarResumo(context.Background(), models.AlvoProfissional, profissionalID); resumo.Media != 3 {
+					t.Fatalf("média do profissional não recalculada: %+v", resumo)
+				}
+			}},
+		{nome: "editar avaliação fora da janela", metodo: "PUT", rota: "/api/avaliacoes/:id", url: "/api/avaliacoes/" + agendamentoID, chamador: cliente,
+			corpo: map[string]interface{}{"nota": 2}, status: http.StatusConflict,
+			preparar: comAvaliacao(time.Now().AddDate(0, 0, -8))},
+		{nome: "editar avaliação de terceiro", metodo: "PUT", rota: "/api/avaliacoes/:id", url: "/api/avaliacoes/" + agendamentoID, chamador: quem{"cli-2", "clientes"},
+			corpo: map[string]interface{}{"nota": 1}, status: http.StatusForbidden,
+			preparar: comAvaliacao(time.Now())},
+		{nome: "responder avaliação", metodo: "POST", rota: "/api/avaliacoes/:id/resposta", url: "/api/avaliacoes/" + avaliacaoID + "/resposta", chamador: profissional,
+			corpo: map[string]string{"texto": "Obrigada pela visita!"}, status: http.StatusOK,
+			verificar: func(t *testing.T, w *httptest.ResponseRecorder, _ *storage.Repositorios) {
+				var av models.Avaliacao
+				decodificar(t, w, &av)
+				if av.Resposta == nil || av.Resposta.Texto != "Obrigada pela visita!" || av.Resposta.PorUID != profissionalID {
+					t.Fatalf("resposta não registrada: %+v", av.Resposta)
+				}
+			}},
+		{nome: "responder avaliação duas vezes", metodo: "POST", rota: "/api/avaliacoes/:id/resposta", url: "/api/avaliacoes/" + avaliacaoID + "/resposta", chamador: profissional,
+			corpo: map[string]string{"texto": "De novo"}, status: http.StatusConflict,
+			preparar: alterarAvaliacao(func(a *models.Avaliacao) error {
+				return a.Responder(models.RespostaAvaliacao{Texto: "Obrigada!", PorUID: profissionalID})
+			})},
+		{nome: "responder avaliação de outro profissional", metodo: "POST", rota: "/api/avaliacoes/:id/resposta", url: "/api/avaliacoes/" + avaliacaoID + "/resposta", chamador: outroProf,
+			corpo: map[string]string{"texto": "Olá"}, status: http.StatusForbidden},
+		{nome: "denunciar avaliação", metodo: "POST", rota: "/api/avaliacoes/:id/denunciar", url: "/api/avaliacoes/" + avaliacaoID + "/denunciar", chamador: outroProf,
+			corpo: denunciar, status: http.StatusOK,
+			verificar: func(t *testing.T, _ *httptest.ResponseRecorder, repos *storage.Repositorios) {
+				av, err := repos.Avaliacoes.Buscar(context.Background(), avaliacaoID)
+				if err != nil || !av.EmModeracao || len(av.Denuncias) != 1 || av.Denuncias[0].PorUID != outroProfID {
+					t.Fatalf("denúncia não registrada: %+v %v", av, err)
+				}
+			}},
+		{nome: "denunciar avaliação sem motivo", metodo: "POST", rota: "/api/avaliacoes/:id/denunciar", url: "/api/avaliacoes/" + avaliacaoID + "/denunciar", chamador: outroProf,
+			corpo: map[string]string{}, status: http.StatusBadRequest},
+		{nome: "denunciar avaliação duas vezes", metodo: "POST", rota: "/api/avaliacoes/:id/denunciar", url: "/api/avaliacoes/" + avaliacaoID + "/denunciar", chamador: outroProf,
+			corpo: denunciar, status: http.StatusConflict, preparar: comDenuncia(outroProfID)},
+		{nome: "relatório de avaliações ignora ocultas", metodo: "GET", rota: "/api/relatorios/avaliacoes/profissional/:id", url: "/api/relatorios/avaliacoes/profissional/" + profissionalID, chamador: cliente, status: http.StatusOK,
+			preparar: alterarAvaliacao(func(a *models.Avaliacao) error {
+				return a.Moderar(models.AcaoModeracao{Acao: models.ModeracaoOcultar, Motivo: "Ofensiva"})
+			}),
+			verificar: esperarCampo("quantidade_avaliacoes", 0.0)},
+
+		// Admin
+		{nome: "fila de moderação", metodo: "GET", rota: "/api/moderacao/avaliacoes", url: "/api/moderacao/avaliacoes", chamador: admin, status: http.StatusOK,
+			preparar: comDenuncia(outroProfID), verificar: esperarTamanho(1)},
+		{nome: "fila de moderação sem ser admin", metodo: "GET", rota: "/api/moderacao/avaliacoes", url: "/api/moderacao/avaliacoes", chamador: profissional, status: http.StatusForbidden},
+		{nome: "ocultar avaliação", metodo: "POST", rota: "/api/moderacao/avaliacoes/:id/ocultar", url: "/api/moderacao/avaliacoes/" + avaliacaoID + "/ocultar", chamador: admin,
+			corpo: moderar, status: http.StatusOK, preparar: comDenuncia(outroProfID),
+			verificar: func(t *testing.T, w *httptest.ResponseRecorder, repos *storage.Repositorios) {
+				var av models.Avaliacao
+				decodificar(t, w, &av)
+				if av.Situacao != models.SituacaoOculta || av.EmModeracao || len(av.Moderacao) != 1 || av.Moderacao[0].Motivo != "Conteúdo ofensivo" {
+					t.Fatalf("moderação não registrada: %+v", av)
+				}
+				if resumo, _ := repos.Avaliacoes.BuscarResumo(context.Background(), models.AlvoProfissional, profissionalID); resumo.Quantidade != 0 {
+					t.Fatalf("avaliação oculta não deveria contar no resumo: %+v", resumo)
+				}
+			}},
+		{nome: "ocultar avaliação sem motivo", metodo: "POST", rota: "/api/moderacao/avaliacoes/:id/ocultar", url: "/api/moderacao/avaliacoes/" + avaliacaoID + "/ocultar", chamador: admin,
+			corpo: map[string]string{"motivo": " "}, status: http.StatusBadRequest},
+		{nome: "restaurar avaliação oculta", metodo: "POST", rota: "/api/moderacao/avaliacoes/:id/restaurar", url: "/api/moderacao/avaliacoes/" + avaliacaoID + "/restaurar", chamador: admin,
+			corpo: moderar, status: http.StatusOK,
+			preparar: alterarAvaliacao(func(a *models.Avaliacao) error {
+				return a.Moderar(models.AcaoModeracao{Acao: models.ModeracaoOcultar, Motivo: "Engano"})
+			}),
+			verificar: esperarCampo("situacao", models.SituacaoPublicada)},
+		{nome: "restaurar avaliação sem denúncia", metodo: "POST", rota: "/api/moderacao/avaliacoes/:id/restaurar", url: "/api/moderacao/avaliacoes/" + avaliacaoID + "/restaurar", chamador: admin,
+			corpo: moderar, status: http.StatusConflict},
+		{nome: "excluir avaliação", metodo: "DELETE", rota: "/api/moderacao/avaliacoes/:id", url: "/api/moderacao/avaliacoes/" + avaliacaoID, chamador: admin,
+			corpo: moderar, status: http.StatusOK, verificar: esperarCampo("situacao", models.SituacaoExcluida)},
+		{nome: "excluir avaliação sem ser admin", metodo: "DELETE", rota: "/api/moderacao/avaliacoes/:id", url: "/api/moderacao/avaliacoes/" + avaliacaoID, chamador: cliente,
+			corpo: moderar, status: http.StatusForbidden},
+		{nome: "listar admins", metodo: "GET", rota: "/api/admins", url: "/api/admins", chamador: admin, status: http.StatusOK, verificar: esperarTamanho(1)},
+		{nome: "listar admins sem ser admin", metodo: "GET", rota: "/api/admins", url: "/api/admins", chamador: profissional, status: http.StatusForbidden},
+		{nome: "buscar admin", metodo: "GET", rota: "/api/admins/:id", url: "/api/admins/" + adminID, chamador: admin, status: http.StatusOK},
+		{nome: "buscar admin inexistente", metodo: "GET", rota: "/api/admins/:id", url: "/api/admins/nao-existe", chamador: admin, status: http.StatusNotFound},
+		{nome: "criar admin", metodo: "POST", rota: "/api/admins", url: "/api/admins", chamador: admin,
+			corpo: map[string]string{"nome": "Outro", "email": "outro@serviflex.com", "senha": "abc123"}, status: http.StatusCreated,
+			verificar: func(t *testing.T, w *httptest.ResponseRecorder, _ *storage.Repositorios) {
+				if strings.Contains(w.Body.String(), "senha") {
+					t.Fatal("resposta não deve conter a senha")
+				}
+			}},
+		{nome: "criar admin sem ser admin", metodo: "POST", rota: "/api/admins", url: "/api/admins", chamador: cliente,
+			corpo: map[string]string{"nome": "Outro", "email": "outro@serviflex.com", "senha": "abc123"}, status: http.StatusForbidden},
+		{nome: "editar admin", metodo: "PUT", rota: "/api/admins/:id", url: "/api/admins/" + adminID, chamador: admin,
+			corpo: map[string]string{"nome": "Admin Editado", "email": emailAdmin}, status: http.StatusOK},
+		{nome: "excluir admin", metodo: "DELETE", rota: "/api/admins/:id", url: "/api/admins/" + adminID, chamador: admin, status: http.StatusOK},
+		{nome: "excluir admin sem ser admin", metodo: "DELETE", rota: "/api/admins/:id", url: "/api/admins/" + adminID, chamador: profissional, status: http.StatusForbidden},
+	}
+}
+
+func TestRotas(t *testing.T) {
+	for _, caso := range casosRotas() {
+		t.Run(caso.nome, func(t *testing.T) {
+			r, repos := novoAmbiente(t)
+			if caso.preparar != nil {
+				caso.preparar(t, repos)
+			}
+			w := requisicao(t, r, caso.metodo, caso.url, caso.chamador, caso.corpo)
+			if w.Code != caso.status {
+				t.Fatalf("%s %s: status %d, esperado %d (corpo: %s)", caso.metodo, caso.url, w.Code, caso.status, w.Body.String())
+			}
+			if caso.verificar != nil {
+				caso.verificar(t, w, repos)
+			}
+		})
+	}
+}
+
+// TestTodasAsRotasCobertas garante que cada rota registrada em SetupRoutes tem ao menos um caso
+func TestTodasAsRotasCobertas(t *testing.T) {
+	cobertas := make(map[string]bool)
+	for _, caso := range casosRotas() {
+		cobertas[caso.metodo+" "+caso.rota] = true
+	}
+
+	r, _ := novoAmbiente(t)
+	for _, rota := range r.Routes() {
+		if !cobertas[rota.Method+" "+rota.Path] {
+			t.Errorf("rota sem caso de teste: %s %s", rota.Method, rota.Path)
+		}
+	}
+}
+
+func TestTokenInvalido(t *testing.T) {
+	r, _ := novoAmbiente(t)
+	req := httptest.NewRequest("GET", "/api/estabelecimentos", nil)
+	req.Header.Set("Authorization", "Bearer token.invalido.aqui")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("status %d, esperado 401", w.Code)
+	}
+}
+
+// perfilApontandoOutroEstabelecimento cria o estabelecimento "est-2" de outroProf e faz
+// o profissional do cenário tentar se vincular a ele editando o próprio perfil
+func perfilApontandoOutroEstabelecimento(t *testing.T, r http.Handler, repos *storage.Repositorios) {
+	t.Helper()
+	if err := repos.Estabelecimentos.Salvar(context.Background(), models.Estabelecimento{ID: "est-2", Nome: "Outro Studio", ResponsavelUID: outroProfID}); err != nil {
+		t.Fatal(err)
+	}
+	w := requisicao(t, r, "PUT", "/api/usuarios/"+profissionalID+"?tipo=profissionais", profissional,
+		map[string]string{"nome": "Maria Silva", "email": "maria@exemplo.com", "estabelecimentoId": "est-2"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("edição do perfil: status %d: %s", w.Code, w.Body.String())
+	}
+}
+
+// agendarPelaAPI agenda o procedimento do cenário para a próxima segunda às 10h, sem
+// informar o estabelecimento
+func agendarPelaAPI(t *testing.T, r http.Handler) models.Agendamento {
+	t.Helper()
+	w := requisicao(t, r, "POST", "/api/agendamentos", cliente, map[string]interface{}{
+		"cliente_id": clienteID, "profissional_id": profissionalID, "procedimento_id": procedimentoID, "data_hora": proximaSegunda(),
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("agendamento: status %d: %s", w.Code, w.Body.String())
+	}
+	var ag models.Agendamento
+	decodificar(t, w, &ag)
+	return ag
+}
+
+// atendimentoConcluido agenda pela API um atendimento do profissional do cenário e o
+// registra como concluído há uma hora
+func atendimentoConcluido(t *testing.T, r http.Handler, repos *storage.Repositorios) models.Agendamento {
+	t.Helper()
+	ag := agendarPelaAPI(t, r)
+	concluido, err := repos.Agendamentos.Atualizar(context.Background(), ag.ID, func(ag *models.Agendamento) error {
+		ag.Status = models.StatusConcluido
+		ag.DataHora = time.Now().Add(-time.Hour)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return *concluido
+}
+
+func TestPerfilNaoDesviaRepasses(t *testing.T) {
+	r, repos := novoAmbiente(t)
+	perfilApontandoOutroEstabelecimento(t, r, repos)
+	if ag := atendimentoConcluido(t, r, repos); ag.EstabelecimentoID != estabID {
+		t.Fatalf("agendamento gravado no estabelecimento %q", ag.EstabelecimentoID)
+	}
+
+	w := requisicao(t, r, "GET", "/api/relatorios/repasses/estabelecimento/"+estabID+"?de="+diaLocal(-10), profissional, nil)
+	var repasses struct {
+		Quantidade int `json:"quantidade_atendimentos"`
+	}
+	decodificar(t, w, &repasses)
+	if repasses.Quantidade != 2 {
+		t.Fatalf("o estabelecimento do vínculo deveria ter os 2 atendimentos: %s", w.Body.String())
+	}
+
+	w = requisicao(t, r, "GET", "/api/relatorios/repasses/estabelecimento/est-2?de="+diaLocal(-10), outroProf, nil)
+	decodificar(t, w, &repasses)
+	if w.Code != http.StatusOK || repasses.Quantidade != 0 {
+		t.Fatalf("atendimentos desviados para outro estabelecimento: %s", w.Body.String())
+	}
+	w = requisicao(t, r, "GET", "/api/relatorios/repasses/estabelecimento/est-2/profissional/"+profissionalID, profissional, nil)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("extrato em estabelecimento sem vínculo: status %d", w.Code)
+	}
+}
+
+func TestPerfilNaoDesviaOcupacao(t *testing.T) {
+	r, repos := novoAmbiente(t)
+	perfilApontandoOutroEstabelecimento(t, r, repos)
+	agendarPelaAPI(t, r)
+
+	dia := proximaSegunda().Format("2006-01-02")
+	var corpo struct {
+		Ocupacao agenda.Ocupacao `json:"ocupacao"`
+	}
+	w := requisicao(t, r, "GET", "/api/relatorios/ocupacao/estabelecimento/"+estabID+"?de="+dia+"&ate="+dia, profissional, nil)
+	decodificar(t, w, &corpo)
+	if corpo.Ocupacao.CapacidadeMin != 600 || corpo.Ocupacao.OcupadoMin != 30 {
+		t.Fatalf("o estabelecimento do vínculo deveria contar o atendimento: %s", w.Body.String())
+	}
+
+	w = requisicao(t, r, "GET", "/api/relatorios/ocupacao/estabelecimento/est-2?de="+dia+"&ate="+dia, outroProf, nil)
+	decodificar(t, w, &corpo)
+	if w.Code != http.StatusOK || corpo.Ocupacao.CapacidadeMin != 0 || corpo.Ocupacao.OcupadoMin != 0 {
+		t.Fatalf("agenda desviada para outro estabelecimento: %s", w.Body.String())
+	}
+}
+
+func TestClienteNaoDefineTaxa(t *testing.T) {
+	r, repos := novoAmbiente(t)
+	w := requisicao(t, r, "POST", "/api/agendamentos", cliente, map[string]interface{}{
+		"cliente_id": clienteID, "profissional_id": profissionalID, "procedimento_id": procedimentoID, "data_hora": proximaSegunda(),
+		"taxa": map[string]interface{}{"motivo": models.TaxaCancelamentoTardio, "percentual": 100, "valor": 999},
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("agendamento: status %d: %s", w.Code, w.Body.String())
+	}
+	var ag models.Agendamento
+	decodificar(t, w, &ag)
+	gravado, err := repos.Agendamentos.Buscar(context.Background(), ag.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gravado.Taxa != nil {
+		t.Fatalf("taxa enviada pelo cliente foi gravada: %+v", gravado.Taxa)
+	}
+
+	dia := proximaSegunda().Format("2006-01-02")
+	w = requisicao(t, r, "GET", "/api/relatorios/estabelecimento/faturamento/"+estabID+"?ate="+dia, profissional, nil)
+	var resumo struct {
+		Quantidade int     `json:"quantidade_taxas"`
+		Taxas      float64 `json:"total_taxas"`
+	}
+	decodificar(t, w, &resumo)
+	if resumo.Quantidade != 0 || resumo.Taxas != 0 {
+		t.Fatalf("faturamento contou a taxa do cliente: %s", w.Body.String())
+	}
+}
+
+// agendamentosConcorrentes executa antes de cada Atualizar uma alteração feita por outra
+// requisição entre a leitura do agendamento e a gravação
+type agendamentosConcorrentes struct {
+	storage.AgendamentoRepository
+	antes func(ag *models.Agendamento)
+}
+
+func (r agendamentosConcorrentes) Atualizar(ctx context.Context, id string, alterar func(ag *models.Agendamento) error) (*models.Agendamento, error) {
+	if _, err := r.AgendamentoRepository.Atualizar(ctx, id, func(ag *models.Agendamento) error {
+		r.antes(ag)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return r.AgendamentoRepository.Atualizar(ctx, id, alterar)
+}
+
+func TestTaxaUsaAgendamentoAtualizado(t *testing.T) {
+	_, repos := novoAmbiente(t)
+	ctx := context.Background()
+	comPolitica(models.PoliticaCancelamento{AntecedenciaHoras: 24, PercentualTardio: 50})(t, repos)
+	ajustarAgendamento(models.StatusConfirmado, time.Now().Add(2*time.Hour))(t, repos)
+	if err := repos.Estabelecimentos.Salvar(ctx, models.Estabelecimento{ID: "est-2", Nome: "Sem política"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Um reagendamento concorrente leva o atendimento para um estabelecimento sem política
+	repos.Agendamentos = agendamentosConcorrentes{repos.Agendamentos, func(ag *models.Agendamento) {
+		ag.EstabelecimentoID = "est-2"
+	}}
+	r := gin.New()
+	SetupRoutes(r, repos)
+
+	w := requisicao(t, r, "POST", "/api/agendamentos/"+agendamentoID+"/cancelar", cliente, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+	var ag models.Agendamento
+	decodificar(t, w, &ag)
+	if ag.Taxa != nil {
+		t.Fatalf("taxa calculada com a política anterior ao reagendamento: %+v", ag.Taxa)
+	}
+}
+
+// usuariosIndisponiveis simula o banco de usuários fora do ar
+type usuariosIndisponiveis struct {
+	storage.UsuarioRepository
+}
+
+func (usuariosIndisponiveis) BuscarPorEmail(context.Context, string, string) (*models.Usuario, error) {
+	return nil, errors.New("conexão recusada")
+}
+
+func TestLoginComBancoIndisponivel(t *testing.T) {
+	repos := memoria.NovosRepositorios()
+	repos.Usuarios = usuariosIndisponiveis{repos.Usuarios}
+	r := gin.New()
+	SetupRoutes(r, repos)
+	w := requisicao(t, r, "POST", "/api/login", anonimo, map[string]string{"email": emailCliente, "senha": senhaPadrao})
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("falha do banco deveria responder 500, obtido %d", w.Code)
+	}
+}
+
+// ajustarAgendamento coloca o agendamento do cenário no status e horário informados
+func ajustarAgendamento(status string, dataHora time.Time) func(*testing.T, *storage.Repositorios) {
+	return func(t *testing.T, repos *storage.Repositorios) {
+		t.Helper()
+		_, err := repos.Agendamentos.Atualizar(context.Background(), agendamentoID, func(ag *models.Agendamento) error {
+			ag.Status = status
+			ag.DataHora = dataHora
+			ag.DuracaoMin = 30
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("erro ao ajustar agendamento: %v", err)
+		}
+	}
+}
+
+// comExcecao cadastra uma exceção de horário para o profissional do cenário; sem ID usa excecaoID
+func comExcecao(e models.ExcecaoHorario) func(*testing.T, *storage.Repositorios) {
+	return func(t *testing.T, repos *storage.Repositorios) {
+		t.Helper()
+		if e.ID == "" {
+			e.ID = excecaoID
+		}
+		e.ProfissionalID = profissionalID
+		if err := repos.Excecoes.Salvar(context.Background(), e); err != nil {
+			t.Fatalf("erro ao salvar exceção de horário: %v", err)
+		}
+	}
+}
+
+// comAvaliacao registra a avaliação do cliente sobre o agendamento do cenário, enviada na data informada
+func comAvaliacao(data time.Time) func(*testing.T, *storage.Repositorios) {
+	return func(t *testing.T, repos *storage.Repositorios) {
+		t.Helper()
+		err := repos.Avaliacoes.Criar(context.Background(), models.Avaliacao{
+			ID: agendamentoID, AgendamentoID: agendamentoID, ProfissionalID: profissionalID,
+			ClienteID: clienteID, EstabelecimentoID: estabID, Nota: 4, Data: data,
+		})
+		if err != nil {
+			t.Fatalf("erro ao criar avaliação: %v", err)
+		}
+	}
+}
+
+// alterarAvaliacao aplica alterar à avaliação anterior à API do cenário
+func alterarAvaliacao(alterar func(*models.Avaliacao) error) func(*testing.T, *storage.Repositorios) {
+	return func(t *testing.T, repos *storage.Repositorios) {
+		t.Helper()
+		if _, err := repos.Avaliacoes.Atualizar(context.Background(), avaliacaoID, alterar); err != nil {
+			t.Fatalf("erro ao alterar avaliação: %v", err)
+		}
+	}
+}
+
+// comDenuncia registra uma denúncia do usuário sobre a avaliação do cenário
+func comDenuncia(porUID string) func(*testing.T, *storage.Repositorios) {
+	return alterarAvaliacao(func(a *models.Avaliacao) error {
+		return a.Denunciar(models.DenunciaAvaliacao{PorUID: porUID, Motivo: "Spam", Em: time.Now()})
+	})
+}
+
+// comFuso define o fuso horário do estabelecimento do cenário
+func comFuso(nome string) func(*testing.T, *storage.Repositorios) {
+	return func(t *testing.T, repos *storage.Repositorios) {
+		t.Helper()
+		ctx := context.Background()
+		e, err := repos.Estabelecimentos.Buscar(ctx, estabID)
+		if err != nil {
+			t.Fatalf("erro ao buscar estabelecimento: %v", err)
+		}
+		e.FusoHorario = nome
+		if err := repos.Estabelecimentos.Salvar(ctx, *e); err != nil {
+			t.Fatalf("erro ao salvar fuso: %v", err)
+		}
+	}
+}
+
+func fuso(nome string) *time.Location {
+	loc, err := models.CarregarFuso(nome)
+	if err != nil {
+		panic(err)
+	}
+	return loc
+}
+
+// inicioDoMes devolve a meia-noite do primeiro dia do mês corrente no fuso informado
+// diaLocal devolve a data de hoje mais dias (AAAA-MM-DD) no fuso do estabelecimento do cenário
+func diaLocal(dias int) string {
+	return time.Now().In(fuso("")).AddDate(0, 0, dias).Format("2006-01-02")
+}
+
+func inicioDoMes(nome string) time.Time {
+	agora := time.Now().In(fuso(nome))
+	return time.Date(agora.Year(), agora.Month(), 1, 0, 0, 0, 0, agora.Location())
+}
+
+// comPolitica define a política de cancelamento do estabelecimento do cenário
+func comPolitica(politica models.PoliticaCancelamento) func(*testing.T, *storage.Repositorios) {
+	return func(t *testing.T, repos *storage.Repositorios) {
+		t.Helper()
+		ctx := context.Background()
+		e, err := repos.Estabelecimentos.Buscar(ctx, estabID)
+		if err != nil {
+			t.Fatalf("erro ao buscar estabelecimento: %v", err)
+		}
+		e.PoliticaCancelamento = &politica
+		if err := repos.Estabelecimentos.Salvar(ctx, *e); err != nil {
+			t.Fatalf("erro ao salvar política: %v", err)
+		}
+	}
+}
+
+func decodificar(t *testing.T, w *httptest.ResponseRecorder, dest interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(w.Body.Bytes(), dest); err != nil {
+		t.Fatalf("resposta não é JSON válido: %v (%s)", err, w.Body.String())
+	}
+}
+
+// vincular liga o profissional ao estabelecimento do cenário, como o aceite de um convite
+func vincular(profID string) func(*testing.T, *storage.Repositorios) {
+	return func(t *testing.T, repos *storage.Repositorios) {
+		t.Helper()
+		ctx := context.Background()
+		err := repos.Estabelecimentos.VincularProfissional(ctx, estabID, models.ProfissionalEstabelecimento{UID: profID, Status: "ativo", AdicionadoEm: time.Now()})
+		if err == nil {
+			err = repos.Usuarios.DefinirEstabelecimentoProfissional(ctx, profID, estabID)
+		}
+		if err != nil {
+			t.Fatalf("erro ao vincular profissional: %v", err)
+		}
+	}
+}
+
+// comRegras define as regras de comissão do estabelecimento do cenário
+func comRegras(regras ...models.RegraComissao) func(*testing.T, *storage.Repositorios) {
+	return func(t *testing.T, repos *storage.Repositorios) {
+		e, _ := repos.Estabelecimentos.Buscar(context.Background(), estabID)
+		e.Comissoes = regras
+		if err := repos.Estabelecimentos.Salvar(context.Background(), *e); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func esperarTamanho(n int) func(*testing.T, *httptest.ResponseRecorder, *storage.Repositorios) {
+	return func(t *testing.T, w *httptest.ResponseRecorder, _ *storage.Repositorios) {
+		t.Helper()
+		var lista []interface{}
+		decodificar(t, w, &lista)
+		if len(lista) != n {
+			t.Fatalf("esperados %d itens, obtidos %d: %s", n, len(lista), w.Body.String())
+		}
+	}
+}
+
+func esperarCampo(campo string, valor interface{}) func(*testing.T, *httptest.ResponseRecorder, *storage.Repositorios) {
+	return func(t *testing.T, w *httptest.ResponseRecorder, _ *storage.Repositorios) {
+		t.Helper()
+		var resp map[string]interface{}
+		decodificar(t, w, &resp)
+		if resp[campo] != valor {
+			t.Fatalf("%s = %v, esperado %v", campo, resp[campo], valor)
+		}
+	}
+}
+
+func comCampo(base map[string]interface{}, campo string, valor interface{}) map[string]interface{} {
+	copia := make(map[string]interface{}, len(base))
+	for k, v := range base {
+		copia[k] = v
+	}
+	copia[campo] = valor
+	return copia
+}
+
+func semCampo(base map[string]interface{}, campo string) map[string]interface{} {
+	copia := comCampo(base, campo, nil)
+	delete(copia, campo)
+	return copia
+}
@@ -0,0 +1,192 @@
+package firestoredb
+
+import (
+	"context"
+	"errors"
+	"servico-api/models"
+	"servico-api/storage"
+	"sort"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// AgendamentoRepository implementa storage.AgendamentoRepository na coleção "agendamentos"
+type AgendamentoRepository struct {
+	client *firestore.Client
+}
+
+func (r *AgendamentoRepository) Criar(ctx context.Context, ag models.Agendamento) error {
+	_, err := r.client.Collection("agendamentos").Doc(ag.ID).Set(ctx, ag)
+	return err
+}
+
+// Reservar verifica conflitos e grava o agendamento numa transação. Todas as reservas
+// de um profissional leem e escrevem o mesmo documento em "travas_agenda", o que faz o
+// Firestore abortar e repetir uma das transações quando duas disputam a mesma agenda.
+func (r *AgendamentoRepository) Reservar(ctx context.Context, ag models.Agendamento) error {
+	trava := r.client.Collection("travas_agenda").Doc(ag.ProfissionalID)
+	novo := r.client.Collection("agendamentos").Doc(ag.ID)
+
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		if _, err := tx.Get(trava); err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+
+		existentes, err := r.existentesNaTransacao(tx, ag)
+		if err != nil {
+			return err
+		}
+		if conflito := storage.BuscarConflito(existentes, ag); conflito != nil {
+			return conflito
+		}
+
+		if err := tx.Set(trava, map[string]interface{}{"atualizadoEm": firestore.ServerTimestamp}); err != nil {
+			return err
+		}
+		return tx.Create(novo, ag)
+	})
+}
+
+func (r *AgendamentoRepository) Buscar(ctx context.Context, id string) (*models.Agendamento, error) {
+	var ag models.Agendamento
+	if err := buscar(ctx, r.client.Collection("agendamentos").Doc(id), &ag); err != nil {
+		return nil, err
+	}
+	return &ag, nil
+}
+
+// Atualizar relê o agendamento dentro de uma transação; mudanças de horário passam pela
+// mesma trava de agenda e verificação de conflitos de Reservar
+func (r *AgendamentoRepository) Atualizar(ctx context.Context, id string, alterar func(ag *models.Agendamento) error) (*models.Agendamento, error) {
+	ref := r.client.Collection("agendamentos").Doc(id)
+	var resultado models.Agendamento
+
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return storage.ErrNaoEncontrado
+		}
+		if err != nil {
+			return err
+		}
+		var antes models.Agendamento
+		if err := doc.DataTo(&antes); err != nil {
+			return err
+		}
+
+		trava := r.client.Collection("travas_agenda").Doc(antes.ProfissionalID)
+		if _, err := tx.Get(trava); err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+
+		depois := antes
+		depois.Historico = append([]models.TransicaoStatus(nil), antes.Historico...)
+		if err := alterar(&depois); err != nil {
+			return err
+		}
+
+		if storage.MudouIntervalo(antes, depois) {
+			existentes, err := r.existentesNaTransacao(tx, depois)
+			if err != nil {
+				return err
+			}
+			if conflito := storage.BuscarConflito(existentes, depois); conflito != nil {
+				return conflito
+			}
+			if err := tx.Set(trava, map[string]interface{}{"atualizadoEm": firestore.ServerTimestamp}); err != nil {
+				return err
+			}
+		}
+
+		resultado = depois
+		return tx.Set(ref, depois)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &resultado, nil
+}
+
+// existentesNaTransacao lê, dentro da transação, os agendamentos do profissional que
+// podem cruzar o intervalo de ag
+func (r *AgendamentoRepository) existentesNaTransacao(tx *firestore.Transaction, ag models.Agendamento) ([]models.Agendamento, error) {
+	q := r.consulta(storage.FiltroAgendamento{
+		ProfissionalID: ag.ProfissionalID,
+		De:             ag.DataHora.Add(-storage.JanelaConflito),
+		Ate:            ag.Fim(),
+	})
+	docs, err := tx.Documents(q).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	var existentes []models.Agendamento
+	for _, doc := range docs {
+		var existente models.Agendamento
+		if err := doc.DataTo(&existente); err == nil {
+			existentes = append(existentes, existente)
+		}
+	}
+	return existentes, nil
+}
+
+func (r *AgendamentoRepository) Listar(ctx context.Context, filtro storage.FiltroAgendamento) ([]models.Agendamento, error) {
+	agendamentos, err := listar[models.Agendamento](ctx, r.consulta(filtro), nil)
+	if err != nil {
+		return nil, err
+	}
+	// Ordena em memória para não exigir um índice composto por combinação de filtros
+	sort.SliceStable(agendamentos, func(i, j int) bool {
+		return agendamentos[i].DataHora.Before(agendamentos[j].DataHora)
+	})
+	return agendamentos, nil
+}
+
+// Percorrer lê os documentos em lotes pelo iterador, ordenados no servidor. Ao contrário
+// de Listar, exige um índice composto de cada filtro usado com dataHora.
+func (r *AgendamentoRepository) Percorrer(ctx context.Context, filtro storage.FiltroAgendamento, visitar func(models.Agendamento) error) error {
+	docs := r.consulta(filtro).OrderBy("dataHora", firestore.Asc).Documents(ctx)
+	defer docs.Stop()
+	for {
+		doc, err := docs.Next()
+		if errors.Is(err, iterator.Done) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var ag models.Agendamento
+		if err := doc.DataTo(&ag); err != nil {
+			continue
+		}
+		if err := visitar(ag); err != nil {
+			return err
+		}
+	}
+}
+
+// consulta traduz o filtro para as cláusulas where da coleção "agendamentos"
+func (r *AgendamentoRepository) consulta(filtro storage.FiltroAgendamento) firestore.Query {
+	q := r.client.Collection("agendamentos").Query
+	if filtro.ClienteID != "" {
+		q = q.Where("clienteId", "==", filtro.ClienteID)
+	}
+	if filtro.ProfissionalID != "" {
+		q = q.Where("profissionalId", "==", filtro.ProfissionalID)
+	}
+	if filtro.EstabelecimentoID != "" {
+		q = q.Where("estabelecimentoId", "==", filtro.EstabelecimentoID)
+	}
+	if !filtro.De.IsZero() {
+		q = q.Where("dataHora", ">=", filtro.De)
+	}
+	if !filtro.Ate.IsZero() {
+		q = q.Where("dataHora", "<=", filtro.Ate)
+	}
+	if filtro.Status != "" {
+		q = q.Where("status", "==", filtro.Status)
+	}
+	return q
+}
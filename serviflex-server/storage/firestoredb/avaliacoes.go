@@ -0,0 +1,263 @@
+package firestoredb
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"servico-api/models"
+	"servico-api/storage"
+	"strconv"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// AvaliacaoRepository implementa storage.AvaliacaoRepository na coleção "avaliacoes". O
+// resumo de notas fica no documento "resumos/avaliacoes" abaixo do profissional ou do
+// estabelecimento, fora do documento principal que os handlers regravam por inteiro.
+// O resumo geral de cada tipo, que toda avaliação da plataforma altera, é dividido em
+// fragmentosResumoGeral documentos em "resumos_avaliacoes/{alvo}/fragmentos": cada
+// gravação incrementa um deles ao acaso, sem lê-lo, e a leitura soma todos.
+type AvaliacaoRepository struct {
+	client *firestore.Client
+}
+
+// fragmentosResumoGeral divide as gravações no resumo geral para ficar abaixo do limite
+// de cerca de uma gravação por segundo em cada documento do Firestore
+const fragmentosResumoGeral = 10
+
+// fragmentoResumo é uma parte do resumo geral; o histograma é indexado pela nota, de "1" a "5"
+type fragmentoResumo struct {
+	Quantidade int            `firestore:"quantidade"`
+	Soma       float64        `firestore:"soma"`
+	Histograma map[string]int `firestore:"histograma"`
+}
+
+func (r *AvaliacaoRepository) Criar(ctx context.Context, a models.Avaliacao) error {
+	ref := r.client.Collection("avaliacoes").Doc(a.ID)
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		resumos, err := r.lerResumos(tx, a)
+		if err != nil {
+			return err
+		}
+		if err := tx.Create(ref, a); err != nil {
+			return err
+		}
+		return r.gravarResumos(tx, a, resumos, nil, &a)
+	})
+	if status.Code(err) == codes.AlreadyExists {
+		return storage.ErrJaExiste
+	}
+	return err
+}
+
+func (r *AvaliacaoRepository) Buscar(ctx context.Context, id string) (*models.Avaliacao, error) {
+	var a models.Avaliacao
+	if err := buscar(ctx, r.client.Collection("avaliacoes").Doc(id), &a); err != nil {
+		return nil, err
+	}
+	a.ID = id
+	return &a, nil
+}
+
+func (r *AvaliacaoRepository) Atualizar(ctx context.Context, id string, alterar func(a *models.Avaliacao) error) (*models.Avaliacao, error) {
+	ref := r.client.Collection("avaliacoes").Doc(id)
+	var resultado models.Avaliacao
+
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return storage.ErrNaoEncontrado
+		}
+		if err != nil {
+			return err
+		}
+		var antes models.Avaliacao
+		if err := doc.DataTo(&antes); err != nil {
+			return err
+		}
+		antes.ID = id
+
+		depois := antes
+		depois.Denuncias = append([]models.DenunciaAvaliacao(nil), antes.Denuncias...)
+		depois.Moderacao = append([]models.AcaoModeracao(nil), antes.Moderacao...)
+		if err := alterar(&depois); err != nil {
+			return err
+		}
+
+		// Respostas e denúncias não mudam nota nem visibilidade e não tocam nos resumos
+		var resumos []resumoLido
+		if _, mudou := storage.VariacaoResumo(&antes, &depois); mudou {
+			if resumos, err = r.lerResumos(tx, antes); err != nil {
+				return err
+			}
+		}
+		if err := tx.Set(ref, depois); err != nil {
+			return err
+		}
+		resultado = depois
+		return r.gravarResumos(tx, antes, resumos, &antes, &depois)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &resultado, nil
+}
+
+// resumoLido é um resumo lido na transação, junto da referência onde será regravado
+type resumoLido struct {
+	ref    *firestore.DocumentRef
+	resumo models.ResumoAvaliacoes
+}
+
+// lerResumos lê na transação os resumos de cada alvo afetado pela avaliação; o resumo
+// geral não é lido. O Firestore exige que todas as leituras venham antes das gravações.
+func (r *AvaliacaoRepository) lerResumos(tx *firestore.Transaction, a models.Avaliacao) ([]resumoLido, error) {
+	var lidos []resumoLido
+	for _, alvo := range storage.AlvosDaAvaliacao(a) {
+		if alvo.ID == "" {
+			continue
+		}
+		lido := resumoLido{ref: r.resumo(alvo.Alvo, alvo.ID)}
+		doc, err := tx.Get(lido.ref)
+		switch {
+		case status.Code(err) == codes.NotFound:
+		case err != nil:
+			return nil, err
+		default:
+			if err := doc.DataTo(&lido.resumo); err != nil {
+				return nil, err
+			}
+		}
+		lidos = append(lidos, lido)
+	}
+	return lidos, nil
+}
+
+// gravarResumos regrava os resumos lidos e soma a variação a um fragmento ao acaso do
+// resumo geral de cada tipo de alvo de a
+func (r *AvaliacaoRepository) gravarResumos(tx *firestore.Transaction, a models.Avaliacao, lidos []resumoLido, antes, depois *models.Avaliacao) error {
+	v, mudou := storage.VariacaoResumo(antes, depois)
+	if !mudou {
+		return nil
+	}
+	for _, lido := range lidos {
+		lido.resumo.Aplicar(antes, depois)
+		if err := tx.Set(lido.ref, lido.resumo); err != nil {
+			return err
+		}
+	}
+
+	histograma := make(map[string]interface{})
+	for i, n := range v.Histograma {
+		if n != 0 {
+			histograma[strconv.Itoa(i+1)] = firestore.Increment(n)
+		}
+	}
+	for _, alvo := range storage.AlvosDaAvaliacao(a) {
+		if alvo.ID != "" {
+			continue
+		}
+		fragmento := r.fragmentos(alvo.Alvo).Doc(strconv.Itoa(rand.Intn(fragmentosResumoGeral)))
+		err := tx.Set(fragmento, map[string]interface{}{
+			"quantidade": firestore.Increment(v.Quantidade),
+			"soma":       firestore.Increment(v.Soma),
+			"histograma": histograma,
+		}, firestore.MergeAll)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *AvaliacaoRepository) ListarEmModeracao(ctx context.Context) ([]models.Avaliacao, error) {
+	q := r.client.Collection("avaliacoes").Where("emModeracao", "==", true)
+	return listar(ctx, q, comIDAvaliacao)
+}
+
+func (r *AvaliacaoRepository) ListarPorProfissional(ctx context.Context, profissionalID string) ([]models.Avaliacao, error) {
+	q := r.client.Collection("avaliacoes").Where("profissionalId", "==", profissionalID)
+	return listar(ctx, q, comIDAvaliacao)
+}
+
+func (r *AvaliacaoRepository) ListarPorEstabelecimento(ctx context.Context, estabelecimentoID string) ([]models.Avaliacao, error) {
+	q := r.client.Collection("avaliacoes").Where("estabelecimentoId", "==", estabelecimentoID)
+	return listar(ctx, q, comIDAvaliacao)
+}
+
+func (r *AvaliacaoRepository) BuscarResumo(ctx context.Context, alvo, id string) (models.ResumoAvaliacoes, error) {
+	if id == "" {
+		return r.resumoGeral(ctx, alvo)
+	}
+	var resumo models.ResumoAvaliacoes
+	err := buscar(ctx, r.resumo(alvo, id), &resumo)
+	if errors.Is(err, storage.ErrNaoEncontrado) {
+		return models.ResumoAvaliacoes{}, nil
+	}
+	return resumo, err
+}
+
+func (r *AvaliacaoRepository) BuscarResumos(ctx context.Context, alvo string, ids []string) (map[string]models.ResumoAvaliacoes, error) {
+	resumos := make(map[string]models.ResumoAvaliacoes)
+	if len(ids) == 0 {
+		return resumos, nil
+	}
+	refs := make([]*firestore.DocumentRef, len(ids))
+	for i, id := range ids {
+		refs[i] = r.resumo(alvo, id)
+	}
+	docs, err := r.client.GetAll(ctx, refs)
+	if err != nil {
+		return nil, err
+	}
+	for i, doc := range docs {
+		var resumo models.ResumoAvaliacoes
+		if !doc.Exists() || doc.DataTo(&resumo) != nil {
+			continue
+		}
+		resumos[ids[i]] = resumo
+	}
+	return resumos, nil
+}
+
+// resumoGeral soma os fragmentos do resumo geral do tipo de alvo
+func (r *AvaliacaoRepository) resumoGeral(ctx context.Context, alvo string) (models.ResumoAvaliacoes, error) {
+	docs, err := r.fragmentos(alvo).Documents(ctx).GetAll()
+	if err != nil {
+		return models.ResumoAvaliacoes{}, err
+	}
+	geral := models.ResumoAvaliacoes{}.ComHistograma()
+	for _, doc := range docs {
+		var f fragmentoResumo
+		if err := doc.DataTo(&f); err != nil {
+			return models.ResumoAvaliacoes{}, err
+		}
+		geral.Quantidade += f.Quantidade
+		geral.Soma += f.Soma
+		for nota, n := range f.Histograma {
+			if estrelas, err := strconv.Atoi(nota); err == nil && estrelas >= 1 && estrelas <= 5 {
+				geral.Histograma[estrelas-1] += n
+			}
+		}
+	}
+	if geral.Quantidade > 0 {
+		geral.Media = geral.Soma / float64(geral.Quantidade)
+	}
+	return geral, nil
+}
+
+// resumo devolve o documento do resumo de um profissional ou estabelecimento
+func (r *AvaliacaoRepository) resumo(alvo, id string) *firestore.DocumentRef {
+	return r.client.Collection(alvo).Doc(id).Collection("resumos").Doc("avaliacoes")
+}
+
+// fragmentos devolve a coleção com os fragmentos do resumo geral do tipo de alvo
+func (r *AvaliacaoRepository) fragmentos(alvo string) *firestore.CollectionRef {
+	return r.client.Collection("resumos_avaliacoes").Doc(alvo).Collection("fragmentos")
+}
+
+func comIDAvaliacao(a *models.Avaliacao, id string) {
+	a.ID = id
+}
@@ -0,0 +1,139 @@
+package firestoredb
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"reflect"
+	"servico-api/models"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// modelosPorRepositorio indica quais modelos cada repositório grava; um campo consultado
+// precisa existir em ao menos um deles
+var modelosPorRepositorio = map[string][]interface{}{
+	"AgendamentoRepository":     {models.Agendamento{}},
+	"AvaliacaoRepository":       {models.Avaliacao{}},
+	"EstabelecimentoRepository": {models.Estabelecimento{}, models.ProfissionalEstabelecimento{}},
+	"ExcecaoHorarioRepository":  {models.ExcecaoHorario{}},
+	"HorarioRepository":         {models.Horario{}},
+	"NotificacaoRepository":     {models.Notificacao{}},
+	"ProcedimentoRepository":    {models.Procedimento{}},
+	"UsuarioRepository":         {models.Cliente{}, models.Profissional{}, models.Admin{}},
+}
+
+// camposGravados lista os caminhos de campo (com ponto nos aninhados) que o Firestore
+// grava para o tipo, seguindo as tags firestore
+func camposGravados(t reflect.Type, prefixo string, campos map[string]bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		nome, _, _ := strings.Cut(f.Tag.Get("firestore"), ",")
+		if nome == "-" || !f.IsExported() {
+			continue
+		}
+		if nome == "" {
+			nome = f.Name
+		}
+		campos[prefixo+nome] = true
+		tipo := f.Type
+		for tipo.Kind() == reflect.Ptr {
+			tipo = tipo.Elem()
+		}
+		if tipo.Kind() == reflect.Struct && tipo != reflect.TypeOf(time.Time{}) {
+			camposGravados(tipo, prefixo+nome+".", campos)
+		}
+	}
+}
+
+// referenciaCampo é um nome de campo usado em Where, OrderBy ou firestore.Update
+type referenciaCampo struct {
+	repositorio string
+	campo       string
+	pos         token.Position
+}
+
+func referenciasDeCampos(t *testing.T) []referenciaCampo {
+	arquivos, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	var refs []referenciaCampo
+	for _, arquivo := range arquivos {
+		if strings.HasSuffix(arquivo, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, arquivo, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv == nil || fn.Body == nil {
+				continue
+			}
+			repositorio := nomeDoReceptor(fn.Recv.List[0].Type)
+			registrar := func(expr ast.Expr) {
+				lit, ok := expr.(*ast.BasicLit)
+				if !ok || lit.Kind != token.STRING {
+					t.Errorf("%s: use um literal como nome de campo para que o esquema possa ser conferido", fset.Position(expr.Pos()))
+					return
+				}
+				campo, _ := strconv.Unquote(lit.Value)
+				refs = append(refs, referenciaCampo{repositorio, campo, fset.Position(lit.Pos())})
+			}
+			ast.Inspect(fn.Body, func(n ast.Node) bool {
+				switch n := n.(type) {
+				case *ast.CallExpr:
+					if sel, ok := n.Fun.(*ast.SelectorExpr); ok && (sel.Sel.Name == "Where" || sel.Sel.Name == "OrderBy") && len(n.Args) > 0 {
+						registrar(n.Args[0])
+					}
+				case *ast.KeyValueExpr:
+					if chave, ok := n.Key.(*ast.Ident); ok && chave.Name == "Path" {
+						registrar(n.Value)
+					}
+				}
+				return true
+			})
+		}
+	}
+	return refs
+}
+
+func nomeDoReceptor(expr ast.Expr) string {
+	if estrela, ok := expr.(*ast.StarExpr); ok {
+		expr = estrela.X
+	}
+	if ident, ok := expr.(*ast.Ident); ok {
+		return ident.Name
+	}
+	return ""
+}
+
+func TestConsultasUsamCamposDosModelos(t *testing.T) {
+	refs := referenciasDeCampos(t)
+	if len(refs) == 0 {
+		t.Fatal("nenhuma consulta encontrada; o teste deixou de enxergar os repositórios")
+	}
+	for _, ref := range refs {
+		modelos, ok := modelosPorRepositorio[ref.repositorio]
+		if !ok {
+			t.Errorf("%s: %s consulta o Firestore mas não está em modelosPorRepositorio", ref.pos, ref.repositorio)
+			continue
+		}
+		campos := make(map[string]bool)
+		for _, m := range modelos {
+			camposGravados(reflect.TypeOf(m), "", campos)
+		}
+		if !campos[ref.campo] {
+			t.Errorf("%s: %s usa o campo %q, que nenhum modelo grava", ref.pos, ref.repositorio, ref.campo)
+		}
+	}
+}
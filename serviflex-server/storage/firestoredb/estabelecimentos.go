@@ -0,0 +1,52 @@
+package firestoredb
+
+import (
+	"context"
+	"servico-api/models"
+
+	"cloud.google.com/go/firestore"
+)
+
+// EstabelecimentoRepository implementa storage.EstabelecimentoRepository na coleção
+// "estabelecimentos" e na subcoleção "profissionais" de cada estabelecimento
+type EstabelecimentoRepository struct {
+	client *firestore.Client
+}
+
+func (r *EstabelecimentoRepository) Salvar(ctx context.Context, e models.Estabelecimento) error {
+	_, err := r.client.Collection("estabelecimentos").Doc(e.ID).Set(ctx, e)
+	return err
+}
+
+func (r *EstabelecimentoRepository) Buscar(ctx context.Context, id string) (*models.Estabelecimento, error) {
+	var e models.Estabelecimento
+	if err := buscar(ctx, r.client.Collection("estabelecimentos").Doc(id), &e); err != nil {
+		return nil, err
+	}
+	e.ID = id
+	return &e, nil
+}
+
+func (r *EstabelecimentoRepository) Listar(ctx context.Context) ([]models.Estabelecimento, error) {
+	return listar(ctx, r.client.Collection("estabelecimentos").Query, func(e *models.Estabelecimento, id string) {
+		e.ID = id
+	})
+}
+
+func (r *EstabelecimentoRepository) VincularProfissional(ctx context.Context, estabelecimentoID string, v models.ProfissionalEstabelecimento) error {
+	_, err := r.profissionais(estabelecimentoID).Doc(v.UID).Set(ctx, v)
+	return err
+}
+
+func (r *EstabelecimentoRepository) DesvincularProfissional(ctx context.Context, estabelecimentoID, profissionalID string) error {
+	_, err := r.profissionais(estabelecimentoID).Doc(profissionalID).Delete(ctx)
+	return err
+}
+
+func (r *EstabelecimentoRepository) ListarProfissionais(ctx context.Context, estabelecimentoID string) ([]models.ProfissionalEstabelecimento, error) {
+	return listar[models.ProfissionalEstabelecimento](ctx, r.profissionais(estabelecimentoID).Query, nil)
+}
+
+func (r *EstabelecimentoRepository) profissionais(estabelecimentoID string) *firestore.CollectionRef {
+	return r.client.Collection("estabelecimentos").Doc(estabelecimentoID).Collection("profissionais")
+}
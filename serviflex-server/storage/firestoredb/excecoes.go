@@ -0,0 +1,52 @@
+package firestoredb
+
+import (
+	"context"
+	"servico-api/models"
+	"servico-api/storage"
+
+	"cloud.google.com/go/firestore"
+)
+
+// ExcecaoHorarioRepository implementa storage.ExcecaoHorarioRepository na coleção "excecoes_horario"
+type ExcecaoHorarioRepository struct {
+	client *firestore.Client
+}
+
+func (r *ExcecaoHorarioRepository) Salvar(ctx context.Context, e models.ExcecaoHorario) error {
+	_, err := r.client.Collection("excecoes_horario").Doc(e.ID).Set(ctx, e)
+	return err
+}
+
+func (r *ExcecaoHorarioRepository) Buscar(ctx context.Context, id string) (*models.ExcecaoHorario, error) {
+	var e models.ExcecaoHorario
+	if err := buscar(ctx, r.client.Collection("excecoes_horario").Doc(id), &e); err != nil {
+		return nil, err
+	}
+	e.ID = id
+	return &e, nil
+}
+
+func (r *ExcecaoHorarioRepository) Excluir(ctx context.Context, id string) error {
+	_, err := r.client.Collection("excecoes_horario").Doc(id).Delete(ctx)
+	return err
+}
+
+// ListarPorProfissional filtra o período na aplicação: o Firestore não compara dois campos
+// de intervalo na mesma consulta e cada profissional tem poucas exceções
+func (r *ExcecaoHorarioRepository) ListarPorProfissional(ctx context.Context, profissionalID, de, ate string) ([]models.ExcecaoHorario, error) {
+	q := r.client.Collection("excecoes_horario").
+		Where("profissionalId", "==", profissionalID).
+		OrderBy("dataInicio", firestore.Asc)
+	todas, err := listar(ctx, q, func(e *models.ExcecaoHorario, id string) { e.ID = id })
+	if err != nil {
+		return nil, err
+	}
+	var excecoes []models.ExcecaoHorario
+	for _, e := range todas {
+		if storage.ExcecaoNoPeriodo(e, de, ate) {
+			excecoes = append(excecoes, e)
+		}
+	}
+	return excecoes, nil
+}
@@ -0,0 +1,64 @@
+// Package firestoredb implementa os repositórios de storage sobre o Cloud Firestore.
+//
+// Os campos dos documentos seguem as tags firestore dos modelos, sempre em camelCase
+// (profissionalId, dataHora). Consultas e atualizações parciais devem usar esses mesmos
+// nomes; TestConsultasUsamCamposDosModelos confere isso.
+package firestoredb
+
+import (
+	"context"
+	"servico-api/storage"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// NovosRepositorios cria todos os repositórios compartilhando o mesmo cliente Firestore
+func NovosRepositorios(client *firestore.Client) *storage.Repositorios {
+	agendamentos := &AgendamentoRepository{client: client}
+	procedimentos := &ProcedimentoRepository{client: client}
+	return &storage.Repositorios{
+		Agendamentos:     agendamentos,
+		Horarios:         &HorarioRepository{client: client},
+		Excecoes:         &ExcecaoHorarioRepository{client: client},
+		Procedimentos:    procedimentos,
+		Estabelecimentos: &EstabelecimentoRepository{client: client},
+		Usuarios:         &UsuarioRepository{client: client},
+		Notificacoes:     &NotificacaoRepository{client: client},
+		Avaliacoes:       &AvaliacaoRepository{client: client},
+		Relatorios:       &RelatorioRepository{agendamentos: agendamentos},
+	}
+}
+
+// buscar lê um documento em dest, convertendo a ausência em storage.ErrNaoEncontrado
+func buscar(ctx context.Context, ref *firestore.DocumentRef, dest interface{}) error {
+	doc, err := ref.Get(ctx)
+	if status.Code(err) == codes.NotFound || (err == nil && !doc.Exists()) {
+		return storage.ErrNaoEncontrado
+	}
+	if err != nil {
+		return err
+	}
+	return doc.DataTo(dest)
+}
+
+// listar executa a consulta e converte os documentos, ignorando os que não decodificam
+func listar[T any](ctx context.Context, q firestore.Query, comID func(*T, string)) ([]T, error) {
+	docs, err := q.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+	var itens []T
+	for _, doc := range docs {
+		var item T
+		if err := doc.DataTo(&item); err != nil {
+			continue
+		}
+		if comID != nil {
+			comID(&item, doc.Ref.ID)
+		}
+		itens = append(itens, item)
+	}
+	return itens, nil
+}
@@ -0,0 +1,87 @@
+package firestoredb
+
+import (
+	"context"
+	"servico-api/models"
+	"servico-api/storage"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// HorarioRepository implementa storage.HorarioRepository na coleção "horarios"
+type HorarioRepository struct {
+	client *firestore.Client
+}
+
+func (r *HorarioRepository) Salvar(ctx context.Context, h models.Horario) error {
+	_, err := r.client.Collection("horarios").Doc(h.ID).Set(ctx, h)
+	return err
+}
+
+// SalvarTurnos verifica sobreposições e grava os turnos numa transação. Como em
+// AgendamentoRepository.Reservar, as gravações de um profissional leem e escrevem o mesmo
+// documento em "travas_turnos", e o Firestore repete uma das transações que disputarem.
+func (r *HorarioRepository) SalvarTurnos(ctx context.Context, profissionalID string, turnos []models.Horario) error {
+	trava := r.client.Collection("travas_turnos").Doc(profissionalID)
+	q := r.client.Collection("horarios").Where("profissionalId", "==", profissionalID)
+
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		if _, err := tx.Get(trava); err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+
+		docs, err := tx.Documents(q).GetAll()
+		if err != nil {
+			return err
+		}
+		var existentes []models.Horario
+		for _, doc := range docs {
+			var h models.Horario
+			if err := doc.DataTo(&h); err == nil {
+				h.ID = doc.Ref.ID
+				existentes = append(existentes, h)
+			}
+		}
+		if sobreposto := storage.BuscarSobreposicao(existentes, turnos); sobreposto != nil {
+			return sobreposto
+		}
+
+		if err := tx.Set(trava, map[string]interface{}{"atualizadoEm": firestore.ServerTimestamp}); err != nil {
+			return err
+		}
+		for _, h := range turnos {
+			if err := tx.Set(r.client.Collection("horarios").Doc(h.ID), h); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *HorarioRepository) Buscar(ctx context.Context, id string) (*models.Horario, error) {
+	var h models.Horario
+	if err := buscar(ctx, r.client.Collection("horarios").Doc(id), &h); err != nil {
+		return nil, err
+	}
+	h.ID = id
+	return &h, nil
+}
+
+func (r *HorarioRepository) Excluir(ctx context.Context, id string) error {
+	_, err := r.client.Collection("horarios").Doc(id).Delete(ctx)
+	return err
+}
+
+func (r *HorarioRepository) ListarPorProfissional(ctx context.Context, profissionalID string) ([]models.Horario, error) {
+	q := r.client.Collection("horarios").Where("profissionalId", "==", profissionalID)
+	return listar[models.Horario](ctx, q, nil)
+}
+
+func (r *HorarioRepository) ListarPorDia(ctx context.Context, profissionalID string, diaSemana models.DiaSemana) ([]models.Horario, error) {
+	q := r.client.Collection("horarios").
+		Where("profissionalId", "==", profissionalID).
+		Where("diaSemana", "==", int(diaSemana))
+	return listar[models.Horario](ctx, q, nil)
+}
@@ -0,0 +1,45 @@
+package firestoredb
+
+import (
+	"context"
+	"servico-api/models"
+
+	"cloud.google.com/go/firestore"
+)
+
+// NotificacaoRepository implementa storage.NotificacaoRepository na coleção "notificacoes"
+type NotificacaoRepository struct {
+	client *firestore.Client
+}
+
+func (r *NotificacaoRepository) Criar(ctx context.Context, n models.Notificacao) error {
+	_, err := r.client.Collection("notificacoes").Doc(n.ID).Set(ctx, n)
+	return err
+}
+
+func (r *NotificacaoRepository) Buscar(ctx context.Context, id string) (*models.Notificacao, error) {
+	var n models.Notificacao
+	if err := buscar(ctx, r.client.Collection("notificacoes").Doc(id), &n); err != nil {
+		return nil, err
+	}
+	n.ID = id
+	return &n, nil
+}
+
+func (r *NotificacaoRepository) Responder(ctx context.Context, id, resposta string) error {
+	_, err := r.client.Collection("notificacoes").Doc(id).Update(ctx, []firestore.Update{
+		{Path: "respondido", Value: true},
+		{Path: "resposta", Value: resposta},
+	})
+	return err
+}
+
+func (r *NotificacaoRepository) ListarConvitesPendentes(ctx context.Context, paraUID string) ([]models.Notificacao, error) {
+	q := r.client.Collection("notificacoes").
+		Where("paraUid", "==", paraUID).
+		Where("tipo", "==", "convite_estabelecimento").
+		Where("respondido", "==", false)
+	return listar(ctx, q, func(n *models.Notificacao, id string) {
+		n.ID = id
+	})
+}
@@ -0,0 +1,64 @@
+package firestoredb
+
+import (
+	"context"
+	"servico-api/models"
+	"servico-api/storage"
+
+	"cloud.google.com/go/firestore"
+)
+
+// ProcedimentoRepository implementa storage.ProcedimentoRepository na coleção "procedimentos"
+type ProcedimentoRepository struct {
+	client *firestore.Client
+}
+
+func (r *ProcedimentoRepository) Salvar(ctx context.Context, p models.Procedimento) error {
+	_, err := r.client.Collection("procedimentos").Doc(p.ID).Set(ctx, p)
+	return err
+}
+
+func (r *ProcedimentoRepository) Buscar(ctx context.Context, id string) (*models.Procedimento, error) {
+	var p models.Procedimento
+	if err := buscar(ctx, r.client.Collection("procedimentos").Doc(id), &p); err != nil {
+		return nil, err
+	}
+	p.ID = id
+	return &p, nil
+}
+
+func (r *ProcedimentoRepository) BuscarPorNome(ctx context.Context, profissionalID, nome string) (*models.Procedimento, error) {
+	q := r.client.Collection("procedimentos").
+		Where("profissionalId", "==", profissionalID).
+		Where("nome", "==", nome).
+		Limit(1)
+	procs, err := listar[models.Procedimento](ctx, q, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(procs) == 0 {
+		return nil, storage.ErrNaoEncontrado
+	}
+	return &procs[0], nil
+}
+
+func (r *ProcedimentoRepository) Listar(ctx context.Context) ([]models.Procedimento, error) {
+	return listar[models.Procedimento](ctx, r.client.Collection("procedimentos").Query, nil)
+}
+
+func (r *ProcedimentoRepository) ListarPorProfissional(ctx context.Context, profissionalID string) ([]models.Procedimento, error) {
+	q := r.client.Collection("procedimentos").Where("profissionalId", "==", profissionalID)
+	return listar[models.Procedimento](ctx, q, nil)
+}
+
+func (r *ProcedimentoRepository) Excluir(ctx context.Context, id string) error {
+	_, err := r.client.Collection("procedimentos").Doc(id).Delete(ctx)
+	return err
+}
+
+func (r *ProcedimentoRepository) AtualizarImagem(ctx context.Context, id, url string) error {
+	_, err := r.client.Collection("procedimentos").Doc(id).Update(ctx, []firestore.Update{
+		{Path: "imagemUrl", Value: url},
+	})
+	return err
+}
@@ -0,0 +1,30 @@
+package firestoredb
+
+import (
+	"context"
+	"servico-api/relatorios"
+	"servico-api/storage"
+	"time"
+)
+
+// RelatorioRepository implementa storage.RelatorioRepository agregando na aplicação os
+// agendamentos consultados, já que o Firestore não faz somas
+type RelatorioRepository struct {
+	agendamentos *AgendamentoRepository
+}
+
+func (r *RelatorioRepository) Faturamento(ctx context.Context, filtro storage.FiltroAgendamento, agrupar relatorios.Agrupamento, loc *time.Location) ([]storage.ResumoFaturamento, error) {
+	agendamentos, err := r.agendamentos.Listar(ctx, filtro)
+	if err != nil {
+		return nil, err
+	}
+	return storage.CalcularFaturamento(agendamentos, agrupar, loc), nil
+}
+
+func (r *RelatorioRepository) Taxas(ctx context.Context, filtro storage.FiltroAgendamento, agrupar relatorios.Agrupamento, loc *time.Location) ([]storage.ResumoFaturamento, error) {
+	agendamentos, err := r.agendamentos.Listar(ctx, filtro)
+	if err != nil {
+		return nil, err
+	}
+	return storage.SomarTaxas(agendamentos, agrupar, loc), nil
+}
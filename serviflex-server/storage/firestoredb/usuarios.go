@@ -0,0 +1,111 @@
+package firestoredb
+
+import (
+	"context"
+	"servico-api/models"
+	"servico-api/storage"
+
+	"cloud.google.com/go/firestore"
+)
+
+// UsuarioRepository implementa storage.UsuarioRepository nas coleções "clientes",
+// "profissionais" e "admin"
+type UsuarioRepository struct {
+	client *firestore.Client
+}
+
+func (r *UsuarioRepository) BuscarPorEmail(ctx context.Context, tipo, email string) (*models.Usuario, error) {
+	q := r.client.Collection(tipo).Where("email", "==", email).Limit(1)
+	usuarios, err := listar(ctx, q, func(u *models.Usuario, id string) {
+		u.ID = id
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(usuarios) == 0 {
+		return nil, storage.ErrNaoEncontrado
+	}
+	usuarios[0].Tipo = tipo
+	return &usuarios[0], nil
+}
+
+func (r *UsuarioRepository) BuscarUsuario(ctx context.Context, tipo, id string) (*models.Usuario, error) {
+	var u models.Usuario
+	if err := buscar(ctx, r.client.Collection(tipo).Doc(id), &u); err != nil {
+		return nil, err
+	}
+	u.ID = id
+	u.Tipo = tipo
+	return &u, nil
+}
+
+func (r *UsuarioRepository) CriarUsuario(ctx context.Context, u models.Usuario) error {
+	_, err := r.client.Collection(u.Tipo).Doc(u.ID).Set(ctx, u)
+	return err
+}
+
+func (r *UsuarioRepository) BuscarCliente(ctx context.Context, id string) (*models.Cliente, error) {
+	var cl models.Cliente
+	if err := buscar(ctx, r.client.Collection("clientes").Doc(id), &cl); err != nil {
+		return nil, err
+	}
+	return &cl, nil
+}
+
+func (r *UsuarioRepository) SalvarCliente(ctx context.Context, cl models.Cliente) error {
+	_, err := r.client.Collection("clientes").Doc(cl.ID).Set(ctx, cl)
+	return err
+}
+
+func (r *UsuarioRepository) BuscarProfissional(ctx context.Context, id string) (*models.Profissional, error) {
+	var p models.Profissional
+	if err := buscar(ctx, r.client.Collection("profissionais").Doc(id), &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *UsuarioRepository) ListarProfissionais(ctx context.Context) ([]models.Profissional, error) {
+	return listar[models.Profissional](ctx, r.client.Collection("profissionais").Query, nil)
+}
+
+func (r *UsuarioRepository) SalvarProfissional(ctx context.Context, p models.Profissional) error {
+	_, err := r.client.Collection("profissionais").Doc(p.ID).Set(ctx, p)
+	return err
+}
+
+func (r *UsuarioRepository) DefinirEstabelecimentoProfissional(ctx context.Context, profissionalID, estabelecimentoID string) error {
+	_, err := r.client.Collection("profissionais").Doc(profissionalID).Update(ctx, []firestore.Update{
+		{Path: "estabelecimentoId", Value: estabelecimentoID},
+	})
+	return err
+}
+
+func (r *UsuarioRepository) AtualizarImagemProfissional(ctx context.Context, id, url string) error {
+	_, err := r.client.Collection("profissionais").Doc(id).Update(ctx, []firestore.Update{
+		{Path: "imagemUrl", Value: url},
+	})
+	return err
+}
+
+func (r *UsuarioRepository) BuscarAdmin(ctx context.Context, id string) (*models.Admin, error) {
+	var a models.Admin
+	if err := buscar(ctx, r.client.Collection("admin").Doc(id), &a); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *UsuarioRepository) ListarAdmins(ctx context.Context) ([]models.Admin, error) {
+	return listar[models.Admin](ctx, r.client.Collection("admin").Query, nil)
+}
+
+func (r *UsuarioRepository) SalvarAdmin(ctx context.Context, a models.Admin) error {
+	_, err := r.client.Collection("admin").Doc(a.ID).Set(ctx, a)
+	return err
+}
+
+func (r *UsuarioRepository) ExcluirAdmin(ctx context.Context, id string) error {
+	_, err := r.client.Collection("admin").Doc(id).Delete(ctx)
+	return err
+}
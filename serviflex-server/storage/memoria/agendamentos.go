@@ -0,0 +1,125 @@
+package memoria
+
+import (
+	"context"
+	"servico-api/models"
+	"servico-api/storage"
+	"sort"
+	"sync"
+)
+
+// AgendamentoRepository implementa storage.AgendamentoRepository em memória
+type AgendamentoRepository struct {
+	tabela *tabela[models.Agendamento]
+	// reservas serializa a verificação de conflito e a gravação em Reservar
+	reservas sync.Mutex
+}
+
+func NovoAgendamentoRepository() *AgendamentoRepository {
+	return &AgendamentoRepository{tabela: novaTabela[models.Agendamento]()}
+}
+
+func (r *AgendamentoRepository) Criar(ctx context.Context, ag models.Agendamento) error {
+	r.tabela.salvar(ag.ID, ag)
+	return nil
+}
+
+func (r *AgendamentoRepository) Reservar(ctx context.Context, ag models.Agendamento) error {
+	r.reservas.Lock()
+	defer r.reservas.Unlock()
+
+	existentes, err := r.Listar(ctx, storage.FiltroAgendamento{
+		ProfissionalID: ag.ProfissionalID,
+		De:             ag.DataHora.Add(-storage.JanelaConflito),
+		Ate:            ag.Fim(),
+	})
+	if err != nil {
+		return err
+	}
+	if conflito := storage.BuscarConflito(existentes, ag); conflito != nil {
+		return conflito
+	}
+	r.tabela.salvar(ag.ID, ag)
+	return nil
+}
+
+func (r *AgendamentoRepository) Buscar(ctx context.Context, id string) (*models.Agendamento, error) {
+	ag, err := r.tabela.buscar(id)
+	if err != nil {
+		return nil, err
+	}
+	return &ag, nil
+}
+
+func (r *AgendamentoRepository) Atualizar(ctx context.Context, id string, alterar func(ag *models.Agendamento) error) (*models.Agendamento, error) {
+	r.reservas.Lock()
+	defer r.reservas.Unlock()
+
+	antes, err := r.tabela.buscar(id)
+	if err != nil {
+		return nil, err
+	}
+	depois := antes
+	depois.Historico = append([]models.TransicaoStatus(nil), antes.Historico...)
+	if err := alterar(&depois); err != nil {
+		return nil, err
+	}
+
+	if storage.MudouIntervalo(antes, depois) {
+		existentes, err := r.Listar(ctx, storage.FiltroAgendamento{
+			ProfissionalID: depois.ProfissionalID,
+			De:             depois.DataHora.Add(-storage.JanelaConflito),
+			Ate:            depois.Fim(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if conflito := storage.BuscarConflito(existentes, depois); conflito != nil {
+			return nil, conflito
+		}
+	}
+	r.tabela.salvar(id, depois)
+	return &depois, nil
+}
+
+func (r *AgendamentoRepository) Listar(ctx context.Context, filtro storage.FiltroAgendamento) ([]models.Agendamento, error) {
+	agendamentos := r.tabela.filtrar(func(ag models.Agendamento) bool {
+		return atendeFiltro(ag, filtro)
+	})
+	sort.SliceStable(agendamentos, func(i, j int) bool {
+		return agendamentos[i].DataHora.Before(agendamentos[j].DataHora)
+	})
+	return agendamentos, nil
+}
+
+func (r *AgendamentoRepository) Percorrer(ctx context.Context, filtro storage.FiltroAgendamento, visitar func(models.Agendamento) error) error {
+	agendamentos, err := r.Listar(ctx, filtro)
+	if err != nil {
+		return err
+	}
+	for _, ag := range agendamentos {
+		if err := visitar(ag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// atendeFiltro reproduz as cláusulas where de storage.FiltroAgendamento
+func atendeFiltro(ag models.Agendamento, f storage.FiltroAgendamento) bool {
+	switch {
+	case f.ClienteID != "" && ag.ClienteID != f.ClienteID:
+		return false
+	case f.ProfissionalID != "" && ag.ProfissionalID != f.ProfissionalID:
+		return false
+	case f.EstabelecimentoID != "" && ag.EstabelecimentoID != f.EstabelecimentoID:
+		return false
+	case !f.De.IsZero() && ag.DataHora.Before(f.De):
+		return false
+	case !f.Ate.IsZero() && ag.DataHora.After(f.Ate):
+		return false
+	case f.Status != "" && ag.StatusAtual() != f.Status:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,104 @@
+package memoria
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"servico-api/models"
+	"servico-api/storage"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestListarAgendamentosFiltraEOrdena(t *testing.T) {
+	ctx := context.Background()
+	repo := NovoAgendamentoRepository()
+	base := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+
+	for _, ag := range []models.Agendamento{
+		{ID: "3", ClienteID: "c1", ProfissionalID: "p1", DataHora: base.Add(48 * time.Hour)},
+		{ID: "1", ClienteID: "c1", ProfissionalID: "p2", DataHora: base},
+		{ID: "2", ClienteID: "c2", ProfissionalID: "p1", DataHora: base.Add(24 * time.Hour)},
+	} {
+		if err := repo.Criar(ctx, ag); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	casos := []struct {
+		nome   string
+		filtro storage.FiltroAgendamento
+		ids    []string
+	}{
+		{"sem filtro em ordem de data", storage.FiltroAgendamento{}, []string{"1", "2", "3"}},
+		{"por cliente", storage.FiltroAgendamento{ClienteID: "c1"}, []string{"1", "3"}},
+		{"por profissional", storage.FiltroAgendamento{ProfissionalID: "p1"}, []string{"2", "3"}},
+		{"intervalo inclusivo", storage.FiltroAgendamento{De: base, Ate: base.Add(24 * time.Hour)}, []string{"1", "2"}},
+		{"combinado", storage.FiltroAgendamento{ProfissionalID: "p1", De: base.Add(25 * time.Hour)}, []string{"3"}},
+	}
+
+	for _, caso := range casos {
+		t.Run(caso.nome, func(t *testing.T) {
+			lista, err := repo.Listar(ctx, caso.filtro)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(lista) != len(caso.ids) {
+				t.Fatalf("obtidos %d agendamentos, esperados %d", len(lista), len(caso.ids))
+			}
+			for i, ag := range lista {
+				if ag.ID != caso.ids[i] {
+					t.Fatalf("posição %d: %s, esperado %s", i, ag.ID, caso.ids[i])
+				}
+			}
+		})
+	}
+}
+
+func TestReservarConcorrenteAceitaApenasUm(t *testing.T) {
+	ctx := context.Background()
+	repo := NovoAgendamentoRepository()
+	inicio := time.Date(2025, 3, 10, 14, 0, 0, 0, time.UTC)
+
+	const tentativas = 20
+	erros := make(chan error, tentativas)
+	var wg sync.WaitGroup
+	for i := 0; i < tentativas; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// Durações diferentes com inícios deslocados: todas cruzam 14:00–14:30
+			erros <- repo.Reservar(ctx, models.Agendamento{
+				ID:             fmt.Sprintf("ag-%d", i),
+				ProfissionalID: "p1",
+				DataHora:       inicio.Add(time.Duration(i%3) * 10 * time.Minute),
+				DuracaoMin:     30 + 15*(i%2),
+			})
+		}(i)
+	}
+	wg.Wait()
+	close(erros)
+
+	sucessos := 0
+	for err := range erros {
+		var conflito *storage.ConflitoAgendamento
+		switch {
+		case err == nil:
+			sucessos++
+		case !errors.As(err, &conflito):
+			t.Fatalf("erro inesperado: %v", err)
+		}
+	}
+	if sucessos != 1 {
+		t.Fatalf("esperava exatamente uma reserva, obtive %d", sucessos)
+	}
+
+	// Outro profissional no mesmo horário e um atendimento encostado não conflitam
+	if err := repo.Reservar(ctx, models.Agendamento{ID: "outro", ProfissionalID: "p2", DataHora: inicio, DuracaoMin: 30}); err != nil {
+		t.Errorf("profissional diferente não deveria conflitar: %v", err)
+	}
+	if err := repo.Reservar(ctx, models.Agendamento{ID: "depois", ProfissionalID: "p1", DataHora: inicio.Add(-time.Hour), DuracaoMin: 60}); err != nil {
+		t.Errorf("atendimento que termina no início do outro não deveria conflitar: %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package memoria
+
+import (
+	"context"
+	"servico-api/models"
+	"servico-api/storage"
+	"sync"
+)
+
+// AvaliacaoRepository implementa storage.AvaliacaoRepository em memória
+type AvaliacaoRepository struct {
+	mu      sync.Mutex // serializa as gravações com o ajuste dos resumos
+	tabela  *tabela[models.Avaliacao]
+	resumos *tabela[models.ResumoAvaliacoes]
+}
+
+func NovoAvaliacaoRepository() *AvaliacaoRepository {
+	return &AvaliacaoRepository{
+		tabela:  novaTabela[models.Avaliacao](),
+		resumos: novaTabela[models.ResumoAvaliacoes](),
+	}
+}
+
+func (r *AvaliacaoRepository) Criar(ctx context.Context, a models.Avaliacao) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.tabela.criar(a.ID, a); err != nil {
+		return err
+	}
+	r.ajustarResumos(nil, &a)
+	return nil
+}
+
+func (r *AvaliacaoRepository) Buscar(ctx context.Context, id string) (*models.Avaliacao, error) {
+	a, err := r.tabela.buscar(id)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *AvaliacaoRepository) Atualizar(ctx context.Context, id string, alterar func(a *models.Avaliacao) error) (*models.Avaliacao, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	antes, err := r.tabela.buscar(id)
+	if err != nil {
+		return nil, err
+	}
+	depois := antes
+	depois.Denuncias = append([]models.DenunciaAvaliacao(nil), antes.Denuncias...)
+	depois.Moderacao = append([]models.AcaoModeracao(nil), antes.Moderacao...)
+	if err := alterar(&depois); err != nil {
+		return nil, err
+	}
+	r.tabela.salvar(id, depois)
+	r.ajustarResumos(&antes, &depois)
+	return &depois, nil
+}
+
+// ajustarResumos aplica a mudança da avaliação aos resumos afetados; exige r.mu
+func (r *AvaliacaoRepository) ajustarResumos(antes, depois *models.Avaliacao) {
+	if _, mudou := storage.VariacaoResumo(antes, depois); !mudou {
+		return
+	}
+	atual := depois
+	if atual == nil {
+		atual = antes
+	}
+	for _, alvo := range storage.AlvosDaAvaliacao(*atual) {
+		resumo, _ := r.resumos.buscar(chaveResumo(alvo.Alvo, alvo.ID))
+		resumo.Histograma = append([]int(nil), resumo.Histograma...)
+		resumo.Aplicar(antes, depois)
+		r.resumos.salvar(chaveResumo(alvo.Alvo, alvo.ID), resumo)
+	}
+}
+
+func (r *AvaliacaoRepository) ListarEmModeracao(ctx context.Context) ([]models.Avaliacao, error) {
+	return r.tabela.filtrar(func(a models.Avaliacao) bool { return a.EmModeracao }), nil
+}
+
+func (r *AvaliacaoRepository) ListarPorProfissional(ctx context.Context, profissionalID string) ([]models.Avaliacao, error) {
+	return r.tabela.filtrar(func(a models.Avaliacao) bool { return a.ProfissionalID == profissionalID }), nil
+}
+
+func (r *AvaliacaoRepository) ListarPorEstabelecimento(ctx context.Context, estabelecimentoID string) ([]models.Avaliacao, error) {
+	return r.tabela.filtrar(func(a models.Avaliacao) bool { return a.EstabelecimentoID == estabelecimentoID }), nil
+}
+
+func (r *AvaliacaoRepository) BuscarResumo(ctx context.Context, alvo, id string) (models.ResumoAvaliacoes, error) {
+	resumo, _ := r.resumos.buscar(chaveResumo(alvo, id))
+	return resumo, nil
+}
+
+func (r *AvaliacaoRepository) BuscarResumos(ctx context.Context, alvo string, ids []string) (map[string]models.ResumoAvaliacoes, error) {
+	resumos := make(map[string]models.ResumoAvaliacoes)
+	for _, id := range ids {
+		if resumo, err := r.resumos.buscar(chaveResumo(alvo, id)); err == nil {
+			resumos[id] = resumo
+		}
+	}
+	return resumos, nil
+}
+
+func chaveResumo(alvo, id string) string {
+	return alvo + "/" + id
+}
@@ -0,0 +1,65 @@
+package memoria
+
+import (
+	"context"
+	"servico-api/models"
+	"sync"
+)
+
+// EstabelecimentoRepository implementa storage.EstabelecimentoRepository em memória
+type EstabelecimentoRepository struct {
+	tabela *tabela[models.Estabelecimento]
+
+	mu       sync.Mutex
+	vinculos map[string]*tabela[models.ProfissionalEstabelecimento]
+}
+
+func NovoEstabelecimentoRepository() *EstabelecimentoRepository {
+	return &EstabelecimentoRepository{
+		tabela:   novaTabela[models.Estabelecimento](),
+		vinculos: make(map[string]*tabela[models.ProfissionalEstabelecimento]),
+	}
+}
+
+func (r *EstabelecimentoRepository) Salvar(ctx context.Context, e models.Estabelecimento) error {
+	r.tabela.salvar(e.ID, e)
+	return nil
+}
+
+func (r *EstabelecimentoRepository) Buscar(ctx context.Context, id string) (*models.Estabelecimento, error) {
+	e, err := r.tabela.buscar(id)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (r *EstabelecimentoRepository) Listar(ctx context.Context) ([]models.Estabelecimento, error) {
+	return r.tabela.filtrar(nil), nil
+}
+
+func (r *EstabelecimentoRepository) VincularProfissional(ctx context.Context, estabelecimentoID string, v models.ProfissionalEstabelecimento) error {
+	r.profissionais(estabelecimentoID).salvar(v.UID, v)
+	return nil
+}
+
+func (r *EstabelecimentoRepository) DesvincularProfissional(ctx context.Context, estabelecimentoID, profissionalID string) error {
+	r.profissionais(estabelecimentoID).excluir(profissionalID)
+	return nil
+}
+
+func (r *EstabelecimentoRepository) ListarProfissionais(ctx context.Context, estabelecimentoID string) ([]models.ProfissionalEstabelecimento, error) {
+	return r.profissionais(estabelecimentoID).filtrar(nil), nil
+}
+
+// profissionais devolve a "subcoleção" de vínculos do estabelecimento, criando-a se preciso
+func (r *EstabelecimentoRepository) profissionais(estabelecimentoID string) *tabela[models.ProfissionalEstabelecimento] {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.vinculos[estabelecimentoID]
+	if !ok {
+		t = novaTabela[models.ProfissionalEstabelecimento]()
+		r.vinculos[estabelecimentoID] = t
+	}
+	return t
+}
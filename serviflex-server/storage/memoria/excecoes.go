@@ -0,0 +1,43 @@
+package memoria
+
+import (
+	"context"
+	"servico-api/models"
+	"servico-api/storage"
+	"sort"
+)
+
+// ExcecaoHorarioRepository implementa storage.ExcecaoHorarioRepository em memória
+type ExcecaoHorarioRepository struct {
+	tabela *tabela[models.ExcecaoHorario]
+}
+
+func NovoExcecaoHorarioRepository() *ExcecaoHorarioRepository {
+	return &ExcecaoHorarioRepository{tabela: novaTabela[models.ExcecaoHorario]()}
+}
+
+func (r *ExcecaoHorarioRepository) Salvar(ctx context.Context, e models.ExcecaoHorario) error {
+	r.tabela.salvar(e.ID, e)
+	return nil
+}
+
+func (r *ExcecaoHorarioRepository) Buscar(ctx context.Context, id string) (*models.ExcecaoHorario, error) {
+	e, err := r.tabela.buscar(id)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (r *ExcecaoHorarioRepository) Excluir(ctx context.Context, id string) error {
+	r.tabela.excluir(id)
+	return nil
+}
+
+func (r *ExcecaoHorarioRepository) ListarPorProfissional(ctx context.Context, profissionalID, de, ate string) ([]models.ExcecaoHorario, error) {
+	excecoes := r.tabela.filtrar(func(e models.ExcecaoHorario) bool {
+		return e.ProfissionalID == profissionalID && storage.ExcecaoNoPeriodo(e, de, ate)
+	})
+	sort.SliceStable(excecoes, func(i, j int) bool { return excecoes[i].DataInicio < excecoes[j].DataInicio })
+	return excecoes, nil
+}
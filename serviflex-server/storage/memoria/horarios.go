@@ -0,0 +1,65 @@
+package memoria
+
+import (
+	"context"
+	"servico-api/models"
+	"servico-api/storage"
+	"sync"
+)
+
+// HorarioRepository implementa storage.HorarioRepository em memória
+type HorarioRepository struct {
+	tabela *tabela[models.Horario]
+	// turnos serializa a verificação de sobreposição e a gravação em SalvarTurnos
+	turnos sync.Mutex
+}
+
+func NovoHorarioRepository() *HorarioRepository {
+	return &HorarioRepository{tabela: novaTabela[models.Horario]()}
+}
+
+func (r *HorarioRepository) Salvar(ctx context.Context, h models.Horario) error {
+	r.tabela.salvar(h.ID, h)
+	return nil
+}
+
+func (r *HorarioRepository) Buscar(ctx context.Context, id string) (*models.Horario, error) {
+	h, err := r.tabela.buscar(id)
+	if err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+func (r *HorarioRepository) Excluir(ctx context.Context, id string) error {
+	r.tabela.excluir(id)
+	return nil
+}
+
+func (r *HorarioRepository) ListarPorProfissional(ctx context.Context, profissionalID string) ([]models.Horario, error) {
+	return r.tabela.filtrar(func(h models.Horario) bool {
+		return h.ProfissionalID == profissionalID
+	}), nil
+}
+
+func (r *HorarioRepository) ListarPorDia(ctx context.Context, profissionalID string, diaSemana models.DiaSemana) ([]models.Horario, error) {
+	return r.tabela.filtrar(func(h models.Horario) bool {
+		return h.ProfissionalID == profissionalID && h.DiaSemana == diaSemana
+	}), nil
+}
+
+// SalvarTurnos verifica e grava os turnos sob uma trava única, o que serializa as
+// gravações concorrentes
+func (r *HorarioRepository) SalvarTurnos(ctx context.Context, profissionalID string, turnos []models.Horario) error {
+	r.turnos.Lock()
+	defer r.turnos.Unlock()
+
+	existentes, _ := r.ListarPorProfissional(ctx, profissionalID)
+	if sobreposto := storage.BuscarSobreposicao(existentes, turnos); sobreposto != nil {
+		return sobreposto
+	}
+	for _, h := range turnos {
+		r.tabela.salvar(h.ID, h)
+	}
+	return nil
+}
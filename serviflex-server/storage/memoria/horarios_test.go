@@ -0,0 +1,62 @@
+package memoria
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"servico-api/models"
+	"servico-api/storage"
+	"sync"
+	"testing"
+)
+
+func TestSalvarTurnosConcorrenteAceitaApenasUm(t *testing.T) {
+	ctx := context.Background()
+	repo := NovoHorarioRepository()
+
+	const tentativas = 20
+	erros := make(chan error, tentativas)
+	var wg sync.WaitGroup
+	for i := 0; i < tentativas; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// Inícios deslocados: todos os turnos cruzam 10:00–11:00 da segunda
+			erros <- repo.SalvarTurnos(ctx, "p1", []models.Horario{{
+				ID:             fmt.Sprintf("h-%d", i),
+				ProfissionalID: "p1",
+				DiaSemana:      models.Segunda,
+				HoraInicio:     fmt.Sprintf("%02d:00", 8+i%3),
+				HoraFim:        "12:00",
+			}})
+		}(i)
+	}
+	wg.Wait()
+	close(erros)
+
+	sucessos := 0
+	for err := range erros {
+		var sobreposto *storage.TurnoSobreposto
+		switch {
+		case err == nil:
+			sucessos++
+		case !errors.As(err, &sobreposto):
+			t.Fatalf("erro inesperado: %v", err)
+		}
+	}
+	if sucessos != 1 {
+		t.Fatalf("esperava exatamente um turno gravado, obtive %d", sucessos)
+	}
+
+	// Turnos encostados, em outro dia ou de outro profissional não se sobrepõem
+	livres := []models.Horario{
+		{ID: "tarde", ProfissionalID: "p1", DiaSemana: models.Segunda, HoraInicio: "12:00", HoraFim: "18:00"},
+		{ID: "terca", ProfissionalID: "p1", DiaSemana: models.Terca, HoraInicio: "08:00", HoraFim: "12:00"},
+	}
+	if err := repo.SalvarTurnos(ctx, "p1", livres); err != nil {
+		t.Errorf("turnos livres não deveriam conflitar: %v", err)
+	}
+	if err := repo.SalvarTurnos(ctx, "p2", []models.Horario{{ID: "outro", ProfissionalID: "p2", DiaSemana: models.Segunda, HoraInicio: "08:00", HoraFim: "12:00"}}); err != nil {
+		t.Errorf("profissional diferente não deveria conflitar: %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+// Package memoria implementa os repositórios de storage em memória, sem dependências
+// externas. É usado nos testes e para rodar a API localmente sem credenciais.
+package memoria
+
+import (
+	"servico-api/storage"
+	"sync"
+)
+
+// NovosRepositorios cria repositórios em memória vazios
+func NovosRepositorios() *storage.Repositorios {
+	agendamentos := NovoAgendamentoRepository()
+	procedimentos := NovoProcedimentoRepository()
+	return &storage.Repositorios{
+		Agendamentos:     agendamentos,
+		Horarios:         NovoHorarioRepository(),
+		Excecoes:         NovoExcecaoHorarioRepository(),
+		Procedimentos:    procedimentos,
+		Estabelecimentos: NovoEstabelecimentoRepository(),
+		Usuarios:         NovoUsuarioRepository(),
+		Notificacoes:     NovoNotificacaoRepository(),
+		Avaliacoes:       NovoAvaliacaoRepository(),
+		Relatorios:       &RelatorioRepository{agendamentos: agendamentos},
+	}
+}
+
+// tabela guarda registros por ID preservando a ordem de inserção, como uma coleção
+type tabela[T any] struct {
+	mu    sync.RWMutex
+	itens map[string]T
+	ordem []string
+}
+
+func novaTabela[T any]() *tabela[T] {
+	return &tabela[T]{itens: make(map[string]T)}
+}
+
+func (t *tabela[T]) salvar(id string, item T) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.itens[id]; !ok {
+		t.ordem = append(t.ordem, id)
+	}
+	t.itens[id] = item
+}
+
+func (t *tabela[T]) buscar(id string) (T, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	item, ok := t.itens[id]
+	if !ok {
+		return item, storage.ErrNaoEncontrado
+	}
+	return item, nil
+}
+
+// criar insere o registro; devolve storage.ErrJaExiste se o ID já estiver em uso
+func (t *tabela[T]) criar(id string, item T) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.itens[id]; ok {
+		return storage.ErrJaExiste
+	}
+	t.ordem = append(t.ordem, id)
+	t.itens[id] = item
+	return nil
+}
+
+// atualizar aplica f ao registro existente; devolve storage.ErrNaoEncontrado se ele não existir
+func (t *tabela[T]) atualizar(id string, f func(*T)) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	item, ok := t.itens[id]
+	if !ok {
+		return storage.ErrNaoEncontrado
+	}
+	f(&item)
+	t.itens[id] = item
+	return nil
+}
+
+func (t *tabela[T]) excluir(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.itens[id]; !ok {
+		return
+	}
+	delete(t.itens, id)
+	for i, existente := range t.ordem {
+		if existente == id {
+			t.ordem = append(t.ordem[:i], t.ordem[i+1:]...)
+			break
+		}
+	}
+}
+
+// filtrar devolve, na ordem de inserção, os registros aceitos por f
+func (t *tabela[T]) filtrar(f func(T) bool) []T {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	var itens []T
+	for _, id := range t.ordem {
+		if item := t.itens[id]; f == nil || f(item) {
+			itens = append(itens, item)
+		}
+	}
+	return itens
+}
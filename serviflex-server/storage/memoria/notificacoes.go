@@ -0,0 +1,41 @@
+package memoria
+
+import (
+	"context"
+	"servico-api/models"
+)
+
+// NotificacaoRepository implementa storage.NotificacaoRepository em memória
+type NotificacaoRepository struct {
+	tabela *tabela[models.Notificacao]
+}
+
+func NovoNotificacaoRepository() *NotificacaoRepository {
+	return &NotificacaoRepository{tabela: novaTabela[models.Notificacao]()}
+}
+
+func (r *NotificacaoRepository) Criar(ctx context.Context, n models.Notificacao) error {
+	r.tabela.salvar(n.ID, n)
+	return nil
+}
+
+func (r *NotificacaoRepository) Buscar(ctx context.Context, id string) (*models.Notificacao, error) {
+	n, err := r.tabela.buscar(id)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+func (r *NotificacaoRepository) Responder(ctx context.Context, id, resposta string) error {
+	return r.tabela.atualizar(id, func(n *models.Notificacao) {
+		n.Respondido = true
+		n.Resposta = &resposta
+	})
+}
+
+func (r *NotificacaoRepository) ListarConvitesPendentes(ctx context.Context, paraUID string) ([]models.Notificacao, error) {
+	return r.tabela.filtrar(func(n models.Notificacao) bool {
+		return n.ParaUID == paraUID && n.Tipo == "convite_estabelecimento" && !n.Respondido
+	}), nil
+}
@@ -0,0 +1,60 @@
+package memoria
+
+import (
+	"context"
+	"servico-api/models"
+	"servico-api/storage"
+)
+
+// ProcedimentoRepository implementa storage.ProcedimentoRepository em memória
+type ProcedimentoRepository struct {
+	tabela *tabela[models.Procedimento]
+}
+
+func NovoProcedimentoRepository() *ProcedimentoRepository {
+	return &ProcedimentoRepository{tabela: novaTabela[models.Procedimento]()}
+}
+
+func (r *ProcedimentoRepository) Salvar(ctx context.Context, p models.Procedimento) error {
+	r.tabela.salvar(p.ID, p)
+	return nil
+}
+
+func (r *ProcedimentoRepository) Buscar(ctx context.Context, id string) (*models.Procedimento, error) {
+	p, err := r.tabela.buscar(id)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *ProcedimentoRepository) BuscarPorNome(ctx context.Context, profissionalID, nome string) (*models.Procedimento, error) {
+	procs := r.tabela.filtrar(func(p models.Procedimento) bool {
+		return p.ProfissionalID == profissionalID && p.Nome == nome
+	})
+	if len(procs) == 0 {
+		return nil, storage.ErrNaoEncontrado
+	}
+	return &procs[0], nil
+}
+
+func (r *ProcedimentoRepository) Listar(ctx context.Context) ([]models.Procedimento, error) {
+	return r.tabela.filtrar(nil), nil
+}
+
+func (r *ProcedimentoRepository) ListarPorProfissional(ctx context.Context, profissionalID string) ([]models.Procedimento, error) {
+	return r.tabela.filtrar(func(p models.Procedimento) bool {
+		return p.ProfissionalID == profissionalID
+	}), nil
+}
+
+func (r *ProcedimentoRepository) Excluir(ctx context.Context, id string) error {
+	r.tabela.excluir(id)
+	return nil
+}
+
+func (r *ProcedimentoRepository) AtualizarImagem(ctx context.Context, id, url string) error {
+	return r.tabela.atualizar(id, func(p *models.Procedimento) {
+		p.ImagemURL = url
+	})
+}
@@ -0,0 +1,29 @@
+package memoria
+
+import (
+	"context"
+	"servico-api/relatorios"
+	"servico-api/storage"
+	"time"
+)
+
+// RelatorioRepository implementa storage.RelatorioRepository sobre os demais repositórios em memória
+type RelatorioRepository struct {
+	agendamentos storage.AgendamentoRepository
+}
+
+func (r *RelatorioRepository) Faturamento(ctx context.Context, filtro storage.FiltroAgendamento, agrupar relatorios.Agrupamento, loc *time.Location) ([]storage.ResumoFaturamento, error) {
+	agendamentos, err := r.agendamentos.Listar(ctx, filtro)
+	if err != nil {
+		return nil, err
+	}
+	return storage.CalcularFaturamento(agendamentos, agrupar, loc), nil
+}
+
+func (r *RelatorioRepository) Taxas(ctx context.Context, filtro storage.FiltroAgendamento, agrupar relatorios.Agrupamento, loc *time.Location) ([]storage.ResumoFaturamento, error) {
+	agendamentos, err := r.agendamentos.Listar(ctx, filtro)
+	if err != nil {
+		return nil, err
+	}
+	return storage.SomarTaxas(agendamentos, agrupar, loc), nil
+}
@@ -0,0 +1,196 @@
+package memoria
+
+import (
+	"context"
+	"servico-api/models"
+	"servico-api/storage"
+)
+
+// UsuarioRepository implementa storage.UsuarioRepository em memória. Cada tipo
+// ("clientes", "profissionais", "admin") é uma tabela de models.Usuario, que
+// contém todos os campos de Cliente, Profissional e Admin.
+type UsuarioRepository struct {
+	tabelas map[string]*tabela[models.Usuario]
+}
+
+func NovoUsuarioRepository() *UsuarioRepository {
+	return &UsuarioRepository{tabelas: map[string]*tabela[models.Usuario]{
+		"clientes":      novaTabela[models.Usuario](),
+		"profissionais": novaTabela[models.Usuario](),
+		"admin":         novaTabela[models.Usuario](),
+	}}
+}
+
+func (r *UsuarioRepository) tabela(tipo string) (*tabela[models.Usuario], error) {
+	t, ok := r.tabelas[tipo]
+	if !ok {
+		return nil, storage.ErrNaoEncontrado
+	}
+	return t, nil
+}
+
+func (r *UsuarioRepository) BuscarPorEmail(ctx context.Context, tipo, email string) (*models.Usuario, error) {
+	t, err := r.tabela(tipo)
+	if err != nil {
+		return nil, err
+	}
+	usuarios := t.filtrar(func(u models.Usuario) bool { return u.Email == email })
+	if len(usuarios) == 0 {
+		return nil, storage.ErrNaoEncontrado
+	}
+	u := usuarios[0]
+	u.Tipo = tipo
+	return &u, nil
+}
+
+func (r *UsuarioRepository) BuscarUsuario(ctx context.Context, tipo, id string) (*models.Usuario, error) {
+	t, err := r.tabela(tipo)
+	if err != nil {
+		return nil, err
+	}
+	u, err := t.buscar(id)
+	if err != nil {
+		return nil, err
+	}
+	u.Tipo = tipo
+	return &u, nil
+}
+
+func (r *UsuarioRepository) CriarUsuario(ctx context.Context, u models.Usuario) error {
+	t, err := r.tabela(u.Tipo)
+	if err != nil {
+		return err
+	}
+	t.salvar(u.ID, u)
+	return nil
+}
+
+func (r *UsuarioRepository) BuscarCliente(ctx context.Context, id string) (*models.Cliente, error) {
+	u, err := r.tabelas["clientes"].buscar(id)
+	if err != nil {
+		return nil, err
+	}
+	return &models.Cliente{
+		ID:       u.ID,
+		Nome:     u.Nome,
+		Email:    u.Email,
+		Senha:    u.Senha,
+		Telefone: u.Telefone,
+		FotoURL:  u.FotoURL,
+		CriadoEm: u.CriadoEm,
+	}, nil
+}
+
+func (r *UsuarioRepository) SalvarCliente(ctx context.Context, cl models.Cliente) error {
+	r.tabelas["clientes"].salvar(cl.ID, models.Usuario{
+		ID:       cl.ID,
+		Nome:     cl.Nome,
+		Email:    cl.Email,
+		Senha:    cl.Senha,
+		Telefone: cl.Telefone,
+		FotoURL:  cl.FotoURL,
+		CriadoEm: cl.CriadoEm,
+	})
+	return nil
+}
+
+func (r *UsuarioRepository) BuscarProfissional(ctx context.Context, id string) (*models.Profissional, error) {
+	u, err := r.tabelas["profissionais"].buscar(id)
+	if err != nil {
+		return nil, err
+	}
+	p := paraProfissional(u)
+	return &p, nil
+}
+
+func (r *UsuarioRepository) ListarProfissionais(ctx context.Context) ([]models.Profissional, error) {
+	var profissionais []models.Profissional
+	for _, u := range r.tabelas["profissionais"].filtrar(nil) {
+		profissionais = append(profissionais, paraProfissional(u))
+	}
+	return profissionais, nil
+}
+
+func (r *UsuarioRepository) SalvarProfissional(ctx context.Context, p models.Profissional) error {
+	r.tabelas["profissionais"].salvar(p.ID, models.Usuario{
+		ID:                p.ID,
+		Nome:              p.Nome,
+		Email:             p.Email,
+		Senha:             p.Senha,
+		Telefone:          p.Telefone,
+		ImagemURL:         p.ImagemURL,
+		EstabelecimentoID: p.EstabelecimentoID,
+		CriadoEm:          p.CriadoEm,
+	})
+	return nil
+}
+
+func (r *UsuarioRepository) DefinirEstabelecimentoProfissional(ctx context.Context, profissionalID, estabelecimentoID string) error {
+	return r.tabelas["profissionais"].atualizar(profissionalID, func(u *models.Usuario) {
+		u.EstabelecimentoID = estabelecimentoID
+	})
+}
+
+func (r *UsuarioRepository) AtualizarImagemProfissional(ctx context.Context, id, url string) error {
+	return r.tabelas["profissionais"].atualizar(id, func(u *models.Usuario) {
+		u.ImagemURL = url
+	})
+}
+
+func (r *UsuarioRepository) BuscarAdmin(ctx context.Context, id string) (*models.Admin, error) {
+	u, err := r.tabelas["admin"].buscar(id)
+	if err != nil {
+		return nil, err
+	}
+	a := paraAdmin(u)
+	return &a, nil
+}
+
+func (r *UsuarioRepository) ListarAdmins(ctx context.Context) ([]models.Admin, error) {
+	var admins []models.Admin
+	for _, u := range r.tabelas["admin"].filtrar(nil) {
+		admins = append(admins, paraAdmin(u))
+	}
+	return admins, nil
+}
+
+func (r *UsuarioRepository) SalvarAdmin(ctx context.Context, a models.Admin) error {
+	r.tabelas["admin"].salvar(a.ID, models.Usuario{
+		ID:       a.ID,
+		Nome:     a.Nome,
+		Email:    a.Email,
+		Senha:    a.Senha,
+		Tipo:     a.Tipo,
+		CriadoEm: a.CriadoEm,
+	})
+	return nil
+}
+
+func (r *UsuarioRepository) ExcluirAdmin(ctx context.Context, id string) error {
+	r.tabelas["admin"].excluir(id)
+	return nil
+}
+
+func paraProfissional(u models.Usuario) models.Profissional {
+	return models.Profissional{
+		ID:                u.ID,
+		Nome:              u.Nome,
+		Email:             u.Email,
+		Senha:             u.Senha,
+		ImagemURL:         u.ImagemURL,
+		Telefone:          u.Telefone,
+		EstabelecimentoID: u.EstabelecimentoID,
+		CriadoEm:          u.CriadoEm,
+	}
+}
+
+func paraAdmin(u models.Usuario) models.Admin {
+	return models.Admin{
+		ID:       u.ID,
+		Nome:     u.Nome,
+		Email:    u.Email,
+		Senha:    u.Senha,
+		CriadoEm: u.CriadoEm,
+		Tipo:     "admin",
+	}
+}
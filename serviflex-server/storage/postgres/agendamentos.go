@@ -0,0 +1,202 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"servico-api/models"
+	"servico-api/storage"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// AgendamentoRepository implementa storage.AgendamentoRepository na tabela "agendamentos"
+type AgendamentoRepository struct {
+	db db
+}
+
+const colunasAgendamento = "id, cliente_id, profissional_id, estabelecimento_id, procedimento_id, procedimento, preco, data_hora, duracao_min, status, historico, taxa"
+
+// fimAgendamento é a expressão SQL equivalente a models.Agendamento.Fim
+const fimAgendamento = "data_hora + make_interval(mins => greatest(duracao_min, 1))"
+
+// ocupaAgenda é a condição SQL equivalente a models.Agendamento.Ocupa
+const ocupaAgenda = "status NOT IN ('cancelado', 'nao_compareceu')"
+
+func scanAgendamento(row pgx.Row, ag *models.Agendamento) error {
+	return row.Scan(&ag.ID, &ag.ClienteID, &ag.ProfissionalID, &ag.EstabelecimentoID, &ag.ProcedimentoID, &ag.Procedimento, &ag.Preco,
+		&ag.DataHora, &ag.DuracaoMin, &ag.Status, &ag.Historico, &ag.Taxa)
+}
+
+// historico evita gravar o histórico nulo na coluna JSONB
+func historico(ag models.Agendamento) []models.TransicaoStatus {
+	if ag.Historico == nil {
+		return []models.TransicaoStatus{}
+	}
+	return ag.Historico
+}
+
+func (r *AgendamentoRepository) Criar(ctx context.Context, ag models.Agendamento) error {
+	return inserirAgendamento(ctx, r.db, ag)
+}
+
+func inserirAgendamento(ctx context.Context, db db, ag models.Agendamento) error {
+	_, err := db.Exec(ctx, `INSERT INTO agendamentos (`+colunasAgendamento+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+		ag.ID, ag.ClienteID, ag.ProfissionalID, ag.EstabelecimentoID, ag.ProcedimentoID, ag.Procedimento, ag.Preco,
+		ag.DataHora, ag.DuracaoMin, ag.StatusAtual(), historico(ag), ag.Taxa)
+	return err
+}
+
+// Reservar serializa as reservas de cada profissional com um advisory lock de transação
+// e só insere se nenhum agendamento existente cruzar o intervalo pedido
+func (r *AgendamentoRepository) Reservar(ctx context.Context, ag models.Agendamento) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := verificarConflito(ctx, tx, ag); err != nil {
+		return err
+	}
+
+	if err := inserirAgendamento(ctx, tx, ag); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// verificarConflito trava a agenda do profissional até o fim da transação e devolve
+// *storage.ConflitoAgendamento se outro agendamento ativo cruzar o intervalo de ag
+func verificarConflito(ctx context.Context, tx pgx.Tx, ag models.Agendamento) error {
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext('agenda:' || $1))", ag.ProfissionalID); err != nil {
+		return err
+	}
+
+	var conflito storage.ConflitoAgendamento
+	err := tx.QueryRow(ctx, `SELECT data_hora, `+fimAgendamento+`
+		FROM agendamentos
+		WHERE profissional_id = $1 AND id <> $2 AND `+ocupaAgenda+`
+		  AND data_hora < $4 AND `+fimAgendamento+` > $3
+		ORDER BY data_hora
+		LIMIT 1`,
+		ag.ProfissionalID, ag.ID, ag.DataHora, ag.Fim()).Scan(&conflito.Inicio, &conflito.Fim)
+	if err == nil {
+		return &conflito
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+	return nil
+}
+
+func (r *AgendamentoRepository) Buscar(ctx context.Context, id string) (*models.Agendamento, error) {
+	var ag models.Agendamento
+	row := r.db.QueryRow(ctx, "SELECT "+colunasAgendamento+" FROM agendamentos WHERE id = $1", id)
+	if err := scanAgendamento(row, &ag); err != nil {
+		return nil, erroBusca(err)
+	}
+	return &ag, nil
+}
+
+// Atualizar bloqueia a linha com FOR UPDATE enquanto aplica a alteração
+func (r *AgendamentoRepository) Atualizar(ctx context.Context, id string, alterar func(ag *models.Agendamento) error) (*models.Agendamento, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var antes models.Agendamento
+	row := tx.QueryRow(ctx, "SELECT "+colunasAgendamento+" FROM agendamentos WHERE id = $1 FOR UPDATE", id)
+	if err := scanAgendamento(row, &antes); err != nil {
+		return nil, erroBusca(err)
+	}
+
+	depois := antes
+	depois.Historico = append([]models.TransicaoStatus(nil), antes.Historico...)
+	if err := alterar(&depois); err != nil {
+		return nil, err
+	}
+	if storage.MudouIntervalo(antes, depois) {
+		if err := verificarConflito(ctx, tx, depois); err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE agendamentos SET
+			cliente_id = $2, profissional_id = $3, estabelecimento_id = $4, procedimento_id = $5,
+			procedimento = $6, preco = $7, data_hora = $8, duracao_min = $9, status = $10, historico = $11, taxa = $12
+		WHERE id = $1`,
+		depois.ID, depois.ClienteID, depois.ProfissionalID, depois.EstabelecimentoID, depois.ProcedimentoID,
+		depois.Procedimento, depois.Preco, depois.DataHora, depois.DuracaoMin, depois.StatusAtual(), historico(depois), depois.Taxa)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &depois, nil
+}
+
+func (r *AgendamentoRepository) Listar(ctx context.Context, filtro storage.FiltroAgendamento) ([]models.Agendamento, error) {
+	where, args := condicoesAgendamento(filtro, "")
+	sql := "SELECT " + colunasAgendamento + " FROM agendamentos" + where + " ORDER BY data_hora"
+	return listar(ctx, r.db, scanAgendamento, sql, args...)
+}
+
+func (r *AgendamentoRepository) Percorrer(ctx context.Context, filtro storage.FiltroAgendamento, visitar func(models.Agendamento) error) error {
+	where, args := condicoesAgendamento(filtro, "")
+	rows, err := r.db.Query(ctx, "SELECT "+colunasAgendamento+" FROM agendamentos"+where+" ORDER BY data_hora", args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var ag models.Agendamento
+		if err := scanAgendamento(rows, &ag); err != nil {
+			return err
+		}
+		if err := visitar(ag); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// condicoesAgendamento traduz o filtro para uma cláusula WHERE, qualificando as
+// colunas com o alias informado (ex.: "a.")
+func condicoesAgendamento(filtro storage.FiltroAgendamento, alias string) (string, []any) {
+	var condicoes []string
+	var args []any
+	adicionar := func(coluna, op string, valor any) {
+		args = append(args, valor)
+		condicoes = append(condicoes, fmt.Sprintf("%s%s %s $%d", alias, coluna, op, len(args)))
+	}
+
+	if filtro.ClienteID != "" {
+		adicionar("cliente_id", "=", filtro.ClienteID)
+	}
+	if filtro.ProfissionalID != "" {
+		adicionar("profissional_id", "=", filtro.ProfissionalID)
+	}
+	if filtro.EstabelecimentoID != "" {
+		adicionar("estabelecimento_id", "=", filtro.EstabelecimentoID)
+	}
+	if !filtro.De.IsZero() {
+		adicionar("data_hora", ">=", filtro.De)
+	}
+	if !filtro.Ate.IsZero() {
+		adicionar("data_hora", "<=", filtro.Ate)
+	}
+	if filtro.Status != "" {
+		adicionar("status", "=", filtro.Status)
+	}
+
+	if len(condicoes) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(condicoes, " AND "), args
+}
@@ -0,0 +1,180 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"servico-api/models"
+	"servico-api/storage"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// AvaliacaoRepository implementa storage.AvaliacaoRepository nas tabelas "avaliacoes" e
+// "resumos_avaliacoes"
+type AvaliacaoRepository struct {
+	db db
+}
+
+const colunasAvaliacao = "id, agendamento_id, profissional_id, cliente_id, estabelecimento_id, nota, comentario, data, editada_em, " +
+	"situacao, resposta, denuncias, em_moderacao, moderacao"
+
+func scanAvaliacao(row pgx.Row, a *models.Avaliacao) error {
+	return row.Scan(&a.ID, &a.AgendamentoID, &a.ProfissionalID, &a.ClienteID, &a.EstabelecimentoID,
+		&a.Nota, &a.Comentario, &a.Data, &a.EditadaEm,
+		&a.Situacao, &a.Resposta, &a.Denuncias, &a.EmModeracao, &a.Moderacao)
+}
+
+func (r *AvaliacaoRepository) Criar(ctx context.Context, a models.Avaliacao) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `INSERT INTO avaliacoes (`+colunasAvaliacao+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		ON CONFLICT (id) DO NOTHING`,
+		a.ID, a.AgendamentoID, a.ProfissionalID, a.ClienteID, a.EstabelecimentoID,
+		a.Nota, a.Comentario, a.Data, a.EditadaEm,
+		a.Situacao, a.Resposta, a.Denuncias, a.EmModeracao, a.Moderacao)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrJaExiste
+	}
+	if err := ajustarResumos(ctx, tx, nil, &a); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *AvaliacaoRepository) Buscar(ctx context.Context, id string) (*models.Avaliacao, error) {
+	var a models.Avaliacao
+	row := r.db.QueryRow(ctx, "SELECT "+colunasAvaliacao+" FROM avaliacoes WHERE id = $1", id)
+	if err := scanAvaliacao(row, &a); err != nil {
+		return nil, erroBusca(err)
+	}
+	return &a, nil
+}
+
+// Atualizar bloqueia a linha com FOR UPDATE enquanto aplica a alteração
+func (r *AvaliacaoRepository) Atualizar(ctx context.Context, id string, alterar func(a *models.Avaliacao) error) (*models.Avaliacao, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var antes models.Avaliacao
+	row := tx.QueryRow(ctx, "SELECT "+colunasAvaliacao+" FROM avaliacoes WHERE id = $1 FOR UPDATE", id)
+	if err := scanAvaliacao(row, &antes); err != nil {
+		return nil, erroBusca(err)
+	}
+	a := antes
+	a.Denuncias = append([]models.DenunciaAvaliacao(nil), antes.Denuncias...)
+	a.Moderacao = append([]models.AcaoModeracao(nil), antes.Moderacao...)
+	if err := alterar(&a); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE avaliacoes SET
+			nota = $2, comentario = $3, editada_em = $4,
+			situacao = $5, resposta = $6, denuncias = $7, em_moderacao = $8, moderacao = $9
+		WHERE id = $1`,
+		a.ID, a.Nota, a.Comentario, a.EditadaEm, a.Situacao, a.Resposta, a.Denuncias, a.EmModeracao, a.Moderacao)
+	if err != nil {
+		return nil, err
+	}
+	if err := ajustarResumos(ctx, tx, &antes, &a); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// ajustarResumos bloqueia os resumos afetados pela avaliação, criando os que faltam, e
+// aplica a mudança de antes para depois. Mudanças que não alteram os resumos não os
+// bloqueiam.
+func ajustarResumos(ctx context.Context, tx pgx.Tx, antes, depois *models.Avaliacao) error {
+	if _, mudou := storage.VariacaoResumo(antes, depois); !mudou {
+		return nil
+	}
+	atual := depois
+	if atual == nil {
+		atual = antes
+	}
+	for _, alvo := range storage.AlvosDaAvaliacao(*atual) {
+		_, err := tx.Exec(ctx, `INSERT INTO resumos_avaliacoes (alvo, alvo_id) VALUES ($1, $2)
+			ON CONFLICT (alvo, alvo_id) DO NOTHING`, alvo.Alvo, alvo.ID)
+		if err != nil {
+			return err
+		}
+		var resumo models.ResumoAvaliacoes
+		err = scanResumo(tx.QueryRow(ctx, `SELECT `+colunasResumo+` FROM resumos_avaliacoes
+			WHERE alvo = $1 AND alvo_id = $2 FOR UPDATE`, alvo.Alvo, alvo.ID), &resumo)
+		if err != nil {
+			return err
+		}
+		resumo.Aplicar(antes, depois)
+		_, err = tx.Exec(ctx, `UPDATE resumos_avaliacoes SET quantidade = $3, soma = $4, media = $5, histograma = $6
+			WHERE alvo = $1 AND alvo_id = $2`, alvo.Alvo, alvo.ID, resumo.Quantidade, resumo.Soma, resumo.Media, resumo.Histograma)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *AvaliacaoRepository) ListarEmModeracao(ctx context.Context) ([]models.Avaliacao, error) {
+	return listar(ctx, r.db, scanAvaliacao,
+		"SELECT "+colunasAvaliacao+" FROM avaliacoes WHERE em_moderacao ORDER BY data")
+}
+
+func (r *AvaliacaoRepository) ListarPorProfissional(ctx context.Context, profissionalID string) ([]models.Avaliacao, error) {
+	return listar(ctx, r.db, scanAvaliacao,
+		"SELECT "+colunasAvaliacao+" FROM avaliacoes WHERE profissional_id = $1 ORDER BY data", profissionalID)
+}
+
+func (r *AvaliacaoRepository) ListarPorEstabelecimento(ctx context.Context, estabelecimentoID string) ([]models.Avaliacao, error) {
+	return listar(ctx, r.db, scanAvaliacao,
+		"SELECT "+colunasAvaliacao+" FROM avaliacoes WHERE estabelecimento_id = $1 ORDER BY data", estabelecimentoID)
+}
+
+const colunasResumo = "quantidade, soma, media, histograma"
+
+func scanResumo(row pgx.Row, r *models.ResumoAvaliacoes) error {
+	return row.Scan(&r.Quantidade, &r.Soma, &r.Media, &r.Histograma)
+}
+
+func (r *AvaliacaoRepository) BuscarResumo(ctx context.Context, alvo, id string) (models.ResumoAvaliacoes, error) {
+	var resumo models.ResumoAvaliacoes
+	err := scanResumo(r.db.QueryRow(ctx, `SELECT `+colunasResumo+` FROM resumos_avaliacoes
+		WHERE alvo = $1 AND alvo_id = $2`, alvo, id), &resumo)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.ResumoAvaliacoes{}, nil
+	}
+	return resumo, err
+}
+
+func (r *AvaliacaoRepository) BuscarResumos(ctx context.Context, alvo string, ids []string) (map[string]models.ResumoAvaliacoes, error) {
+	rows, err := r.db.Query(ctx, `SELECT alvo_id, `+colunasResumo+` FROM resumos_avaliacoes
+		WHERE alvo = $1 AND alvo_id = ANY($2)`, alvo, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	resumos := make(map[string]models.ResumoAvaliacoes)
+	for rows.Next() {
+		var id string
+		var resumo models.ResumoAvaliacoes
+		if err := rows.Scan(&id, &resumo.Quantidade, &resumo.Soma, &resumo.Media, &resumo.Histograma); err != nil {
+			return nil, err
+		}
+		resumos[id] = resumo
+	}
+	return resumos, rows.Err()
+}
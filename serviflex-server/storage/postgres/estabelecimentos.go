@@ -0,0 +1,82 @@
+package postgres
+
+import (
+	"context"
+	"servico-api/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// EstabelecimentoRepository implementa storage.EstabelecimentoRepository nas tabelas
+// "estabelecimentos" e "estabelecimento_profissionais"
+type EstabelecimentoRepository struct {
+	db db
+}
+
+const colunasEstabelecimento = "id, nome, descricao, foto_url, categoria, endereco, cidade, uf, criado_em, responsavel_uid, politica_cancelamento, fuso_horario, comissoes"
+
+func scanEstabelecimento(row pgx.Row, e *models.Estabelecimento) error {
+	return row.Scan(&e.ID, &e.Nome, &e.Descricao, &e.FotoURL, &e.Categoria,
+		&e.Localizacao.Endereco, &e.Localizacao.Cidade, &e.Localizacao.UF, &e.CriadoEm, &e.ResponsavelUID, &e.PoliticaCancelamento, &e.FusoHorario, &e.Comissoes)
+}
+
+func scanVinculo(row pgx.Row, v *models.ProfissionalEstabelecimento) error {
+	return row.Scan(&v.UID, &v.Nome, &v.Status, &v.AdicionadoEm)
+}
+
+func (r *EstabelecimentoRepository) Salvar(ctx context.Context, e models.Estabelecimento) error {
+	_, err := r.db.Exec(ctx, `INSERT INTO estabelecimentos (`+colunasEstabelecimento+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		ON CONFLICT (id) DO UPDATE SET
+			nome = EXCLUDED.nome,
+			descricao = EXCLUDED.descricao,
+			foto_url = EXCLUDED.foto_url,
+			categoria = EXCLUDED.categoria,
+			endereco = EXCLUDED.endereco,
+			cidade = EXCLUDED.cidade,
+			uf = EXCLUDED.uf,
+			criado_em = EXCLUDED.criado_em,
+			responsavel_uid = EXCLUDED.responsavel_uid,
+			politica_cancelamento = EXCLUDED.politica_cancelamento,
+			fuso_horario = EXCLUDED.fuso_horario,
+			comissoes = EXCLUDED.comissoes`,
+		e.ID, e.Nome, e.Descricao, e.FotoURL, e.Categoria,
+		e.Localizacao.Endereco, e.Localizacao.Cidade, e.Localizacao.UF, e.CriadoEm, e.ResponsavelUID, e.PoliticaCancelamento, e.FusoHorario, e.Comissoes)
+	return err
+}
+
+func (r *EstabelecimentoRepository) Buscar(ctx context.Context, id string) (*models.Estabelecimento, error) {
+	var e models.Estabelecimento
+	row := r.db.QueryRow(ctx, "SELECT "+colunasEstabelecimento+" FROM estabelecimentos WHERE id = $1", id)
+	if err := scanEstabelecimento(row, &e); err != nil {
+		return nil, erroBusca(err)
+	}
+	return &e, nil
+}
+
+func (r *EstabelecimentoRepository) Listar(ctx context.Context) ([]models.Estabelecimento, error) {
+	return listar(ctx, r.db, scanEstabelecimento, "SELECT "+colunasEstabelecimento+" FROM estabelecimentos ORDER BY criado_em")
+}
+
+func (r *EstabelecimentoRepository) VincularProfissional(ctx context.Context, estabelecimentoID string, v models.ProfissionalEstabelecimento) error {
+	_, err := r.db.Exec(ctx, `INSERT INTO estabelecimento_profissionais
+			(estabelecimento_id, profissional_id, nome, status, adicionado_em)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (estabelecimento_id, profissional_id) DO UPDATE SET
+			nome = EXCLUDED.nome,
+			status = EXCLUDED.status,
+			adicionado_em = EXCLUDED.adicionado_em`,
+		estabelecimentoID, v.UID, v.Nome, v.Status, v.AdicionadoEm)
+	return err
+}
+
+func (r *EstabelecimentoRepository) DesvincularProfissional(ctx context.Context, estabelecimentoID, profissionalID string) error {
+	_, err := r.db.Exec(ctx, "DELETE FROM estabelecimento_profissionais WHERE estabelecimento_id = $1 AND profissional_id = $2",
+		estabelecimentoID, profissionalID)
+	return err
+}
+
+func (r *EstabelecimentoRepository) ListarProfissionais(ctx context.Context, estabelecimentoID string) ([]models.ProfissionalEstabelecimento, error) {
+	return listar(ctx, r.db, scanVinculo, `SELECT profissional_id, nome, status, adicionado_em
+		FROM estabelecimento_profissionais WHERE estabelecimento_id = $1 ORDER BY adicionado_em`, estabelecimentoID)
+}
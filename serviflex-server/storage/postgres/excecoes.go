@@ -0,0 +1,66 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"servico-api/models"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ExcecaoHorarioRepository implementa storage.ExcecaoHorarioRepository na tabela "excecoes_horario"
+type ExcecaoHorarioRepository struct {
+	db db
+}
+
+const colunasExcecao = "id, profissional_id, tipo, data_inicio, data_fim, hora_inicio, hora_fim, motivo"
+
+func scanExcecao(row pgx.Row, e *models.ExcecaoHorario) error {
+	return row.Scan(&e.ID, &e.ProfissionalID, &e.Tipo, &e.DataInicio, &e.DataFim, &e.HoraInicio, &e.HoraFim, &e.Motivo)
+}
+
+func (r *ExcecaoHorarioRepository) Salvar(ctx context.Context, e models.ExcecaoHorario) error {
+	_, err := r.db.Exec(ctx, `INSERT INTO excecoes_horario (`+colunasExcecao+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (id) DO UPDATE SET
+			profissional_id = EXCLUDED.profissional_id,
+			tipo = EXCLUDED.tipo,
+			data_inicio = EXCLUDED.data_inicio,
+			data_fim = EXCLUDED.data_fim,
+			hora_inicio = EXCLUDED.hora_inicio,
+			hora_fim = EXCLUDED.hora_fim,
+			motivo = EXCLUDED.motivo`,
+		e.ID, e.ProfissionalID, e.Tipo, e.DataInicio, e.DataFim, e.HoraInicio, e.HoraFim, e.Motivo)
+	return err
+}
+
+func (r *ExcecaoHorarioRepository) Buscar(ctx context.Context, id string) (*models.ExcecaoHorario, error) {
+	var e models.ExcecaoHorario
+	row := r.db.QueryRow(ctx, "SELECT "+colunasExcecao+" FROM excecoes_horario WHERE id = $1", id)
+	if err := scanExcecao(row, &e); err != nil {
+		return nil, erroBusca(err)
+	}
+	return &e, nil
+}
+
+func (r *ExcecaoHorarioRepository) Excluir(ctx context.Context, id string) error {
+	_, err := r.db.Exec(ctx, "DELETE FROM excecoes_horario WHERE id = $1", id)
+	return err
+}
+
+// ListarPorProfissional compara as datas como texto: o formato AAAA-MM-DD preserva a ordem
+func (r *ExcecaoHorarioRepository) ListarPorProfissional(ctx context.Context, profissionalID, de, ate string) ([]models.ExcecaoHorario, error) {
+	condicoes := []string{"profissional_id = $1"}
+	args := []any{profissionalID}
+	if de != "" {
+		args = append(args, de)
+		condicoes = append(condicoes, fmt.Sprintf("data_fim >= $%d", len(args)))
+	}
+	if ate != "" {
+		args = append(args, ate)
+		condicoes = append(condicoes, fmt.Sprintf("data_inicio <= $%d", len(args)))
+	}
+	return listar(ctx, r.db, scanExcecao, "SELECT "+colunasExcecao+" FROM excecoes_horario WHERE "+
+		strings.Join(condicoes, " AND ")+" ORDER BY data_inicio", args...)
+}
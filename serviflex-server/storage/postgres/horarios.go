@@ -0,0 +1,108 @@
+package postgres
+
+import (
+	"context"
+	"servico-api/models"
+	"servico-api/storage"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// HorarioRepository implementa storage.HorarioRepository na tabela "horarios"
+type HorarioRepository struct {
+	db db
+}
+
+const colunasHorario = "id, profissional_id, dia_semana, hora_inicio, hora_fim, disponivel, pausas"
+
+func scanHorario(row pgx.Row, h *models.Horario) error {
+	var dia int16
+	if err := row.Scan(&h.ID, &h.ProfissionalID, &dia, &h.HoraInicio, &h.HoraFim, &h.Disponivel, &h.Pausas); err != nil {
+		return err
+	}
+	h.DiaSemana = models.DiaSemana(dia)
+	return nil
+}
+
+// pausas evita gravar a lista nula na coluna JSONB
+func pausas(h models.Horario) []models.Pausa {
+	if h.Pausas == nil {
+		return []models.Pausa{}
+	}
+	return h.Pausas
+}
+
+func (r *HorarioRepository) Salvar(ctx context.Context, h models.Horario) error {
+	return salvarHorario(ctx, r.db, h)
+}
+
+func salvarHorario(ctx context.Context, db db, h models.Horario) error {
+	_, err := db.Exec(ctx, `INSERT INTO horarios (`+colunasHorario+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (id) DO UPDATE SET
+			profissional_id = EXCLUDED.profissional_id,
+			dia_semana = EXCLUDED.dia_semana,
+			hora_inicio = EXCLUDED.hora_inicio,
+			hora_fim = EXCLUDED.hora_fim,
+			disponivel = EXCLUDED.disponivel,
+			pausas = EXCLUDED.pausas`,
+		h.ID, h.ProfissionalID, int16(h.DiaSemana), h.HoraInicio, h.HoraFim, h.Disponivel, pausas(h))
+	return err
+}
+
+// SalvarTurnos serializa as gravações de turnos de cada profissional com um advisory
+// lock de transação e só grava se nenhum turno novo cruzar os já cadastrados
+func (r *HorarioRepository) SalvarTurnos(ctx context.Context, profissionalID string, turnos []models.Horario) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext('turnos:' || $1))", profissionalID); err != nil {
+		return err
+	}
+	existentes, err := listarHorarios(ctx, tx, profissionalID)
+	if err != nil {
+		return err
+	}
+	if sobreposto := storage.BuscarSobreposicao(existentes, turnos); sobreposto != nil {
+		return sobreposto
+	}
+	for _, h := range turnos {
+		if err := salvarHorario(ctx, tx, h); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *HorarioRepository) Buscar(ctx context.Context, id string) (*models.Horario, error) {
+	var h models.Horario
+	row := r.db.QueryRow(ctx, "SELECT "+colunasHorario+" FROM horarios WHERE id = $1", id)
+	if err := scanHorario(row, &h); err != nil {
+		return nil, erroBusca(err)
+	}
+	return &h, nil
+}
+
+func (r *HorarioRepository) Excluir(ctx context.Context, id string) error {
+	_, err := r.db.Exec(ctx, "DELETE FROM horarios WHERE id = $1", id)
+	return err
+}
+
+func (r *HorarioRepository) ListarPorProfissional(ctx context.Context, profissionalID string) ([]models.Horario, error) {
+	return listarHorarios(ctx, r.db, profissionalID)
+}
+
+func listarHorarios(ctx context.Context, db db, profissionalID string) ([]models.Horario, error) {
+	return listar(ctx, db, scanHorario,
+		"SELECT "+colunasHorario+" FROM horarios WHERE profissional_id = $1 ORDER BY dia_semana, hora_inicio",
+		profissionalID)
+}
+
+func (r *HorarioRepository) ListarPorDia(ctx context.Context, profissionalID string, diaSemana models.DiaSemana) ([]models.Horario, error) {
+	return listar(ctx, r.db, scanHorario,
+		"SELECT "+colunasHorario+" FROM horarios WHERE profissional_id = $1 AND dia_semana = $2 ORDER BY hora_inicio",
+		profissionalID, int16(diaSemana))
+}
@@ -0,0 +1,143 @@
+package postgres
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+//go:embed migrations/*.sql
+var arquivosMigracao embed.FS
+
+// chaveLockMigracoes identifica o advisory lock que serializa migrações concorrentes
+const chaveLockMigracoes = 23063
+
+// migracao é um arquivo migrations/NNNN_nome.sql
+type migracao struct {
+	Versao int
+	Nome   string
+	SQL    string
+}
+
+// carregarMigracoes lê as migrações embutidas em ordem de versão
+func carregarMigracoes() ([]migracao, error) {
+	entradas, err := arquivosMigracao.ReadDir("migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	var migracoes []migracao
+	vistas := make(map[int]string)
+	for _, e := range entradas {
+		versao, nome, err := interpretarNomeMigracao(e.Name())
+		if err != nil {
+			return nil, err
+		}
+		if anterior, ok := vistas[versao]; ok {
+			return nil, fmt.Errorf("migrações %s e %s usam a mesma versão", anterior, e.Name())
+		}
+		vistas[versao] = e.Name()
+
+		conteudo, err := arquivosMigracao.ReadFile(path.Join("migrations", e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		migracoes = append(migracoes, migracao{Versao: versao, Nome: nome, SQL: string(conteudo)})
+	}
+	sort.Slice(migracoes, func(i, j int) bool { return migracoes[i].Versao < migracoes[j].Versao })
+	return migracoes, nil
+}
+
+// interpretarNomeMigracao extrai versão e nome de "0001_inicial.sql"
+func interpretarNomeMigracao(arquivo string) (int, string, error) {
+	base, ok := strings.CutSuffix(arquivo, ".sql")
+	if !ok {
+		return 0, "", fmt.Errorf("migração %s não é um arquivo .sql", arquivo)
+	}
+	numero, nome, ok := strings.Cut(base, "_")
+	if !ok || nome == "" {
+		return 0, "", fmt.Errorf("migração %s fora do padrão NNNN_nome.sql", arquivo)
+	}
+	versao, err := strconv.Atoi(numero)
+	if err != nil || versao <= 0 {
+		return 0, "", fmt.Errorf("migração %s com versão inválida", arquivo)
+	}
+	return versao, nome, nil
+}
+
+// Migrar aplica, cada uma em sua transação, as migrações ainda não registradas em
+// schema_migrations. Um advisory lock impede que duas instâncias migrem ao mesmo tempo.
+func Migrar(ctx context.Context, pool *pgxpool.Pool) ([]string, error) {
+	migracoes, err := carregarMigracoes()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", chaveLockMigracoes); err != nil {
+		return nil, err
+	}
+	defer conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", chaveLockMigracoes)
+
+	_, err = conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		versao     INTEGER PRIMARY KEY,
+		nome       TEXT NOT NULL,
+		aplicada_em TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`)
+	if err != nil {
+		return nil, err
+	}
+
+	aplicadas := make(map[int]bool)
+	rows, err := conn.Query(ctx, "SELECT versao FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		aplicadas[v] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var novas []string
+	for _, m := range migracoes {
+		if aplicadas[m.Versao] {
+			continue
+		}
+		tx, err := conn.Begin(ctx)
+		if err != nil {
+			return novas, err
+		}
+		if _, err := tx.Exec(ctx, m.SQL); err != nil {
+			tx.Rollback(ctx)
+			return novas, fmt.Errorf("migração %04d_%s: %w", m.Versao, m.Nome, err)
+		}
+		if _, err := tx.Exec(ctx, "INSERT INTO schema_migrations (versao, nome) VALUES ($1, $2)", m.Versao, m.Nome); err != nil {
+			tx.Rollback(ctx)
+			return novas, err
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return novas, err
+		}
+		novas = append(novas, fmt.Sprintf("%04d_%s", m.Versao, m.Nome))
+	}
+	return novas, nil
+}
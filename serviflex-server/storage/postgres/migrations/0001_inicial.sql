@@ -0,0 +1,115 @@
+-- Esquema inicial, equivalente às coleções do Firestore
+
+CREATE TABLE clientes (
+    id        TEXT PRIMARY KEY,
+    nome      TEXT NOT NULL DEFAULT '',
+    email     TEXT NOT NULL,
+    senha     TEXT NOT NULL DEFAULT '',
+    telefone  TEXT NOT NULL DEFAULT '',
+    foto_url  TEXT NOT NULL DEFAULT '',
+    criado_em TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE UNIQUE INDEX clientes_email_idx ON clientes (email);
+
+CREATE TABLE profissionais (
+    id                 TEXT PRIMARY KEY,
+    nome               TEXT NOT NULL DEFAULT '',
+    email              TEXT NOT NULL,
+    senha              TEXT NOT NULL DEFAULT '',
+    telefone           TEXT NOT NULL DEFAULT '',
+    imagem_url         TEXT NOT NULL DEFAULT '',
+    estabelecimento_id TEXT NOT NULL DEFAULT '',
+    criado_em          TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE UNIQUE INDEX profissionais_email_idx ON profissionais (email);
+
+CREATE TABLE admin (
+    id        TEXT PRIMARY KEY,
+    nome      TEXT NOT NULL DEFAULT '',
+    email     TEXT NOT NULL,
+    senha     TEXT NOT NULL DEFAULT '',
+    tipo      TEXT NOT NULL DEFAULT 'admin',
+    criado_em TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE UNIQUE INDEX admin_email_idx ON admin (email);
+
+CREATE TABLE estabelecimentos (
+    id              TEXT PRIMARY KEY,
+    nome            TEXT NOT NULL,
+    descricao       TEXT NOT NULL DEFAULT '',
+    foto_url        TEXT NOT NULL DEFAULT '',
+    categoria       TEXT NOT NULL DEFAULT '',
+    endereco        TEXT NOT NULL DEFAULT '',
+    cidade          TEXT NOT NULL DEFAULT '',
+    uf              TEXT NOT NULL DEFAULT '',
+    responsavel_uid TEXT NOT NULL DEFAULT '',
+    criado_em       TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX estabelecimentos_responsavel_idx ON estabelecimentos (responsavel_uid);
+
+-- Equivale à subcoleção estabelecimentos/{id}/profissionais
+CREATE TABLE estabelecimento_profissionais (
+    estabelecimento_id TEXT NOT NULL REFERENCES estabelecimentos (id) ON DELETE CASCADE,
+    profissional_id    TEXT NOT NULL,
+    nome               TEXT NOT NULL DEFAULT '',
+    status             TEXT NOT NULL DEFAULT 'pendente',
+    adicionado_em      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (estabelecimento_id, profissional_id)
+);
+
+CREATE TABLE horarios (
+    id              TEXT PRIMARY KEY,
+    profissional_id TEXT NOT NULL,
+    dia_semana      TEXT NOT NULL,
+    hora_inicio     TEXT NOT NULL,
+    hora_fim        TEXT NOT NULL,
+    disponivel      BOOLEAN NOT NULL DEFAULT true
+);
+CREATE INDEX horarios_profissional_dia_idx ON horarios (profissional_id, dia_semana);
+
+CREATE TABLE procedimentos (
+    id              TEXT PRIMARY KEY,
+    profissional_id TEXT NOT NULL,
+    nome            TEXT NOT NULL,
+    descricao       TEXT NOT NULL DEFAULT '',
+    preco           NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    duracao_min     INTEGER NOT NULL DEFAULT 0,
+    imagem_url      TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX procedimentos_profissional_nome_idx ON procedimentos (profissional_id, nome);
+
+CREATE TABLE agendamentos (
+    id                 TEXT PRIMARY KEY,
+    cliente_id         TEXT NOT NULL,
+    profissional_id    TEXT NOT NULL,
+    estabelecimento_id TEXT NOT NULL DEFAULT '',
+    procedimento       TEXT NOT NULL,
+    data_hora          TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX agendamentos_cliente_idx ON agendamentos (cliente_id, data_hora);
+CREATE INDEX agendamentos_profissional_idx ON agendamentos (profissional_id, data_hora);
+CREATE INDEX agendamentos_estabelecimento_idx ON agendamentos (estabelecimento_id, data_hora);
+
+CREATE TABLE avaliacoes (
+    id                 BIGSERIAL PRIMARY KEY,
+    profissional_id    TEXT NOT NULL,
+    cliente_id         TEXT NOT NULL,
+    estabelecimento_id TEXT NOT NULL DEFAULT '',
+    nota               DOUBLE PRECISION NOT NULL,
+    comentario         TEXT NOT NULL DEFAULT '',
+    data               TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX avaliacoes_profissional_idx ON avaliacoes (profissional_id);
+CREATE INDEX avaliacoes_estabelecimento_idx ON avaliacoes (estabelecimento_id);
+
+CREATE TABLE notificacoes (
+    id                 TEXT PRIMARY KEY,
+    para_uid           TEXT NOT NULL,
+    tipo               TEXT NOT NULL,
+    mensagem           TEXT NOT NULL DEFAULT '',
+    estabelecimento_id TEXT NOT NULL DEFAULT '',
+    respondido         BOOLEAN NOT NULL DEFAULT false,
+    resposta           TEXT,
+    criado_em          TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX notificacoes_pendentes_idx ON notificacoes (para_uid, tipo) WHERE NOT respondido;
@@ -0,0 +1,10 @@
+-- Duração copiada do procedimento no momento do agendamento, usada na detecção de conflitos
+
+ALTER TABLE agendamentos ADD COLUMN duracao_min INTEGER NOT NULL DEFAULT 0;
+
+UPDATE agendamentos a
+SET duracao_min = p.duracao_min
+FROM procedimentos p
+WHERE p.profissional_id = a.profissional_id
+  AND p.nome = a.procedimento
+  AND a.duracao_min = 0;
@@ -0,0 +1,12 @@
+-- Ciclo de vida dos agendamentos: status atual e histórico de transições
+
+ALTER TABLE agendamentos
+    ADD COLUMN status TEXT NOT NULL DEFAULT 'pendente'
+        CHECK (status IN ('pendente', 'confirmado', 'concluido', 'cancelado', 'nao_compareceu')),
+    ADD COLUMN historico JSONB NOT NULL DEFAULT '[]';
+
+-- Antes do ciclo de vida todo agendamento contava como faturamento; os que já
+-- aconteceram passam a concluídos para preservar os relatórios
+UPDATE agendamentos SET status = 'concluido' WHERE data_hora < now();
+
+CREATE INDEX agendamentos_status_idx ON agendamentos (status, data_hora);
@@ -0,0 +1,8 @@
+-- Política de cancelamento por estabelecimento (opcionalmente sobrescrita no procedimento)
+-- e taxa gerada no agendamento cancelado tarde ou com falta do cliente
+
+ALTER TABLE estabelecimentos ADD COLUMN politica_cancelamento JSONB;
+ALTER TABLE procedimentos ADD COLUMN politica_cancelamento JSONB;
+ALTER TABLE agendamentos ADD COLUMN taxa JSONB;
+
+CREATE INDEX agendamentos_taxa_idx ON agendamentos (estabelecimento_id) WHERE taxa IS NOT NULL;
@@ -0,0 +1,14 @@
+-- Exceções à agenda semanal: bloqueios (folgas, férias, feriados) e horários extras.
+-- Datas em AAAA-MM-DD e horas em HH:MM, como em "horarios"; vazias = dia inteiro.
+
+CREATE TABLE excecoes_horario (
+    id              TEXT PRIMARY KEY,
+    profissional_id TEXT NOT NULL,
+    tipo            TEXT NOT NULL CHECK (tipo IN ('bloqueio', 'extra')),
+    data_inicio     TEXT NOT NULL,
+    data_fim        TEXT NOT NULL CHECK (data_fim >= data_inicio),
+    hora_inicio     TEXT NOT NULL DEFAULT '',
+    hora_fim        TEXT NOT NULL DEFAULT '',
+    motivo          TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX excecoes_horario_profissional_idx ON excecoes_horario (profissional_id, data_inicio);
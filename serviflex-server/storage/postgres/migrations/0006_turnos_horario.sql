@@ -0,0 +1,3 @@
+-- Vários turnos por dia da semana e pausas dentro de cada turno
+
+ALTER TABLE horarios ADD COLUMN pausas JSONB NOT NULL DEFAULT '[]';
@@ -0,0 +1,4 @@
+-- Fuso horário IANA de cada estabelecimento, herdado pelos seus profissionais.
+-- Vazio usa o fuso padrão da aplicação (America/Sao_Paulo).
+
+ALTER TABLE estabelecimentos ADD COLUMN fuso_horario TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,17 @@
+-- Dia da semana dos horários no padrão ISO-8601 (1 = segunda ... 7 = domingo) em vez do
+-- nome em português, que variava conforme o cliente ("Terça", "terca", "Terca").
+-- Um nome não reconhecido vira NULL e barra a migração pelo NOT NULL da coluna.
+
+ALTER TABLE horarios
+    ALTER COLUMN dia_semana TYPE SMALLINT USING (
+        CASE translate(lower(trim(dia_semana)), 'çá', 'ca')
+            WHEN '1' THEN 1 WHEN 'segunda' THEN 1 WHEN 'segunda-feira' THEN 1 WHEN 'seg' THEN 1
+            WHEN '2' THEN 2 WHEN 'terca' THEN 2 WHEN 'terca-feira' THEN 2 WHEN 'ter' THEN 2
+            WHEN '3' THEN 3 WHEN 'quarta' THEN 3 WHEN 'quarta-feira' THEN 3 WHEN 'qua' THEN 3
+            WHEN '4' THEN 4 WHEN 'quinta' THEN 4 WHEN 'quinta-feira' THEN 4 WHEN 'qui' THEN 4
+            WHEN '5' THEN 5 WHEN 'sexta' THEN 5 WHEN 'sexta-feira' THEN 5 WHEN 'sex' THEN 5
+            WHEN '6' THEN 6 WHEN 'sabado' THEN 6 WHEN 'sab' THEN 6
+            WHEN '7' THEN 7 WHEN 'domingo' THEN 7 WHEN 'dom' THEN 7
+        END
+    ),
+    ADD CONSTRAINT horarios_dia_semana_iso CHECK (dia_semana BETWEEN 1 AND 7);
@@ -0,0 +1,19 @@
+-- Agendamentos passam a referenciar o procedimento pelo ID e a guardar o preço da reserva,
+-- para que renomear ou reajustar o procedimento não altere o faturamento já realizado
+
+ALTER TABLE agendamentos
+    ADD COLUMN procedimento_id TEXT NOT NULL DEFAULT '',
+    ADD COLUMN preco NUMERIC(10, 2) NOT NULL DEFAULT 0;
+
+-- Registros antigos: procedimento do mesmo profissional com o nome agendado. A duração
+-- só é copiada se ainda não tiver sido preenchida pela migração 0002.
+UPDATE agendamentos a
+SET procedimento_id = p.id,
+    preco = p.preco,
+    duracao_min = CASE WHEN a.duracao_min = 0 THEN p.duracao_min ELSE a.duracao_min END
+FROM procedimentos p
+WHERE p.profissional_id = a.profissional_id
+  AND p.nome = a.procedimento
+  AND a.procedimento_id = '';
+
+CREATE INDEX agendamentos_procedimento_idx ON agendamentos (procedimento_id);
@@ -0,0 +1,29 @@
+-- Avaliações passam a ser criadas pela API, uma por agendamento: o ID passa a ser o do
+-- agendamento avaliado. As avaliações antigas mantêm o número como texto.
+ALTER TABLE avaliacoes ALTER COLUMN id DROP DEFAULT;
+ALTER TABLE avaliacoes ALTER COLUMN id TYPE TEXT USING id::text;
+DROP SEQUENCE IF EXISTS avaliacoes_id_seq;
+
+ALTER TABLE avaliacoes
+    ADD COLUMN agendamento_id TEXT NOT NULL DEFAULT '',
+    ADD COLUMN editada_em TIMESTAMPTZ;
+
+-- Resumo de notas por profissional ou estabelecimento, mantido a cada avaliação
+CREATE TABLE resumos_avaliacoes (
+    alvo       TEXT NOT NULL,
+    alvo_id    TEXT NOT NULL,
+    quantidade INTEGER NOT NULL DEFAULT 0,
+    media      DOUBLE PRECISION NOT NULL DEFAULT 0,
+    PRIMARY KEY (alvo, alvo_id)
+);
+
+INSERT INTO resumos_avaliacoes (alvo, alvo_id, quantidade, media)
+SELECT 'profissionais', profissional_id, count(*), avg(nota)
+FROM avaliacoes
+GROUP BY profissional_id;
+
+INSERT INTO resumos_avaliacoes (alvo, alvo_id, quantidade, media)
+SELECT 'estabelecimentos', estabelecimento_id, count(*), avg(nota)
+FROM avaliacoes
+WHERE estabelecimento_id <> ''
+GROUP BY estabelecimento_id;
@@ -0,0 +1,12 @@
+-- Moderação das avaliações: resposta pública, denúncias e decisões dos admins.
+-- Avaliações sem situação estão publicadas.
+
+ALTER TABLE avaliacoes
+    ADD COLUMN situacao TEXT NOT NULL DEFAULT ''
+        CHECK (situacao IN ('', 'publicada', 'oculta', 'excluida')),
+    ADD COLUMN resposta JSONB,
+    ADD COLUMN denuncias JSONB,
+    ADD COLUMN em_moderacao BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN moderacao JSONB;
+
+CREATE INDEX avaliacoes_em_moderacao_idx ON avaliacoes (data) WHERE em_moderacao;
@@ -0,0 +1,36 @@
+-- Resumos de avaliações passam a ser ajustados a cada gravação, com soma e histograma de
+-- notas (1 a 5), e ganham uma linha geral por tipo de alvo (alvo_id vazio), usada como
+-- referência da média bayesiana. Os resumos existentes são refeitos só com as avaliações
+-- visíveis.
+
+ALTER TABLE resumos_avaliacoes
+    ADD COLUMN soma DOUBLE PRECISION NOT NULL DEFAULT 0,
+    ADD COLUMN histograma INTEGER[] NOT NULL DEFAULT '{0,0,0,0,0}';
+
+DELETE FROM resumos_avaliacoes;
+
+WITH visiveis AS (
+    SELECT profissional_id, estabelecimento_id, nota,
+           LEAST(GREATEST(round(nota)::int, 1), 5) AS estrelas
+    FROM avaliacoes
+    WHERE situacao IN ('', 'publicada')
+), alvos AS (
+    SELECT 'profissionais' AS alvo, profissional_id AS alvo_id, nota, estrelas FROM visiveis
+    UNION ALL
+    SELECT 'profissionais', '', nota, estrelas FROM visiveis
+    UNION ALL
+    SELECT 'estabelecimentos', estabelecimento_id, nota, estrelas FROM visiveis WHERE estabelecimento_id <> ''
+    UNION ALL
+    SELECT 'estabelecimentos', '', nota, estrelas FROM visiveis WHERE estabelecimento_id <> ''
+)
+INSERT INTO resumos_avaliacoes (alvo, alvo_id, quantidade, soma, media, histograma)
+SELECT alvo, alvo_id, count(*), sum(nota), avg(nota),
+       ARRAY[
+           (count(*) FILTER (WHERE estrelas = 1))::int,
+           (count(*) FILTER (WHERE estrelas = 2))::int,
+           (count(*) FILTER (WHERE estrelas = 3))::int,
+           (count(*) FILTER (WHERE estrelas = 4))::int,
+           (count(*) FILTER (WHERE estrelas = 5))::int
+       ]
+FROM alvos
+GROUP BY alvo, alvo_id;
@@ -0,0 +1,3 @@
+-- Regras de comissão dos profissionais, guardadas no estabelecimento
+
+ALTER TABLE estabelecimentos ADD COLUMN comissoes JSONB;
@@ -0,0 +1,45 @@
+package postgres
+
+import (
+	"context"
+	"servico-api/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// NotificacaoRepository implementa storage.NotificacaoRepository na tabela "notificacoes"
+type NotificacaoRepository struct {
+	db db
+}
+
+const colunasNotificacao = "id, para_uid, tipo, mensagem, estabelecimento_id, respondido, resposta, criado_em"
+
+func scanNotificacao(row pgx.Row, n *models.Notificacao) error {
+	return row.Scan(&n.ID, &n.ParaUID, &n.Tipo, &n.Mensagem, &n.EstabelecimentoID, &n.Respondido, &n.Resposta, &n.CriadoEm)
+}
+
+func (r *NotificacaoRepository) Criar(ctx context.Context, n models.Notificacao) error {
+	_, err := r.db.Exec(ctx, `INSERT INTO notificacoes (`+colunasNotificacao+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		n.ID, n.ParaUID, n.Tipo, n.Mensagem, n.EstabelecimentoID, n.Respondido, n.Resposta, n.CriadoEm)
+	return err
+}
+
+func (r *NotificacaoRepository) Buscar(ctx context.Context, id string) (*models.Notificacao, error) {
+	var n models.Notificacao
+	row := r.db.QueryRow(ctx, "SELECT "+colunasNotificacao+" FROM notificacoes WHERE id = $1", id)
+	if err := scanNotificacao(row, &n); err != nil {
+		return nil, erroBusca(err)
+	}
+	return &n, nil
+}
+
+func (r *NotificacaoRepository) Responder(ctx context.Context, id, resposta string) error {
+	return exigirLinha(r.db.Exec(ctx, "UPDATE notificacoes SET respondido = true, resposta = $2 WHERE id = $1", id, resposta))
+}
+
+func (r *NotificacaoRepository) ListarConvitesPendentes(ctx context.Context, paraUID string) ([]models.Notificacao, error) {
+	return listar(ctx, r.db, scanNotificacao, `SELECT `+colunasNotificacao+` FROM notificacoes
+		WHERE para_uid = $1 AND tipo = 'convite_estabelecimento' AND NOT respondido
+		ORDER BY criado_em`, paraUID)
+}
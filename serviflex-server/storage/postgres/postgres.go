@@ -0,0 +1,81 @@
+// Package postgres implementa os repositórios de storage sobre o PostgreSQL, com
+// migrações versionadas e relatórios calculados por agregação no banco.
+package postgres
+
+import (
+	"context"
+	"errors"
+	"servico-api/storage"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// db é o subconjunto comum a *pgxpool.Pool e pgx.Tx usado pelos repositórios
+type db interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// Conectar abre o pool de conexões a partir de uma URL no formato postgres://
+func Conectar(ctx context.Context, url string) (*pgxpool.Pool, error) {
+	pool, err := pgxpool.New(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, err
+	}
+	return pool, nil
+}
+
+// NovosRepositorios cria todos os repositórios compartilhando o mesmo pool
+func NovosRepositorios(pool *pgxpool.Pool) *storage.Repositorios {
+	return &storage.Repositorios{
+		Agendamentos:     &AgendamentoRepository{db: pool},
+		Horarios:         &HorarioRepository{db: pool},
+		Excecoes:         &ExcecaoHorarioRepository{db: pool},
+		Procedimentos:    &ProcedimentoRepository{db: pool},
+		Estabelecimentos: &EstabelecimentoRepository{db: pool},
+		Usuarios:         &UsuarioRepository{db: pool},
+		Notificacoes:     &NotificacaoRepository{db: pool},
+		Avaliacoes:       &AvaliacaoRepository{db: pool},
+		Relatorios:       &RelatorioRepository{db: pool},
+	}
+}
+
+// erroBusca converte a ausência de linha de um QueryRow em storage.ErrNaoEncontrado
+func erroBusca(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return storage.ErrNaoEncontrado
+	}
+	return err
+}
+
+// listar executa a consulta e converte cada linha com scan
+func listar[T any](ctx context.Context, db db, scan func(pgx.Row, *T) error, sql string, args ...any) ([]T, error) {
+	rows, err := db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (T, error) {
+		var item T
+		err := scan(row, &item)
+		return item, err
+	})
+}
+
+// exigirLinha devolve storage.ErrNaoEncontrado quando um UPDATE não afetou nenhuma linha
+func exigirLinha(tag pgconn.CommandTag, err error) error {
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNaoEncontrado
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"os"
+	"servico-api/models"
+	"servico-api/relatorios"
+	"servico-api/storage"
+	"testing"
+	"time"
+)
+
+func TestMigracoesEmbutidas(t *testing.T) {
+	migracoes, err := carregarMigracoes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migracoes) == 0 || migracoes[0].Versao != 1 {
+		t.Fatalf("esperava a migração 0001 primeiro, obtive %+v", migracoes)
+	}
+	for i := 1; i < len(migracoes); i++ {
+		if migracoes[i].Versao <= migracoes[i-1].Versao {
+			t.Errorf("migrações fora de ordem: %d depois de %d", migracoes[i].Versao, migracoes[i-1].Versao)
+		}
+	}
+}
+
+func TestInterpretarNomeMigracao(t *testing.T) {
+	versao, nome, err := interpretarNomeMigracao("0012_status_agendamento.sql")
+	if err != nil || versao != 12 || nome != "status_agendamento" {
+		t.Errorf("obtive %d %q %v", versao, nome, err)
+	}
+	for _, invalido := range []string{"inicial.sql", "0001.sql", "abc_x.sql", "0001_x.txt", "0000_x.sql"} {
+		if _, _, err := interpretarNomeMigracao(invalido); err == nil {
+			t.Errorf("%s deveria ser rejeitado", invalido)
+		}
+	}
+}
+
+func TestCondicoesAgendamento(t *testing.T) {
+	de := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	where, args := condicoesAgendamento(storage.FiltroAgendamento{ProfissionalID: "prof-1", De: de}, "a.")
+	if where != " WHERE a.profissional_id = $1 AND a.data_hora >= $2" {
+		t.Errorf("where inesperado: %q", where)
+	}
+	if len(args) != 2 || args[0] != "prof-1" || args[1] != de {
+		t.Errorf("args inesperados: %v", args)
+	}
+
+	if where, args := condicoesAgendamento(storage.FiltroAgendamento{}, ""); where != "" || args != nil {
+		t.Errorf("filtro vazio não deveria gerar condições: %q %v", where, args)
+	}
+}
+
+// bancoDeTeste conecta ao banco de POSTGRES_TESTE_URL, aplica as migrações e esvazia as
+// tabelas informadas. O banco deve ser descartável.
+func bancoDeTeste(t *testing.T, tabelas string) *storage.Repositorios {
+	t.Helper()
+	url := os.Getenv("POSTGRES_TESTE_URL")
+	if url == "" {
+		t.Skip("POSTGRES_TESTE_URL não definida")
+	}
+	ctx := context.Background()
+	pool, err := Conectar(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	if _, err := Migrar(ctx, pool); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pool.Exec(ctx, "TRUNCATE "+tabelas); err != nil {
+		t.Fatal(err)
+	}
+	return NovosRepositorios(pool)
+}
+
+func TestRelatoriosSQL(t *testing.T) {
+	repos := bancoDeTeste(t, "agendamentos")
+	ctx := context.Background()
+	for i, data := range []time.Time{
+		time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC),
+		time.Date(2025, 1, 20, 12, 0, 0, 0, time.UTC),
+		time.Date(2025, 2, 5, 12, 0, 0, 0, time.UTC),
+	} {
+		repos.Agendamentos.Criar(ctx, models.Agendamento{
+			ID: string(rune('a' + i)), ClienteID: "cli-1", ProfissionalID: "prof-1",
+			EstabelecimentoID: "est-1", ProcedimentoID: "p1", Procedimento: "Corte", Preco: 50, DataHora: data,
+		})
+	}
+
+	resumo, err := repos.Relatorios.Faturamento(ctx, storage.FiltroAgendamento{EstabelecimentoID: "est-1"}, relatorios.SemAgrupamento, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resumo) != 1 || resumo[0].Quantidade != 3 || resumo[0].Total != 150 {
+		t.Errorf("faturamento inesperado: %+v", resumo)
+	}
+
+	porMes, err := repos.Relatorios.Faturamento(ctx, storage.FiltroAgendamento{ProfissionalID: "prof-1"}, relatorios.AgruparMes, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(porMes) != 2 || porMes[0].Chave != "2025-01" || porMes[0].Quantidade != 2 || porMes[1].Quantidade != 1 {
+		t.Errorf("contagem mensal inesperada: %+v", porMes)
+	}
+
+	// As chaves do SQL precisam ser as mesmas da agregação em memória
+	semanas, err := repos.Relatorios.Faturamento(ctx, storage.FiltroAgendamento{}, relatorios.AgruparSemana, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(semanas) != 3 || semanas[0].Chave != relatorios.AgruparSemana.Chave(time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("semanas inesperadas: %+v", semanas)
+	}
+	porProcedimento, err := repos.Relatorios.Faturamento(ctx, storage.FiltroAgendamento{}, relatorios.AgruparProcedimento, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(porProcedimento) != 1 || porProcedimento[0].Chave != "p1" || porProcedimento[0].Rotulo != "Corte" {
+		t.Errorf("agrupamento por procedimento inesperado: %+v", porProcedimento)
+	}
+}
+
+func TestReservarSQL(t *testing.T) {
+	repos := bancoDeTeste(t, "agendamentos")
+	ctx := context.Background()
+	inicio := time.Date(2025, 3, 10, 14, 0, 0, 0, time.UTC)
+
+	if err := repos.Agendamentos.Reservar(ctx, models.Agendamento{ID: "a", ProfissionalID: "prof-1", DataHora: inicio, DuracaoMin: 30}); err != nil {
+		t.Fatal(err)
+	}
+	err := repos.Agendamentos.Reservar(ctx, models.Agendamento{ID: "b", ProfissionalID: "prof-1", DataHora: inicio.Add(20 * time.Minute), DuracaoMin: 30})
+	var conflito *storage.ConflitoAgendamento
+	if !errors.As(err, &conflito) || !conflito.Inicio.Equal(inicio) || !conflito.Fim.Equal(inicio.Add(30*time.Minute)) {
+		t.Fatalf("esperava conflito com 14:00–14:30, obtive %v", err)
+	}
+	if err := repos.Agendamentos.Reservar(ctx, models.Agendamento{ID: "c", ProfissionalID: "prof-1", DataHora: inicio.Add(30 * time.Minute), DuracaoMin: 30}); err != nil {
+		t.Errorf("atendimento encostado não deveria conflitar: %v", err)
+	}
+}
+
+func TestSalvarTurnosSQL(t *testing.T) {
+	repos := bancoDeTeste(t, "horarios")
+	ctx := context.Background()
+	manha := models.Horario{ID: "manha", ProfissionalID: "prof-1", DiaSemana: models.Segunda, HoraInicio: "08:00", HoraFim: "12:00"}
+
+	if err := repos.Horarios.SalvarTurnos(ctx, "prof-1", []models.Horario{manha}); err != nil {
+		t.Fatal(err)
+	}
+	err := repos.Horarios.SalvarTurnos(ctx, "prof-1", []models.Horario{{ID: "cruza", ProfissionalID: "prof-1", DiaSemana: models.Segunda, HoraInicio: "11:00", HoraFim: "14:00"}})
+	var sobreposto *storage.TurnoSobreposto
+	if !errors.As(err, &sobreposto) || sobreposto.Existente.ID != "manha" {
+		t.Fatalf("esperava sobreposição com o turno da manhã, obtive %v", err)
+	}
+	// Editar o próprio turno não conflita com ele mesmo
+	manha.HoraFim = "13:00"
+	if err := repos.Horarios.SalvarTurnos(ctx, "prof-1", []models.Horario{manha}); err != nil {
+		t.Errorf("edição do próprio turno não deveria conflitar: %v", err)
+	}
+}
+
+func TestAvaliacoesSQL(t *testing.T) {
+	repos := bancoDeTeste(t, "avaliacoes, resumos_avaliacoes")
+	ctx := context.Background()
+	av := models.Avaliacao{ID: "ag-1", AgendamentoID: "ag-1", ProfissionalID: "prof-1", ClienteID: "cli-1", Nota: 4, Data: time.Now()}
+
+	if err := repos.Avaliacoes.Criar(ctx, av); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.Avaliacoes.Criar(ctx, av); !errors.Is(err, storage.ErrJaExiste) {
+		t.Errorf("segunda avaliação do agendamento deveria dar ErrJaExiste, obtive %v", err)
+	}
+
+	if _, err := repos.Avaliacoes.Atualizar(ctx, "ag-1", func(a *models.Avaliacao) error {
+		a.Nota = 2
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	resumos, err := repos.Avaliacoes.BuscarResumos(ctx, models.AlvoProfissional, []string{"prof-1", "prof-2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, ok := resumos["prof-1"]; !ok || r.Quantidade != 1 || r.Media != 2 || r.Histograma[1] != 1 || r.Histograma[3] != 0 {
+		t.Errorf("resumo do profissional inesperado: %+v", resumos)
+	}
+	if _, ok := resumos["prof-2"]; ok {
+		t.Error("profissional sem avaliações não deveria ter resumo")
+	}
+	if geral, err := repos.Avaliacoes.BuscarResumo(ctx, models.AlvoProfissional, ""); err != nil || geral.Quantidade != 1 {
+		t.Errorf("resumo geral inesperado: %+v %v", geral, err)
+	}
+}
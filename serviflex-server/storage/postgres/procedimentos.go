@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"context"
+	"servico-api/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ProcedimentoRepository implementa storage.ProcedimentoRepository na tabela "procedimentos"
+type ProcedimentoRepository struct {
+	db db
+}
+
+const colunasProcedimento = "id, profissional_id, nome, descricao, preco, duracao_min, imagem_url, politica_cancelamento"
+
+func scanProcedimento(row pgx.Row, p *models.Procedimento) error {
+	return row.Scan(&p.ID, &p.ProfissionalID, &p.Nome, &p.Descricao, &p.Preco, &p.DuracaoMin, &p.ImagemURL, &p.PoliticaCancelamento)
+}
+
+func (r *ProcedimentoRepository) Salvar(ctx context.Context, p models.Procedimento) error {
+	_, err := r.db.Exec(ctx, `INSERT INTO procedimentos (`+colunasProcedimento+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (id) DO UPDATE SET
+			profissional_id = EXCLUDED.profissional_id,
+			nome = EXCLUDED.nome,
+			descricao = EXCLUDED.descricao,
+			preco = EXCLUDED.preco,
+			duracao_min = EXCLUDED.duracao_min,
+			imagem_url = EXCLUDED.imagem_url,
+			politica_cancelamento = EXCLUDED.politica_cancelamento`,
+		p.ID, p.ProfissionalID, p.Nome, p.Descricao, p.Preco, p.DuracaoMin, p.ImagemURL, p.PoliticaCancelamento)
+	return err
+}
+
+func (r *ProcedimentoRepository) Buscar(ctx context.Context, id string) (*models.Procedimento, error) {
+	var p models.Procedimento
+	row := r.db.QueryRow(ctx, "SELECT "+colunasProcedimento+" FROM procedimentos WHERE id = $1", id)
+	if err := scanProcedimento(row, &p); err != nil {
+		return nil, erroBusca(err)
+	}
+	return &p, nil
+}
+
+func (r *ProcedimentoRepository) BuscarPorNome(ctx context.Context, profissionalID, nome string) (*models.Procedimento, error) {
+	var p models.Procedimento
+	row := r.db.QueryRow(ctx, "SELECT "+colunasProcedimento+" FROM procedimentos WHERE profissional_id = $1 AND nome = $2 LIMIT 1",
+		profissionalID, nome)
+	if err := scanProcedimento(row, &p); err != nil {
+		return nil, erroBusca(err)
+	}
+	return &p, nil
+}
+
+func (r *ProcedimentoRepository) Listar(ctx context.Context) ([]models.Procedimento, error) {
+	return listar(ctx, r.db, scanProcedimento, "SELECT "+colunasProcedimento+" FROM procedimentos ORDER BY nome")
+}
+
+func (r *ProcedimentoRepository) ListarPorProfissional(ctx context.Context, profissionalID string) ([]models.Procedimento, error) {
+	return listar(ctx, r.db, scanProcedimento,
+		"SELECT "+colunasProcedimento+" FROM procedimentos WHERE profissional_id = $1 ORDER BY nome", profissionalID)
+}
+
+func (r *ProcedimentoRepository) Excluir(ctx context.Context, id string) error {
+	_, err := r.db.Exec(ctx, "DELETE FROM procedimentos WHERE id = $1", id)
+	return err
+}
+
+func (r *ProcedimentoRepository) AtualizarImagem(ctx context.Context, id, url string) error {
+	return exigirLinha(r.db.Exec(ctx, "UPDATE procedimentos SET imagem_url = $2 WHERE id = $1", id, url))
+}
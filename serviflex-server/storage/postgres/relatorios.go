@@ -0,0 +1,98 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"servico-api/relatorios"
+	"servico-api/storage"
+	"strings"
+	"time"
+)
+
+// RelatorioRepository implementa storage.RelatorioRepository com agregações SQL,
+// sem carregar agendamentos ou procedimentos na aplicação
+type RelatorioRepository struct {
+	db db
+}
+
+func (r *RelatorioRepository) Faturamento(ctx context.Context, filtro storage.FiltroAgendamento, agrupar relatorios.Agrupamento, loc *time.Location) ([]storage.ResumoFaturamento, error) {
+	// O preço é o copiado para o agendamento na reserva
+	return r.somar(ctx, "preco::float8", "", filtro, agrupar, loc)
+}
+
+func (r *RelatorioRepository) Taxas(ctx context.Context, filtro storage.FiltroAgendamento, agrupar relatorios.Agrupamento, loc *time.Location) ([]storage.ResumoFaturamento, error) {
+	return r.somar(ctx, "(taxa->>'valor')::float8", "taxa IS NOT NULL", filtro, agrupar, loc)
+}
+
+// somar agrega valor sobre os agendamentos do filtro que atendem a condicao, agrupados
+// como storage.ChaveAgendamento faz em memória
+func (r *RelatorioRepository) somar(ctx context.Context, valor, condicao string, filtro storage.FiltroAgendamento, agrupar relatorios.Agrupamento, loc *time.Location) ([]storage.ResumoFaturamento, error) {
+	where, args := condicoesAgendamento(filtro, "")
+	if condicao != "" {
+		if where == "" {
+			where = " WHERE " + condicao
+		} else {
+			where += " AND " + condicao
+		}
+	}
+
+	chave, rotulo := "''", "''"
+	switch agrupar {
+	case relatorios.AgruparDia, relatorios.AgruparSemana, relatorios.AgruparMes:
+		formato := map[relatorios.Agrupamento]string{
+			relatorios.AgruparDia:    "YYYY-MM-DD",
+			relatorios.AgruparSemana: `IYYY-"W"IW`,
+			relatorios.AgruparMes:    "YYYY-MM",
+		}[agrupar]
+		args = append(args, nomeFuso(loc))
+		chave = fmt.Sprintf("to_char(data_hora AT TIME ZONE $%d, '%s')", len(args), formato)
+	case relatorios.AgruparProcedimento:
+		// Reservas anteriores ao ID são agrupadas pelo nome; o rótulo é o da mais recente
+		chave = "CASE WHEN procedimento_id <> '' THEN procedimento_id ELSE procedimento END"
+		rotulo = "(array_agg(procedimento ORDER BY data_hora DESC))[1]"
+	case relatorios.AgruparProfissional:
+		chave = "profissional_id"
+	}
+
+	sql := fmt.Sprintf(`SELECT %s AS chave, %s, count(*), coalesce(sum(%s), 0)
+		FROM agendamentos%s
+		GROUP BY 1
+		ORDER BY 1`, chave, rotulo, valor, where)
+	rows, err := r.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var resumos []storage.ResumoFaturamento
+	for rows.Next() {
+		var resumo storage.ResumoFaturamento
+		if err := rows.Scan(&resumo.Chave, &resumo.Rotulo, &resumo.Quantidade, &resumo.Total); err != nil {
+			return nil, err
+		}
+		resumos = append(resumos, resumo)
+	}
+	return resumos, rows.Err()
+}
+
+// nomeFuso devolve o nome IANA do fuso para o PostgreSQL. time.Local se chama
+// "Local", então o nome real vem de TZ ou do link /etc/localtime.
+func nomeFuso(loc *time.Location) string {
+	if loc == nil {
+		return "UTC"
+	}
+	if nome := loc.String(); nome != "Local" {
+		return nome
+	}
+	if tz := os.Getenv("TZ"); tz != "" {
+		return strings.TrimPrefix(tz, ":")
+	}
+	if destino, err := filepath.EvalSymlinks("/etc/localtime"); err == nil {
+		if _, nome, ok := strings.Cut(destino, "zoneinfo/"); ok {
+			return nome
+		}
+	}
+	return "UTC"
+}
@@ -0,0 +1,188 @@
+package postgres
+
+import (
+	"context"
+	"servico-api/models"
+	"servico-api/storage"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// UsuarioRepository implementa storage.UsuarioRepository nas tabelas "clientes",
+// "profissionais" e "admin"
+type UsuarioRepository struct {
+	db db
+}
+
+// consultasUsuario projeta cada tabela nas colunas de models.Usuario, na ordem de scanUsuario
+var consultasUsuario = map[string]string{
+	"clientes":      "SELECT id, nome, email, senha, telefone, foto_url, '', '', criado_em FROM clientes",
+	"profissionais": "SELECT id, nome, email, senha, telefone, '', imagem_url, estabelecimento_id, criado_em FROM profissionais",
+	"admin":         "SELECT id, nome, email, senha, '', '', '', '', criado_em FROM admin",
+}
+
+const (
+	colunasCliente      = "id, nome, email, senha, telefone, foto_url, criado_em"
+	colunasProfissional = "id, nome, email, senha, telefone, imagem_url, estabelecimento_id, criado_em"
+	colunasAdmin        = "id, nome, email, senha, criado_em"
+)
+
+func scanUsuario(row pgx.Row, u *models.Usuario) error {
+	return row.Scan(&u.ID, &u.Nome, &u.Email, &u.Senha, &u.Telefone, &u.FotoURL, &u.ImagemURL, &u.EstabelecimentoID, &u.CriadoEm)
+}
+
+func scanProfissional(row pgx.Row, p *models.Profissional) error {
+	return row.Scan(&p.ID, &p.Nome, &p.Email, &p.Senha, &p.Telefone, &p.ImagemURL, &p.EstabelecimentoID, &p.CriadoEm)
+}
+
+func scanAdmin(row pgx.Row, a *models.Admin) error {
+	a.Tipo = "admin"
+	return row.Scan(&a.ID, &a.Nome, &a.Email, &a.Senha, &a.CriadoEm)
+}
+
+func (r *UsuarioRepository) buscarUsuario(ctx context.Context, tipo, coluna, valor string) (*models.Usuario, error) {
+	consulta, ok := consultasUsuario[tipo]
+	if !ok {
+		return nil, storage.ErrNaoEncontrado
+	}
+	var u models.Usuario
+	row := r.db.QueryRow(ctx, consulta+" WHERE "+coluna+" = $1 LIMIT 1", valor)
+	if err := scanUsuario(row, &u); err != nil {
+		return nil, erroBusca(err)
+	}
+	u.Tipo = tipo
+	return &u, nil
+}
+
+func (r *UsuarioRepository) BuscarPorEmail(ctx context.Context, tipo, email string) (*models.Usuario, error) {
+	return r.buscarUsuario(ctx, tipo, "email", email)
+}
+
+func (r *UsuarioRepository) BuscarUsuario(ctx context.Context, tipo, id string) (*models.Usuario, error) {
+	return r.buscarUsuario(ctx, tipo, "id", id)
+}
+
+func (r *UsuarioRepository) CriarUsuario(ctx context.Context, u models.Usuario) error {
+	switch u.Tipo {
+	case "clientes":
+		return r.SalvarCliente(ctx, models.Cliente{
+			ID:       u.ID,
+			Nome:     u.Nome,
+			Email:    u.Email,
+			Senha:    u.Senha,
+			Telefone: u.Telefone,
+			FotoURL:  u.FotoURL,
+			CriadoEm: u.CriadoEm,
+		})
+	case "profissionais":
+		return r.SalvarProfissional(ctx, models.Profissional{
+			ID:                u.ID,
+			Nome:              u.Nome,
+			Email:             u.Email,
+			Senha:             u.Senha,
+			Telefone:          u.Telefone,
+			ImagemURL:         u.ImagemURL,
+			EstabelecimentoID: u.EstabelecimentoID,
+			CriadoEm:          u.CriadoEm,
+		})
+	case "admin":
+		return r.SalvarAdmin(ctx, models.Admin{
+			ID:       u.ID,
+			Nome:     u.Nome,
+			Email:    u.Email,
+			Senha:    u.Senha,
+			CriadoEm: u.CriadoEm,
+		})
+	}
+	return storage.ErrNaoEncontrado
+}
+
+func (r *UsuarioRepository) BuscarCliente(ctx context.Context, id string) (*models.Cliente, error) {
+	var cl models.Cliente
+	row := r.db.QueryRow(ctx, "SELECT "+colunasCliente+" FROM clientes WHERE id = $1", id)
+	if err := row.Scan(&cl.ID, &cl.Nome, &cl.Email, &cl.Senha, &cl.Telefone, &cl.FotoURL, &cl.CriadoEm); err != nil {
+		return nil, erroBusca(err)
+	}
+	return &cl, nil
+}
+
+func (r *UsuarioRepository) SalvarCliente(ctx context.Context, cl models.Cliente) error {
+	_, err := r.db.Exec(ctx, `INSERT INTO clientes (`+colunasCliente+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (id) DO UPDATE SET
+			nome = EXCLUDED.nome,
+			email = EXCLUDED.email,
+			senha = EXCLUDED.senha,
+			telefone = EXCLUDED.telefone,
+			foto_url = EXCLUDED.foto_url,
+			criado_em = EXCLUDED.criado_em`,
+		cl.ID, cl.Nome, cl.Email, cl.Senha, cl.Telefone, cl.FotoURL, cl.CriadoEm)
+	return err
+}
+
+func (r *UsuarioRepository) BuscarProfissional(ctx context.Context, id string) (*models.Profissional, error) {
+	var p models.Profissional
+	row := r.db.QueryRow(ctx, "SELECT "+colunasProfissional+" FROM profissionais WHERE id = $1", id)
+	if err := scanProfissional(row, &p); err != nil {
+		return nil, erroBusca(err)
+	}
+	return &p, nil
+}
+
+func (r *UsuarioRepository) ListarProfissionais(ctx context.Context) ([]models.Profissional, error) {
+	return listar(ctx, r.db, scanProfissional, "SELECT "+colunasProfissional+" FROM profissionais ORDER BY nome")
+}
+
+func (r *UsuarioRepository) SalvarProfissional(ctx context.Context, p models.Profissional) error {
+	_, err := r.db.Exec(ctx, `INSERT INTO profissionais (`+colunasProfissional+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (id) DO UPDATE SET
+			nome = EXCLUDED.nome,
+			email = EXCLUDED.email,
+			senha = EXCLUDED.senha,
+			telefone = EXCLUDED.telefone,
+			imagem_url = EXCLUDED.imagem_url,
+			estabelecimento_id = EXCLUDED.estabelecimento_id,
+			criado_em = EXCLUDED.criado_em`,
+		p.ID, p.Nome, p.Email, p.Senha, p.Telefone, p.ImagemURL, p.EstabelecimentoID, p.CriadoEm)
+	return err
+}
+
+func (r *UsuarioRepository) DefinirEstabelecimentoProfissional(ctx context.Context, profissionalID, estabelecimentoID string) error {
+	return exigirLinha(r.db.Exec(ctx, "UPDATE profissionais SET estabelecimento_id = $2 WHERE id = $1",
+		profissionalID, estabelecimentoID))
+}
+
+func (r *UsuarioRepository) AtualizarImagemProfissional(ctx context.Context, id, url string) error {
+	return exigirLinha(r.db.Exec(ctx, "UPDATE profissionais SET imagem_url = $2 WHERE id = $1", id, url))
+}
+
+func (r *UsuarioRepository) BuscarAdmin(ctx context.Context, id string) (*models.Admin, error) {
+	var a models.Admin
+	row := r.db.QueryRow(ctx, "SELECT "+colunasAdmin+" FROM admin WHERE id = $1", id)
+	if err := scanAdmin(row, &a); err != nil {
+		return nil, erroBusca(err)
+	}
+	return &a, nil
+}
+
+func (r *UsuarioRepository) ListarAdmins(ctx context.Context) ([]models.Admin, error) {
+	return listar(ctx, r.db, scanAdmin, "SELECT "+colunasAdmin+" FROM admin ORDER BY nome")
+}
+
+func (r *UsuarioRepository) SalvarAdmin(ctx context.Context, a models.Admin) error {
+	_, err := r.db.Exec(ctx, `INSERT INTO admin (`+colunasAdmin+`)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (id) DO UPDATE SET
+			nome = EXCLUDED.nome,
+			email = EXCLUDED.email,
+			senha = EXCLUDED.senha,
+			criado_em = EXCLUDED.criado_em`,
+		a.ID, a.Nome, a.Email, a.Senha, a.CriadoEm)
+	return err
+}
+
+func (r *UsuarioRepository) ExcluirAdmin(ctx context.Context, id string) error {
+	_, err := r.db.Exec(ctx, "DELETE FROM admin WHERE id = $1", id)
+	return err
+}
@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"context"
+	"servico-api/models"
+	"servico-api/relatorios"
+	"time"
+)
+
+// ResumoFaturamento é o total faturado por um conjunto de agendamentos. Nos resultados
+// agrupados, Chave identifica o grupo (veja relatorios.Agrupamento.Chave); no agrupamento
+// por procedimento, Rotulo traz o nome do procedimento na reserva mais recente.
+type ResumoFaturamento struct {
+	Chave      string
+	Rotulo     string
+	Quantidade int     // agendamentos considerados
+	Total      float64 // soma dos valores (preços dos procedimentos ou taxas)
+}
+
+// RelatorioRepository calcula os agregados usados pelos relatórios. Backends com
+// suporte a consultas agregadas (como o PostgreSQL) resolvem tudo no banco. Os métodos
+// agrupam os agendamentos conforme agrupar, com datas no fuso loc; sem agrupamento
+// devolvem um único resumo, ou nenhum se não houver agendamentos.
+type RelatorioRepository interface {
+	// Faturamento soma o preço copiado na reserva de cada agendamento que atende ao filtro.
+	// Sem filtro de status, Quantidade conta todos os agendamentos do período.
+	Faturamento(ctx context.Context, filtro FiltroAgendamento, agrupar relatorios.Agrupamento, loc *time.Location) ([]ResumoFaturamento, error)
+	// Taxas soma as taxas de cancelamento tardio e não comparecimento dos agendamentos do filtro
+	Taxas(ctx context.Context, filtro FiltroAgendamento, agrupar relatorios.Agrupamento, loc *time.Location) ([]ResumoFaturamento, error)
+}
+
+// CalcularFaturamento soma o preço de cada agendamento no momento da reserva, de modo que
+// mudar o preço ou o nome do procedimento não reescreve o faturamento passado. É a
+// implementação em memória usada pelos backends sem agregação.
+func CalcularFaturamento(agendamentos []models.Agendamento, agrupar relatorios.Agrupamento, loc *time.Location) []ResumoFaturamento {
+	return somar(agendamentos, agrupar, loc, func(ag models.Agendamento) (float64, bool) {
+		return ag.Preco, true
+	})
+}
+
+// SomarTaxas totaliza as taxas cobradas nos agendamentos; Quantidade conta só os que têm taxa
+func SomarTaxas(agendamentos []models.Agendamento, agrupar relatorios.Agrupamento, loc *time.Location) []ResumoFaturamento {
+	return somar(agendamentos, agrupar, loc, func(ag models.Agendamento) (float64, bool) {
+		if ag.Taxa == nil {
+			return 0, false
+		}
+		return ag.Taxa.Valor, true
+	})
+}
+
+// somar agrupa os agendamentos com valor, na ordem em que aparecem. Como Listar ordena por
+// data, o rótulo de cada procedimento fica sendo o nome da reserva mais recente.
+func somar(agendamentos []models.Agendamento, agrupar relatorios.Agrupamento, loc *time.Location, valor func(models.Agendamento) (float64, bool)) []ResumoFaturamento {
+	var resumos []ResumoFaturamento
+	indice := make(map[string]int)
+	for _, ag := range agendamentos {
+		v, ok := valor(ag)
+		if !ok {
+			continue
+		}
+		chave, rotulo := ChaveAgendamento(ag, agrupar, loc)
+		i, existe := indice[chave]
+		if !existe {
+			i = len(resumos)
+			indice[chave] = i
+			resumos = append(resumos, ResumoFaturamento{Chave: chave})
+		}
+		resumos[i].Rotulo = rotulo
+		resumos[i].Quantidade++
+		resumos[i].Total += v
+	}
+	return resumos
+}
+
+// ChaveAgendamento devolve o grupo do agendamento no agrupamento pedido. Procedimentos são
+// agrupados pelo ID ou, nas reservas anteriores ao ID, pelo nome.
+func ChaveAgendamento(ag models.Agendamento, agrupar relatorios.Agrupamento, loc *time.Location) (chave, rotulo string) {
+	switch agrupar {
+	case relatorios.AgruparProcedimento:
+		if ag.ProcedimentoID == "" {
+			return ag.Procedimento, ag.Procedimento
+		}
+		return ag.ProcedimentoID, ag.Procedimento
+	case relatorios.AgruparProfissional:
+		return ag.ProfissionalID, ""
+	}
+	return agrupar.Chave(ag.DataHora.In(loc)), ""
+}
@@ -0,0 +1,253 @@
+// Package storage define os contratos de persistência usados pelos handlers,
+// desacoplando a regra de negócio do banco de dados utilizado.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"servico-api/models"
+	"time"
+)
+
+// ErrNaoEncontrado é devolvido quando o registro buscado não existe
+var ErrNaoEncontrado = errors.New("registro não encontrado")
+
+// ErrJaExiste é devolvido ao criar um registro cujo ID já está em uso
+var ErrJaExiste = errors.New("registro já existe")
+
+// ExcecaoNoPeriodo indica se a exceção vale em algum dia entre de e ate (AAAA-MM-DD,
+// inclusivos; vazios não filtram). Usada pelos backends que filtram na aplicação.
+func ExcecaoNoPeriodo(e models.ExcecaoHorario, de, ate string) bool {
+	return (de == "" || e.DataFim >= de) && (ate == "" || e.DataInicio <= ate)
+}
+
+// ConflitoAgendamento é devolvido por AgendamentoRepository.Reservar quando o horário
+// pedido se sobrepõe a outro agendamento do mesmo profissional
+type ConflitoAgendamento struct {
+	Inicio time.Time
+	Fim    time.Time
+}
+
+func (e *ConflitoAgendamento) Error() string {
+	return fmt.Sprintf("horário conflita com o agendamento de %s a %s",
+		e.Inicio.Format(time.RFC3339), e.Fim.Format(time.RFC3339))
+}
+
+// TurnoSobreposto é devolvido por HorarioRepository.SalvarTurnos quando um turno novo
+// cruza outro já cadastrado do mesmo profissional
+type TurnoSobreposto struct {
+	Existente models.Horario
+}
+
+func (e *TurnoSobreposto) Error() string {
+	return fmt.Sprintf("turno sobreposto ao de %s a %s", e.Existente.HoraInicio, e.Existente.HoraFim)
+}
+
+// BuscarSobreposicao devolve o primeiro turno, entre os existentes e os anteriores da
+// própria lista, que se sobrepõe a algum dos novos. O próprio registro, numa edição, é
+// ignorado.
+func BuscarSobreposicao(existentes, novos []models.Horario) *TurnoSobreposto {
+	for i, novo := range novos {
+		for _, lista := range [][]models.Horario{existentes, novos[:i]} {
+			for _, outro := range lista {
+				if outro.ID != novo.ID && novo.Sobrepoe(outro) {
+					return &TurnoSobreposto{Existente: outro}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// JanelaConflito é quanto antes do início de um novo agendamento é preciso procurar
+// atendimentos que ainda possam estar em andamento. Nenhum procedimento dura mais que isso.
+const JanelaConflito = 24 * time.Hour
+
+// BuscarConflito devolve o primeiro agendamento existente que se sobrepõe ao novo.
+// Cancelados e faltas não ocupam a agenda.
+func BuscarConflito(existentes []models.Agendamento, novo models.Agendamento) *ConflitoAgendamento {
+	for _, ag := range existentes {
+		if ag.ID != novo.ID && ag.ProfissionalID == novo.ProfissionalID && ag.Ocupa() && ag.Sobrepoe(novo) {
+			return &ConflitoAgendamento{Inicio: ag.DataHora, Fim: ag.Fim()}
+		}
+	}
+	return nil
+}
+
+// MudouIntervalo indica se uma atualização exige nova verificação de conflitos
+func MudouIntervalo(antes, depois models.Agendamento) bool {
+	return depois.Ocupa() && (!antes.DataHora.Equal(depois.DataHora) || antes.DuracaoMin != depois.DuracaoMin)
+}
+
+// Repositorios agrupa as implementações injetadas nos handlers
+type Repositorios struct {
+	Agendamentos     AgendamentoRepository
+	Horarios         HorarioRepository
+	Excecoes         ExcecaoHorarioRepository
+	Procedimentos    ProcedimentoRepository
+	Estabelecimentos EstabelecimentoRepository
+	Usuarios         UsuarioRepository
+	Notificacoes     NotificacaoRepository
+	Avaliacoes       AvaliacaoRepository
+	Relatorios       RelatorioRepository
+}
+
+// FiltroAgendamento restringe a listagem de agendamentos. Campos vazios não filtram;
+// De e Ate são inclusivos e ignorados quando zero.
+type FiltroAgendamento struct {
+	ClienteID         string
+	ProfissionalID    string
+	EstabelecimentoID string
+	De                time.Time
+	Ate               time.Time
+	Status            string // agendamentos sem status contam como pendentes
+}
+
+// AgendamentoRepository persiste os agendamentos entre clientes e profissionais
+type AgendamentoRepository interface {
+	Criar(ctx context.Context, ag models.Agendamento) error
+	// Reservar cria o agendamento somente se seu intervalo não se sobrepõe a outro do
+	// mesmo profissional. A verificação e a gravação são atômicas, então reservas
+	// concorrentes do mesmo horário não podem ambas ter sucesso. Em caso de conflito
+	// devolve *ConflitoAgendamento.
+	Reservar(ctx context.Context, ag models.Agendamento) error
+	Buscar(ctx context.Context, id string) (*models.Agendamento, error)
+	// Atualizar aplica "alterar" ao agendamento atomicamente e devolve o resultado. Se o
+	// horário ou a duração mudarem, verifica conflitos como Reservar. Um erro devolvido
+	// por "alterar" cancela a atualização e é repassado a quem chamou.
+	Atualizar(ctx context.Context, id string, alterar func(ag *models.Agendamento) error) (*models.Agendamento, error)
+	// Listar devolve os agendamentos que atendem ao filtro, ordenados por data e hora
+	Listar(ctx context.Context, filtro FiltroAgendamento) ([]models.Agendamento, error)
+	// Percorrer entrega a visitar, um por vez e na ordem de Listar, os agendamentos que
+	// atendem ao filtro, sem carregar todos na memória; serve às exportações. Um erro de
+	// visitar interrompe a leitura e é devolvido.
+	Percorrer(ctx context.Context, filtro FiltroAgendamento, visitar func(models.Agendamento) error) error
+}
+
+// HorarioRepository persiste os horários de atendimento dos profissionais
+type HorarioRepository interface {
+	Salvar(ctx context.Context, h models.Horario) error
+	// SalvarTurnos grava os turnos de um profissional somente se nenhum deles se sobrepõe
+	// a outro turno dele. Como em AgendamentoRepository.Reservar, verificação e gravação
+	// são atômicas; em caso de sobreposição devolve *TurnoSobreposto.
+	SalvarTurnos(ctx context.Context, profissionalID string, turnos []models.Horario) error
+	Buscar(ctx context.Context, id string) (*models.Horario, error)
+	Excluir(ctx context.Context, id string) error
+	ListarPorProfissional(ctx context.Context, profissionalID string) ([]models.Horario, error)
+	ListarPorDia(ctx context.Context, profissionalID string, diaSemana models.DiaSemana) ([]models.Horario, error)
+}
+
+// ExcecaoHorarioRepository persiste as folgas, feriados e horários extras dos profissionais
+type ExcecaoHorarioRepository interface {
+	Salvar(ctx context.Context, e models.ExcecaoHorario) error
+	Buscar(ctx context.Context, id string) (*models.ExcecaoHorario, error)
+	Excluir(ctx context.Context, id string) error
+	// ListarPorProfissional devolve as exceções que valem em algum dia entre de e ate
+	// (AAAA-MM-DD, inclusivos), ordenadas pela data inicial. Limites vazios não filtram.
+	ListarPorProfissional(ctx context.Context, profissionalID, de, ate string) ([]models.ExcecaoHorario, error)
+}
+
+// ProcedimentoRepository persiste os procedimentos oferecidos pelos profissionais
+type ProcedimentoRepository interface {
+	Salvar(ctx context.Context, p models.Procedimento) error
+	Buscar(ctx context.Context, id string) (*models.Procedimento, error)
+	BuscarPorNome(ctx context.Context, profissionalID, nome string) (*models.Procedimento, error)
+	Listar(ctx context.Context) ([]models.Procedimento, error)
+	ListarPorProfissional(ctx context.Context, profissionalID string) ([]models.Procedimento, error)
+	Excluir(ctx context.Context, id string) error
+	AtualizarImagem(ctx context.Context, id, url string) error
+}
+
+// EstabelecimentoRepository persiste os estabelecimentos e os vínculos com profissionais
+type EstabelecimentoRepository interface {
+	Salvar(ctx context.Context, e models.Estabelecimento) error
+	Buscar(ctx context.Context, id string) (*models.Estabelecimento, error)
+	Listar(ctx context.Context) ([]models.Estabelecimento, error)
+	VincularProfissional(ctx context.Context, estabelecimentoID string, v models.ProfissionalEstabelecimento) error
+	DesvincularProfissional(ctx context.Context, estabelecimentoID, profissionalID string) error
+	ListarProfissionais(ctx context.Context, estabelecimentoID string) ([]models.ProfissionalEstabelecimento, error)
+}
+
+// UsuarioRepository persiste clientes, profissionais e admins. O tipo corresponde
+// às coleções "clientes", "profissionais" e "admin".
+type UsuarioRepository interface {
+	// BuscarPorEmail devolve o usuário do tipo informado com ID e Tipo preenchidos
+	BuscarPorEmail(ctx context.Context, tipo, email string) (*models.Usuario, error)
+	BuscarUsuario(ctx context.Context, tipo, id string) (*models.Usuario, error)
+	CriarUsuario(ctx context.Context, u models.Usuario) error
+
+	BuscarCliente(ctx context.Context, id string) (*models.Cliente, error)
+	SalvarCliente(ctx context.Context, cl models.Cliente) error
+
+	BuscarProfissional(ctx context.Context, id string) (*models.Profissional, error)
+	ListarProfissionais(ctx context.Context) ([]models.Profissional, error)
+	SalvarProfissional(ctx context.Context, p models.Profissional) error
+	DefinirEstabelecimentoProfissional(ctx context.Context, profissionalID, estabelecimentoID string) error
+	AtualizarImagemProfissional(ctx context.Context, id, url string) error
+
+	BuscarAdmin(ctx context.Context, id string) (*models.Admin, error)
+	ListarAdmins(ctx context.Context) ([]models.Admin, error)
+	SalvarAdmin(ctx context.Context, a models.Admin) error
+	ExcluirAdmin(ctx context.Context, id string) error
+}
+
+// NotificacaoRepository persiste as notificações, como convites de estabelecimentos
+type NotificacaoRepository interface {
+	Criar(ctx context.Context, n models.Notificacao) error
+	Buscar(ctx context.Context, id string) (*models.Notificacao, error)
+	Responder(ctx context.Context, id, resposta string) error
+	ListarConvitesPendentes(ctx context.Context, paraUID string) ([]models.Notificacao, error)
+}
+
+// AvaliacaoRepository persiste as avaliações feitas pelos clientes e os resumos de notas
+// de cada profissional e estabelecimento. Criar devolve ErrJaExiste se o agendamento já
+// foi avaliado. Atualizar aplica alterar à versão atual de forma atômica, como em
+// AgendamentoRepository. Criar e Atualizar ajustam, na mesma transação, os resumos de
+// AlvosDaAvaliacao, mas só quando VariacaoResumo indica mudança.
+type AvaliacaoRepository interface {
+	Criar(ctx context.Context, a models.Avaliacao) error
+	Buscar(ctx context.Context, id string) (*models.Avaliacao, error)
+	Atualizar(ctx context.Context, id string, alterar func(a *models.Avaliacao) error) (*models.Avaliacao, error)
+	ListarEmModeracao(ctx context.Context) ([]models.Avaliacao, error)
+	ListarPorProfissional(ctx context.Context, profissionalID string) ([]models.Avaliacao, error)
+	ListarPorEstabelecimento(ctx context.Context, estabelecimentoID string) ([]models.Avaliacao, error)
+
+	// BuscarResumo devolve o resumo do alvo, zerado se ele não tiver avaliações. Com id
+	// vazio devolve o resumo geral do tipo de alvo.
+	BuscarResumo(ctx context.Context, alvo, id string) (models.ResumoAvaliacoes, error)
+	// BuscarResumos devolve, de uma vez, os resumos dos ids que têm avaliações
+	BuscarResumos(ctx context.Context, alvo string, ids []string) (map[string]models.ResumoAvaliacoes, error)
+}
+
+// AlvoResumo identifica um resumo de avaliações; ID vazio é o resumo geral do tipo de alvo
+type AlvoResumo struct {
+	Alvo string
+	ID   string
+}
+
+// VariacaoResumo é o quanto a mudança de uma avaliação de antes para depois altera os
+// resumos; mudou é falso quando nota e visibilidade continuam as mesmas, como em
+// respostas e denúncias, e então os resumos não precisam ser tocados
+func VariacaoResumo(antes, depois *models.Avaliacao) (v models.ResumoAvaliacoes, mudou bool) {
+	v.Aplicar(antes, depois)
+	if v.Quantidade != 0 || v.Soma != 0 {
+		return v, true
+	}
+	for _, n := range v.Histograma {
+		if n != 0 {
+			return v, true
+		}
+	}
+	return v, false
+}
+
+// AlvosDaAvaliacao lista os resumos afetados por uma avaliação, sempre na mesma ordem
+// para que transações concorrentes os bloqueiem sem deadlock
+func AlvosDaAvaliacao(a models.Avaliacao) []AlvoResumo {
+	alvos := []AlvoResumo{{models.AlvoProfissional, a.ProfissionalID}, {models.AlvoProfissional, ""}}
+	if a.EstabelecimentoID != "" {
+		alvos = append(alvos, AlvoResumo{models.AlvoEstabelecimento, a.EstabelecimentoID}, AlvoResumo{models.AlvoEstabelecimento, ""})
+	}
+	return alvos
+}
@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"servico-api/models"
+	"testing"
+)
+
+func TestVariacaoResumo(t *testing.T) {
+	publicada := models.Avaliacao{ID: "a", Nota: 4}
+	respondida := publicada
+	respondida.Resposta = &models.RespostaAvaliacao{Texto: "Obrigado!"}
+	editada := publicada
+	editada.Nota = 2
+	oculta := publicada
+	oculta.Situacao = models.SituacaoOculta
+
+	casos := []struct {
+		nome          string
+		antes, depois *models.Avaliacao
+		mudou         bool
+		quantidade    int
+		soma          float64
+	}{
+		{"nova avaliação", nil, &publicada, true, 1, 4},
+		{"resposta do profissional", &publicada, &respondida, false, 0, 0},
+		{"nota editada", &publicada, &editada, true, 0, -2},
+		{"ocultada pela moderação", &publicada, &oculta, true, -1, -4},
+		{"oculta continua oculta", &oculta, &oculta, false, 0, 0},
+	}
+	for _, c := range casos {
+		t.Run(c.nome, func(t *testing.T) {
+			v, mudou := VariacaoResumo(c.antes, c.depois)
+			if mudou != c.mudou || v.Quantidade != c.quantidade || v.Soma != c.soma {
+				t.Fatalf("variação inesperada: mudou=%v %+v", mudou, v)
+			}
+		})
+	}
+}
@@ -1,28 +1,38 @@
 package utils
 
 import (
-	"context"
+	"errors"
 	"net/http"
-	"servico-api/config"
 	"servico-api/models"
+	"servico-api/storage"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
-// Login realiza autenticação simples via Firestore
+// Auth reúne os handlers de autenticação e cadastro
+type Auth struct {
+	usuarios storage.UsuarioRepository
+}
+
+// NovoAuth cria os handlers de autenticação sobre o repositório de usuários
+func NovoAuth(usuarios storage.UsuarioRepository) *Auth {
+	return &Auth{usuarios: usuarios}
+}
+
+// Login autentica o usuário e emite um token de acesso assinado
 // @Summary Login de usuário
 // @Description Autentica um usuário com email e senha
 // @Tags Autenticação
 // @Accept json
 // @Produce json
 // @Param credenciais body models.Login true "Email e Senha"
-// @Success 200 {object} models.Usuario
+// @Success 200 {object} map[string]interface{} "mensagem, token e usuario"
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /login [post]
-func Login(c *gin.Context) {
+func (a *Auth) Login(c *gin.Context) {
 	var credenciais struct {
 		Email string `json:"email"`
 		Senha string `json:"senha"`
@@ -32,32 +42,35 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	ctx := context.Background()
-	client, err := config.App.Firestore(ctx)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao conectar com Firestore"})
-		return
-	}
-	defer client.Close()
+	ctx := c.Request.Context()
 
 	colecoes := []string{"clientes", "profissionais", "admin"}
 	for _, colecao := range colecoes {
-		docs, err := client.Collection(colecao).
-			Where("email", "==", credenciais.Email).
-			Where("senha", "==", credenciais.Senha).
-			Limit(1).Documents(ctx).GetAll()
-
-		if err == nil && len(docs) > 0 {
-			var usuario models.Usuario
-			if err := docs[0].DataTo(&usuario); err == nil {
-				usuario.Tipo = colecao
-				c.JSON(http.StatusOK, gin.H{
-					"mensagem": "Login realizado com sucesso",
-					"usuario":  usuario,
-				})
-				return
-			}
+		usuario, err := a.usuarios.BuscarPorEmail(ctx, colecao, credenciais.Email)
+		if errors.Is(err, storage.ErrNaoEncontrado) {
+			continue
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao buscar usuário"})
+			return
+		}
+		if !VerificarSenha(usuario.Senha, credenciais.Senha) {
+			continue
 		}
+		usuario.Senha = ""
+
+		token, err := GerarToken(usuario.ID, usuario.Tipo)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao gerar token de acesso"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"mensagem": "Login realizado com sucesso",
+			"token":    token,
+			"usuario":  usuario,
+		})
+		return
 	}
 
 	c.JSON(http.StatusUnauthorized, gin.H{"error": "Email ou senha inválidos"})
@@ -65,7 +78,7 @@ func Login(c *gin.Context) {
 
 // CadastrarUsuario cria um novo cliente ou profissional
 // @Summary Cadastro de usuário
-// @Description Cadastra novo cliente ou profissional no Firestore
+// @Description Cadastra novo cliente ou profissional
 // @Tags Autenticação
 // @Accept json
 // @Produce json
@@ -75,7 +88,7 @@ func Login(c *gin.Context) {
 // @Failure 409 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /cadastro [post]
-func CadastrarUsuario(c *gin.Context) {
+func (a *Auth) CadastrarUsuario(c *gin.Context) {
 	var novoUsuario models.Usuario
 
 	if err := c.ShouldBindJSON(&novoUsuario); err != nil {
@@ -83,34 +96,44 @@ func CadastrarUsuario(c *gin.Context) {
 		return
 	}
 
-	if novoUsuario.Tipo != "clientes" && novoUsuario.Tipo != "profissionais" && novoUsuario.Tipo != "admin" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Tipo inválido (use 'clientes', 'admin' ou 'profissionais')"})
+	// Admins só são criados por outro admin em POST /api/admins
+	if novoUsuario.Tipo != "clientes" && novoUsuario.Tipo != "profissionais" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tipo inválido (use 'clientes' ou 'profissionais')"})
 		return
 	}
 
-	ctx := context.Background()
-	client, err := config.App.Firestore(ctx)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao conectar com Firestore"})
+	ctx := c.Request.Context()
+
+	_, err := a.usuarios.BuscarPorEmail(ctx, novoUsuario.Tipo, novoUsuario.Email)
+	if err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email já cadastrado"})
+		return
+	}
+	if !errors.Is(err, storage.ErrNaoEncontrado) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao verificar email"})
 		return
 	}
-	defer client.Close()
 
-	exists, err := client.Collection(novoUsuario.Tipo).
-		Where("email", "==", novoUsuario.Email).
-		Limit(1).Documents(ctx).GetAll()
-	if err == nil && len(exists) > 0 {
-		c.JSON(http.StatusConflict, gin.H{"error": "Email já cadastrado"})
+	if novoUsuario.Senha == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Senha é obrigatória"})
+		return
+	}
+
+	hash, err := HashSenha(novoUsuario.Senha)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao processar senha"})
 		return
 	}
 
 	novoUsuario.ID = uuid.New().String()
+	novoUsuario.Senha = hash
+	novoUsuario.EstabelecimentoID = "" // o vínculo vem do aceite de um convite
 
-	_, err = client.Collection(novoUsuario.Tipo).Doc(novoUsuario.ID).Set(ctx, novoUsuario)
-	if err != nil {
+	if err := a.usuarios.CriarUsuario(ctx, novoUsuario); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Erro ao salvar usuário"})
 		return
 	}
 
+	novoUsuario.Senha = ""
 	c.JSON(http.StatusCreated, gin.H{"mensagem": "Usuário cadastrado com sucesso", "usuario": novoUsuario})
 }
@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AutenticacaoMiddleware exige um token Bearer válido e injeta "uid" e "tipo" no contexto
+func AutenticacaoMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		tokenStr, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok || strings.TrimSpace(tokenStr) == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Token de acesso ausente"})
+			return
+		}
+
+		claims, err := ValidarToken(strings.TrimSpace(tokenStr))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Token de acesso inválido ou expirado"})
+			return
+		}
+
+		c.Set("uid", claims.UID)
+		c.Set("tipo", claims.Tipo)
+		c.Next()
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 	"servico-api/config"
 	"servico-api/models"
+	"servico-api/utils"
 	"time"
 
 	"github.com/google/uuid"
@@ -27,6 +28,11 @@ func SeedFirestore() {
 		}
 	}
 
+	senhaPadrao, err := utils.HashSenha("123456")
+	if err != nil {
+		log.Fatalf("Erro ao gerar hash da senha: %v", err)
+	}
+
 	// Cria um estabelecimento
 	estabID := uuid.New().String()
 	estabelecimento := models.Estabelecimento{
@@ -50,7 +56,7 @@ func SeedFirestore() {
 		ID:                profID,
 		Nome:              "Maria Silva",
 		Email:             "maria@exemplo.com",
-		Senha:             "123456",
+		Senha:             senhaPadrao,
 		ImagemURL:         "https://exemplo.com/maria.jpg",
 		Telefone:          "(34) 99999-9999",
 		EstabelecimentoID: estabID,
@@ -64,7 +70,7 @@ func SeedFirestore() {
 		ID:       clienteID,
 		Nome:     "João Cliente",
 		Email:    "joao@cliente.com",
-		Senha:    "123456",
+		Senha:    senhaPadrao,
 		Telefone: "(34) 98888-7777",
 		FotoURL:  "https://exemplo.com/joao.jpg",
 		CriadoEm: time.Now(),
@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// HashSenha gera o hash bcrypt de uma senha em texto puro
+func HashSenha(senha string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(senha), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// VerificarSenha compara uma senha em texto puro com o hash armazenado
+func VerificarSenha(hash, senha string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(senha)) == nil
+}
+
+// SenhaEhHash indica se o valor armazenado já é um hash bcrypt
+func SenhaEhHash(valor string) bool {
+	if len(valor) != 60 {
+		return false
+	}
+	return strings.HasPrefix(valor, "$2a$") || strings.HasPrefix(valor, "$2b$") || strings.HasPrefix(valor, "$2y$")
+}
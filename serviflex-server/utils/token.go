@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"errors"
+	"servico-api/config"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ClaimsUsuario são os dados carregados no token de acesso
+type ClaimsUsuario struct {
+	UID  string `json:"uid"`
+	Tipo string `json:"tipo"` // clientes | profissionais | admin
+	jwt.RegisteredClaims
+}
+
+// GerarToken emite um token de acesso assinado para o usuário
+func GerarToken(uid, tipo string) (string, error) {
+	agora := time.Now()
+	claims := ClaimsUsuario{
+		UID:  uid,
+		Tipo: tipo,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   uid,
+			IssuedAt:  jwt.NewNumericDate(agora),
+			ExpiresAt: jwt.NewNumericDate(agora.Add(config.JWTDuracao)),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(config.JWTSecret)
+}
+
+// ValidarToken verifica a assinatura e a validade de um token e devolve seus claims
+func ValidarToken(tokenStr string) (*ClaimsUsuario, error) {
+	claims := &ClaimsUsuario{}
+	token, err := jwt.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (interface{}, error) {
+		return config.JWTSecret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid || claims.UID == "" {
+		return nil, errors.New("token inválido")
+	}
+	return claims, nil
+}